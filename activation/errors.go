@@ -0,0 +1,9 @@
+package activation
+
+import "errors"
+
+// ErrUnsupportedObjective is returned when an XGBoost objective has no matching activation.
+var ErrUnsupportedObjective = errors.New("unsupported objective")
+
+// ErrUnknownActivation is returned when no activation is registered under a name.
+var ErrUnknownActivation = errors.New("unknown activation")
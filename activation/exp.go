@@ -0,0 +1,36 @@
+package activation
+
+import (
+	"math"
+
+	"github.com/lordberre/xgboost-go/mat"
+	"github.com/lordberre/xgboost-go/protobuf"
+)
+
+// Exp is struct contains necessary data for applying the exponential inverse link of log link objectives such
+// as XGBoost count:poisson, whose raw predictions are log rates, or survival:cox, whose predictions are hazard
+// ratios, for now is empty.
+type Exp struct{}
+
+// exp applies the exponential function to each raw prediction.
+func exp(rawPredictions mat.Vector) mat.Vector {
+	for i, v := range rawPredictions {
+		rawPredictions[i] = math.Exp(v)
+	}
+	return rawPredictions
+}
+
+// Transform passes each raw prediction through the exponential function.
+func (a *Exp) Transform(rawPredictions mat.Vector) (mat.Vector, error) {
+	return exp(rawPredictions), nil
+}
+
+// Type returns activation type.
+func (a *Exp) Type() protobuf.ActivateType {
+	return protobuf.ActivateType_EXP
+}
+
+// Name returns activation name.
+func (a *Exp) Name() string {
+	return protobuf.ActivateType_name[int32(protobuf.ActivateType_EXP)]
+}
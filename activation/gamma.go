@@ -0,0 +1,25 @@
+package activation
+
+import (
+	"github.com/lordberre/xgboost-go/mat"
+	"github.com/lordberre/xgboost-go/protobuf"
+)
+
+// Gamma is struct contains necessary data for predicting the mean of XGBoost reg:gamma models, which use a log
+// link like count:poisson, for now is empty.
+type Gamma struct{}
+
+// Transform passes each raw prediction through the exponential function.
+func (a *Gamma) Transform(rawPredictions mat.Vector) (mat.Vector, error) {
+	return exp(rawPredictions), nil
+}
+
+// Type returns activation type.
+func (a *Gamma) Type() protobuf.ActivateType {
+	return protobuf.ActivateType_GAMMA
+}
+
+// Name returns activation name.
+func (a *Gamma) Name() string {
+	return protobuf.ActivateType_name[int32(protobuf.ActivateType_GAMMA)]
+}
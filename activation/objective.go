@@ -0,0 +1,93 @@
+package activation
+
+import (
+	"fmt"
+
+	"github.com/lordberre/xgboost-go/protobuf"
+)
+
+// objectiveTypes maps XGBoost objectives to the activation type applied to their raw predictions.
+var objectiveTypes = map[string]protobuf.ActivateType{
+	"binary:logistic":      protobuf.ActivateType_LOGISTIC,
+	"binary:logitraw":      protobuf.ActivateType_RAW,
+	"reg:logistic":         protobuf.ActivateType_LOGISTIC,
+	"reg:linear":           protobuf.ActivateType_RAW,
+	"reg:squarederror":     protobuf.ActivateType_RAW,
+	"reg:squaredlogerror":  protobuf.ActivateType_RAW,
+	"reg:pseudohubererror": protobuf.ActivateType_RAW,
+	"reg:absoluteerror":    protobuf.ActivateType_RAW,
+	"reg:quantileerror":    protobuf.ActivateType_RAW,
+	"count:poisson":        protobuf.ActivateType_EXP,
+	"reg:gamma":            protobuf.ActivateType_GAMMA,
+	"reg:tweedie":          protobuf.ActivateType_TWEEDIE,
+	"survival:cox":         protobuf.ActivateType_EXP,
+	"survival:aft":         protobuf.ActivateType_SURVIVAL_AFT,
+	"multi:softprob":       protobuf.ActivateType_SOFTMAX,
+	"multi:softmax":        protobuf.ActivateType_SOFTMAX_CLASS,
+	"rank:pairwise":        protobuf.ActivateType_RAW,
+	"rank:ndcg":            protobuf.ActivateType_RAW,
+	"rank:map":             protobuf.ActivateType_RAW,
+}
+
+// ObjectiveType returns the activation type matching an XGBoost objective, or the type of the activation
+// registered under the objective name.
+func ObjectiveType(objective string) (protobuf.ActivateType, error) {
+	t, ok := objectiveTypes[objective]
+	if !ok {
+		a, err := ByName(objective)
+		if err != nil {
+			return protobuf.ActivateType_UNKNOWN, fmt.Errorf("%w %s", ErrUnsupportedObjective, objective)
+		}
+		return a.Type(), nil
+	}
+	return t, nil
+}
+
+// CheckObjective returns an error if activation a does not match the XGBoost objective.
+func CheckObjective(objective string, a Activation) error {
+	t, err := ObjectiveType(objective)
+	if err != nil {
+		return err
+	}
+	if a == nil {
+		return fmt.Errorf("no activation for objective %s", objective)
+	}
+	if a.Type() != t {
+		return fmt.Errorf("objective %s expects %s activation, got %s", objective,
+			protobuf.ActivateType_name[int32(t)], a.Name())
+	}
+	return nil
+}
+
+// FromObjective returns the activation matching an XGBoost objective, or the activation registered under the
+// objective name for objectives without a built-in activation.
+func FromObjective(objective string) (Activation, error) {
+	if _, ok := objectiveTypes[objective]; !ok {
+		if a, err := ByName(objective); err == nil {
+			return a, nil
+		}
+	}
+	t, err := ObjectiveType(objective)
+	if err != nil {
+		return nil, err
+	}
+	switch t {
+	case protobuf.ActivateType_RAW:
+		return &Raw{}, nil
+	case protobuf.ActivateType_LOGISTIC:
+		return &Logistic{}, nil
+	case protobuf.ActivateType_SOFTMAX:
+		return &Softmax{}, nil
+	case protobuf.ActivateType_SOFTMAX_CLASS:
+		return &SoftmaxClass{}, nil
+	case protobuf.ActivateType_EXP:
+		return &Exp{}, nil
+	case protobuf.ActivateType_GAMMA:
+		return &Gamma{}, nil
+	case protobuf.ActivateType_TWEEDIE:
+		return &Tweedie{VariancePower: DefaultTweedieVariancePower}, nil
+	case protobuf.ActivateType_SURVIVAL_AFT:
+		return &SurvivalAFT{Distribution: DefaultAFTDistribution, Sigma: DefaultAFTSigma}, nil
+	}
+	return nil, fmt.Errorf("%w %s", ErrUnsupportedObjective, objective)
+}
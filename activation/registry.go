@@ -0,0 +1,63 @@
+package activation
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// registry contains activations by name, built-in activations are registered under their type name.
+var (
+	registryMu sync.RWMutex
+	registry   = map[string]Activation{}
+)
+
+func init() {
+	for _, a := range []Activation{&Raw{}, &Logistic{}, &Softmax{}, &SoftmaxClass{}, &Exp{}, &Gamma{},
+		&Tweedie{VariancePower: DefaultTweedieVariancePower},
+		&SurvivalAFT{Distribution: DefaultAFTDistribution, Sigma: DefaultAFTSigma}} {
+		Register(a.Name(), a)
+	}
+}
+
+// Register makes an activation available by name, e.g. a calibrated sigmoid, so that it can be resolved with
+// ByName. Activations registered under an objective name, e.g. "reg:custom", are used by FromObjective when
+// models with this objective are loaded. The same activation is returned to every caller so it must be safe for
+// concurrent use. Register panics if a is nil or if name is empty or already registered.
+func Register(name string, a Activation) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if a == nil {
+		panic("activation: Register activation is nil")
+	}
+	if name == "" {
+		panic("activation: Register name is empty")
+	}
+	if _, dup := registry[name]; dup {
+		panic("activation: Register called twice for " + name)
+	}
+	registry[name] = a
+}
+
+// ByName returns the activation registered under name.
+func ByName(name string) (Activation, error) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	a, ok := registry[name]
+	if !ok {
+		return nil, fmt.Errorf("%w %s", ErrUnknownActivation, name)
+	}
+	return a, nil
+}
+
+// Names returns the sorted names of registered activations.
+func Names() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,35 @@
+package activation
+
+import (
+	"fmt"
+
+	"github.com/lordberre/xgboost-go/mat"
+	"github.com/lordberre/xgboost-go/protobuf"
+)
+
+// SoftmaxClass is struct contains necessary data for predicting the class with the highest softmax
+// probability like XGBoost multi:softmax objective, for now is empty.
+type SoftmaxClass struct{}
+
+// Transform returns a single element vector containing the index of the predicted class.
+func (a *SoftmaxClass) Transform(rawPredictions mat.Vector) (mat.Vector, error) {
+	if len(rawPredictions) == 0 {
+		return mat.Vector{}, fmt.Errorf("prediction should have at least 1 dimension")
+	}
+	// softmax is monotonic so the class with the highest raw score has the highest probability.
+	idx, err := mat.GetVectorMaxIdx(&rawPredictions)
+	if err != nil {
+		return mat.Vector{}, err
+	}
+	return mat.Vector{float64(idx)}, nil
+}
+
+// Type returns activation type.
+func (a *SoftmaxClass) Type() protobuf.ActivateType {
+	return protobuf.ActivateType_SOFTMAX_CLASS
+}
+
+// Name returns activation name.
+func (a *SoftmaxClass) Name() string {
+	return protobuf.ActivateType_name[int32(protobuf.ActivateType_SOFTMAX_CLASS)]
+}
@@ -0,0 +1,37 @@
+package activation
+
+import (
+	"github.com/lordberre/xgboost-go/mat"
+	"github.com/lordberre/xgboost-go/protobuf"
+)
+
+// Defaults of the aft_loss_param of XGBoost survival:aft objective.
+const (
+	DefaultAFTDistribution = "normal"
+	DefaultAFTSigma        = 1.0
+)
+
+// SurvivalAFT is struct contains necessary data for predicting survival times of XGBoost survival:aft models,
+// whose raw predictions are the log of the predicted time.
+type SurvivalAFT struct {
+	// Distribution is the aft_loss_distribution of the model, normal, logistic or extreme.
+	Distribution string
+	// Sigma is the aft_loss_distribution_scale of the model. Like the distribution it does not change predicted
+	// times and is kept as model metadata, e.g. to compute survival probabilities.
+	Sigma float64
+}
+
+// Transform passes each raw prediction through the exponential function.
+func (a *SurvivalAFT) Transform(rawPredictions mat.Vector) (mat.Vector, error) {
+	return exp(rawPredictions), nil
+}
+
+// Type returns activation type.
+func (a *SurvivalAFT) Type() protobuf.ActivateType {
+	return protobuf.ActivateType_SURVIVAL_AFT
+}
+
+// Name returns activation name.
+func (a *SurvivalAFT) Name() string {
+	return protobuf.ActivateType_name[int32(protobuf.ActivateType_SURVIVAL_AFT)]
+}
@@ -0,0 +1,32 @@
+package activation
+
+import (
+	"github.com/lordberre/xgboost-go/mat"
+	"github.com/lordberre/xgboost-go/protobuf"
+)
+
+// DefaultTweedieVariancePower is the tweedie_variance_power XGBoost uses when it is not set.
+const DefaultTweedieVariancePower = 1.5
+
+// Tweedie is struct contains necessary data for predicting the mean of XGBoost reg:tweedie models, which use a
+// log link whatever the variance power.
+type Tweedie struct {
+	// VariancePower is the tweedie_variance_power the model was trained with, in [1, 2). It does not change
+	// predictions and is kept as model metadata, e.g. to compute the deviance of predictions.
+	VariancePower float64
+}
+
+// Transform passes each raw prediction through the exponential function.
+func (a *Tweedie) Transform(rawPredictions mat.Vector) (mat.Vector, error) {
+	return exp(rawPredictions), nil
+}
+
+// Type returns activation type.
+func (a *Tweedie) Type() protobuf.ActivateType {
+	return protobuf.ActivateType_TWEEDIE
+}
+
+// Name returns activation name.
+func (a *Tweedie) Name() string {
+	return protobuf.ActivateType_name[int32(protobuf.ActivateType_TWEEDIE)]
+}
@@ -0,0 +1,73 @@
+package calibration
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/lordberre/xgboost-go/mat"
+)
+
+// Calibrator is an interface that a probability calibrator needs to implement. It is fitted on scores of a
+// validation set, either probabilities or raw margins, and then maps new scores to calibrated probabilities.
+type Calibrator interface {
+	Fit(scores mat.Vector, labels []int) error
+	Transform(scores mat.Vector) (mat.Vector, error)
+	Name() string
+}
+
+// calibrator types used for JSON persistence.
+const (
+	plattName    = "platt"
+	isotonicName = "isotonic"
+)
+
+type calibratorJSON struct {
+	Type   string          `json:"type"`
+	Params json.RawMessage `json:"params"`
+}
+
+func checkFitInput(scores mat.Vector, labels []int) error {
+	if len(scores) != len(labels) {
+		return fmt.Errorf("%w: %d scores but %d labels", mat.ErrDimensionMismatch, len(scores), len(labels))
+	}
+	if len(scores) == 0 {
+		return fmt.Errorf("cannot fit calibrator on empty input")
+	}
+	for i, l := range labels {
+		if l != 0 && l != 1 {
+			return fmt.Errorf("label at index %d must be 0 or 1, got %d", i, l)
+		}
+	}
+	return nil
+}
+
+// WriteCalibratorJSON writes a fitted calibrator to w as JSON.
+func WriteCalibratorJSON(c Calibrator, w io.Writer) error {
+	params, err := json.Marshal(c)
+	if err != nil {
+		return fmt.Errorf("cannot encode %s calibrator: %s", c.Name(), err)
+	}
+	return json.NewEncoder(w).Encode(calibratorJSON{Type: c.Name(), Params: params})
+}
+
+// ReadCalibratorJSON reads a calibrator written by WriteCalibratorJSON.
+func ReadCalibratorJSON(r io.Reader) (Calibrator, error) {
+	var cj calibratorJSON
+	if err := json.NewDecoder(r).Decode(&cj); err != nil {
+		return nil, fmt.Errorf("cannot decode calibrator: %s", err)
+	}
+	var c Calibrator
+	switch cj.Type {
+	case plattName:
+		c = &PlattScaling{}
+	case isotonicName:
+		c = &Isotonic{}
+	default:
+		return nil, fmt.Errorf("unknown calibrator type %q", cj.Type)
+	}
+	if err := json.Unmarshal(cj.Params, c); err != nil {
+		return nil, fmt.Errorf("cannot decode %s calibrator: %s", cj.Type, err)
+	}
+	return c, nil
+}
@@ -0,0 +1,49 @@
+package calibration
+
+import (
+	"bytes"
+	"errors"
+	"math"
+	"math/rand"
+	"testing"
+
+	"gotest.tools/assert"
+
+	"github.com/lordberre/xgboost-go/mat"
+)
+
+func TestCalibration(t *testing.T) {
+	// labels are drawn from p = 1 / (1 + exp(-2*s - 1)).
+	rng := rand.New(rand.NewSource(42))
+	scores := make(mat.Vector, 5000)
+	labels := make([]int, len(scores))
+	for i := range scores {
+		scores[i] = rng.Float64()*6 - 3
+		if rng.Float64() < 1/(1+math.Exp(-2*scores[i]-1)) {
+			labels[i] = 1
+		}
+	}
+	platt := &PlattScaling{}
+	assert.NilError(t, platt.Fit(scores, labels))
+	assert.Check(t, math.Abs(platt.A+2) < 0.2, "a=%f", platt.A)
+	assert.Check(t, math.Abs(platt.B+1) < 0.2, "b=%f", platt.B)
+
+	isotonic := &Isotonic{}
+	assert.NilError(t, isotonic.Fit(mat.Vector{1, 2, 3, 4}, []int{0, 1, 0, 1}))
+	calibrated, err := isotonic.Transform(mat.Vector{0, 1, 2.5, 3.5, 5})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, calibrated, mat.Vector{0, 0, 0.5, 0.75, 1})
+
+	for _, c := range []Calibrator{platt, isotonic} {
+		var buf bytes.Buffer
+		assert.NilError(t, WriteCalibratorJSON(c, &buf))
+		read, err := ReadCalibratorJSON(&buf)
+		assert.NilError(t, err)
+		assert.DeepEqual(t, read, c)
+	}
+
+	err = platt.Fit(mat.Vector{1, 2}, []int{1})
+	assert.Check(t, errors.Is(err, mat.ErrDimensionMismatch))
+	_, err = (&Isotonic{}).Transform(mat.Vector{1})
+	assert.ErrorContains(t, err, "not fitted")
+}
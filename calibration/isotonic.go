@@ -0,0 +1,104 @@
+package calibration
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/lordberre/xgboost-go/mat"
+)
+
+// Isotonic calibrates scores with a non decreasing piecewise linear function fitted by pool adjacent violators.
+// Scores outside of the fitted range are clipped to the closest end.
+type Isotonic struct {
+	X []float64 `json:"x"`
+	Y []float64 `json:"y"`
+}
+
+// Name returns calibrator name.
+func (c *Isotonic) Name() string {
+	return isotonicName
+}
+
+// Fit fits the isotonic regression of labels on scores.
+func (c *Isotonic) Fit(scores mat.Vector, labels []int) error {
+	if err := checkFitInput(scores, labels); err != nil {
+		return err
+	}
+	order := make([]int, len(scores))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return scores[order[i]] < scores[order[j]]
+	})
+
+	// blocks of pooled points, equal scores always share a block.
+	type block struct {
+		x, sum, weight float64
+	}
+	blocks := make([]block, 0, len(scores))
+	for _, i := range order {
+		s, l := scores[i], float64(labels[i])
+		if n := len(blocks); n > 0 && blocks[n-1].x == s {
+			blocks[n-1].sum += l
+			blocks[n-1].weight++
+		} else {
+			blocks = append(blocks, block{x: s, sum: l, weight: 1})
+		}
+	}
+	// pooled blocks keep track of the points they contain so the fitted steps can be expanded afterwards.
+	type pool struct {
+		sum, weight float64
+		start, end  int
+	}
+	pools := make([]pool, 0, len(blocks))
+	for i, b := range blocks {
+		pools = append(pools, pool{sum: b.sum, weight: b.weight, start: i, end: i})
+		for n := len(pools); n > 1 && pools[n-2].sum/pools[n-2].weight >= pools[n-1].sum/pools[n-1].weight; n-- {
+			last := pools[n-1]
+			pools = pools[:n-1]
+			pools[n-2].sum += last.sum
+			pools[n-2].weight += last.weight
+			pools[n-2].end = last.end
+		}
+	}
+
+	c.X = make([]float64, 0, 2*len(pools))
+	c.Y = make([]float64, 0, 2*len(pools))
+	for _, p := range pools {
+		y := p.sum / p.weight
+		c.X = append(c.X, blocks[p.start].x)
+		c.Y = append(c.Y, y)
+		if p.end != p.start {
+			c.X = append(c.X, blocks[p.end].x)
+			c.Y = append(c.Y, y)
+		}
+	}
+	return nil
+}
+
+// Transform returns calibrated probabilities of scores by linear interpolation of the fitted points.
+func (c *Isotonic) Transform(scores mat.Vector) (mat.Vector, error) {
+	if len(c.X) == 0 || len(c.X) != len(c.Y) {
+		return mat.Vector{}, fmt.Errorf("isotonic calibrator is not fitted")
+	}
+	r := make(mat.Vector, len(scores))
+	last := len(c.X) - 1
+	for i, s := range scores {
+		switch {
+		case s <= c.X[0]:
+			r[i] = c.Y[0]
+		case s >= c.X[last]:
+			r[i] = c.Y[last]
+		default:
+			j := sort.SearchFloat64s(c.X, s)
+			if c.X[j] == s {
+				r[i] = c.Y[j]
+				continue
+			}
+			x0, x1 := c.X[j-1], c.X[j]
+			r[i] = c.Y[j-1] + (c.Y[j]-c.Y[j-1])*(s-x0)/(x1-x0)
+		}
+	}
+	return r, nil
+}
@@ -0,0 +1,124 @@
+package calibration
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/lordberre/xgboost-go/mat"
+)
+
+// Platt scaling fitting constants, see Lin, Lin and Weng, A note on Platt's probabilistic outputs for support
+// vector machines.
+const (
+	plattMaxIter = 100
+	plattMinStep = 1e-10
+	plattSigma   = 1e-12
+	plattEps     = 1e-5
+)
+
+// PlattScaling calibrates scores with p = 1 / (1 + exp(A*score + B)). Scores are usually raw margins, e.g. from
+// Ensemble.PredictLogOdds.
+type PlattScaling struct {
+	A float64 `json:"a"`
+	B float64 `json:"b"`
+}
+
+// Name returns calibrator name.
+func (c *PlattScaling) Name() string {
+	return plattName
+}
+
+// Fit fits A and B by regularized maximum likelihood using Newton's method with backtracking line search.
+func (c *PlattScaling) Fit(scores mat.Vector, labels []int) error {
+	if err := checkFitInput(scores, labels); err != nil {
+		return err
+	}
+	prior1, prior0 := 0.0, 0.0
+	for _, l := range labels {
+		if l == 1 {
+			prior1++
+		} else {
+			prior0++
+		}
+	}
+	hiTarget := (prior1 + 1) / (prior1 + 2)
+	loTarget := 1 / (prior0 + 2)
+	t := make([]float64, len(labels))
+	for i, l := range labels {
+		if l == 1 {
+			t[i] = hiTarget
+		} else {
+			t[i] = loTarget
+		}
+	}
+
+	a, b := 0.0, math.Log((prior0+1)/(prior1+1))
+	objective := func(a, b float64) float64 {
+		f := 0.0
+		for i, s := range scores {
+			fApB := s*a + b
+			if fApB >= 0 {
+				f += t[i]*fApB + math.Log1p(math.Exp(-fApB))
+			} else {
+				f += (t[i]-1)*fApB + math.Log1p(math.Exp(fApB))
+			}
+		}
+		return f
+	}
+	fval := objective(a, b)
+	for iter := 0; iter < plattMaxIter; iter++ {
+		// gradient and hessian of the objective, the hessian diagonal is regularized by plattSigma.
+		h11, h22, h21 := plattSigma, plattSigma, 0.0
+		g1, g2 := 0.0, 0.0
+		for i, s := range scores {
+			fApB := s*a + b
+			var p, q float64
+			if fApB >= 0 {
+				p = math.Exp(-fApB) / (1 + math.Exp(-fApB))
+				q = 1 / (1 + math.Exp(-fApB))
+			} else {
+				p = 1 / (1 + math.Exp(fApB))
+				q = math.Exp(fApB) / (1 + math.Exp(fApB))
+			}
+			d2 := p * q
+			h11 += s * s * d2
+			h22 += d2
+			h21 += s * d2
+			d1 := t[i] - p
+			g1 += s * d1
+			g2 += d1
+		}
+		if math.Abs(g1) < plattEps && math.Abs(g2) < plattEps {
+			break
+		}
+		det := h11*h22 - h21*h21
+		dA := -(h22*g1 - h21*g2) / det
+		dB := -(-h21*g1 + h11*g2) / det
+		gd := g1*dA + g2*dB
+
+		step := 1.0
+		for step >= plattMinStep {
+			newA, newB := a+step*dA, b+step*dB
+			newF := objective(newA, newB)
+			if newF < fval+0.0001*step*gd {
+				a, b, fval = newA, newB, newF
+				break
+			}
+			step /= 2
+		}
+		if step < plattMinStep {
+			return fmt.Errorf("line search fails while fitting platt scaling")
+		}
+	}
+	c.A, c.B = a, b
+	return nil
+}
+
+// Transform returns calibrated probabilities of scores.
+func (c *PlattScaling) Transform(scores mat.Vector) (mat.Vector, error) {
+	r := make(mat.Vector, len(scores))
+	for i, s := range scores {
+		r[i] = 1 / (1 + math.Exp(c.A*s+c.B))
+	}
+	return r, nil
+}
@@ -0,0 +1,116 @@
+// Command xgbserve serves the predictions of a model over HTTP, so that a scoring sidecar can be stood up without
+// writing Go code. The model is loaded with xgboost.LoadXGBoostModel, whatever the format it was saved with.
+//
+// Endpoints:
+//
+//	POST /predict        predictions like inference.Ensemble.Predict: probabilities of binary models, classes of
+//	                     multiclass models
+//	POST /predict_proba  probabilities of every row like inference.Ensemble.PredictProba
+//	GET  /health         {"status": "ok"} once the model is loaded
+//	GET  /model/info     name, objective, number of classes, features and trees of the model
+//
+// Rows are sent as a JSON object {"rows": [...]} where each row is either an array of feature values, null
+// values being missing, or an object of feature values keyed by feature index or feature name. With a
+// text/plain or text/libsvm content type the body is read as libsvm lines whose labels are ignored. Responses are
+// JSON objects {"predictions": [[...], ...]} with one array per row in request order, or {"error": "..."}.
+// Requests cancelled by their client are answered with status 499 and requests past their deadline with 503.
+//
+// Usage:
+//
+//	xgbserve -model model.json [-addr :8080] [-max-rows 10000] [-concurrency 8] [-workers 1]
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"runtime"
+	"syscall"
+	"time"
+
+	"github.com/lordberre/xgboost-go"
+	"github.com/lordberre/xgboost-go/activation"
+	"github.com/lordberre/xgboost-go/inference"
+)
+
+func main() {
+	var (
+		modelPath   = flag.String("model", "", "path of the model file")
+		featureMap  = flag.String("fmap", "", "optional feature map of json dump models")
+		numClasses  = flag.Int("num-classes", 0, "number of classes, checked against the model unless 0")
+		maxDepth    = flag.Int("max-depth", 0, "maximum depth of json dump models, 0 to not check it")
+		objective   = flag.String("objective", "", "objective of models which do not save it, like binary:logistic")
+		actName     = flag.String("activation", "", "activation name, the one matching the objective if empty")
+		addr        = flag.String("addr", ":8080", "address to listen on")
+		maxRows     = flag.Int("max-rows", 10000, "maximum number of rows of a request")
+		maxBody     = flag.Int64("max-body", 32<<20, "maximum size of a request body in bytes")
+		concurrency = flag.Int("concurrency", runtime.GOMAXPROCS(0), "maximum number of requests predicted at once")
+		workers     = flag.Int("workers", 1, "goroutines predicting the rows of a request, GOMAXPROCS if <= 0")
+		timeout     = flag.Duration("timeout", 30*time.Second, "read and write timeout of requests")
+	)
+	flag.Parse()
+	if *modelPath == "" {
+		fmt.Fprintln(os.Stderr, "xgbserve: -model is required")
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	ensemble, err := loadModel(*modelPath, *featureMap, *numClasses, *maxDepth, *objective, *actName)
+	if err != nil {
+		log.Fatalf("xgbserve: cannot load %s: %s", *modelPath, err)
+	}
+	s, err := newServer(ensemble, serverConfig{
+		maxRows:     *maxRows,
+		maxBody:     *maxBody,
+		concurrency: *concurrency,
+		workers:     *workers,
+	})
+	if err != nil {
+		log.Fatalf("xgbserve: %s", err)
+	}
+	httpServer := &http.Server{
+		Addr:         *addr,
+		Handler:      s,
+		ReadTimeout:  *timeout,
+		WriteTimeout: *timeout,
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		signals := make(chan os.Signal, 1)
+		signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+		<-signals
+		ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+		defer cancel()
+		if err := httpServer.Shutdown(ctx); err != nil {
+			log.Printf("xgbserve: shutdown: %s", err)
+		}
+	}()
+	log.Printf("xgbserve: serving %s model %s on %s", ensemble.Name(), *modelPath, *addr)
+	if err := httpServer.ListenAndServe(); err != http.ErrServerClosed {
+		log.Fatalf("xgbserve: %s", err)
+	}
+	<-done
+}
+
+// loadModel loads the model at modelPath with the activation registered under actName, if any.
+func loadModel(modelPath, featureMap string, numClasses, maxDepth int, objective, actName string) (
+	*inference.Ensemble, error) {
+	var act activation.Activation
+	if actName != "" {
+		var err error
+		if act, err = activation.ByName(actName); err != nil {
+			return nil, err
+		}
+	}
+	var opts []xgboost.LoadOption
+	if objective != "" {
+		opts = append(opts, xgboost.WithObjective(objective))
+	}
+	return xgboost.LoadXGBoostModel(modelPath, featureMap, numClasses, maxDepth, act, opts...)
+}
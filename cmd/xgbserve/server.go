@@ -0,0 +1,302 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"github.com/lordberre/xgboost-go/inference"
+	"github.com/lordberre/xgboost-go/mat"
+	"github.com/lordberre/xgboost-go/protobuf"
+)
+
+// serverConfig contains the limits of a server.
+type serverConfig struct {
+	// maxRows is the maximum number of rows of a request.
+	maxRows int
+	// maxBody is the maximum size of a request body in bytes.
+	maxBody int64
+	// concurrency is the maximum number of requests predicted at once, other requests wait for a slot.
+	concurrency int
+	// workers is the number of goroutines predicting the rows of a request, see inference.Ensemble.PredictBatch.
+	workers int
+}
+
+// server serves the predictions of an ensemble.
+type server struct {
+	ensemble *inference.Ensemble
+	cfg      serverConfig
+	// slots holds a value per request being predicted.
+	slots chan struct{}
+	mux   *http.ServeMux
+}
+
+// newServer returns the HTTP handler of ensemble.
+func newServer(ensemble *inference.Ensemble, cfg serverConfig) (*server, error) {
+	if cfg.maxRows <= 0 || cfg.maxBody <= 0 || cfg.concurrency <= 0 {
+		return nil, fmt.Errorf("maximum rows, body size and concurrency must be positive")
+	}
+	s := &server{ensemble: ensemble, cfg: cfg, slots: make(chan struct{}, cfg.concurrency), mux: http.NewServeMux()}
+	s.mux.HandleFunc("/predict", s.handlePredict(false))
+	s.mux.HandleFunc("/predict_proba", s.handlePredict(true))
+	s.mux.HandleFunc("/health", s.handleHealth)
+	s.mux.HandleFunc("/model/info", s.handleInfo)
+	return s, nil
+}
+
+// ServeHTTP implements http.Handler.
+func (s *server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// statusClientClosedRequest is the non standard status of requests cancelled by their client, as logged by nginx.
+const statusClientClosedRequest = 499
+
+// httpError is an error with the status code of its response.
+type httpError struct {
+	status int
+	err    error
+}
+
+func (e *httpError) Error() string {
+	return e.err.Error()
+}
+
+// badRequest returns an error answered with http.StatusBadRequest.
+func badRequest(format string, a ...interface{}) error {
+	return &httpError{status: http.StatusBadRequest, err: fmt.Errorf(format, a...)}
+}
+
+// writeJSON writes v as the JSON response of status.
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeError writes err as a JSON response, with the status of httpError errors, of request contexts which are
+// cancelled or past their deadline and of input errors of the mat package, http.StatusInternalServerError
+// otherwise.
+func writeError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	var he *httpError
+	switch {
+	case errors.As(err, &he):
+		status = he.status
+	case errors.Is(err, context.Canceled):
+		status = statusClientClosedRequest
+	case errors.Is(err, context.DeadlineExceeded):
+		status = http.StatusServiceUnavailable
+	case errors.Is(err, mat.ErrDimensionMismatch), errors.Is(err, mat.ErrFeatureIndexOutOfRange),
+		errors.Is(err, mat.ErrMalformedRow), errors.Is(err, inference.ErrUnknownFeature):
+		status = http.StatusBadRequest
+	}
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+// handleHealth answers that the model is loaded.
+func (s *server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// modelInfo is the response of /model/info.
+type modelInfo struct {
+	Name         string            `json:"name"`
+	Objective    string            `json:"objective,omitempty"`
+	Activation   string            `json:"activation"`
+	NumClasses   int               `json:"num_classes"`
+	NumFeatures  int               `json:"num_features"`
+	NumTrees     int               `json:"num_trees"`
+	BaseScore    float64           `json:"base_score"`
+	FeatureNames []string          `json:"feature_names,omitempty"`
+	Attributes   map[string]string `json:"attributes,omitempty"`
+}
+
+// handleInfo answers the metadata of the model.
+func (s *server) handleInfo(w http.ResponseWriter, r *http.Request) {
+	e := s.ensemble
+	info := modelInfo{
+		Name:        e.Name(),
+		Objective:   e.Objective(),
+		Activation:  e.Activation.Name(),
+		NumClasses:  e.NumClasses(),
+		NumFeatures: e.NumFeatures(),
+		NumTrees:    e.NumTrees(),
+		BaseScore:   e.BaseScore(),
+		Attributes:  e.Attributes(),
+	}
+	if n, ok := e.EnsembleBase.(inference.FeatureNamer); ok {
+		info.FeatureNames = n.FeatureNames()
+	}
+	writeJSON(w, http.StatusOK, info)
+}
+
+// predictResponse is the response of /predict and /predict_proba.
+type predictResponse struct {
+	Predictions []*mat.Vector `json:"predictions"`
+}
+
+// handlePredict returns the handler predicting the rows of a request, probabilities of every class if proba is
+// true, the predicted class of multiclass models otherwise.
+func (s *server) handlePredict(proba bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			writeError(w, &httpError{status: http.StatusMethodNotAllowed,
+				err: fmt.Errorf("%s only accepts POST", r.URL.Path)})
+			return
+		}
+		features, err := s.readRows(w, r)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+
+		select {
+		case s.slots <- struct{}{}:
+		case <-r.Context().Done():
+			writeError(w, r.Context().Err())
+			return
+		}
+		preds, err := s.ensemble.PredictBatch(r.Context(), features, s.cfg.workers)
+		<-s.slots
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		if !proba {
+			if preds, err = s.classes(preds); err != nil {
+				writeError(w, err)
+				return
+			}
+		}
+		writeJSON(w, http.StatusOK, predictResponse{Predictions: preds.Vectors})
+	}
+}
+
+// classes returns the predicted class of probabilities of multiclass models like inference.Ensemble.Predict,
+// other predictions are returned unchanged.
+func (s *server) classes(preds mat.Matrix) (mat.Matrix, error) {
+	e := s.ensemble
+	if e.NumClasses() == 1 || e.Type() == protobuf.ActivateType_SOFTMAX_CLASS {
+		return preds, nil
+	}
+	for i, v := range preds.Vectors {
+		idx, err := mat.GetVectorMaxIdx(v)
+		if err != nil {
+			return mat.Matrix{}, err
+		}
+		preds.Vectors[i] = &mat.Vector{float64(idx)}
+	}
+	return preds, nil
+}
+
+// countingBody counts the bytes read from a request body, to tell the error of http.MaxBytesReader
+// from the other read errors.
+type countingBody struct {
+	io.ReadCloser
+	n int64
+}
+
+func (b *countingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.n += int64(n)
+	return n, err
+}
+
+// readRows returns the rows of the body of r, as JSON or libsvm lines depending on its content type.
+func (s *server) readRows(w http.ResponseWriter, r *http.Request) (mat.SparseMatrix, error) {
+	counted := &countingBody{ReadCloser: r.Body}
+	body, err := ioutil.ReadAll(http.MaxBytesReader(w, counted, s.cfg.maxBody))
+	switch {
+	case err == nil:
+	case counted.n > s.cfg.maxBody:
+		return mat.SparseMatrix{}, &httpError{status: http.StatusRequestEntityTooLarge, err: err}
+	case r.Context().Err() != nil:
+		// the client went away or the deadline passed while sending the body.
+		return mat.SparseMatrix{}, r.Context().Err()
+	default:
+		return mat.SparseMatrix{}, badRequest("cannot read body: %s", err)
+	}
+	var features mat.SparseMatrix
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch mediaType {
+	case "text/plain", "text/libsvm":
+		if features, err = mat.ReadLibsvmToSparseMatrix(bytes.NewReader(body)); err != nil {
+			return mat.SparseMatrix{}, badRequest("cannot read libsvm rows: %s", err)
+		}
+	case "application/json", "":
+		if features, err = s.parseJSONRows(body); err != nil {
+			return mat.SparseMatrix{}, err
+		}
+	default:
+		return mat.SparseMatrix{}, &httpError{status: http.StatusUnsupportedMediaType,
+			err: fmt.Errorf("unsupported content type %s", mediaType)}
+	}
+	if len(features.Vectors) > s.cfg.maxRows {
+		return mat.SparseMatrix{}, &httpError{status: http.StatusRequestEntityTooLarge,
+			err: fmt.Errorf("%d rows exceed the maximum of %d", len(features.Vectors), s.cfg.maxRows)}
+	}
+	return features, nil
+}
+
+// parseJSONRows returns the rows of a JSON request, arrays of values or objects keyed by feature index or name.
+func (s *server) parseJSONRows(body []byte) (mat.SparseMatrix, error) {
+	var req struct {
+		Rows []json.RawMessage `json:"rows"`
+	}
+	if err := json.Unmarshal(body, &req); err != nil {
+		return mat.SparseMatrix{}, badRequest("cannot decode rows: %s", err)
+	}
+	features := mat.SparseMatrix{Vectors: make([]mat.SparseVector, len(req.Rows))}
+	for i, raw := range req.Rows {
+		raw = bytes.TrimSpace(raw)
+		if len(raw) == 0 {
+			return mat.SparseMatrix{}, badRequest("row %d is empty", i)
+		}
+		switch raw[0] {
+		case '[':
+			var values []*float64
+			if err := json.Unmarshal(raw, &values); err != nil {
+				return mat.SparseMatrix{}, badRequest("cannot decode row %d: %s", i, err)
+			}
+			row := make(mat.SparseVector, len(values))
+			for j, v := range values {
+				if v != nil {
+					row[j] = *v
+				}
+			}
+			features.Vectors[i] = row
+		case '{':
+			var values map[string]float64
+			if err := json.Unmarshal(raw, &values); err != nil {
+				return mat.SparseMatrix{}, badRequest("cannot decode row %d: %s", i, err)
+			}
+			row := make(mat.SparseVector, len(values))
+			for key, v := range values {
+				idx, err := strconv.Atoi(key)
+				if err == nil && idx < 0 {
+					return mat.SparseMatrix{}, badRequest("row %d: negative feature index %d", i, idx)
+				}
+				if err != nil {
+					var ok bool
+					if idx, ok = s.ensemble.FeatureIndex(key); !ok {
+						return mat.SparseMatrix{}, badRequest("row %d: %s %q", i, inference.ErrUnknownFeature, key)
+					}
+				}
+				row[idx] = v
+			}
+			features.Vectors[i] = row
+		default:
+			return mat.SparseMatrix{}, badRequest("row %d must be an array or an object", i)
+		}
+	}
+	return features, nil
+}
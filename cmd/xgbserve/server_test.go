@@ -0,0 +1,169 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"gotest.tools/assert"
+
+	"github.com/lordberre/xgboost-go/mat"
+)
+
+func newTestServer(t *testing.T, modelPath string) (*httptest.Server, *server) {
+	ensemble, err := loadModel(modelPath, "", 0, 0, "", "")
+	assert.NilError(t, err)
+	s, err := newServer(ensemble, serverConfig{maxRows: 3, maxBody: 1 << 20, concurrency: 2, workers: 2})
+	assert.NilError(t, err)
+	ts := httptest.NewServer(s)
+	t.Cleanup(ts.Close)
+	return ts, s
+}
+
+func post(t *testing.T, url, contentType, body string) (int, map[string]json.RawMessage) {
+	resp, err := http.Post(url, contentType, strings.NewReader(body))
+	assert.NilError(t, err)
+	defer resp.Body.Close()
+	data, err := ioutil.ReadAll(resp.Body)
+	assert.NilError(t, err)
+	var decoded map[string]json.RawMessage
+	assert.NilError(t, json.Unmarshal(data, &decoded), string(data))
+	return resp.StatusCode, decoded
+}
+
+func TestServer(t *testing.T) {
+	ts, s := newTestServer(t, "../../test/data/breast_cancer_xgboost_model.json")
+
+	resp, err := http.Get(ts.URL + "/health")
+	assert.NilError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, resp.StatusCode, http.StatusOK)
+
+	resp, err = http.Get(ts.URL + "/model/info")
+	assert.NilError(t, err)
+	var info modelInfo
+	assert.NilError(t, json.NewDecoder(resp.Body).Decode(&info))
+	resp.Body.Close()
+	assert.Equal(t, info.Objective, "binary:logistic")
+	assert.Equal(t, info.NumClasses, 1)
+	assert.Equal(t, info.NumFeatures, s.ensemble.NumFeatures())
+
+	// arrays, objects keyed by index and libsvm lines give the same predictions.
+	expected, err := s.ensemble.PredictProba(mat.SparseMatrix{Vectors: []mat.SparseVector{{0: 14.2, 2: 91}, {1: 20}}})
+	assert.NilError(t, err)
+	for _, c := range []struct {
+		contentType, body string
+	}{
+		{"application/json", `{"rows": [[14.2, null, 91], [null, 20]]}`},
+		{"application/json", `{"rows": [{"0": 14.2, "2": 91}, {"1": 20}]}`},
+		{"text/plain", "1 0:14.2 2:91\n0 1:20\n"},
+	} {
+		status, body := post(t, ts.URL+"/predict_proba", c.contentType, c.body)
+		assert.Equal(t, status, http.StatusOK, c.body)
+		var preds []*mat.Vector
+		assert.NilError(t, json.Unmarshal(body["predictions"], &preds))
+		assert.DeepEqual(t, preds, expected.Vectors)
+	}
+
+	status, _ := post(t, ts.URL+"/predict", "application/json", `{"rows": [[1], [2], [3], [4]]}`)
+	assert.Equal(t, status, http.StatusRequestEntityTooLarge)
+	status, _ = post(t, ts.URL+"/predict", "application/json", `{"rows": [{"radius": 1}]}`)
+	assert.Equal(t, status, http.StatusBadRequest)
+	status, _ = post(t, ts.URL+"/predict", "application/json", `{"rows": [1]}`)
+	assert.Equal(t, status, http.StatusBadRequest)
+	status, _ = post(t, ts.URL+"/predict", "application/xml", `<rows/>`)
+	assert.Equal(t, status, http.StatusUnsupportedMediaType)
+	resp, err = http.Get(ts.URL + "/predict")
+	assert.NilError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, resp.StatusCode, http.StatusMethodNotAllowed)
+}
+
+func TestServerMulticlass(t *testing.T) {
+	ts, s := newTestServer(t, "../../test/data/iris_xgboost_model.json")
+	rows := mat.SparseMatrix{Vectors: []mat.SparseVector{{0: 5.1, 1: 3.5, 2: 1.4, 3: 0.2}, {0: 6.7, 1: 3, 2: 5.2, 3: 2.3}}}
+	body := `{"rows": [[5.1, 3.5, 1.4, 0.2], [6.7, 3, 5.2, 2.3]]}`
+
+	status, resp := post(t, ts.URL+"/predict_proba", "application/json", body)
+	assert.Equal(t, status, http.StatusOK)
+	var proba []*mat.Vector
+	assert.NilError(t, json.Unmarshal(resp["predictions"], &proba))
+	expectedProba, err := s.ensemble.PredictProba(rows)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, proba, expectedProba.Vectors)
+
+	status, resp = post(t, ts.URL+"/predict", "application/json", body)
+	assert.Equal(t, status, http.StatusOK)
+	var classes []*mat.Vector
+	assert.NilError(t, json.Unmarshal(resp["predictions"], &classes))
+	expected, err := s.ensemble.Predict(rows)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, classes, expected.Vectors)
+}
+
+func TestServerContext(t *testing.T) {
+	_, s := newTestServer(t, "../../test/data/breast_cancer_xgboost_model.json")
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	expired, cancel := context.WithTimeout(context.Background(), 0)
+	defer cancel()
+	for ctx, status := range map[context.Context]int{
+		cancelled: statusClientClosedRequest,
+		expired:   http.StatusServiceUnavailable,
+	} {
+		// with a free slot the request is either rejected while waiting for it or stopped by PredictBatch.
+		for i := 0; i < 10; i++ {
+			r := httptest.NewRequest(http.MethodPost, "/predict", strings.NewReader(`{"rows": [[14.2]]}`))
+			w := httptest.NewRecorder()
+			s.ServeHTTP(w, r.WithContext(ctx))
+			assert.Equal(t, w.Code, status, w.Body.String())
+		}
+	}
+	// the slot is released whatever the outcome.
+	assert.Equal(t, len(s.slots), 0)
+}
+
+// failingBody returns the beginning of a body then err, like a truncated body or a client going away.
+type failingBody struct {
+	data string
+	err  error
+}
+
+func (b *failingBody) Read(p []byte) (int, error) {
+	if len(b.data) == 0 {
+		return 0, b.err
+	}
+	n := copy(p, b.data)
+	b.data = b.data[n:]
+	return n, nil
+}
+
+func TestServerBody(t *testing.T) {
+	_, s := newTestServer(t, "../../test/data/breast_cancer_xgboost_model.json")
+	s.cfg.maxBody = 16
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	for _, c := range []struct {
+		body   io.Reader
+		ctx    context.Context
+		status int
+	}{
+		{strings.NewReader(`{"rows": [[14.2]]}`), context.Background(), http.StatusRequestEntityTooLarge},
+		{&failingBody{`{"rows": [[14.`, io.ErrUnexpectedEOF}, context.Background(), http.StatusBadRequest},
+		// a body failing past the limit is too large whatever the error.
+		{&failingBody{`{"rows": [[14.2, 1]]}`, io.ErrUnexpectedEOF}, context.Background(),
+			http.StatusRequestEntityTooLarge},
+		{&failingBody{`{"rows"`, context.Canceled}, cancelled, statusClientClosedRequest},
+		{strings.NewReader(`{"rows": [[1]]}`), context.Background(), http.StatusOK},
+	} {
+		r := httptest.NewRequest(http.MethodPost, "/predict", c.body)
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, r.WithContext(c.ctx))
+		assert.Equal(t, w.Code, c.status, w.Body.String())
+	}
+}
@@ -5,7 +5,9 @@ go 1.14
 require (
 	github.com/golang/protobuf v1.4.3
 	github.com/google/go-cmp v0.5.2 // indirect
+	github.com/klauspost/compress v1.15.9
 	github.com/pkg/errors v0.9.1
 	golang.org/x/xerrors v0.0.0-20200804184101-5ec99f83aff1 // indirect
+	google.golang.org/protobuf v1.23.0
 	gotest.tools v2.2.0+incompatible
 )
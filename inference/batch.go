@@ -0,0 +1,88 @@
+package inference
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"sync"
+	"sync/atomic"
+
+	"github.com/lordberre/xgboost-go/mat"
+)
+
+// batchBlockSize is the number of consecutive rows a worker of PredictBatch predicts at once, the context is
+// checked before each block.
+const batchBlockSize = ctxCheckInterval
+
+// PredictBatch predicts probabilities like PredictProbaContext with rows shared between workers goroutines, the
+// results are in the order of the rows whatever the number of workers. workers <= 0 uses runtime.GOMAXPROCS(0)
+// goroutines and workers == 1 predicts in the calling goroutine. Workers take blocks of consecutive rows so that
+// rows of different costs are balanced, they stop once ctx is cancelled or a row fails, in which case the error of
+// the first failed row is returned.
+func (e *Ensemble) PredictBatch(ctx context.Context, features mat.SparseMatrix, workers int) (mat.Matrix, error) {
+	if e.NumClasses() == 0 {
+		return mat.Matrix{}, fmt.Errorf("0 class please check your model")
+	}
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	numBlocks := (len(features.Vectors) + batchBlockSize - 1) / batchBlockSize
+	if workers > numBlocks {
+		workers = numBlocks
+	}
+	if workers <= 1 {
+		return e.PredictProbaContext(ctx, features)
+	}
+
+	results := mat.Matrix{Vectors: make([]*mat.Vector, len(features.Vectors))}
+	var (
+		next    int64
+		stopped int32
+		mu      sync.Mutex
+		failed  = len(features.Vectors)
+		err     error
+	)
+	fail := func(row int, rowErr error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if row < failed {
+			failed, err = row, rowErr
+		}
+		atomic.StoreInt32(&stopped, 1)
+	}
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		go func() {
+			defer wg.Done()
+			for atomic.LoadInt32(&stopped) == 0 {
+				block := int(atomic.AddInt64(&next, 1) - 1)
+				if block >= numBlocks {
+					return
+				}
+				begin := block * batchBlockSize
+				if ctxErr := ctx.Err(); ctxErr != nil {
+					fail(begin, ctxErr)
+					return
+				}
+				end := begin + batchBlockSize
+				if end > len(features.Vectors) {
+					end = len(features.Vectors)
+				}
+				for i := begin; i < end; i++ {
+					pred, rowErr := e.predictProbaRow(features.Vectors[i])
+					if rowErr != nil {
+						fail(i, rowErr)
+						return
+					}
+					results.Vectors[i] = &pred
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if err != nil {
+		return mat.Matrix{}, err
+	}
+	return results, nil
+}
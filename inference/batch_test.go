@@ -0,0 +1,49 @@
+package inference
+
+import (
+	"context"
+	"testing"
+
+	"gotest.tools/assert"
+
+	"github.com/lordberre/xgboost-go/activation"
+	"github.com/lordberre/xgboost-go/mat"
+)
+
+func TestEnsemble_PredictBatch(t *testing.T) {
+	ensemble := newTestEnsemble(1, &activation.Logistic{})
+	// several blocks of rows so that several workers have blocks to predict.
+	input := testRows(10*batchBlockSize + 7)
+	expected, err := ensemble.PredictProba(input)
+	assert.NilError(t, err)
+	for _, workers := range []int{0, 1, 3, 1000} {
+		predictions, err := ensemble.PredictBatch(context.Background(), input, workers)
+		assert.NilError(t, err)
+		assert.DeepEqual(t, predictions, expected)
+	}
+	predictions, err := ensemble.PredictBatch(context.Background(), mat.SparseMatrix{}, 4)
+	assert.NilError(t, err)
+	assert.Equal(t, len(predictions.Vectors), 0)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	for _, workers := range []int{1, 4} {
+		_, err = ensemble.PredictBatch(ctx, input, workers)
+		assert.Equal(t, err, context.Canceled, workers)
+	}
+
+	// the error of the first failed row is returned whatever the worker which predicted it.
+	failing := testRows(len(input.Vectors))
+	failing.Vectors[3*batchBlockSize+1][5] = 1
+	failing.Vectors[8*batchBlockSize][6] = 1
+	for _, workers := range []int{1, 4} {
+		_, err = ensemble.PredictBatch(context.Background(), failing, workers)
+		assert.ErrorContains(t, err, "feature 5", workers)
+	}
+
+	// every row fails with a logistic activation of a multiclass model.
+	_, err = newTestEnsemble(3, &activation.Logistic{}).PredictBatch(context.Background(), input, 4)
+	assert.ErrorContains(t, err, "prediction should have only 1 dimension got 3")
+	_, err = newTestEnsemble(0, &activation.Logistic{}).PredictBatch(context.Background(), input, 4)
+	assert.ErrorContains(t, err, "0 class")
+}
@@ -0,0 +1,132 @@
+package inference
+
+import (
+	"container/list"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+
+	"github.com/lordberre/xgboost-go/mat"
+)
+
+// CachedPredictor wraps an ensemble with a LRU cache of predicted probabilities keyed by the input features.
+// It is safe for concurrent use.
+type CachedPredictor struct {
+	ensemble *Ensemble
+	size     int
+
+	mu    sync.Mutex
+	items map[string]*list.Element
+	order *list.List
+}
+
+type cacheEntry struct {
+	key  string
+	pred mat.Vector
+}
+
+// NewCachedPredictor creates a predictor caching up to size distinct inputs.
+func NewCachedPredictor(e *Ensemble, size int) (*CachedPredictor, error) {
+	if e == nil {
+		return nil, fmt.Errorf("nil ensemble")
+	}
+	if size <= 0 {
+		return nil, fmt.Errorf("cache size must be positive: %d", size)
+	}
+	return &CachedPredictor{
+		ensemble: e,
+		size:     size,
+		items:    make(map[string]*list.Element, size),
+		order:    list.New(),
+	}, nil
+}
+
+// cacheKey encodes features sorted by index so that equal vectors always produce the same key.
+func cacheKey(features mat.SparseVector) string {
+	indices := make([]int, 0, len(features))
+	for idx := range features {
+		indices = append(indices, idx)
+	}
+	sort.Ints(indices)
+	buf := make([]byte, 16*len(indices))
+	for i, idx := range indices {
+		binary.LittleEndian.PutUint64(buf[16*i:], uint64(idx))
+		binary.LittleEndian.PutUint64(buf[16*i+8:], math.Float64bits(features[idx]))
+	}
+	return string(buf)
+}
+
+func (c *CachedPredictor) get(key string) (mat.Vector, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(el)
+	return el.Value.(*cacheEntry).pred, true
+}
+
+func (c *CachedPredictor) add(key string, pred mat.Vector) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.items[key]; ok {
+		c.order.MoveToFront(el)
+		return
+	}
+	c.items[key] = c.order.PushFront(&cacheEntry{key: key, pred: pred})
+	if c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.items, oldest.Value.(*cacheEntry).key)
+	}
+}
+
+// PredictProbaRow predicts probabilities of a single row, using the cached result if available.
+func (c *CachedPredictor) PredictProbaRow(features mat.SparseVector) (mat.Vector, error) {
+	key := cacheKey(features)
+	pred, ok := c.get(key)
+	if !ok {
+		var err error
+		pred, err = c.ensemble.predictProbaRow(features)
+		if err != nil {
+			return mat.Vector{}, err
+		}
+		c.add(key, pred)
+	}
+	// return a copy so callers cannot modify cached values.
+	r := make(mat.Vector, len(pred))
+	copy(r, pred)
+	return r, nil
+}
+
+// PredictProba predicts probabilities like Ensemble.PredictProba using cached results when possible.
+func (c *CachedPredictor) PredictProba(features mat.SparseMatrix) (mat.Matrix, error) {
+	if c.ensemble.NumClasses() == 0 {
+		return mat.Matrix{}, fmt.Errorf("0 class please check your model")
+	}
+	results := mat.Matrix{Vectors: make([]*mat.Vector, len(features.Vectors))}
+	for i, row := range features.Vectors {
+		pred, err := c.PredictProbaRow(row)
+		if err != nil {
+			return mat.Matrix{}, err
+		}
+		results.Vectors[i] = &pred
+	}
+	return results, nil
+}
+
+// Clone returns a predictor of the same ensemble and size with its own, initially empty, cache.
+func (c *CachedPredictor) Clone() *CachedPredictor {
+	clone, _ := NewCachedPredictor(c.ensemble, c.size)
+	return clone
+}
+
+// Len returns number of cached inputs.
+func (c *CachedPredictor) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
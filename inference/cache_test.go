@@ -0,0 +1,64 @@
+package inference
+
+import (
+	"sync"
+	"testing"
+
+	"gotest.tools/assert"
+
+	"github.com/lordberre/xgboost-go/activation"
+	"github.com/lordberre/xgboost-go/mat"
+)
+
+func TestCachedPredictor(t *testing.T) {
+	ensemble := newTestEnsemble(3, &activation.Softmax{})
+	input := testRows(30)
+	expected, err := ensemble.PredictProba(input)
+	assert.NilError(t, err)
+
+	_, err = NewCachedPredictor(ensemble, 0)
+	assert.Check(t, err != nil)
+	_, err = NewCachedPredictor(nil, 8)
+	assert.Check(t, err != nil)
+
+	cached, err := NewCachedPredictor(ensemble, 8)
+	assert.NilError(t, err)
+	var wg sync.WaitGroup
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 3; i++ {
+				predictions, err := cached.PredictProba(input)
+				assert.Check(t, err)
+				assert.Check(t, mat.IsEqualMatrices(&predictions, &expected, 0))
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, cached.Len(), 8)
+
+	// the least recently used rows are evicted.
+	last := input.Vectors[len(input.Vectors)-1]
+	_, ok := cached.get(cacheKey(last))
+	assert.Check(t, ok)
+	_, ok = cached.get(cacheKey(input.Vectors[0]))
+	assert.Check(t, !ok)
+
+	// equal rows share their entry and modifying returned predictions must not change the cache.
+	pred, err := cached.PredictProbaRow(last)
+	assert.NilError(t, err)
+	pred[0] = -1
+	copied := mat.SparseVector{}
+	for idx, v := range last {
+		copied[idx] = v
+	}
+	pred, err = cached.PredictProbaRow(copied)
+	assert.NilError(t, err)
+	assert.NilError(t, mat.IsEqualVectors(&pred, expected.Vectors[len(input.Vectors)-1], 0))
+	assert.Equal(t, cached.Len(), 8)
+
+	_, err = cached.PredictProbaRow(mat.SparseVector{9: 1})
+	assert.Check(t, err != nil)
+	assert.Equal(t, cached.Len(), 8)
+}
@@ -0,0 +1,55 @@
+package inference
+
+import (
+	"fmt"
+
+	"github.com/lordberre/xgboost-go/mat"
+)
+
+// CSRPredictor is an optional interface for base models able to predict rows of a CSR matrix directly, indices
+// being sorted in increasing order.
+type CSRPredictor interface {
+	PredictInnerCSR(indices []int, values []float64) (mat.Vector, error)
+}
+
+// PredictProbaCSR predicts probabilities of the rows of a CSR matrix like PredictProba. Base models implementing
+// CSRPredictor, like XGBoost models, read the flat slices of each row without building a map per row, other
+// models predict the rows converted to sparse vectors.
+func (e *Ensemble) PredictProbaCSR(features mat.CSRMatrix) (mat.Matrix, error) {
+	if e.NumClasses() == 0 {
+		return mat.Matrix{}, fmt.Errorf("0 class please check your model")
+	}
+	if err := features.Validate(); err != nil {
+		return mat.Matrix{}, err
+	}
+	p, native := e.EnsembleBase.(CSRPredictor)
+
+	results := mat.Matrix{Vectors: make([]*mat.Vector, features.Rows())}
+	for i := range results.Vectors {
+		indices, values := features.Row(i)
+		var pred mat.Vector
+		var err error
+		if native {
+			pred, err = p.PredictInnerCSR(indices, values)
+		} else {
+			row := make(mat.SparseVector, len(indices))
+			for j, idx := range indices {
+				row[idx] = values[j]
+			}
+			pred, err = e.PredictInner(row)
+		}
+		if err != nil {
+			return mat.Matrix{}, err
+		}
+		if len(pred) != e.NumClasses() {
+			return mat.Matrix{}, fmt.Errorf("%w: number of predicted value (%d) must match number of classes (%d)",
+				mat.ErrDimensionMismatch, len(pred), e.NumClasses())
+		}
+		pred, err = e.Transform(pred)
+		if err != nil {
+			return mat.Matrix{}, err
+		}
+		results.Vectors[i] = &pred
+	}
+	return results, nil
+}
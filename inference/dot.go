@@ -0,0 +1,48 @@
+package inference
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ToDOT returns tree treeIndex in the Graphviz DOT language, laid out like the output of XGBoost to_graphviz so
+// that both can be compared. Edges to yes children are blue and edges to no children are red, the edge followed
+// by missing values is labelled missing.
+func (e *Ensemble) ToDOT(treeIndex int) (string, error) {
+	trees, err := e.Trees()
+	if err != nil {
+		return "", err
+	}
+	if treeIndex < 0 || treeIndex >= len(trees) {
+		return "", fmt.Errorf("tree index %d out of range [0, %d)", treeIndex, len(trees))
+	}
+	t := trees[treeIndex]
+	names := e.featureNames()
+	var b strings.Builder
+	b.WriteString("digraph {\n    graph [ rankdir=TB ]\n")
+	for _, n := range t.Nodes {
+		if n == nil {
+			continue
+		}
+		if n.Leaf {
+			fmt.Fprintf(&b, "    %d [ label=%q ]\n", n.ID, "leaf="+formatFloat(n.Value))
+			continue
+		}
+		fmt.Fprintf(&b, "    %d [ label=%q ]\n", n.ID, splitCondition(n, names))
+		for _, edge := range []struct {
+			child        int
+			label, color string
+		}{{n.Yes, "yes", "#0000FF"}, {n.No, "no", "#FF0000"}} {
+			if edge.child < 0 || edge.child >= len(t.Nodes) || t.Nodes[edge.child] == nil {
+				return "", fmt.Errorf("tree %d node %d has no child %d", treeIndex, n.ID, edge.child)
+			}
+			label := edge.label
+			if edge.child == n.Missing {
+				label += ", missing"
+			}
+			fmt.Fprintf(&b, "    %d -> %d [label=%q color=%q]\n", n.ID, edge.child, label, edge.color)
+		}
+	}
+	b.WriteString("}\n")
+	return b.String(), nil
+}
@@ -0,0 +1,59 @@
+package inference
+
+import "fmt"
+
+// Importance types of FeatureImportance, named like the importance_type of XGBoost get_score.
+const (
+	// ImportanceWeight is the number of splits on the feature.
+	ImportanceWeight = "weight"
+	// ImportanceGain is the average gain of the splits on the feature.
+	ImportanceGain = "gain"
+	// ImportanceCover is the average cover of the splits on the feature.
+	ImportanceCover = "cover"
+	// ImportanceTotalGain is the total gain of the splits on the feature.
+	ImportanceTotalGain = "total_gain"
+	// ImportanceTotalCover is the total cover of the splits on the feature.
+	ImportanceTotalCover = "total_cover"
+)
+
+// ImportanceProvider is an optional interface for base models able to compute feature importances.
+type ImportanceProvider interface {
+	FeatureImportanceInner(kind string) (map[int]float64, error)
+}
+
+// FeatureNamer is an optional interface for base models knowing the names of their features.
+type FeatureNamer interface {
+	FeatureNames() []string
+}
+
+// Importance contains the importance of every feature used by a split of the model.
+type Importance struct {
+	ByIndex map[int]float64
+	// ByName contains the same importances keyed by feature name, nil if the model does not name its features.
+	ByName map[string]float64
+}
+
+// FeatureImportance returns the importance of every feature used by a split, like XGBoost get_score with
+// importance_type kind. Unused features are absent. Gain and cover importances need split statistics, which json
+// dumps only contain when dumped with with_stats=True.
+func (e *Ensemble) FeatureImportance(kind string) (Importance, error) {
+	p, ok := e.EnsembleBase.(ImportanceProvider)
+	if !ok {
+		return Importance{}, fmt.Errorf("%s model does not support feature importance", e.Name())
+	}
+	byIndex, err := p.FeatureImportanceInner(kind)
+	if err != nil {
+		return Importance{}, err
+	}
+	r := Importance{ByIndex: byIndex}
+	if names := e.featureNames(); names != nil {
+		r.ByName = make(map[string]float64, len(byIndex))
+		for idx, v := range byIndex {
+			if idx >= len(names) || names[idx] == "" {
+				return Importance{}, fmt.Errorf("feature %d has no name", idx)
+			}
+			r.ByName[names[idx]] = v
+		}
+	}
+	return r, nil
+}
@@ -1,26 +1,101 @@
 package inference
 
 import (
+	"context"
 	"fmt"
+	"io"
 
 	"github.com/lordberre/xgboost-go/activation"
 	"github.com/lordberre/xgboost-go/mat"
 	"github.com/lordberre/xgboost-go/protobuf"
 )
 
-// EnsembleBase contains interface of a base model.
+// EnsembleBase contains interface of a base model. Implementations must not be modified by predictions so that
+// a loaded model can be shared between goroutines.
 type EnsembleBase interface {
 	PredictInner(features mat.SparseVector) (mat.Vector, error)
 	Name() string
 	NumClasses() int
 }
 
+// FeatureCounter is an optional interface for base models knowing their number of features.
+type FeatureCounter interface {
+	NumFeatures() int
+}
+
+// DensePredictor is an optional interface for base models able to predict dense rows directly.
+type DensePredictor interface {
+	PredictInnerDense(features mat.Vector) (mat.Vector, error)
+}
+
+// ctxCheckInterval is the number of rows predicted between two context cancellation checks.
+const ctxCheckInterval = 64
+
+// Float32Predictor is an optional interface for base models able to predict dense rows of float32 features.
+type Float32Predictor interface {
+	PredictInnerFloat32(features []float32) (mat.Vector, error)
+}
+
+// InteractionPredictor is an optional interface for base models able to calculate SHAP interaction values.
+type InteractionPredictor interface {
+	PredictInteractionsInner(features mat.SparseVector) (mat.Matrix, error)
+}
+
+// ContributionPredictor is an optional interface for base models able to calculate SHAP values.
+type ContributionPredictor interface {
+	PredictContributionsInner(features mat.SparseVector) (mat.Vector, error)
+}
+
+// ApproxContributionPredictor is an optional interface for base models able to calculate approximate feature
+// contributions.
+type ApproxContributionPredictor interface {
+	PredictApproxContributionsInner(features mat.SparseVector) (mat.Vector, error)
+}
+
+// StagedPredictor is an optional interface for base models able to return raw predictions after each boosting
+// round.
+type StagedPredictor interface {
+	PredictStagedInner(features mat.SparseVector) (mat.Matrix, error)
+}
+
+// BaseMarginPredictor is an optional interface for base models able to predict from a per row base margin.
+type BaseMarginPredictor interface {
+	PredictInnerWithBaseMargin(features mat.SparseVector, baseMargin mat.Vector) (mat.Vector, error)
+}
+
+// LeafPredictor is an optional interface for base models able to return the leaves reached in each tree.
+type LeafPredictor interface {
+	PredictLeafIndicesInner(features mat.SparseVector) ([]int, error)
+}
+
+// TreeContributionPredictor is an optional interface for base models able to return the value of each tree.
+type TreeContributionPredictor interface {
+	PredictTreeContributionsInner(features mat.SparseVector) (mat.Vector, error)
+}
+
 // Ensemble struct contains ensemble model interface that a model needs to implement.
+// Loaded ensembles are immutable: all Predict methods only read the model and may be called concurrently from
+// any number of goroutines. Setting Activation, or a field of it, is not safe while predictions are running, use
+// Clone to get an ensemble whose activation can be replaced independently.
 type Ensemble struct {
 	EnsembleBase
 	activation.Activation
 }
 
+// Clone returns a copy of the ensemble sharing the immutable base model. Assigning another Activation to the copy
+// does not affect e, the activation itself is shared so its fields must not be modified.
+func (e *Ensemble) Clone() *Ensemble {
+	return &Ensemble{EnsembleBase: e.EnsembleBase, Activation: e.Activation}
+}
+
+// NumFeatures returns the number of features of the model, 0 if its base model does not implement FeatureCounter.
+func (e *Ensemble) NumFeatures() int {
+	if c, ok := e.EnsembleBase.(FeatureCounter); ok {
+		return c.NumFeatures()
+	}
+	return 0
+}
+
 // PredictRegression predicts float number for regression task using ensemble model interface.
 func (e *Ensemble) PredictRegression(features mat.SparseMatrix, baseVal float64) (mat.Matrix, error) {
 	if e.NumClasses() == 0 {
@@ -37,8 +112,8 @@ func (e *Ensemble) PredictRegression(features mat.SparseMatrix, baseVal float64)
 			return mat.Matrix{}, err
 		}
 		if len(pred) != e.NumClasses() {
-			return mat.Matrix{}, fmt.Errorf("number of predicted value (%d) must match number of classes (%d)",
-				len(pred), e.NumClasses())
+			return mat.Matrix{}, fmt.Errorf("%w: number of predicted value (%d) must match number of classes (%d)",
+				mat.ErrDimensionMismatch, len(pred), e.NumClasses())
 		}
 		if e.Type() != protobuf.ActivateType_RAW {
 			return mat.Matrix{}, fmt.Errorf("regression model must have raw activation")
@@ -53,31 +128,134 @@ func (e *Ensemble) PredictRegression(features mat.SparseMatrix, baseVal float64)
 	return results, nil
 }
 
+// predictProbaRow predicts probabilities of a single row.
+func (e *Ensemble) predictProbaRow(row mat.SparseVector) (mat.Vector, error) {
+	pred, err := e.PredictInner(row)
+	if err != nil {
+		return mat.Vector{}, err
+	}
+	if len(pred) != e.NumClasses() {
+		return mat.Vector{}, fmt.Errorf("%w: number of predicted value (%d) must match number of classes (%d)",
+			mat.ErrDimensionMismatch, len(pred), e.NumClasses())
+	}
+	return e.Transform(pred)
+}
+
 // PredictProba predicts probabilities using ensemble model interface.
+// With SoftmaxClass activation (multi:softmax objective) each row holds the predicted class instead.
 func (e *Ensemble) PredictProba(features mat.SparseMatrix) (mat.Matrix, error) {
+	return e.PredictProbaContext(context.Background(), features)
+}
+
+// PredictProbaContext predicts probabilities like PredictProba but stops early and returns ctx.Err() once the
+// context is cancelled. The context is checked every ctxCheckInterval rows.
+func (e *Ensemble) PredictProbaContext(ctx context.Context, features mat.SparseMatrix) (mat.Matrix, error) {
 	if e.NumClasses() == 0 {
 		return mat.Matrix{}, fmt.Errorf("0 class please check your model")
 	}
 
 	results := mat.Matrix{Vectors: make([]*mat.Vector, len(features.Vectors))}
 	for i, row := range features.Vectors {
-		pred, err := e.PredictInner(row)
+		if i%ctxCheckInterval == 0 {
+			if err := ctx.Err(); err != nil {
+				return mat.Matrix{}, err
+			}
+		}
+		pred, err := e.predictProbaRow(row)
 		if err != nil {
 			return mat.Matrix{}, err
 		}
-		if len(pred) != e.NumClasses() {
-			return mat.Matrix{}, fmt.Errorf("number of predicted value (%d) must match number of classes (%d)",
-				len(pred), e.NumClasses())
+		results.Vectors[i] = &pred
+	}
+	return results, nil
+}
+
+// PredictProbaWithBaseMargin predicts probabilities like PredictProba but the raw prediction of row i starts from
+// baseMargin row i instead of base_score, like DMatrix.set_base_margin in XGBoost. baseMargin must have one row per
+// input row and one value per class, it is a margin so e.g. log(exposure) for a poisson model.
+func (e *Ensemble) PredictProbaWithBaseMargin(features mat.SparseMatrix, baseMargin mat.Matrix) (mat.Matrix, error) {
+	if e.NumClasses() == 0 {
+		return mat.Matrix{}, fmt.Errorf("0 class please check your model")
+	}
+	p, ok := e.EnsembleBase.(BaseMarginPredictor)
+	if !ok {
+		return mat.Matrix{}, fmt.Errorf("%s model does not support base margin", e.Name())
+	}
+	if len(baseMargin.Vectors) != len(features.Vectors) {
+		return mat.Matrix{}, fmt.Errorf("%w: base margin has %d rows for %d rows", mat.ErrDimensionMismatch,
+			len(baseMargin.Vectors), len(features.Vectors))
+	}
+	results := mat.Matrix{Vectors: make([]*mat.Vector, len(features.Vectors))}
+	for i, row := range features.Vectors {
+		pred, err := p.PredictInnerWithBaseMargin(row, *baseMargin.Vectors[i])
+		if err != nil {
+			return mat.Matrix{}, fmt.Errorf("row %d: %w", i, err)
 		}
-		pred, err = e.Transform(pred)
+		if pred, err = e.Transform(pred); err != nil {
+			return mat.Matrix{}, err
+		}
+		results.Vectors[i] = &pred
+	}
+	return results, nil
+}
+
+// PredictDMatrix predicts probabilities of the rows of d like PredictProba, starting from the base margins of d
+// like PredictProbaWithBaseMargin when it has some.
+func (e *Ensemble) PredictDMatrix(d mat.DMatrix) (mat.Matrix, error) {
+	if err := d.Validate(); err != nil {
+		return mat.Matrix{}, err
+	}
+	if d.BaseMargins.Vectors != nil {
+		return e.PredictProbaWithBaseMargin(d.Features, d.BaseMargins)
+	}
+	return e.PredictProba(d.Features)
+}
+
+// PredictMargin returns the raw margin of each row, i.e. the untransformed sum of leaf values and base_score,
+// whatever the activation of the model. It matches XGBoost predict(output_margin=True).
+func (e *Ensemble) PredictMargin(features mat.SparseMatrix) (mat.Matrix, error) {
+	if e.NumClasses() == 0 {
+		return mat.Matrix{}, fmt.Errorf("0 class please check your model")
+	}
+	results := mat.Matrix{Vectors: make([]*mat.Vector, len(features.Vectors))}
+	for i, row := range features.Vectors {
+		pred, err := e.PredictInner(row)
 		if err != nil {
 			return mat.Matrix{}, err
 		}
+		if len(pred) != e.NumClasses() {
+			return mat.Matrix{}, fmt.Errorf("%w: number of predicted value (%d) must match number of classes (%d)",
+				mat.ErrDimensionMismatch, len(pred), e.NumClasses())
+		}
 		results.Vectors[i] = &pred
 	}
 	return results, nil
 }
 
+// PredictLogOdds returns the raw margin of a binary logistic model for each row, i.e. the value before the sigmoid
+// is applied, so that sigmoid(PredictLogOdds) equals PredictProba.
+func (e *Ensemble) PredictLogOdds(features mat.SparseMatrix) (mat.Vector, error) {
+	if e.NumClasses() != 1 {
+		return mat.Vector{}, fmt.Errorf("log-odds prediction only support binary classes, got %d classes", e.NumClasses())
+	}
+	if e.Type() != protobuf.ActivateType_LOGISTIC {
+		return mat.Vector{}, fmt.Errorf("log-odds prediction requires logistic activation, got %s", e.Type())
+	}
+	results := make(mat.Vector, len(features.Vectors))
+	for i, row := range features.Vectors {
+		pred, err := e.PredictInner(row)
+		if err != nil {
+			return mat.Vector{}, err
+		}
+		if len(pred) != 1 {
+			return mat.Vector{}, fmt.Errorf("%w: number of predicted value (%d) must match number of classes (1)",
+				mat.ErrDimensionMismatch, len(pred))
+		}
+		results[i] = pred[0]
+	}
+	return results, nil
+}
+
 // Predict predicts class using ensemble model interface.
 // If model is a binary classification model, the prediction results will be probabilities instead of classes.
 func (e *Ensemble) Predict(features mat.SparseMatrix) (mat.Matrix, error) {
@@ -91,8 +269,8 @@ func (e *Ensemble) Predict(features mat.SparseMatrix) (mat.Matrix, error) {
 			return mat.Matrix{}, err
 		}
 		if len(pred) != e.NumClasses() {
-			return mat.Matrix{}, fmt.Errorf("number of predicted value (%d) must match number of classes (%d)",
-				len(pred), e.NumClasses())
+			return mat.Matrix{}, fmt.Errorf("%w: number of predicted value (%d) must match number of classes (%d)",
+				mat.ErrDimensionMismatch, len(pred), e.NumClasses())
 		}
 		if len(pred) == 0 {
 			return mat.Matrix{}, fmt.Errorf("empty inner prediction")
@@ -101,8 +279,9 @@ func (e *Ensemble) Predict(features mat.SparseMatrix) (mat.Matrix, error) {
 		if err != nil {
 			return mat.Matrix{}, err
 		}
-		if e.NumClasses() == 1 {
-			// for binary classification prediction results is probabilities.
+		if e.NumClasses() == 1 || e.Type() == protobuf.ActivateType_SOFTMAX_CLASS {
+			// for binary classification prediction results is probabilities, softmax class activation
+			// already returns the predicted class.
 			results.Vectors[i] = &pred
 		} else {
 			idx, err := mat.GetVectorMaxIdx(&pred)
@@ -120,3 +299,322 @@ func (e *Ensemble) Predict(features mat.SparseMatrix) (mat.Matrix, error) {
 func (e *Ensemble) Name() string {
 	return e.EnsembleBase.Name()
 }
+
+// PartialDependence calculates partial dependence of the model output on a single feature. For each value in grid
+// the feature is set to that value across all background rows and the predicted probabilities are averaged.
+// Only models with a single output (binary classification or regression) are supported.
+func (e *Ensemble) PartialDependence(background mat.SparseMatrix, feature int, grid []float64) (mat.Vector, error) {
+	if e.NumClasses() != 1 {
+		return mat.Vector{}, fmt.Errorf("partial dependence only supports single output models, got %d classes",
+			e.NumClasses())
+	}
+	if len(background.Vectors) == 0 {
+		return mat.Vector{}, fmt.Errorf("empty background data")
+	}
+	if feature < 0 {
+		return mat.Vector{}, fmt.Errorf("feature index cannot be negative: %d", feature)
+	}
+
+	input := background.Clone()
+	for j, row := range input.Vectors {
+		if row == nil {
+			// rows without any feature are valid, every feature but the varied one is missing.
+			input.Vectors[j] = make(mat.SparseVector, 1)
+		}
+	}
+	result := make(mat.Vector, len(grid))
+	for i, val := range grid {
+		for _, row := range input.Vectors {
+			row[feature] = val
+		}
+		pred, err := e.PredictProba(input)
+		if err != nil {
+			return mat.Vector{}, err
+		}
+		sum := 0.0
+		for _, p := range pred.Vectors {
+			sum += (*p)[0]
+		}
+		result[i] = sum / float64(len(pred.Vectors))
+	}
+	return result, nil
+}
+
+// PredictContributions returns SHAP values for each row computed with TreeSHAP, equivalent to XGBoost
+// predict(pred_contribs=True). Each row has F+1 values per class where F is the number of features, the last one
+// being the bias, and classes follow each other. The values of a class sum to its raw prediction.
+func (e *Ensemble) PredictContributions(features mat.SparseMatrix) (mat.Matrix, error) {
+	p, ok := e.EnsembleBase.(ContributionPredictor)
+	if !ok {
+		return mat.Matrix{}, fmt.Errorf("%s model does not support contributions", e.Name())
+	}
+	results := mat.Matrix{Vectors: make([]*mat.Vector, len(features.Vectors))}
+	for i, row := range features.Vectors {
+		r, err := p.PredictContributionsInner(row)
+		if err != nil {
+			return mat.Matrix{}, err
+		}
+		results.Vectors[i] = &r
+	}
+	return results, nil
+}
+
+// PredictApproxContributions returns approximate feature contributions for each row, equivalent to XGBoost
+// predict(pred_contribs=True, approx_contribs=True). Every split on the decision path attributes the change of the
+// expected tree value to its feature (Saabas method), which only needs a single pass over each tree and is much
+// cheaper than TreeSHAP but is not consistent. The layout is the same as PredictContributions and the values of a
+// class still sum to its raw prediction.
+func (e *Ensemble) PredictApproxContributions(features mat.SparseMatrix) (mat.Matrix, error) {
+	p, ok := e.EnsembleBase.(ApproxContributionPredictor)
+	if !ok {
+		return mat.Matrix{}, fmt.Errorf("%s model does not support approximate contributions", e.Name())
+	}
+	results := mat.Matrix{Vectors: make([]*mat.Vector, len(features.Vectors))}
+	for i, row := range features.Vectors {
+		r, err := p.PredictApproxContributionsInner(row)
+		if err != nil {
+			return mat.Matrix{}, err
+		}
+		results.Vectors[i] = &r
+	}
+	return results, nil
+}
+
+// PredictInteractions returns SHAP interaction values for each row, equivalent to XGBoost
+// predict(pred_interactions=True). Each matrix is (F+1)x(F+1) where F is the number of features and the last
+// row and column hold the bias term. Summing a row gives the SHAP contribution of that feature. For multiclass
+// models the matrices of each class are stacked so that the matrix has NumClasses*(F+1) rows.
+func (e *Ensemble) PredictInteractions(features mat.SparseMatrix) ([]mat.Matrix, error) {
+	p, ok := e.EnsembleBase.(InteractionPredictor)
+	if !ok {
+		return nil, fmt.Errorf("%s model does not support interaction values", e.Name())
+	}
+	results := make([]mat.Matrix, len(features.Vectors))
+	for i, row := range features.Vectors {
+		r, err := p.PredictInteractionsInner(row)
+		if err != nil {
+			return nil, err
+		}
+		results[i] = r
+	}
+	return results, nil
+}
+
+// PredictTreeContributions returns the leaf value reached in each tree for a single row, in tree order. For
+// multiclass models tree i contributes to class i % NumClasses. Summing the values of a class gives its raw margin,
+// which is useful to find which tree diverges when a prediction disagrees with XGBoost.
+func (e *Ensemble) PredictTreeContributions(features mat.SparseVector) (mat.Vector, error) {
+	p, ok := e.EnsembleBase.(TreeContributionPredictor)
+	if !ok {
+		return mat.Vector{}, fmt.Errorf("%s model does not support tree contributions", e.Name())
+	}
+	return p.PredictTreeContributionsInner(features)
+}
+
+// PredictStaged returns the cumulative predictions after each boosting round, element k of the result holds the
+// probabilities of every row predicted with the rounds [0, k]. The last element equals PredictProba. It is
+// useful to evaluate early stopping points without reloading the model, see also xgboost.WithIterationRange.
+func (e *Ensemble) PredictStaged(features mat.SparseMatrix) ([]mat.Matrix, error) {
+	p, ok := e.EnsembleBase.(StagedPredictor)
+	if !ok {
+		return nil, fmt.Errorf("%s model does not support staged prediction", e.Name())
+	}
+	var results []mat.Matrix
+	for i, row := range features.Vectors {
+		staged, err := p.PredictStagedInner(row)
+		if err != nil {
+			return nil, err
+		}
+		if results == nil {
+			results = make([]mat.Matrix, len(staged.Vectors))
+			for k := range results {
+				results[k].Vectors = make([]*mat.Vector, len(features.Vectors))
+			}
+		}
+		if len(staged.Vectors) != len(results) {
+			return nil, fmt.Errorf("%w: row %d has %d rounds, expected %d", mat.ErrDimensionMismatch, i,
+				len(staged.Vectors), len(results))
+		}
+		for k, raw := range staged.Vectors {
+			pred, err := e.Transform(*raw)
+			if err != nil {
+				return nil, err
+			}
+			results[k].Vectors[i] = &pred
+		}
+	}
+	return results, nil
+}
+
+// PredictLeafIndices returns for each row the id of the leaf reached in each tree, equivalent to XGBoost
+// predict(pred_leaf=True). Leaf ids are commonly used as categorical features of downstream models.
+func (e *Ensemble) PredictLeafIndices(features mat.SparseMatrix) ([][]int, error) {
+	p, ok := e.EnsembleBase.(LeafPredictor)
+	if !ok {
+		return nil, fmt.Errorf("%s model does not support leaf indices", e.Name())
+	}
+	results := make([][]int, len(features.Vectors))
+	for i, row := range features.Vectors {
+		r, err := p.PredictLeafIndicesInner(row)
+		if err != nil {
+			return nil, err
+		}
+		results[i] = r
+	}
+	return results, nil
+}
+
+// PredictClass predicts class labels. For binary classification the positive class is predicted when the
+// probability is at least 0.5, see PredictClassWithThreshold to use another threshold.
+func (e *Ensemble) PredictClass(features mat.SparseMatrix) ([]int, error) {
+	return e.PredictClassWithThreshold(features, 0.5)
+}
+
+// PredictClassWithThreshold predicts class labels using threshold for binary classification. For multiclass
+// models the class with the highest probability is returned and threshold is ignored.
+func (e *Ensemble) PredictClassWithThreshold(features mat.SparseMatrix, threshold float64) ([]int, error) {
+	probs, err := e.PredictProba(features)
+	if err != nil {
+		return nil, err
+	}
+	results := make([]int, len(probs.Vectors))
+	for i, p := range probs.Vectors {
+		if e.NumClasses() == 1 {
+			if (*p)[0] >= threshold {
+				results[i] = 1
+			}
+			continue
+		}
+		if e.Type() == protobuf.ActivateType_SOFTMAX_CLASS {
+			results[i] = int((*p)[0])
+			continue
+		}
+		idx, err := mat.GetVectorMaxIdx(p)
+		if err != nil {
+			return nil, err
+		}
+		results[i] = idx
+	}
+	return results, nil
+}
+
+// PredictClassProba predicts the probability of each class, so that models loaded with the multi:softmax objective
+// give the same probability matrix as multi:softprob models while PredictProba and PredictClass return their
+// predicted classes. Binary logistic models return the probability of the positive class like PredictProba.
+func (e *Ensemble) PredictClassProba(features mat.SparseMatrix) (mat.Matrix, error) {
+	switch e.Type() {
+	case protobuf.ActivateType_LOGISTIC, protobuf.ActivateType_SOFTMAX:
+		return e.PredictProba(features)
+	case protobuf.ActivateType_SOFTMAX_CLASS:
+	default:
+		return mat.Matrix{}, fmt.Errorf("class probabilities require a logistic or softmax activation, got %s",
+			e.Activation.Name())
+	}
+	margins, err := e.PredictMargin(features)
+	if err != nil {
+		return mat.Matrix{}, err
+	}
+	softmax := &activation.Softmax{}
+	for i, m := range margins.Vectors {
+		pred, err := softmax.Transform(*m)
+		if err != nil {
+			return mat.Matrix{}, err
+		}
+		margins.Vectors[i] = &pred
+	}
+	return margins, nil
+}
+
+// PredictProbaDense predicts probabilities of dense rows without converting them to a sparse matrix.
+// Only NaN values are treated as missing, zero values are real feature values.
+func (e *Ensemble) PredictProbaDense(features mat.Matrix) (mat.Matrix, error) {
+	if e.NumClasses() == 0 {
+		return mat.Matrix{}, fmt.Errorf("0 class please check your model")
+	}
+	p, ok := e.EnsembleBase.(DensePredictor)
+	if !ok {
+		return mat.Matrix{}, fmt.Errorf("%s model does not support dense prediction", e.Name())
+	}
+
+	results := mat.Matrix{Vectors: make([]*mat.Vector, len(features.Vectors))}
+	for i, row := range features.Vectors {
+		pred, err := p.PredictInnerDense(*row)
+		if err != nil {
+			return mat.Matrix{}, err
+		}
+		if len(pred) != e.NumClasses() {
+			return mat.Matrix{}, fmt.Errorf("%w: number of predicted value (%d) must match number of classes (%d)",
+				mat.ErrDimensionMismatch, len(pred), e.NumClasses())
+		}
+		pred, err = e.Transform(pred)
+		if err != nil {
+			return mat.Matrix{}, err
+		}
+		results.Vectors[i] = &pred
+	}
+	return results, nil
+}
+
+// PredictProbaFloat32 predicts probabilities of dense rows of float32 features like PredictProbaDense, using half
+// the memory of float64 inputs. Models loaded with the WithFloat32Values option then only traverse float32 values.
+func (e *Ensemble) PredictProbaFloat32(features [][]float32) (mat.Matrix, error) {
+	if e.NumClasses() == 0 {
+		return mat.Matrix{}, fmt.Errorf("0 class please check your model")
+	}
+	p, ok := e.EnsembleBase.(Float32Predictor)
+	if !ok {
+		return mat.Matrix{}, fmt.Errorf("%s model does not support float32 prediction", e.Name())
+	}
+
+	results := mat.Matrix{Vectors: make([]*mat.Vector, len(features))}
+	for i, row := range features {
+		pred, err := p.PredictInnerFloat32(row)
+		if err != nil {
+			return mat.Matrix{}, err
+		}
+		if len(pred) != e.NumClasses() {
+			return mat.Matrix{}, fmt.Errorf("%w: number of predicted value (%d) must match number of classes (%d)",
+				mat.ErrDimensionMismatch, len(pred), e.NumClasses())
+		}
+		pred, err = e.Transform(pred)
+		if err != nil {
+			return mat.Matrix{}, err
+		}
+		results.Vectors[i] = &pred
+	}
+	return results, nil
+}
+
+// PredictProbaFromArrow predicts probabilities of the rows of an Arrow IPC stream or file read from r, one record
+// batch at a time so that the stream is not buffered. columns selects the model features in order like
+// mat.ReadArrowToMatrix, null values are missing.
+func (e *Ensemble) PredictProbaFromArrow(r io.Reader, columns []string) (mat.Matrix, error) {
+	reader, err := mat.NewArrowStreamReader(r, columns)
+	if err != nil {
+		return mat.Matrix{}, err
+	}
+	results := mat.Matrix{Vectors: make([]*mat.Vector, 0)}
+	for {
+		batch, err := reader.Next()
+		if err == io.EOF {
+			return results, nil
+		}
+		if err != nil {
+			return mat.Matrix{}, err
+		}
+		preds, err := e.PredictProbaDense(batch)
+		if err != nil {
+			return mat.Matrix{}, err
+		}
+		results.Vectors = append(results.Vectors, preds.Vectors...)
+	}
+}
+
+// PredictProbaFromLibsvm reads a libsvm file and predicts probabilities of its rows.
+func (e *Ensemble) PredictProbaFromLibsvm(fileName string, opts ...mat.LibsvmOption) (mat.Matrix, error) {
+	features, err := mat.ReadLibsvmFileToSparseMatrix(fileName, opts...)
+	if err != nil {
+		return mat.Matrix{}, fmt.Errorf("cannot read %s: %w", fileName, err)
+	}
+	return e.PredictProba(features)
+}
@@ -0,0 +1,78 @@
+package inference
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/lordberre/xgboost-go/activation"
+	"github.com/lordberre/xgboost-go/mat"
+)
+
+// linearModel is a base model predicting for each class the sum of the features weighted by the weights of the
+// class, missing features count as 0.
+type linearModel struct {
+	weights [][]float64
+}
+
+func (m *linearModel) PredictInner(features mat.SparseVector) (mat.Vector, error) {
+	pred := make(mat.Vector, len(m.weights))
+	for c, w := range m.weights {
+		for idx := range features {
+			if idx < 0 || idx >= len(w) {
+				return nil, fmt.Errorf("%w: feature %d", mat.ErrFeatureIndexOutOfRange, idx)
+			}
+		}
+		// sum in feature order so that predictions do not depend on the map order.
+		for idx := range w {
+			if v, ok := features[idx]; ok && !math.IsNaN(v) {
+				pred[c] += w[idx] * v
+			}
+		}
+	}
+	return pred, nil
+}
+
+func (m *linearModel) PredictInnerDense(features mat.Vector) (mat.Vector, error) {
+	pred := make(mat.Vector, len(m.weights))
+	for c, w := range m.weights {
+		if len(features) != len(w) {
+			return nil, fmt.Errorf("%w: %d features", mat.ErrDimensionMismatch, len(features))
+		}
+		for idx, v := range features {
+			if !math.IsNaN(v) {
+				pred[c] += w[idx] * v
+			}
+		}
+	}
+	return pred, nil
+}
+
+func (m *linearModel) Name() string {
+	return "linear"
+}
+
+func (m *linearModel) NumClasses() int {
+	return len(m.weights)
+}
+
+// newTestEnsemble returns an ensemble of a linear model of 3 features with one output per class.
+func newTestEnsemble(numClasses int, act activation.Activation) *Ensemble {
+	weights := make([][]float64, numClasses)
+	for c := range weights {
+		weights[c] = []float64{0.5, -1, float64(c + 1)}
+	}
+	return &Ensemble{EnsembleBase: &linearModel{weights: weights}, Activation: act}
+}
+
+// testRows returns n distinct sparse rows of the features of newTestEnsemble.
+func testRows(n int) mat.SparseMatrix {
+	rows := mat.SparseMatrix{Vectors: make([]mat.SparseVector, n)}
+	for i := range rows.Vectors {
+		x := float64(i%100) / 100
+		rows.Vectors[i] = mat.SparseVector{0: x, 1: 1 - x}
+		if i%3 == 0 {
+			rows.Vectors[i][2] = x * x
+		}
+	}
+	return rows
+}
@@ -0,0 +1,63 @@
+package inference
+
+// MetadataProvider is an optional interface for base models exposing the metadata parsed from their model file.
+type MetadataProvider interface {
+	Objective() string
+	BaseScore() float64
+	NumTrees() int
+	Attributes() map[string]string
+}
+
+// Objective returns the XGBoost objective of the model, like binary:logistic, empty if it is unknown.
+func (e *Ensemble) Objective() string {
+	if p, ok := e.EnsembleBase.(MetadataProvider); ok {
+		return p.Objective()
+	}
+	return ""
+}
+
+// BaseScore returns the base_score of the model in the output space of its objective, 0 if it is unknown.
+func (e *Ensemble) BaseScore() float64 {
+	if p, ok := e.EnsembleBase.(MetadataProvider); ok {
+		return p.BaseScore()
+	}
+	return 0
+}
+
+// NumTrees returns the number of trees of the model, so that tree ranges can be chosen programmatically. For
+// multiclass models it is the number of boosting rounds times the number of classes, times num_parallel_tree for
+// boosted random forests. It returns 0 if the model does not expose its trees.
+func (e *Ensemble) NumTrees() int {
+	if p, ok := e.EnsembleBase.(MetadataProvider); ok {
+		return p.NumTrees()
+	}
+	return 0
+}
+
+// Attributes returns a copy of the attributes saved with the model, like best_iteration and best_score of models
+// trained with early stopping. It returns nil if the model has no attributes.
+func (e *Ensemble) Attributes() map[string]string {
+	p, ok := e.EnsembleBase.(MetadataProvider)
+	if !ok {
+		return nil
+	}
+	attributes := p.Attributes()
+	if attributes == nil {
+		return nil
+	}
+	r := make(map[string]string, len(attributes))
+	for k, v := range attributes {
+		r[k] = v
+	}
+	return r
+}
+
+// Attribute returns the attribute key saved with the model and whether it exists.
+func (e *Ensemble) Attribute(key string) (string, bool) {
+	p, ok := e.EnsembleBase.(MetadataProvider)
+	if !ok {
+		return "", false
+	}
+	v, ok := p.Attributes()[key]
+	return v, ok
+}
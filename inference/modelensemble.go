@@ -0,0 +1,97 @@
+package inference
+
+import (
+	"fmt"
+
+	"github.com/lordberre/xgboost-go/mat"
+)
+
+// ModelEnsemble averages predictions of several loaded models, e.g. a bagged set of XGBoost models.
+type ModelEnsemble struct {
+	members []*Ensemble
+	weights []float64
+}
+
+// NewModelEnsemble creates a model ensemble. If weights is nil all members get the same weight, otherwise
+// there must be one non-negative weight per member. All members must have the same number of classes,
+// number of features and activation type.
+func NewModelEnsemble(members []*Ensemble, weights []float64) (*ModelEnsemble, error) {
+	if len(members) == 0 {
+		return nil, fmt.Errorf("no member models")
+	}
+	if weights == nil {
+		weights = make([]float64, len(members))
+		for i := range weights {
+			weights[i] = 1.0
+		}
+	}
+	if len(weights) != len(members) {
+		return nil, fmt.Errorf("number of weights (%d) must match number of members (%d)",
+			len(weights), len(members))
+	}
+
+	sum := 0.0
+	for i, w := range weights {
+		if w < 0 {
+			return nil, fmt.Errorf("weight of member %d cannot be negative: %f", i, w)
+		}
+		sum += w
+	}
+	if sum <= 0 {
+		return nil, fmt.Errorf("sum of weights must be positive: %f", sum)
+	}
+
+	first := members[0]
+	for i, m := range members {
+		if m == nil {
+			return nil, fmt.Errorf("member %d is nil", i)
+		}
+		if m.NumClasses() != first.NumClasses() {
+			return nil, fmt.Errorf("member %d has %d classes, expected %d", i, m.NumClasses(), first.NumClasses())
+		}
+		if m.NumFeatures() != first.NumFeatures() {
+			return nil, fmt.Errorf("member %d expects %d features, expected %d",
+				i, m.NumFeatures(), first.NumFeatures())
+		}
+		if m.Type() != first.Type() {
+			return nil, fmt.Errorf("member %d has %s activation, expected %s", i, m.Activation.Name(),
+				first.Activation.Name())
+		}
+	}
+
+	normalized := make([]float64, len(weights))
+	for i, w := range weights {
+		normalized[i] = w / sum
+	}
+	return &ModelEnsemble{members: members, weights: normalized}, nil
+}
+
+// NumClasses returns number of classes of the member models.
+func (m *ModelEnsemble) NumClasses() int {
+	return m.members[0].NumClasses()
+}
+
+// PredictProba returns the weighted average of member probabilities.
+func (m *ModelEnsemble) PredictProba(features mat.SparseMatrix) (mat.Matrix, error) {
+	results := mat.Matrix{Vectors: make([]*mat.Vector, len(features.Vectors))}
+	for i := range results.Vectors {
+		v := make(mat.Vector, m.NumClasses())
+		results.Vectors[i] = &v
+	}
+	for k, member := range m.members {
+		pred, err := member.PredictProba(features)
+		if err != nil {
+			return mat.Matrix{}, fmt.Errorf("member %d: %s", k, err)
+		}
+		for i, p := range pred.Vectors {
+			if len(*p) != m.NumClasses() {
+				return mat.Matrix{}, fmt.Errorf("member %d predicted %d values, expected %d", k, len(*p),
+					m.NumClasses())
+			}
+			for j, val := range *p {
+				(*results.Vectors[i])[j] += m.weights[k] * val
+			}
+		}
+	}
+	return results, nil
+}
@@ -0,0 +1,62 @@
+package inference
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/lordberre/xgboost-go/mat"
+)
+
+// ErrUnknownFeature is returned when a row names a feature the model does not have.
+var ErrUnknownFeature = errors.New("unknown feature")
+
+// FeatureIndexer is an optional interface for base models able to find the index of a named feature without
+// scanning their feature names.
+type FeatureIndexer interface {
+	FeatureIndex(name string) (int, bool)
+}
+
+// FeatureIndex returns the index of the feature called name and false if the model has no such feature.
+func (e *Ensemble) FeatureIndex(name string) (int, bool) {
+	if i, ok := e.EnsembleBase.(FeatureIndexer); ok {
+		return i.FeatureIndex(name)
+	}
+	for idx, n := range e.featureNames() {
+		if n == name {
+			return idx, true
+		}
+	}
+	return 0, false
+}
+
+// VectorByName returns the sparse row of features keyed by feature name, so that rows built in Go do not depend
+// on the column order of the training data. Features absent from features are missing. An unknown name returns an
+// error wrapping ErrUnknownFeature.
+func (e *Ensemble) VectorByName(features map[string]float64) (mat.SparseVector, error) {
+	if e.featureNames() == nil {
+		return nil, fmt.Errorf("%s model does not name its features", e.Name())
+	}
+	row := make(mat.SparseVector, len(features))
+	for name, v := range features {
+		idx, ok := e.FeatureIndex(name)
+		if !ok {
+			return nil, fmt.Errorf("%w %q", ErrUnknownFeature, name)
+		}
+		row[idx] = v
+	}
+	return row, nil
+}
+
+// PredictByName predicts the probabilities of a single row of features keyed by feature name, like a row of
+// PredictProba. Names come from the model, like the feature_names of XGBoost models or the fmap of json dumps, or
+// from the WithFeatureNames and WithFeatureMap load options of the xgboost package.
+func (e *Ensemble) PredictByName(features map[string]float64) (mat.Vector, error) {
+	if e.NumClasses() == 0 {
+		return mat.Vector{}, fmt.Errorf("0 class please check your model")
+	}
+	row, err := e.VectorByName(features)
+	if err != nil {
+		return mat.Vector{}, err
+	}
+	return e.predictProbaRow(row)
+}
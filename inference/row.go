@@ -0,0 +1,88 @@
+package inference
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/lordberre/xgboost-go/mat"
+)
+
+// RowPredictor is an optional interface for base models able to predict a single row of a single output model
+// without allocating.
+type RowPredictor interface {
+	PredictRowInner(features []float64) (float64, error)
+	PredictSparseRowInner(features mat.SparseVector) (float64, error)
+}
+
+// rowPool contains the single element vectors given to activations by PredictRow.
+var rowPool = sync.Pool{New: func() interface{} {
+	v := make(mat.Vector, 1)
+	return &v
+}}
+
+// PredictRow predicts the probability, or the transformed value for regression, of a single dense row of a
+// binary or regression model, NaN values are missing. It is meant for low latency serving: rows do not have to be
+// wrapped in a matrix and base models implementing RowPredictor, like XGBoost models, predict without allocating
+// when the activation transforms values in place like the built-in ones.
+func (e *Ensemble) PredictRow(features []float64) (float64, error) {
+	if p, ok := e.EnsembleBase.(RowPredictor); ok {
+		raw, err := p.PredictRowInner(features)
+		if err != nil {
+			return 0, err
+		}
+		return e.transformRow(raw)
+	}
+	d, ok := e.EnsembleBase.(DensePredictor)
+	if !ok {
+		return 0, fmt.Errorf("%s model does not support dense rows", e.Name())
+	}
+	pred, err := d.PredictInnerDense(features)
+	if err != nil {
+		return 0, err
+	}
+	return e.transformVector(pred)
+}
+
+// PredictSparseRow predicts a single sparse row like PredictRow.
+func (e *Ensemble) PredictSparseRow(features mat.SparseVector) (float64, error) {
+	if p, ok := e.EnsembleBase.(RowPredictor); ok {
+		raw, err := p.PredictSparseRowInner(features)
+		if err != nil {
+			return 0, err
+		}
+		return e.transformRow(raw)
+	}
+	pred, err := e.PredictInner(features)
+	if err != nil {
+		return 0, err
+	}
+	return e.transformVector(pred)
+}
+
+// transformRow applies the activation to a single raw prediction using a pooled vector.
+func (e *Ensemble) transformRow(raw float64) (float64, error) {
+	v := rowPool.Get().(*mat.Vector)
+	defer rowPool.Put(v)
+	(*v)[0] = raw
+	pred, err := e.Transform(*v)
+	if err != nil {
+		return 0, err
+	}
+	if len(pred) != 1 {
+		return 0, fmt.Errorf("%w: activation returned %d values for a single output model", mat.ErrDimensionMismatch,
+			len(pred))
+	}
+	return pred[0], nil
+}
+
+// transformVector applies the activation to the raw prediction of a single output model.
+func (e *Ensemble) transformVector(pred mat.Vector) (float64, error) {
+	if e.NumClasses() != 1 || len(pred) != 1 {
+		return 0, fmt.Errorf("single row prediction requires a single output model, got %d classes", e.NumClasses())
+	}
+	pred, err := e.Transform(pred)
+	if err != nil {
+		return 0, err
+	}
+	return pred[0], nil
+}
@@ -0,0 +1,85 @@
+package inference
+
+import (
+	"math"
+	"testing"
+
+	"gotest.tools/assert"
+
+	"github.com/lordberre/xgboost-go/activation"
+	"github.com/lordberre/xgboost-go/mat"
+)
+
+// raceEnabled is set when testing with the race detector.
+var raceEnabled bool
+
+// rowModel is a single output linear model predicting single rows without allocating.
+type rowModel struct {
+	*linearModel
+}
+
+func (m rowModel) PredictRowInner(features []float64) (float64, error) {
+	w := m.weights[0]
+	if len(features) != len(w) {
+		return 0, mat.ErrDimensionMismatch
+	}
+	var sum float64
+	for idx, v := range features {
+		if !math.IsNaN(v) {
+			sum += w[idx] * v
+		}
+	}
+	return sum, nil
+}
+
+func (m rowModel) PredictSparseRowInner(features mat.SparseVector) (float64, error) {
+	w := m.weights[0]
+	var sum float64
+	for idx := range w {
+		if v, ok := features[idx]; ok && !math.IsNaN(v) {
+			sum += w[idx] * v
+		}
+	}
+	return sum, nil
+}
+
+func TestEnsemble_PredictRow(t *testing.T) {
+	ensemble := newTestEnsemble(1, &activation.Logistic{})
+	rows := &Ensemble{EnsembleBase: rowModel{ensemble.EnsembleBase.(*linearModel)}, Activation: ensemble.Activation}
+	input := testRows(10)
+	expected, err := ensemble.PredictProba(input)
+	assert.NilError(t, err)
+
+	dense := make([]float64, 3)
+	for i, row := range input.Vectors {
+		for j := range dense {
+			dense[j] = math.NaN()
+		}
+		for j, v := range row {
+			dense[j] = v
+		}
+		// through RowPredictor and through the dense and sparse predictions of base models.
+		for _, e := range []*Ensemble{rows, ensemble} {
+			pred, err := e.PredictRow(dense)
+			assert.NilError(t, err)
+			assert.Equal(t, pred, (*expected.Vectors[i])[0])
+			pred, err = e.PredictSparseRow(row)
+			assert.NilError(t, err)
+			assert.Equal(t, pred, (*expected.Vectors[i])[0])
+		}
+	}
+
+	// in place activations transform the pooled vector of PredictRow.
+	if !raceEnabled {
+		allocs := testing.AllocsPerRun(100, func() {
+			_, _ = rows.PredictRow(dense)
+			_, _ = rows.PredictSparseRow(input.Vectors[0])
+		})
+		assert.Equal(t, allocs, 0.0)
+	}
+
+	_, err = rows.PredictRow(dense[:2])
+	assert.Check(t, err != nil)
+	_, err = newTestEnsemble(3, &activation.Softmax{}).PredictSparseRow(input.Vectors[0])
+	assert.ErrorContains(t, err, "single row prediction requires a single output model")
+}
@@ -0,0 +1,62 @@
+package inference
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/lordberre/xgboost-go/mat"
+)
+
+// StatsPredictor is an optional interface for base models able to report the number of visited tree nodes.
+type StatsPredictor interface {
+	PredictInnerWithStats(features mat.SparseVector) (mat.Vector, int, error)
+}
+
+// Stats contains profiling information of a prediction call.
+type Stats struct {
+	// Rows is the number of predicted rows.
+	Rows int
+	// NodesVisited is the total number of tree nodes visited, including leaves.
+	NodesVisited int
+	// TraversalTime is the time spent walking the trees.
+	TraversalTime time.Duration
+	// ActivationTime is the time spent applying the activation.
+	ActivationTime time.Duration
+}
+
+// PredictProbaWithStats predicts probabilities like PredictProba and reports where time was spent.
+// Use PredictProba when stats are not needed, it does not pay for the timing calls.
+func (e *Ensemble) PredictProbaWithStats(features mat.SparseMatrix) (mat.Matrix, Stats, error) {
+	if e.NumClasses() == 0 {
+		return mat.Matrix{}, Stats{}, fmt.Errorf("0 class please check your model")
+	}
+	p, ok := e.EnsembleBase.(StatsPredictor)
+	if !ok {
+		return mat.Matrix{}, Stats{}, fmt.Errorf("%s model does not support prediction stats", e.Name())
+	}
+
+	stats := Stats{}
+	results := mat.Matrix{Vectors: make([]*mat.Vector, len(features.Vectors))}
+	for i, row := range features.Vectors {
+		start := time.Now()
+		pred, visited, err := p.PredictInnerWithStats(row)
+		if err != nil {
+			return mat.Matrix{}, Stats{}, err
+		}
+		traversed := time.Now()
+		stats.TraversalTime += traversed.Sub(start)
+		stats.NodesVisited += visited
+		if len(pred) != e.NumClasses() {
+			return mat.Matrix{}, Stats{}, fmt.Errorf("%w: number of predicted value (%d) must match number of classes (%d)",
+				mat.ErrDimensionMismatch, len(pred), e.NumClasses())
+		}
+		pred, err = e.Transform(pred)
+		if err != nil {
+			return mat.Matrix{}, Stats{}, err
+		}
+		stats.ActivationTime += time.Since(traversed)
+		stats.Rows++
+		results.Vectors[i] = &pred
+	}
+	return results, stats, nil
+}
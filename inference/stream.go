@@ -0,0 +1,55 @@
+package inference
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/lordberre/xgboost-go/mat"
+)
+
+// Result is the prediction of a row received by PredictStream.
+type Result struct {
+	// Index is the position of the row in the input stream.
+	Index int
+	// Prediction contains the probabilities of the row like a row of PredictProba, it is nil if Err is not nil.
+	Prediction mat.Vector
+	Err        error
+}
+
+// PredictStream predicts probabilities of rows as they are received from rows, so that streams can be scored
+// without materializing a matrix. Results are sent in the order of the rows on the returned channel, which is
+// unbuffered: the next row is only received once the result of the previous one has been consumed, so a slow
+// consumer slows down the producer. A failed row sends a Result with Err and the stream goes on. The returned
+// channel is closed once rows is closed or ctx is cancelled, in which case remaining rows are not received.
+func (e *Ensemble) PredictStream(ctx context.Context, rows <-chan mat.SparseVector) <-chan Result {
+	results := make(chan Result)
+	go func() {
+		defer close(results)
+		for i := 0; ; i++ {
+			var row mat.SparseVector
+			var ok bool
+			select {
+			case <-ctx.Done():
+				return
+			case row, ok = <-rows:
+				if !ok {
+					return
+				}
+			}
+			r := Result{Index: i}
+			if e.NumClasses() == 0 {
+				r.Err = fmt.Errorf("0 class please check your model")
+			} else if pred, err := e.predictProbaRow(row); err != nil {
+				r.Err = err
+			} else {
+				r.Prediction = pred
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case results <- r:
+			}
+		}
+	}()
+	return results
+}
@@ -0,0 +1,71 @@
+package inference
+
+import (
+	"context"
+	"testing"
+
+	"gotest.tools/assert"
+
+	"github.com/lordberre/xgboost-go/activation"
+	"github.com/lordberre/xgboost-go/mat"
+)
+
+func TestEnsemble_PredictStream(t *testing.T) {
+	ensemble := newTestEnsemble(3, &activation.Softmax{})
+	input := testRows(100)
+	expected, err := ensemble.PredictProba(input)
+	assert.NilError(t, err)
+
+	rows := make(chan mat.SparseVector)
+	go func() {
+		defer close(rows)
+		for _, row := range input.Vectors {
+			rows <- row
+		}
+	}()
+	n := 0
+	for r := range ensemble.PredictStream(context.Background(), rows) {
+		assert.NilError(t, r.Err)
+		assert.Equal(t, r.Index, n)
+		assert.DeepEqual(t, r.Prediction, *expected.Vectors[n])
+		n++
+	}
+	assert.Equal(t, n, len(input.Vectors))
+
+	// the stream stops on cancellation even if rows is never closed.
+	ctx, cancel := context.WithCancel(context.Background())
+	rows = make(chan mat.SparseVector, 1)
+	rows <- input.Vectors[0]
+	results := ensemble.PredictStream(ctx, rows)
+	r := <-results
+	assert.NilError(t, r.Err)
+	cancel()
+	_, ok := <-results
+	assert.Check(t, !ok)
+
+	// failed rows are reported and the stream goes on.
+	rows = make(chan mat.SparseVector, 3)
+	rows <- mat.SparseVector{7: 1}
+	rows <- input.Vectors[1]
+	rows <- mat.SparseVector{-1: 1}
+	close(rows)
+	var got []Result
+	for r := range ensemble.PredictStream(context.Background(), rows) {
+		got = append(got, r)
+	}
+	assert.Equal(t, len(got), 3)
+	for _, i := range []int{0, 2} {
+		assert.Equal(t, got[i].Index, i)
+		assert.Check(t, got[i].Err != nil)
+		assert.Check(t, got[i].Prediction == nil)
+	}
+	assert.NilError(t, got[1].Err)
+	assert.DeepEqual(t, got[1].Prediction, *expected.Vectors[1])
+
+	rows = make(chan mat.SparseVector, 1)
+	rows <- input.Vectors[0]
+	close(rows)
+	for r := range newTestEnsemble(0, &activation.Raw{}).PredictStream(context.Background(), rows) {
+		assert.ErrorContains(t, r.Err, "0 class")
+	}
+}
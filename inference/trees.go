@@ -0,0 +1,135 @@
+package inference
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Node is a node of a tree returned by Trees.
+type Node struct {
+	// ID is the index of the node in Tree.Nodes, the root has ID 0.
+	ID   int
+	Leaf bool
+	// Value is the value of leaves.
+	Value float64
+	// Feature is the index of the split feature.
+	Feature int
+	// Threshold is the threshold of numerical splits, values lower than it go to the Yes child.
+	Threshold float64
+	// Categories contains the sorted categories going to the Yes child of categorical splits, nil for numerical
+	// splits.
+	Categories []int
+	// Yes, No and Missing are the IDs of the children of splits, Missing is the child of missing values.
+	Yes     int
+	No      int
+	Missing int
+	// ZeroAsMissing is set for LightGBM splits sending zero values to the Missing child.
+	ZeroAsMissing bool
+	// Cover and Gain are the statistics of the node, 0 if the model does not contain them.
+	Cover float64
+	Gain  float64
+}
+
+// Tree is a tree of the ensemble.
+type Tree struct {
+	// Class is the class whose raw score the tree contributes to.
+	Class int
+	// Nodes contains the nodes indexed by ID, IDs without node are nil.
+	Nodes []*Node
+}
+
+// TreeProvider is an optional interface for base models exposing their trees.
+type TreeProvider interface {
+	TreesInner() []Tree
+}
+
+// Trees returns a copy of the trees of the ensemble in prediction order, modifying them does not change the
+// ensemble.
+func (e *Ensemble) Trees() ([]Tree, error) {
+	p, ok := e.EnsembleBase.(TreeProvider)
+	if !ok {
+		return nil, fmt.Errorf("%s model does not expose its trees", e.Name())
+	}
+	return p.TreesInner(), nil
+}
+
+// DumpText writes the trees in the text format of XGBoost Booster.dump_model, with gain and cover statistics if
+// withStats is true. Features are named by the model or f<index> otherwise.
+func (e *Ensemble) DumpText(w io.Writer, withStats bool) error {
+	trees, err := e.Trees()
+	if err != nil {
+		return err
+	}
+	names := e.featureNames()
+	bw := bufio.NewWriter(w)
+	for i, t := range trees {
+		fmt.Fprintf(bw, "booster[%d]:\n", i)
+		if err := dumpNode(bw, t, 0, 0, names, withStats); err != nil {
+			return fmt.Errorf("error while dumping %d tree: %w", i, err)
+		}
+	}
+	return bw.Flush()
+}
+
+// dumpNode writes the node id of tree t and its children indented by depth.
+func dumpNode(w *bufio.Writer, t Tree, id, depth int, names []string, withStats bool) error {
+	if id < 0 || id >= len(t.Nodes) || t.Nodes[id] == nil {
+		return fmt.Errorf("no node %d", id)
+	}
+	if depth > len(t.Nodes) {
+		return fmt.Errorf("tree has a cycle")
+	}
+	n := t.Nodes[id]
+	w.WriteString(strings.Repeat("\t", depth))
+	if n.Leaf {
+		fmt.Fprintf(w, "%d:leaf=%s", id, formatFloat(n.Value))
+		if withStats {
+			fmt.Fprintf(w, ",cover=%s", formatFloat(n.Cover))
+		}
+		w.WriteString("\n")
+		return nil
+	}
+	fmt.Fprintf(w, "%d:[%s]", id, splitCondition(n, names))
+	fmt.Fprintf(w, " yes=%d,no=%d,missing=%d", n.Yes, n.No, n.Missing)
+	if withStats {
+		fmt.Fprintf(w, ",gain=%s,cover=%s", formatFloat(n.Gain), formatFloat(n.Cover))
+	}
+	w.WriteString("\n")
+	if err := dumpNode(w, t, n.Yes, depth+1, names, withStats); err != nil {
+		return err
+	}
+	return dumpNode(w, t, n.No, depth+1, names, withStats)
+}
+
+// featureNames returns the names of the features of the model, nil if it does not name them.
+func (e *Ensemble) featureNames() []string {
+	if n, ok := e.EnsembleBase.(FeatureNamer); ok {
+		return n.FeatureNames()
+	}
+	return nil
+}
+
+// splitCondition returns the condition of split n sending values to the yes child, like f0<0.5 or f1:{1,3} for
+// categorical splits. Features are named f<index> if names does not contain them.
+func splitCondition(n *Node, names []string) string {
+	name := "f" + strconv.Itoa(n.Feature)
+	if n.Feature < len(names) && names[n.Feature] != "" {
+		name = names[n.Feature]
+	}
+	if n.Categories == nil {
+		return name + "<" + formatFloat(n.Threshold)
+	}
+	categories := make([]string, len(n.Categories))
+	for i, c := range n.Categories {
+		categories[i] = strconv.Itoa(c)
+	}
+	return name + ":{" + strings.Join(categories, ",") + "}"
+}
+
+// formatFloat returns the shortest representation of v.
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
@@ -0,0 +1,688 @@
+package mat
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+)
+
+// Arrow IPC format constants, see https://arrow.apache.org/docs/format/Columnar.html for details.
+const (
+	arrowMagic        = "ARROW1"
+	arrowContinuation = 0xFFFFFFFF
+
+	arrowHeaderSchema      = 1
+	arrowHeaderDictionary  = 2
+	arrowHeaderRecordBatch = 3
+
+	arrowTypeNull            = 1
+	arrowTypeInt             = 2
+	arrowTypeFloatingPoint   = 3
+	arrowTypeBinary          = 4
+	arrowTypeUtf8            = 5
+	arrowTypeBool            = 6
+	arrowTypeDecimal         = 7
+	arrowTypeDate            = 8
+	arrowTypeTime            = 9
+	arrowTypeTimestamp       = 10
+	arrowTypeInterval        = 11
+	arrowTypeFixedSizeBinary = 15
+	arrowTypeDuration        = 18
+	arrowTypeLargeBinary     = 19
+	arrowTypeLargeUtf8       = 20
+
+	arrowPrecisionSingle = 1
+	arrowPrecisionDouble = 2
+)
+
+// fbTable is a flatbuffers table used to decode Arrow metadata.
+type fbTable struct {
+	buf []byte
+	pos int
+}
+
+func fbRoot(buf []byte) (fbTable, error) {
+	if len(buf) < 4 {
+		return fbTable{}, fmt.Errorf("%w: truncated arrow metadata", ErrMalformedRow)
+	}
+	t := fbTable{buf: buf, pos: int(binary.LittleEndian.Uint32(buf))}
+	if err := t.check(t.pos, 4); err != nil {
+		return fbTable{}, err
+	}
+	return t, nil
+}
+
+func (t fbTable) check(pos, size int) error {
+	if pos < 0 || size < 0 || pos+size > len(t.buf) {
+		return fmt.Errorf("%w: truncated arrow metadata", ErrMalformedRow)
+	}
+	return nil
+}
+
+// field returns the absolute position of field id or 0 if the field is absent.
+func (t fbTable) field(id int) int {
+	vtable := t.pos - int(int32(binary.LittleEndian.Uint32(t.buf[t.pos:])))
+	if t.check(vtable, 4) != nil {
+		return 0
+	}
+	vtableSize := int(binary.LittleEndian.Uint16(t.buf[vtable:]))
+	entry := 4 + 2*id
+	if entry+2 > vtableSize || t.check(vtable+entry, 2) != nil {
+		return 0
+	}
+	off := int(binary.LittleEndian.Uint16(t.buf[vtable+entry:]))
+	if off == 0 {
+		return 0
+	}
+	return t.pos + off
+}
+
+func (t fbTable) uint8(id int) uint8 {
+	pos := t.field(id)
+	if pos == 0 || t.check(pos, 1) != nil {
+		return 0
+	}
+	return t.buf[pos]
+}
+
+func (t fbTable) int16(id int) int16 {
+	pos := t.field(id)
+	if pos == 0 || t.check(pos, 2) != nil {
+		return 0
+	}
+	return int16(binary.LittleEndian.Uint16(t.buf[pos:]))
+}
+
+func (t fbTable) int32(id int) int32 {
+	pos := t.field(id)
+	if pos == 0 || t.check(pos, 4) != nil {
+		return 0
+	}
+	return int32(binary.LittleEndian.Uint32(t.buf[pos:]))
+}
+
+func (t fbTable) int64(id int) int64 {
+	pos := t.field(id)
+	if pos == 0 || t.check(pos, 8) != nil {
+		return 0
+	}
+	return int64(binary.LittleEndian.Uint64(t.buf[pos:]))
+}
+
+// indirect returns the position an offset field points to.
+func (t fbTable) indirect(id int) (int, bool) {
+	pos := t.field(id)
+	if pos == 0 || t.check(pos, 4) != nil {
+		return 0, false
+	}
+	target := pos + int(binary.LittleEndian.Uint32(t.buf[pos:]))
+	if t.check(target, 4) != nil {
+		return 0, false
+	}
+	return target, true
+}
+
+func (t fbTable) table(id int) (fbTable, bool) {
+	pos, ok := t.indirect(id)
+	if !ok {
+		return fbTable{}, false
+	}
+	return fbTable{buf: t.buf, pos: pos}, true
+}
+
+func (t fbTable) string(id int) string {
+	pos, ok := t.indirect(id)
+	if !ok {
+		return ""
+	}
+	n := int(binary.LittleEndian.Uint32(t.buf[pos:]))
+	if t.check(pos+4, n) != nil {
+		return ""
+	}
+	return string(t.buf[pos+4 : pos+4+n])
+}
+
+// vector returns the position of the first element and the number of elements of a vector field.
+func (t fbTable) vector(id int, elemSize int) (int, int, error) {
+	pos, ok := t.indirect(id)
+	if !ok {
+		return 0, 0, nil
+	}
+	n := int(binary.LittleEndian.Uint32(t.buf[pos:]))
+	if err := t.check(pos+4, n*elemSize); err != nil {
+		return 0, 0, err
+	}
+	return pos + 4, n, nil
+}
+
+func (t fbTable) tables(id int) ([]fbTable, error) {
+	pos, n, err := t.vector(id, 4)
+	if err != nil {
+		return nil, err
+	}
+	r := make([]fbTable, n)
+	for i := range r {
+		elem := pos + 4*i
+		r[i] = fbTable{buf: t.buf, pos: elem + int(binary.LittleEndian.Uint32(t.buf[elem:]))}
+		if err := t.check(r[i].pos, 4); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+// arrowField is a top level column of an Arrow schema.
+type arrowField struct {
+	name      string
+	typeID    uint8
+	bitWidth  int
+	signed    bool
+	precision int16
+}
+
+func (f arrowField) numeric() bool {
+	switch f.typeID {
+	case arrowTypeInt:
+		return f.bitWidth == 8 || f.bitWidth == 16 || f.bitWidth == 32 || f.bitWidth == 64
+	case arrowTypeFloatingPoint:
+		return f.precision == arrowPrecisionSingle || f.precision == arrowPrecisionDouble
+	}
+	return false
+}
+
+// byteWidth returns the number of bytes of a value of a numeric column.
+func (f arrowField) byteWidth() int {
+	if f.typeID == arrowTypeFloatingPoint {
+		if f.precision == arrowPrecisionDouble {
+			return 8
+		}
+		return 4
+	}
+	return f.bitWidth / 8
+}
+
+// numBuffers returns the number of body buffers of a non nested column.
+func (f arrowField) numBuffers() (int, error) {
+	switch f.typeID {
+	case arrowTypeNull:
+		return 0, nil
+	case arrowTypeInt, arrowTypeFloatingPoint, arrowTypeBool, arrowTypeDecimal, arrowTypeDate, arrowTypeTime,
+		arrowTypeTimestamp, arrowTypeInterval, arrowTypeFixedSizeBinary, arrowTypeDuration:
+		return 2, nil
+	case arrowTypeBinary, arrowTypeUtf8, arrowTypeLargeBinary, arrowTypeLargeUtf8:
+		return 3, nil
+	}
+	return 0, fmt.Errorf("column %s has unsupported arrow type %d, only flat schemas are supported", f.name, f.typeID)
+}
+
+func parseArrowSchema(schema fbTable) ([]arrowField, error) {
+	if schema.int16(0) != 0 {
+		return nil, fmt.Errorf("big endian arrow data is not supported")
+	}
+	fields, err := schema.tables(1)
+	if err != nil {
+		return nil, err
+	}
+	r := make([]arrowField, len(fields))
+	for i, field := range fields {
+		f := arrowField{name: field.string(0), typeID: field.uint8(2)}
+		if _, ok := field.table(4); ok {
+			return nil, fmt.Errorf("column %s is dictionary encoded which is not supported", f.name)
+		}
+		if typ, ok := field.table(3); ok {
+			switch f.typeID {
+			case arrowTypeInt:
+				f.bitWidth = int(typ.int32(0))
+				f.signed = typ.uint8(1) != 0
+			case arrowTypeFloatingPoint:
+				f.precision = typ.int16(0)
+			}
+		}
+		r[i] = f
+	}
+	return r, nil
+}
+
+// arrowMessage is an IPC message with its flatbuffers header and body.
+type arrowMessage struct {
+	headerType uint8
+	header     fbTable
+	body       []byte
+}
+
+// readArrowMessage reads the message at pos and returns the position of the next one, ok is false at the end of
+// the stream.
+func readArrowMessage(data []byte, pos int) (arrowMessage, int, bool, error) {
+	if pos+4 > len(data) {
+		return arrowMessage{}, pos, false, nil
+	}
+	if binary.LittleEndian.Uint32(data[pos:]) == arrowContinuation {
+		pos += 4
+	}
+	if pos+4 > len(data) {
+		return arrowMessage{}, pos, false, fmt.Errorf("%w: truncated arrow message", ErrMalformedRow)
+	}
+	size := int(int32(binary.LittleEndian.Uint32(data[pos:])))
+	pos += 4
+	if size == 0 {
+		return arrowMessage{}, pos, false, nil
+	}
+	if size < 0 || pos+size > len(data) {
+		return arrowMessage{}, pos, false, fmt.Errorf("%w: truncated arrow message", ErrMalformedRow)
+	}
+	msg, err := fbRoot(data[pos : pos+size])
+	if err != nil {
+		return arrowMessage{}, pos, false, err
+	}
+	pos += size
+	bodyLength := int(msg.int64(3))
+	if bodyLength < 0 || pos+bodyLength > len(data) {
+		return arrowMessage{}, pos, false, fmt.Errorf("%w: truncated arrow message body", ErrMalformedRow)
+	}
+	header, _ := msg.table(2)
+	r := arrowMessage{headerType: msg.uint8(1), header: header, body: data[pos : pos+bodyLength]}
+	return r, pos + bodyLength, true, nil
+}
+
+// arrowColumn decodes a numeric column of a record batch, null values are NaN.
+func arrowColumn(f arrowField, rows, nullCount int, validity, values []byte) (Vector, error) {
+	width := f.byteWidth()
+	if len(values) < rows*width {
+		return nil, fmt.Errorf("%w: column %s has %d bytes of values for %d rows", ErrMalformedRow, f.name,
+			len(values), rows)
+	}
+	if nullCount > 0 && len(validity) < (rows+7)/8 {
+		return nil, fmt.Errorf("%w: column %s has truncated validity bitmap", ErrMalformedRow, f.name)
+	}
+	r := make(Vector, rows)
+	for i := range r {
+		if nullCount > 0 && validity[i/8]&(1<<(uint(i)%8)) == 0 {
+			r[i] = math.NaN()
+			continue
+		}
+		b := values[i*width:]
+		switch {
+		case f.typeID == arrowTypeFloatingPoint && width == 4:
+			r[i] = float64(math.Float32frombits(binary.LittleEndian.Uint32(b)))
+		case f.typeID == arrowTypeFloatingPoint:
+			r[i] = math.Float64frombits(binary.LittleEndian.Uint64(b))
+		case width == 1 && f.signed:
+			r[i] = float64(int8(b[0]))
+		case width == 1:
+			r[i] = float64(b[0])
+		case width == 2 && f.signed:
+			r[i] = float64(int16(binary.LittleEndian.Uint16(b)))
+		case width == 2:
+			r[i] = float64(binary.LittleEndian.Uint16(b))
+		case width == 4 && f.signed:
+			r[i] = float64(int32(binary.LittleEndian.Uint32(b)))
+		case width == 4:
+			r[i] = float64(binary.LittleEndian.Uint32(b))
+		case f.signed:
+			r[i] = float64(int64(binary.LittleEndian.Uint64(b)))
+		default:
+			r[i] = float64(binary.LittleEndian.Uint64(b))
+		}
+	}
+	return r, nil
+}
+
+// appendArrowRecordBatch appends rows of a record batch to matrix using the given schema columns.
+func appendArrowRecordBatch(matrix *Matrix, msg arrowMessage, fields []arrowField, selected []int) error {
+	if _, ok := msg.header.table(3); ok {
+		return fmt.Errorf("compressed arrow record batches are not supported")
+	}
+	length := msg.header.int64(0)
+	if length < 0 {
+		return fmt.Errorf("%w: record batch has negative length %d", ErrMalformedRow, length)
+	}
+	// the values of every numeric column are in the body, so a larger length cannot be valid and must not be
+	// allocated.
+	for _, f := range fields {
+		if f.numeric() && length > int64(len(msg.body)/f.byteWidth()) {
+			return fmt.Errorf("%w: record batch of %d rows exceeds its %d bytes body", ErrMalformedRow, length,
+				len(msg.body))
+		}
+	}
+	rows := int(length)
+	nodesPos, numNodes, err := msg.header.vector(1, 16)
+	if err != nil {
+		return err
+	}
+	buffersPos, numBuffers, err := msg.header.vector(2, 16)
+	if err != nil {
+		return err
+	}
+	if numNodes != len(fields) {
+		return fmt.Errorf("%w: record batch has %d columns, schema has %d", ErrDimensionMismatch, numNodes,
+			len(fields))
+	}
+	buf := msg.header.buf
+	buffer := func(i int) ([]byte, error) {
+		if i >= numBuffers {
+			return nil, fmt.Errorf("%w: missing arrow buffer %d", ErrMalformedRow, i)
+		}
+		offset := int(binary.LittleEndian.Uint64(buf[buffersPos+16*i:]))
+		length := int(binary.LittleEndian.Uint64(buf[buffersPos+16*i+8:]))
+		if offset < 0 || length < 0 || offset+length > len(msg.body) {
+			return nil, fmt.Errorf("%w: arrow buffer %d is out of the message body", ErrMalformedRow, i)
+		}
+		return msg.body[offset : offset+length], nil
+	}
+
+	columns := make([]Vector, len(fields))
+	bufferIdx := 0
+	for i, f := range fields {
+		n, err := f.numBuffers()
+		if err != nil {
+			return err
+		}
+		if f.numeric() {
+			length := int(binary.LittleEndian.Uint64(buf[nodesPos+16*i:]))
+			nullCount := int(binary.LittleEndian.Uint64(buf[nodesPos+16*i+8:]))
+			if length != rows {
+				return fmt.Errorf("%w: column %s has %d rows, record batch has %d", ErrDimensionMismatch, f.name,
+					length, rows)
+			}
+			validity, err := buffer(bufferIdx)
+			if err != nil {
+				return err
+			}
+			values, err := buffer(bufferIdx + 1)
+			if err != nil {
+				return err
+			}
+			if columns[i], err = arrowColumn(f, rows, nullCount, validity, values); err != nil {
+				return err
+			}
+		}
+		bufferIdx += n
+	}
+	for r := 0; r < rows; r++ {
+		vec := make(Vector, len(selected))
+		for j, c := range selected {
+			vec[j] = columns[c][r]
+		}
+		matrix.Vectors = append(matrix.Vectors, &vec)
+	}
+	return nil
+}
+
+// arrowColumns returns the indices and names of the schema fields to read, every numeric field when columns is
+// empty. name identifies the data in errors.
+func arrowColumns(fields []arrowField, columns []string, name string) ([]int, []string, error) {
+	selected := make([]int, 0, len(fields))
+	if len(columns) == 0 {
+		for i, f := range fields {
+			if f.numeric() {
+				selected = append(selected, i)
+			}
+		}
+	} else {
+		index := make(map[string]int, len(fields))
+		for i, f := range fields {
+			index[f.name] = i
+		}
+		for _, column := range columns {
+			i, ok := index[column]
+			if !ok {
+				return nil, nil, fmt.Errorf("column %s not found in %s", column, name)
+			}
+			if !fields[i].numeric() {
+				return nil, nil, fmt.Errorf("column %s is not numeric", column)
+			}
+			selected = append(selected, i)
+		}
+	}
+	names := make([]string, len(selected))
+	for j, c := range selected {
+		names[j] = fields[c].name
+	}
+	return selected, names, nil
+}
+
+// ReadArrowToMatrix reads an Apache Arrow IPC file (Feather v2) or stream into a dense matrix. columns selects
+// the columns to read in the given order, if empty all numeric columns are read. Integer and floating point
+// columns are supported, null values become NaN so they are treated as missing by dense prediction.
+// It returns the names of the read columns which can be used to align them with a feature map.
+// Compressed, dictionary encoded and nested data is not supported, Parquet files are read by
+// ReadParquetToDenseMatrix.
+func ReadArrowToMatrix(fileName string, columns []string) (Matrix, []string, error) {
+	data, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		return Matrix{}, nil, fmt.Errorf("unable to open %s: %w", fileName, err)
+	}
+	return readArrow(data, fileName, columns)
+}
+
+// ReadArrow reads an Apache Arrow IPC file or stream from r like ReadArrowToMatrix. The whole content is buffered
+// since the file format has its footer at the end, see ArrowStreamReader to score record batches as they arrive.
+func ReadArrow(r io.Reader, columns []string) (Matrix, []string, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return Matrix{}, nil, err
+	}
+	return readArrow(data, "input", columns)
+}
+
+// readArrow reads Arrow IPC file or stream content, name identifies the data in errors.
+func readArrow(data []byte, name string, columns []string) (Matrix, []string, error) {
+
+	var fields []arrowField
+	var blocks []int
+	pos := 0
+	if bytes.HasPrefix(data, []byte(arrowMagic)) {
+		// file format, read the schema and record batch locations from the footer.
+		if len(data) < 2*len(arrowMagic)+6 || !bytes.HasSuffix(data, []byte(arrowMagic)) {
+			return Matrix{}, nil, fmt.Errorf("%w: truncated arrow file %s", ErrMalformedRow, name)
+		}
+		end := len(data) - len(arrowMagic) - 4
+		footerLen := int(int32(binary.LittleEndian.Uint32(data[end:])))
+		if footerLen <= 0 || footerLen > end-len(arrowMagic) {
+			return Matrix{}, nil, fmt.Errorf("%w: wrong arrow footer length %d", ErrMalformedRow, footerLen)
+		}
+		footer, err := fbRoot(data[end-footerLen : end])
+		if err != nil {
+			return Matrix{}, nil, err
+		}
+		schema, ok := footer.table(1)
+		if !ok {
+			return Matrix{}, nil, fmt.Errorf("%w: arrow file %s has no schema", ErrMalformedRow, name)
+		}
+		if fields, err = parseArrowSchema(schema); err != nil {
+			return Matrix{}, nil, err
+		}
+		blocksPos, n, err := footer.vector(3, 24)
+		if err != nil {
+			return Matrix{}, nil, err
+		}
+		for i := 0; i < n; i++ {
+			blocks = append(blocks, int(binary.LittleEndian.Uint64(footer.buf[blocksPos+24*i:])))
+		}
+	} else {
+		msg, next, ok, err := readArrowMessage(data, 0)
+		if err != nil {
+			return Matrix{}, nil, err
+		}
+		if !ok || msg.headerType != arrowHeaderSchema {
+			return Matrix{}, nil, fmt.Errorf("%w: %s is not an arrow file or stream", ErrMalformedRow, name)
+		}
+		if fields, err = parseArrowSchema(msg.header); err != nil {
+			return Matrix{}, nil, err
+		}
+		pos = next
+	}
+
+	selected, names, err := arrowColumns(fields, columns, name)
+	if err != nil {
+		return Matrix{}, nil, err
+	}
+
+	matrix := Matrix{Vectors: make([]*Vector, 0)}
+	next := func() (arrowMessage, bool, error) {
+		if blocks != nil {
+			if len(blocks) == 0 {
+				return arrowMessage{}, false, nil
+			}
+			msg, _, ok, err := readArrowMessage(data, blocks[0])
+			blocks = blocks[1:]
+			if err == nil && !ok {
+				err = fmt.Errorf("%w: missing arrow record batch", ErrMalformedRow)
+			}
+			return msg, err == nil, err
+		}
+		msg, nextPos, ok, err := readArrowMessage(data, pos)
+		pos = nextPos
+		return msg, ok, err
+	}
+	for {
+		msg, ok, err := next()
+		if err != nil {
+			return Matrix{}, nil, err
+		}
+		if !ok {
+			break
+		}
+		switch msg.headerType {
+		case arrowHeaderRecordBatch:
+			if err := appendArrowRecordBatch(&matrix, msg, fields, selected); err != nil {
+				return Matrix{}, nil, fmt.Errorf("error while reading record batch of %s: %w", name, err)
+			}
+		case arrowHeaderDictionary:
+			// dictionary encoded columns are rejected with the schema so dictionaries can be skipped.
+		default:
+			return Matrix{}, nil, fmt.Errorf("%w: unexpected arrow message type %d", ErrMalformedRow, msg.headerType)
+		}
+	}
+	return matrix, names, nil
+}
+
+// ArrowStreamReader reads the record batches of an Arrow IPC stream one at a time, so that services moving data in
+// Arrow can score each batch as it arrives without buffering the whole stream. Arrow files are read sequentially
+// as well when they are written by Arrow 0.15 or later, their footer is not needed.
+type ArrowStreamReader struct {
+	r *bufio.Reader
+	// file is set for the file format, whose footer follows the last message.
+	file     bool
+	fields   []arrowField
+	selected []int
+	names    []string
+}
+
+// NewArrowStreamReader reads the schema at the start of r and returns a reader of its record batches. columns
+// selects the columns to read like ReadArrowToMatrix.
+func NewArrowStreamReader(r io.Reader, columns []string) (*ArrowStreamReader, error) {
+	s := &ArrowStreamReader{r: bufio.NewReader(r)}
+	if head, err := s.r.Peek(len(arrowMagic)); err == nil && string(head) == arrowMagic {
+		// the file magic is padded to 8 bytes and followed by the stream format.
+		if _, err := s.r.Discard(8); err != nil {
+			return nil, fmt.Errorf("%w: truncated arrow file", ErrMalformedRow)
+		}
+		s.file = true
+	}
+	msg, ok, err := s.message()
+	if err != nil {
+		return nil, err
+	}
+	if !ok || msg.headerType != arrowHeaderSchema {
+		return nil, fmt.Errorf("%w: input is not an arrow stream", ErrMalformedRow)
+	}
+	if s.fields, err = parseArrowSchema(msg.header); err != nil {
+		return nil, err
+	}
+	if s.selected, s.names, err = arrowColumns(s.fields, columns, "input"); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Names returns the names of the read columns, in the order of the values of each row.
+func (s *ArrowStreamReader) Names() []string {
+	return s.names
+}
+
+// Next returns the rows of the next record batch, null values are NaN. It returns io.EOF after the last batch.
+func (s *ArrowStreamReader) Next() (Matrix, error) {
+	for {
+		msg, ok, err := s.message()
+		if err != nil {
+			return Matrix{}, err
+		}
+		if !ok {
+			return Matrix{}, io.EOF
+		}
+		switch msg.headerType {
+		case arrowHeaderRecordBatch:
+			matrix := Matrix{Vectors: make([]*Vector, 0)}
+			if err := appendArrowRecordBatch(&matrix, msg, s.fields, s.selected); err != nil {
+				return Matrix{}, fmt.Errorf("error while reading record batch: %w", err)
+			}
+			return matrix, nil
+		case arrowHeaderDictionary:
+			// dictionary encoded columns are rejected with the schema so dictionaries can be skipped.
+		default:
+			return Matrix{}, fmt.Errorf("%w: unexpected arrow message type %d", ErrMalformedRow, msg.headerType)
+		}
+	}
+}
+
+// message reads the next message like readArrowMessage, ok is false at the end of the stream.
+func (s *ArrowStreamReader) message() (arrowMessage, bool, error) {
+	prefix := make([]byte, 4)
+	if _, err := io.ReadFull(s.r, prefix); err != nil {
+		if err == io.EOF {
+			// streams may end without the end of stream marker.
+			return arrowMessage{}, false, nil
+		}
+		return arrowMessage{}, false, fmt.Errorf("%w: truncated arrow message", ErrMalformedRow)
+	}
+	switch {
+	case binary.LittleEndian.Uint32(prefix) == arrowContinuation:
+		if _, err := io.ReadFull(s.r, prefix); err != nil {
+			return arrowMessage{}, false, fmt.Errorf("%w: truncated arrow message", ErrMalformedRow)
+		}
+	case s.file:
+		// messages of files written since Arrow 0.15 start with a continuation marker, so this is the footer of
+		// a file without end of stream marker.
+		return arrowMessage{}, false, nil
+	}
+	size := int(int32(binary.LittleEndian.Uint32(prefix)))
+	if size == 0 {
+		return arrowMessage{}, false, nil
+	}
+	if size < 0 {
+		return arrowMessage{}, false, fmt.Errorf("%w: invalid arrow message size %d", ErrMalformedRow, size)
+	}
+	metadata, err := readArrowBytes(s.r, size)
+	if err != nil {
+		return arrowMessage{}, false, err
+	}
+	msg, err := fbRoot(metadata)
+	if err != nil {
+		return arrowMessage{}, false, err
+	}
+	bodyLength := int(msg.int64(3))
+	if bodyLength < 0 {
+		return arrowMessage{}, false, fmt.Errorf("%w: invalid arrow message body length", ErrMalformedRow)
+	}
+	body, err := readArrowBytes(s.r, bodyLength)
+	if err != nil {
+		return arrowMessage{}, false, err
+	}
+	header, _ := msg.table(2)
+	return arrowMessage{headerType: msg.uint8(1), header: header, body: body}, true, nil
+}
+
+// readArrowBytes reads n bytes of a message, growing the buffer as data arrives so that a corrupted size does not
+// allocate more memory than the input has.
+func readArrowBytes(r io.Reader, n int) ([]byte, error) {
+	var buf bytes.Buffer
+	if _, err := io.CopyN(&buf, r, int64(n)); err != nil {
+		return nil, fmt.Errorf("%w: truncated arrow message", ErrMalformedRow)
+	}
+	return buf.Bytes(), nil
+}
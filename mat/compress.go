@@ -0,0 +1,41 @@
+package mat
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Magic numbers starting the compressed streams the readers decompress transparently.
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+)
+
+// decompressed returns a reader of the content of r, decompressed when r starts with a gzip or zstd magic number so
+// that large compressed datasets do not need to be decompressed to disk first. close releases the decompressor and
+// must be called once reading is done. name identifies the data in errors.
+func decompressed(r io.Reader, name string) (reader *bufio.Reader, close func(), err error) {
+	br := bufio.NewReader(r)
+	// Peek returns fewer bytes for short inputs which then cannot be compressed.
+	head, _ := br.Peek(len(zstdMagic))
+	switch {
+	case bytes.HasPrefix(head, gzipMagic):
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, nil, fmt.Errorf("cannot decompress gzip %s: %w", name, err)
+		}
+		return bufio.NewReader(gz), func() { gz.Close() }, nil
+	case bytes.HasPrefix(head, zstdMagic):
+		zr, err := zstd.NewReader(br, zstd.WithDecoderConcurrency(1))
+		if err != nil {
+			return nil, nil, fmt.Errorf("cannot decompress zstd %s: %w", name, err)
+		}
+		return bufio.NewReader(zr), zr.Close, nil
+	}
+	return br, func() {}, nil
+}
@@ -0,0 +1,119 @@
+package mat
+
+import (
+	"fmt"
+	"sort"
+)
+
+// CSRMatrix is a sparse matrix in compressed sparse row format. Rows are stored one after the other in flat
+// slices instead of one map per row, which uses less memory and is faster to traverse for wide data.
+type CSRMatrix struct {
+	// Indptr has one offset per row plus a final one, the features of row i are stored at the positions
+	// Indptr[i] to Indptr[i+1] (excluded) of Indices and Values.
+	Indptr []int
+	// Indices contains the feature indices of each row in increasing order.
+	Indices []int
+	// Values contains the value of each feature index.
+	Values []float64
+}
+
+// ToCSR returns the sparse matrix in compressed sparse row format, the features of each row are sorted by index.
+func (m SparseMatrix) ToCSR() CSRMatrix {
+	nnz := 0
+	for _, v := range m.Vectors {
+		nnz += len(v)
+	}
+	result := CSRMatrix{
+		Indptr:  make([]int, 1, len(m.Vectors)+1),
+		Indices: make([]int, 0, nnz),
+		Values:  make([]float64, 0, nnz),
+	}
+	for _, v := range m.Vectors {
+		start := len(result.Indices)
+		for idx := range v {
+			result.Indices = append(result.Indices, idx)
+		}
+		row := result.Indices[start:]
+		sort.Ints(row)
+		for _, idx := range row {
+			result.Values = append(result.Values, v[idx])
+		}
+		result.Indptr = append(result.Indptr, len(result.Indices))
+	}
+	return result
+}
+
+// Rows returns number of rows in the CSR matrix.
+func (m CSRMatrix) Rows() int {
+	if len(m.Indptr) == 0 {
+		return 0
+	}
+	return len(m.Indptr) - 1
+}
+
+// Cols returns number of columns in the CSR matrix which is the maximum feature index + 1.
+func (m CSRMatrix) Cols() (int, error) {
+	maxIdx := -1
+	for _, idx := range m.Indices {
+		if idx > maxIdx {
+			maxIdx = idx
+		}
+	}
+	if maxIdx < 0 {
+		return 0, fmt.Errorf("empty sparse matrix")
+	}
+	return maxIdx + 1, nil
+}
+
+// Row returns the feature indices and values of row i, they share memory with m.
+func (m CSRMatrix) Row(i int) ([]int, []float64) {
+	start, end := m.Indptr[i], m.Indptr[i+1]
+	return m.Indices[start:end:end], m.Values[start:end:end]
+}
+
+// Validate returns an error wrapping ErrDimensionMismatch if the slices of m are not consistent, or
+// ErrMalformedRow if the indices of a row are negative or not strictly increasing.
+func (m CSRMatrix) Validate() error {
+	if len(m.Indptr) == 0 {
+		if len(m.Indices) != 0 || len(m.Values) != 0 {
+			return fmt.Errorf("%w: csr matrix has values but no row offsets", ErrDimensionMismatch)
+		}
+		return nil
+	}
+	if len(m.Indices) != len(m.Values) {
+		return fmt.Errorf("%w: csr matrix has %d indices and %d values", ErrDimensionMismatch, len(m.Indices),
+			len(m.Values))
+	}
+	if m.Indptr[0] != 0 || m.Indptr[len(m.Indptr)-1] != len(m.Indices) {
+		return fmt.Errorf("%w: csr row offsets must go from 0 to %d, got %d to %d", ErrDimensionMismatch,
+			len(m.Indices), m.Indptr[0], m.Indptr[len(m.Indptr)-1])
+	}
+	for i := 0; i < m.Rows(); i++ {
+		if m.Indptr[i+1] < m.Indptr[i] {
+			return fmt.Errorf("%w: csr row offsets decrease at row %d", ErrDimensionMismatch, i)
+		}
+	}
+	for i := 0; i < m.Rows(); i++ {
+		indices, _ := m.Row(i)
+		for j, idx := range indices {
+			if idx < 0 || (j > 0 && idx <= indices[j-1]) {
+				return fmt.Errorf("%w: feature indices of row %d must be positive and increasing", ErrMalformedRow, i)
+			}
+		}
+	}
+	return nil
+}
+
+// ToSparseMatrix returns the CSR matrix as a list of sparse vectors.
+func (m CSRMatrix) ToSparseMatrix() SparseMatrix {
+	result := SparseMatrix{Vectors: make([]SparseVector, m.Rows())}
+	for i := range result.Vectors {
+		indices, values := m.Row(i)
+		vec := make(SparseVector, len(indices))
+		for j, idx := range indices {
+			vec[idx] = values[j]
+		}
+		result.Vectors[i] = vec
+	}
+	return result
+}
@@ -0,0 +1,334 @@
+package mat
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// CSVData is the content of a CSV file read by ReadCSVFile.
+type CSVData struct {
+	// Features contains the feature columns of each row in file order, without the label and dropped columns.
+	Features Matrix
+	// Labels contains the label of each row, nil if no label column is selected.
+	Labels Vector
+	// FeatureNames contains the header name of each feature column, nil if the file has no header.
+	FeatureNames []string
+	// LabelName contains the header name of the label column, empty if the file has no header or no label column.
+	LabelName string
+}
+
+// CSVOption configures how CSV files are read.
+type CSVOption func(*csvConfig)
+
+type csvConfig struct {
+	delimiter   string
+	defaultVal  float64
+	header      bool
+	labelName   string
+	labelIndex  int
+	dropNames   []string
+	dropIndices []int
+	values      map[string]float64
+}
+
+// WithCSVDelimiter sets the string separating the values of a row, "," by default.
+func WithCSVDelimiter(delimiter string) CSVOption {
+	return func(c *csvConfig) {
+		c.delimiter = delimiter
+	}
+}
+
+// WithCSVDefaultValue sets the value of empty cells, NaN by default so that they are missing for the models.
+func WithCSVDefaultValue(v float64) CSVOption {
+	return func(c *csvConfig) {
+		c.defaultVal = v
+	}
+}
+
+// WithCSVHeader tells the reader that the first row contains the column names, which are returned as the
+// feature names and can select the label and dropped columns. Surrounding double quotes of names are removed.
+func WithCSVHeader() CSVOption {
+	return func(c *csvConfig) {
+		c.header = true
+	}
+}
+
+// WithCSVLabelColumn selects the label column by its header name, its values are returned as the labels instead
+// of a feature. It requires WithCSVHeader.
+func WithCSVLabelColumn(name string) CSVOption {
+	return func(c *csvConfig) {
+		c.labelName, c.labelIndex = name, -1
+	}
+}
+
+// WithCSVLabelColumnIndex selects the label column by its 0-based index, its values are returned as the labels
+// instead of a feature.
+func WithCSVLabelColumnIndex(idx int) CSVOption {
+	return func(c *csvConfig) {
+		c.labelName, c.labelIndex = "", idx
+	}
+}
+
+// WithCSVDroppedColumns skips the columns with the given header names, like identifiers which are not features.
+// Their values are not parsed. It requires WithCSVHeader.
+func WithCSVDroppedColumns(names ...string) CSVOption {
+	return func(c *csvConfig) {
+		c.dropNames = append(c.dropNames, names...)
+	}
+}
+
+// WithCSVDroppedColumnIndices skips the columns with the given 0-based indices, their values are not parsed.
+func WithCSVDroppedColumnIndices(indices ...int) CSVOption {
+	return func(c *csvConfig) {
+		c.dropIndices = append(c.dropIndices, indices...)
+	}
+}
+
+// WithCSVValueMap maps non-numeric cells to numbers, like {"yes": 1, "no": 0} or {"NA": math.NaN()}. Cells are
+// looked up in values before being parsed, in feature and label columns alike.
+func WithCSVValueMap(values map[string]float64) CSVOption {
+	return func(c *csvConfig) {
+		if c.values == nil {
+			c.values = map[string]float64{}
+		}
+		for k, v := range values {
+			c.values[k] = v
+		}
+	}
+}
+
+// parse returns the number in cell, empty cells are defaultVal.
+func (c *csvConfig) parse(cell string, row, col int) (float64, error) {
+	if v, ok := c.values[cell]; ok {
+		return v, nil
+	}
+	if cell == "" {
+		return c.defaultVal, nil
+	}
+	v, err := strconv.ParseFloat(cell, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%w: cannot convert to float %s at row %d column %d: %s", ErrMalformedRow, cell, row,
+			col, err)
+	}
+	return v, nil
+}
+
+// columns returns the index of the label column, -1 if there is none, and the indices of the feature columns of
+// rows of numCols values.
+func (c *csvConfig) columns(names []string, numCols int) (int, []int, error) {
+	index := func(name string) (int, error) {
+		if names == nil {
+			return 0, fmt.Errorf("column %q can only be selected by name in files with a header", name)
+		}
+		for i, n := range names {
+			if n == name {
+				return i, nil
+			}
+		}
+		return 0, fmt.Errorf("%w: no column %q in header", ErrMalformedRow, name)
+	}
+	label := c.labelIndex
+	if c.labelName != "" {
+		idx, err := index(c.labelName)
+		if err != nil {
+			return 0, nil, err
+		}
+		label = idx
+	}
+	if label >= numCols {
+		return 0, nil, fmt.Errorf("%w: label column %d of rows with %d columns", ErrDimensionMismatch, label, numCols)
+	}
+	dropped := make([]bool, numCols)
+	for _, name := range c.dropNames {
+		idx, err := index(name)
+		if err != nil {
+			return 0, nil, err
+		}
+		dropped[idx] = true
+	}
+	for _, idx := range c.dropIndices {
+		if idx < 0 || idx >= numCols {
+			return 0, nil, fmt.Errorf("%w: dropped column %d of rows with %d columns", ErrDimensionMismatch, idx,
+				numCols)
+		}
+		dropped[idx] = true
+	}
+	var features []int
+	for i := 0; i < numCols; i++ {
+		if i != label && !dropped[i] {
+			features = append(features, i)
+		}
+	}
+	return label, features, nil
+}
+
+// ReadCSVFile reads a CSV file to a dense matrix of features and optionally a vector of labels. Every row must
+// have the same number of values and reading stops at the first empty line like ReadCSVFileToDenseMatrix. gzip and
+// zstd compressed files are decompressed transparently.
+func ReadCSVFile(fileName string, opts ...CSVOption) (CSVData, error) {
+	file, err := os.Open(fileName)
+	if err != nil {
+		return CSVData{}, fmt.Errorf("unable to open %s: %w", fileName, err)
+	}
+	defer file.Close()
+	return readCSV(file, fileName, opts)
+}
+
+// ReadCSV reads CSV data from r like ReadCSVFile, so that rows can come from request bodies, embedded files or
+// in-memory buffers.
+func ReadCSV(r io.Reader, opts ...CSVOption) (CSVData, error) {
+	return readCSV(r, "input", opts)
+}
+
+// readCSV reads CSV data from r, name identifies the data in errors.
+func readCSV(r io.Reader, name string, opts []CSVOption) (CSVData, error) {
+	cfg := csvConfig{delimiter: ",", defaultVal: math.NaN(), labelIndex: -1}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	reader, closeReader, err := decompressed(r, name)
+	if err != nil {
+		return CSVData{}, err
+	}
+	defer closeReader()
+	data := CSVData{Features: Matrix{Vectors: make([]*Vector, 0)}}
+	var names []string
+	label, features := -1, []int(nil)
+	numCols := -1
+	for row := 0; ; {
+		line, err := reader.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return CSVData{}, err
+		}
+		if strings.TrimSpace(line) == "" {
+			break
+		}
+		// only the line ending is trimmed so that trailing empty cells of whitespace delimiters are kept.
+		tokens := strings.Split(strings.TrimRight(line, "\r\n"), cfg.delimiter)
+		for i := range tokens {
+			tokens[i] = strings.TrimSpace(tokens[i])
+		}
+		if numCols == -1 {
+			numCols = len(tokens)
+			if cfg.header {
+				names = make([]string, len(tokens))
+				for i, token := range tokens {
+					names[i] = strings.Trim(token, `"`)
+				}
+			}
+			if label, features, err = cfg.columns(names, numCols); err != nil {
+				return CSVData{}, err
+			}
+			if cfg.header {
+				data.FeatureNames = make([]string, len(features))
+				for i, col := range features {
+					data.FeatureNames[i] = names[col]
+				}
+				if label >= 0 {
+					data.LabelName = names[label]
+				}
+				continue
+			}
+		}
+		if len(tokens) != numCols {
+			return CSVData{}, fmt.Errorf("%w: row %d has different dimension: %d, please check your file",
+				ErrDimensionMismatch, row, len(tokens))
+		}
+		vec := make(Vector, len(features))
+		for i, col := range features {
+			if vec[i], err = cfg.parse(tokens[col], row, col); err != nil {
+				return CSVData{}, err
+			}
+		}
+		if label >= 0 {
+			if _, ok := cfg.values[""]; !ok && tokens[label] == "" {
+				return CSVData{}, fmt.Errorf("%w: empty label at row %d", ErrMalformedRow, row)
+			}
+			v, err := cfg.parse(tokens[label], row, label)
+			if err != nil {
+				return CSVData{}, err
+			}
+			data.Labels = append(data.Labels, v)
+		}
+		data.Features.Vectors = append(data.Features.Vectors, &vec)
+		row++
+	}
+	if len(data.Features.Vectors) == 0 {
+		return CSVData{}, fmt.Errorf("%w: %s has no rows", ErrEmptyInput, name)
+	}
+	return data, nil
+}
+
+// WriteCSV writes data to w so that ReadCSV reads it back with the same delimiter: the labels, when there are some,
+// are the first column and a header row is written when data has feature names, the label column being called
+// LabelName or "label". NaN values are written as empty cells, which ReadCSV reads as NaN by default.
+func WriteCSV(w io.Writer, data CSVData, delimiter string) error {
+	rows := len(data.Features.Vectors)
+	if data.Labels != nil && len(data.Labels) != rows {
+		return fmt.Errorf("%w: %d labels for %d rows", ErrDimensionMismatch, len(data.Labels), rows)
+	}
+	bw := bufio.NewWriter(w)
+	write := func(i int, cell string) error {
+		if i > 0 {
+			if _, err := bw.WriteString(delimiter); err != nil {
+				return err
+			}
+		}
+		_, err := bw.WriteString(cell)
+		return err
+	}
+	offset := 0
+	if data.Labels != nil {
+		offset = 1
+	}
+	if data.FeatureNames != nil {
+		names := data.FeatureNames
+		if data.Labels != nil {
+			label := data.LabelName
+			if label == "" {
+				label = "label"
+			}
+			names = append([]string{label}, names...)
+		}
+		for i, name := range names {
+			if strings.Contains(name, delimiter) || strings.ContainsAny(name, "\r\n") {
+				return fmt.Errorf("column name %q cannot be written with delimiter %q", name, delimiter)
+			}
+			if err := write(i, name); err != nil {
+				return err
+			}
+		}
+		if err := bw.WriteByte('\n'); err != nil {
+			return err
+		}
+	}
+	for row, v := range data.Features.Vectors {
+		if data.FeatureNames != nil && len(*v) != len(data.FeatureNames) {
+			return fmt.Errorf("%w: row %d has %d values for %d feature names", ErrDimensionMismatch, row, len(*v),
+				len(data.FeatureNames))
+		}
+		if data.Labels != nil {
+			if err := write(0, strconv.FormatFloat(data.Labels[row], 'g', -1, 64)); err != nil {
+				return err
+			}
+		}
+		for j, val := range *v {
+			cell := ""
+			if !math.IsNaN(val) {
+				cell = strconv.FormatFloat(val, 'g', -1, 64)
+			}
+			if err := write(offset+j, cell); err != nil {
+				return err
+			}
+		}
+		if err := bw.WriteByte('\n'); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
@@ -0,0 +1,195 @@
+package mat
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// DMatrix bundles rows of features with the metadata of an XGBoost DMatrix, which evaluation and ranking need.
+// Metadata fields are nil when the data does not contain them.
+type DMatrix struct {
+	Features SparseMatrix
+	// Labels contains the label of each row.
+	Labels Vector
+	// Weights contains the instance weight of each row.
+	Weights Vector
+	// GroupPtr contains the group boundaries of ranking data like the group_ptr of XGBoost: group i is made of
+	// rows [GroupPtr[i], GroupPtr[i+1]), so GroupPtr starts with 0 and ends with the number of rows.
+	GroupPtr []int
+	// BaseMargins contains the base margin of each row, one value per class, like DMatrix.set_base_margin.
+	BaseMargins Matrix
+}
+
+// Rows returns number of rows in the DMatrix.
+func (d DMatrix) Rows() int {
+	return len(d.Features.Vectors)
+}
+
+// NumGroups returns the number of ranking groups, 0 if the rows are not grouped.
+func (d DMatrix) NumGroups() int {
+	if len(d.GroupPtr) == 0 {
+		return 0
+	}
+	return len(d.GroupPtr) - 1
+}
+
+// GroupSizes returns the number of rows of each ranking group, nil if the rows are not grouped.
+func (d DMatrix) GroupSizes() []int {
+	if d.NumGroups() == 0 {
+		return nil
+	}
+	sizes := make([]int, d.NumGroups())
+	for i := range sizes {
+		sizes[i] = d.GroupPtr[i+1] - d.GroupPtr[i]
+	}
+	return sizes
+}
+
+// SetGroupSizes sets the group boundaries from the number of rows of each group, like DMatrix.set_group.
+func (d *DMatrix) SetGroupSizes(sizes []int) error {
+	ptr := make([]int, 1, len(sizes)+1)
+	for i, size := range sizes {
+		if size < 0 {
+			return fmt.Errorf("group %d has negative size %d", i, size)
+		}
+		ptr = append(ptr, ptr[i]+size)
+	}
+	if ptr[len(ptr)-1] != d.Rows() {
+		return fmt.Errorf("%w: groups have %d rows for %d rows", ErrDimensionMismatch, ptr[len(ptr)-1], d.Rows())
+	}
+	d.GroupPtr = ptr
+	return nil
+}
+
+// Validate returns an error wrapping ErrDimensionMismatch if the metadata does not match the rows.
+func (d DMatrix) Validate() error {
+	n := d.Rows()
+	if d.Labels != nil && len(d.Labels) != n {
+		return fmt.Errorf("%w: %d labels for %d rows", ErrDimensionMismatch, len(d.Labels), n)
+	}
+	if d.Weights != nil && len(d.Weights) != n {
+		return fmt.Errorf("%w: %d weights for %d rows", ErrDimensionMismatch, len(d.Weights), n)
+	}
+	if d.BaseMargins.Vectors != nil && len(d.BaseMargins.Vectors) != n {
+		return fmt.Errorf("%w: base margin has %d rows for %d rows", ErrDimensionMismatch,
+			len(d.BaseMargins.Vectors), n)
+	}
+	if d.GroupPtr != nil {
+		if len(d.GroupPtr) < 2 || d.GroupPtr[0] != 0 || d.GroupPtr[len(d.GroupPtr)-1] != n {
+			return fmt.Errorf("%w: group boundaries must go from 0 to %d rows", ErrDimensionMismatch, n)
+		}
+		for i := 1; i < len(d.GroupPtr); i++ {
+			if d.GroupPtr[i] < d.GroupPtr[i-1] {
+				return fmt.Errorf("group boundaries must be increasing, got %d after %d", d.GroupPtr[i],
+					d.GroupPtr[i-1])
+			}
+		}
+	}
+	return nil
+}
+
+// ReadLibsvmFileToDMatrix reads a libsvm file like ReadLibsvmFileToSparseMatrix but keeps the label of each row
+// instead of skipping it. Rows follow the format of the XGBoost text loader, label[:weight] [qid:id] index:value...:
+// weights default to 1 for rows without one and consecutive rows with the same qid form a ranking group, every row
+// must then have a qid and the rows of a qid must be contiguous like XGBoost requires. gzip and zstd compressed
+// files are decompressed transparently.
+func ReadLibsvmFileToDMatrix(fileName string, opts ...LibsvmOption) (DMatrix, error) {
+	file, err := os.Open(fileName)
+	if err != nil {
+		return DMatrix{}, fmt.Errorf("unable to open %s: %w", fileName, err)
+	}
+	defer file.Close()
+	return readLibsvmDMatrix(file, fileName, opts)
+}
+
+// ReadLibsvmToDMatrix reads libsvm data from r into a DMatrix like ReadLibsvmFileToDMatrix.
+func ReadLibsvmToDMatrix(r io.Reader, opts ...LibsvmOption) (DMatrix, error) {
+	return readLibsvmDMatrix(r, "input", opts)
+}
+
+// readLibsvmDMatrix reads libsvm data with labels from r, name identifies the data in errors.
+func readLibsvmDMatrix(r io.Reader, name string, opts []LibsvmOption) (DMatrix, error) {
+	cfg := libsvmConfig{}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	reader, closeReader, err := decompressed(r, name)
+	if err != nil {
+		return DMatrix{}, err
+	}
+	defer closeReader()
+	d := DMatrix{Features: SparseMatrix{Vectors: make([]SparseVector, 0)}}
+	var weights Vector
+	hasWeights, hasQid := false, false
+	var lastQid string
+	seenQids := map[string]bool{}
+	for row := 0; ; row++ {
+		line, err := reader.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return DMatrix{}, err
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			break
+		}
+		tokens := strings.Split(line, " ")
+		labelToken := strings.SplitN(tokens[0], ":", 2)
+		label, err := strconv.ParseFloat(labelToken[0], 64)
+		if err != nil {
+			return DMatrix{}, fmt.Errorf("%w: cannot parse label %s at row %d: %s", ErrMalformedRow, tokens[0], row,
+				err)
+		}
+		weight := 1.0
+		if len(labelToken) == 2 {
+			if weight, err = strconv.ParseFloat(labelToken[1], 64); err != nil {
+				return DMatrix{}, fmt.Errorf("%w: cannot parse weight %s at row %d: %s", ErrMalformedRow, tokens[0],
+					row, err)
+			}
+			hasWeights = true
+		}
+		tokens = tokens[1:]
+		qid := ""
+		if len(tokens) > 0 && strings.HasPrefix(tokens[0], "qid:") {
+			qid, tokens = tokens[0][len("qid:"):], tokens[1:]
+		}
+		switch {
+		case row == 0:
+			hasQid = qid != ""
+			if hasQid {
+				d.GroupPtr = []int{0}
+			}
+		case hasQid != (qid != ""):
+			return DMatrix{}, fmt.Errorf("%w: qid is missing at row %d while other rows have one", ErrMalformedRow,
+				row)
+		}
+		if hasQid && row > 0 && qid != lastQid {
+			if seenQids[qid] {
+				return DMatrix{}, fmt.Errorf("%w: rows of qid %s are not contiguous at row %d, sort the rows by qid",
+					ErrMalformedRow, qid, row)
+			}
+			d.GroupPtr = append(d.GroupPtr, row)
+		}
+		seenQids[qid] = true
+		lastQid = qid
+		vec, err := parseLibsvmFeatures(tokens, row, &cfg)
+		if err != nil {
+			return DMatrix{}, err
+		}
+		d.Features.Vectors = append(d.Features.Vectors, vec)
+		d.Labels = append(d.Labels, label)
+		weights = append(weights, weight)
+	}
+	if d.Rows() == 0 {
+		return DMatrix{}, fmt.Errorf("%w: %s has no rows", ErrEmptyInput, name)
+	}
+	if hasWeights {
+		d.Weights = weights
+	}
+	if hasQid {
+		d.GroupPtr = append(d.GroupPtr, d.Rows())
+	}
+	return d, nil
+}
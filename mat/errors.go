@@ -0,0 +1,16 @@
+package mat
+
+import "errors"
+
+// Errors returned by readers and helpers of this package, wrapped with details so callers can check them
+// using errors.Is.
+var (
+	// ErrMalformedRow is returned when a row of an input file cannot be parsed.
+	ErrMalformedRow = errors.New("malformed row")
+	// ErrDimensionMismatch is returned when vectors or matrices do not have the expected dimension.
+	ErrDimensionMismatch = errors.New("dimension mismatch")
+	// ErrFeatureIndexOutOfRange is returned when a row has a feature index the model does not have.
+	ErrFeatureIndexOutOfRange = errors.New("feature index out of range")
+	// ErrEmptyInput is returned when an input file does not contain any row.
+	ErrEmptyInput = errors.New("empty input")
+)
@@ -0,0 +1,100 @@
+package mat
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// Histogram counts values of v into bins equally spaced between the minimum and maximum of v.
+// It returns bins counts and bins+1 edges, bin i covers [edges[i], edges[i+1]) except the last bin which also
+// includes its right edge. When all values are equal, or too close to each other to have distinct edges, the range
+// is extended by 0.5 on both sides. An empty vector, a vector containing NaN or infinite values and ranges whose
+// width overflows return an error.
+func Histogram(v Vector, bins int) ([]int, []float64, error) {
+	if bins <= 0 {
+		return nil, nil, fmt.Errorf("number of bins must be positive: %d", bins)
+	}
+	if len(v) == 0 {
+		return nil, nil, fmt.Errorf("empty vector")
+	}
+	minVal, maxVal := math.Inf(1), math.Inf(-1)
+	for i, val := range v {
+		if math.IsNaN(val) {
+			return nil, nil, fmt.Errorf("NaN value at index %d", i)
+		}
+		minVal = math.Min(minVal, val)
+		maxVal = math.Max(maxVal, val)
+	}
+	if math.IsInf(minVal, 0) || math.IsInf(maxVal, 0) {
+		return nil, nil, fmt.Errorf("cannot build histogram with infinite values")
+	}
+	if math.IsInf(maxVal-minVal, 0) {
+		return nil, nil, fmt.Errorf("range of values [%g, %g] is too large to build a histogram", minVal, maxVal)
+	}
+	edges, ok := histogramEdges(minVal, maxVal, bins)
+	if !ok {
+		// all values are equal or too close to have distinct edges.
+		if edges, ok = histogramEdges(minVal-0.5, maxVal+0.5, bins); !ok {
+			return nil, nil, fmt.Errorf("values around %g are too large to have %d distinct bins", minVal, bins)
+		}
+	}
+
+	counts := make([]int, bins)
+	idx, err := Digitize(v, edges)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, i := range idx {
+		counts[i]++
+	}
+	return counts, edges, nil
+}
+
+// histogramEdges returns bins+1 edges equally spaced between minVal and maxVal, false if they are not strictly
+// increasing.
+func histogramEdges(minVal, maxVal float64, bins int) ([]float64, bool) {
+	edges := make([]float64, bins+1)
+	width := (maxVal - minVal) / float64(bins)
+	for i := range edges {
+		edges[i] = minVal + float64(i)*width
+	}
+	edges[bins] = maxVal
+	for i := 1; i < len(edges); i++ {
+		if !(edges[i] > edges[i-1]) {
+			return nil, false
+		}
+	}
+	return edges, true
+}
+
+// Digitize returns for each value of v the index of the bin it belongs to, bin i covers [edges[i], edges[i+1])
+// and the last bin also includes its right edge. Values smaller than edges[0] get -1, values greater than the
+// last edge get len(edges)-1 and NaN values get -1. Edges must be strictly increasing with at least 2 values.
+func Digitize(v Vector, edges []float64) ([]int, error) {
+	if len(edges) < 2 {
+		return nil, fmt.Errorf("at least 2 edges are required, got %d", len(edges))
+	}
+	for i := 1; i < len(edges); i++ {
+		if !(edges[i] > edges[i-1]) {
+			return nil, fmt.Errorf("edges must be strictly increasing: edges[%d]=%f, edges[%d]=%f",
+				i-1, edges[i-1], i, edges[i])
+		}
+	}
+	last := len(edges) - 1
+	result := make([]int, len(v))
+	for i, val := range v {
+		switch {
+		case math.IsNaN(val) || val < edges[0]:
+			result[i] = -1
+		case val > edges[last]:
+			result[i] = last
+		case val == edges[last]:
+			result[i] = last - 1
+		default:
+			// first edge strictly greater than val.
+			result[i] = sort.SearchFloat64s(edges, math.Nextafter(val, math.Inf(1))) - 1
+		}
+	}
+	return result, nil
+}
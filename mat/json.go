@@ -0,0 +1,72 @@
+package mat
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// WriteMatrixToJSONL writes matrix as newline delimited JSON, one array per row.
+func WriteMatrixToJSONL(m *Matrix, w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	for i, v := range m.Vectors {
+		b, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Errorf("cannot encode row %d: %s", i, err)
+		}
+		if _, err := bw.Write(b); err != nil {
+			return err
+		}
+		if err := bw.WriteByte('\n'); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// WritePredictionsJSON writes predictions as newline delimited JSON, one object per row whose fields are the
+// class names in the given order, e.g. {"setosa":0.9,"versicolor":0.05,"virginica":0.05}.
+func WritePredictionsJSON(m *Matrix, classNames []string, w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	names := make([][]byte, len(classNames))
+	for i, n := range classNames {
+		b, err := json.Marshal(n)
+		if err != nil {
+			return err
+		}
+		names[i] = b
+	}
+	for i, v := range m.Vectors {
+		if len(*v) != len(classNames) {
+			return fmt.Errorf("row %d has %d values but %d class names were given", i, len(*v), len(classNames))
+		}
+		if err := bw.WriteByte('{'); err != nil {
+			return err
+		}
+		for j, val := range *v {
+			b, err := json.Marshal(val)
+			if err != nil {
+				return fmt.Errorf("cannot encode row %d: %s", i, err)
+			}
+			if j > 0 {
+				if err := bw.WriteByte(','); err != nil {
+					return err
+				}
+			}
+			if _, err := bw.Write(names[j]); err != nil {
+				return err
+			}
+			if err := bw.WriteByte(':'); err != nil {
+				return err
+			}
+			if _, err := bw.Write(b); err != nil {
+				return err
+			}
+		}
+		if _, err := bw.WriteString("}\n"); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
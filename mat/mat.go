@@ -6,6 +6,7 @@ import (
 	"io"
 	"math"
 	"os"
+	"sort"
 	"strconv"
 	"strings"
 
@@ -28,6 +29,202 @@ type Matrix struct {
 	Vectors []*Vector
 }
 
+// Rows returns number of rows in the matrix.
+func (m Matrix) Rows() int {
+	return len(m.Vectors)
+}
+
+// Cols returns number of columns in the matrix. All rows must be non nil and have the same length.
+func (m Matrix) Cols() (int, error) {
+	if len(m.Vectors) == 0 {
+		return 0, fmt.Errorf("empty matrix")
+	}
+	for i, v := range m.Vectors {
+		if v == nil {
+			return 0, fmt.Errorf("%w: row %d is nil", ErrMalformedRow, i)
+		}
+	}
+	cols := len(*m.Vectors[0])
+	for i, v := range m.Vectors {
+		if len(*v) != cols {
+			return 0, fmt.Errorf("%w: row %d has different dimension: %d, expected %d", ErrDimensionMismatch, i, len(*v), cols)
+		}
+	}
+	return cols, nil
+}
+
+// Shape returns number of rows and columns in the matrix.
+func (m Matrix) Shape() (int, int, error) {
+	cols, err := m.Cols()
+	if err != nil {
+		return 0, 0, err
+	}
+	return m.Rows(), cols, nil
+}
+
+// Rows returns number of rows in the sparse matrix.
+func (m SparseMatrix) Rows() int {
+	return len(m.Vectors)
+}
+
+// Cols returns number of columns in the sparse matrix which is the maximum feature index + 1.
+func (m SparseMatrix) Cols() (int, error) {
+	maxIdx := -1
+	for _, v := range m.Vectors {
+		for idx := range v {
+			if idx > maxIdx {
+				maxIdx = idx
+			}
+		}
+	}
+	if maxIdx < 0 {
+		return 0, fmt.Errorf("empty sparse matrix")
+	}
+	return maxIdx + 1, nil
+}
+
+// Shape returns number of rows and columns in the sparse matrix.
+func (m SparseMatrix) Shape() (int, int, error) {
+	cols, err := m.Cols()
+	if err != nil {
+		return 0, 0, err
+	}
+	return m.Rows(), cols, nil
+}
+
+// Clone returns a deep copy of the matrix.
+func (m Matrix) Clone() Matrix {
+	result := Matrix{Vectors: make([]*Vector, len(m.Vectors))}
+	for i, v := range m.Vectors {
+		if v == nil {
+			continue
+		}
+		vec := make(Vector, len(*v))
+		copy(vec, *v)
+		result.Vectors[i] = &vec
+	}
+	return result
+}
+
+// Clone returns a deep copy of the sparse matrix.
+func (m SparseMatrix) Clone() SparseMatrix {
+	result := SparseMatrix{Vectors: make([]SparseVector, len(m.Vectors))}
+	for i, v := range m.Vectors {
+		if v == nil {
+			continue
+		}
+		vec := make(SparseVector, len(v))
+		for idx, val := range v {
+			vec[idx] = val
+		}
+		result.Vectors[i] = vec
+	}
+	return result
+}
+
+// SelectColumns returns a new matrix containing only the given columns in the given order.
+func (m Matrix) SelectColumns(indices []int) (Matrix, error) {
+	result := Matrix{Vectors: make([]*Vector, len(m.Vectors))}
+	for i, v := range m.Vectors {
+		vec := make(Vector, len(indices))
+		for j, idx := range indices {
+			if idx < 0 || idx >= len(*v) {
+				return Matrix{}, fmt.Errorf("column index %d out of range at row %d with %d columns", idx, i, len(*v))
+			}
+			vec[j] = (*v)[idx]
+		}
+		result.Vectors[i] = &vec
+	}
+	return result, nil
+}
+
+// SelectColumns returns a new sparse matrix containing only the given columns in the given order, column
+// indices[j] becoming column j. Absent features stay absent.
+func (m SparseMatrix) SelectColumns(indices []int) (SparseMatrix, error) {
+	positions := make(map[int][]int, len(indices))
+	for j, idx := range indices {
+		if idx < 0 {
+			return SparseMatrix{}, fmt.Errorf("column index %d out of range", idx)
+		}
+		positions[idx] = append(positions[idx], j)
+	}
+	result := SparseMatrix{Vectors: make([]SparseVector, len(m.Vectors))}
+	for i, v := range m.Vectors {
+		vec := make(SparseVector)
+		for idx, val := range v {
+			for _, j := range positions[idx] {
+				vec[j] = val
+			}
+		}
+		result.Vectors[i] = vec
+	}
+	return result, nil
+}
+
+// Slice returns a matrix made of the given rows in the given order, like an evaluation subset. Rows are shared
+// with m, use Clone on the result to modify them independently.
+func (m Matrix) Slice(rows []int) (Matrix, error) {
+	result := Matrix{Vectors: make([]*Vector, len(rows))}
+	for i, r := range rows {
+		if r < 0 || r >= len(m.Vectors) {
+			return Matrix{}, fmt.Errorf("row index %d out of range of %d rows", r, len(m.Vectors))
+		}
+		result.Vectors[i] = m.Vectors[r]
+	}
+	return result, nil
+}
+
+// Slice returns a sparse matrix made of the given rows in the given order like Matrix.Slice, rows are shared
+// with m.
+func (m SparseMatrix) Slice(rows []int) (SparseMatrix, error) {
+	result := SparseMatrix{Vectors: make([]SparseVector, len(rows))}
+	for i, r := range rows {
+		if r < 0 || r >= len(m.Vectors) {
+			return SparseMatrix{}, fmt.Errorf("row index %d out of range of %d rows", r, len(m.Vectors))
+		}
+		result.Vectors[i] = m.Vectors[r]
+	}
+	return result, nil
+}
+
+// Row returns row i of the matrix, which shares memory with m.
+func (m Matrix) Row(i int) (Vector, error) {
+	if i < 0 || i >= len(m.Vectors) {
+		return nil, fmt.Errorf("row index %d out of range of %d rows", i, len(m.Vectors))
+	}
+	return *m.Vectors[i], nil
+}
+
+// Row returns row i of the sparse matrix, which shares memory with m.
+func (m SparseMatrix) Row(i int) (SparseVector, error) {
+	if i < 0 || i >= len(m.Vectors) {
+		return nil, fmt.Errorf("row index %d out of range of %d rows", i, len(m.Vectors))
+	}
+	return m.Vectors[i], nil
+}
+
+// Batches returns an iterator over successive sub matrices of at most size rows, the last one may be smaller.
+// The iterator returns false once all rows have been consumed. Batches share rows with m, a size smaller than
+// 1 yields the whole matrix as a single batch.
+func (m SparseMatrix) Batches(size int) func() (SparseMatrix, bool) {
+	if size < 1 {
+		size = len(m.Vectors)
+	}
+	start := 0
+	return func() (SparseMatrix, bool) {
+		if start >= len(m.Vectors) {
+			return SparseMatrix{}, false
+		}
+		end := start + size
+		if end > len(m.Vectors) {
+			end = len(m.Vectors)
+		}
+		batch := SparseMatrix{Vectors: m.Vectors[start:end:end]}
+		start = end
+		return batch, true
+	}
+}
+
 // Converts a SparseMatrix to a slice of float64
 func (m SparseMatrix) ToFloat64() [][]float64 {
 	result := make([][]float64, len(m.Vectors))
@@ -72,25 +269,162 @@ func (m SparseMatrix) Flatten() []float64 {
 	return result
 }
 
-// ReadLibsvmFileToSparseMatrix reads libsvm file into sparse matrix.
-func ReadLibsvmFileToSparseMatrix(fileName string) (SparseMatrix, error) {
+// LibsvmOption configures how libsvm files are read.
+type LibsvmOption func(*libsvmConfig)
+
+type libsvmConfig struct {
+	errorOnDuplicateIndex bool
+	oneBased              bool
+	expectedNumFeatures   int
+	maxFeatureIndex       int
+}
+
+// WithDuplicateIndexError makes the libsvm reader fail when a row contains the same feature index twice,
+// by default the last value wins.
+func WithDuplicateIndexError() LibsvmOption {
+	return func(c *libsvmConfig) {
+		c.errorOnDuplicateIndex = true
+	}
+}
+
+// WithOneBasedIndex tells the libsvm reader that feature indices in the file start from 1, they will be
+// decremented so the resulting matrix is 0-based like XGBoost models expect.
+func WithOneBasedIndex() LibsvmOption {
+	return func(c *libsvmConfig) {
+		c.oneBased = true
+	}
+}
+
+// WithExpectedNumFeatures makes the reader return an error if any row references a feature index >= n.
+// n <= 0 keeps the reader permissive.
+func WithExpectedNumFeatures(n int) LibsvmOption {
+	return func(c *libsvmConfig) {
+		c.expectedNumFeatures = n
+	}
+}
+
+// WithMaxFeatureIndex makes the reader return an error if any row references a feature index > max, which
+// protects callers from huge allocations when loading untrusted files. max <= 0 only rejects indices which
+// do not fit in an int32.
+func WithMaxFeatureIndex(max int) LibsvmOption {
+	return func(c *libsvmConfig) {
+		c.maxFeatureIndex = max
+	}
+}
+
+// parseLibsvmFeatures parses the index:value tokens of a libsvm row.
+func parseLibsvmFeatures(tokens []string, row int, cfg *libsvmConfig) (SparseVector, error) {
+	vec := SparseVector{}
+	for c := 0; c < len(tokens); c++ {
+		if len(tokens[c]) == 0 {
+			return nil, fmt.Errorf("%w: corrupted data format at row %d please check for empty spaces",
+				ErrMalformedRow, row)
+		}
+		pair := strings.Split(tokens[c], ":")
+		if len(pair) != 2 {
+			return nil, fmt.Errorf("%w: wrong data format %s at row %d", ErrMalformedRow, tokens[c], row)
+		}
+		colIdx, err := strconv.ParseUint(pair[0], 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("%w: cannot parse to int %s at row %d: %s", ErrMalformedRow, pair[0], row, err)
+		}
+		val, err := strconv.ParseFloat(pair[1], 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: cannot parse to float %s at row %d: %s", ErrMalformedRow, pair[1], row,
+				err)
+		}
+		if colIdx > math.MaxInt32 {
+			return nil, fmt.Errorf("%w: feature index %s at row %d overflows int32", ErrMalformedRow,
+				pair[0], row)
+		}
+		if cfg.oneBased {
+			if colIdx == 0 {
+				return nil, fmt.Errorf("%w: feature index 0 at row %d is not valid for 1-based file",
+					ErrMalformedRow, row)
+			}
+			colIdx--
+		}
+		if cfg.maxFeatureIndex > 0 && int(colIdx) > cfg.maxFeatureIndex {
+			return nil, fmt.Errorf("%w: feature index %s at row %d exceeds maximum %d", ErrMalformedRow,
+				pair[0], row, cfg.maxFeatureIndex)
+		}
+		if cfg.expectedNumFeatures > 0 && int(colIdx) >= cfg.expectedNumFeatures {
+			return nil, fmt.Errorf("%w: feature index %s at row %d exceeds expected %d features",
+				ErrDimensionMismatch, pair[0], row, cfg.expectedNumFeatures)
+		}
+		if _, ok := vec[int(colIdx)]; ok && cfg.errorOnDuplicateIndex {
+			return nil, fmt.Errorf("%w: duplicate feature index %s at row %d", ErrMalformedRow, pair[0], row)
+		}
+		vec[int(colIdx)] = val
+	}
+	return vec, nil
+}
+
+// ReadLibsvmFileToSparseMatrix reads libsvm file into sparse matrix, gzip and zstd compressed files are
+// decompressed transparently. Labels and the qid of ranking data are skipped, see ReadLibsvmFileToDMatrix to
+// keep them.
+func ReadLibsvmFileToSparseMatrix(fileName string, opts ...LibsvmOption) (SparseMatrix, error) {
+	file, err := os.Open(fileName)
+	if err != nil {
+		return SparseMatrix{}, fmt.Errorf("unable to open %s: %w", fileName, err)
+	}
+	defer file.Close()
+	return readLibsvm(file, fileName, opts)
+}
+
+// ReadLibsvmToSparseMatrix reads libsvm data from r into sparse matrix like ReadLibsvmFileToSparseMatrix, so that
+// rows can come from request bodies, embedded files or in-memory buffers.
+func ReadLibsvmToSparseMatrix(r io.Reader, opts ...LibsvmOption) (SparseMatrix, error) {
+	return readLibsvm(r, "input", opts)
+}
+
+// ReadLibsvmFileToSparseMatrixWithLabels reads a libsvm file like ReadLibsvmFileToSparseMatrix and returns the
+// label of each row too, so that metrics can be computed after prediction without parsing the file again.
+// Instance weights and qids are accepted and left out, see ReadLibsvmFileToDMatrix to keep them.
+func ReadLibsvmFileToSparseMatrixWithLabels(fileName string, opts ...LibsvmOption) (SparseMatrix, Vector, error) {
 	file, err := os.Open(fileName)
 	if err != nil {
-		return SparseMatrix{}, fmt.Errorf("unable to open %s: %s", fileName, err)
+		return SparseMatrix{}, nil, fmt.Errorf("unable to open %s: %w", fileName, err)
 	}
 	defer file.Close()
+	d, err := readLibsvmDMatrix(file, fileName, opts)
+	if err != nil {
+		return SparseMatrix{}, nil, err
+	}
+	return d.Features, d.Labels, nil
+}
 
-	reader := bufio.NewReader(file)
+// ReadLibsvmToSparseMatrixWithLabels reads libsvm data from r like ReadLibsvmFileToSparseMatrixWithLabels.
+func ReadLibsvmToSparseMatrixWithLabels(r io.Reader, opts ...LibsvmOption) (SparseMatrix, Vector, error) {
+	d, err := readLibsvmDMatrix(r, "input", opts)
+	if err != nil {
+		return SparseMatrix{}, nil, err
+	}
+	return d.Features, d.Labels, nil
+}
+
+// readLibsvm reads libsvm data from r, name identifies the data in errors.
+func readLibsvm(r io.Reader, name string, opts []LibsvmOption) (SparseMatrix, error) {
+	cfg := libsvmConfig{}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	reader, closeReader, err := decompressed(r, name)
+	if err != nil {
+		return SparseMatrix{}, err
+	}
+	defer closeReader()
 
 	sparseMatrix := SparseMatrix{Vectors: make([]SparseVector, 0)}
-	for {
+	for row := 0; ; row++ {
 		line, err := reader.ReadString('\n')
 		if err != nil {
 			if err != io.EOF {
 				return SparseMatrix{}, err
 			}
-			break
-
+			if line == "" {
+				break
+			}
 		}
 		line = strings.TrimSpace(line)
 		if line == "" {
@@ -98,30 +432,22 @@ func ReadLibsvmFileToSparseMatrix(fileName string) (SparseMatrix, error) {
 		}
 		tokens := strings.Split(line, " ")
 		if len(tokens) < 2 {
-			return SparseMatrix{}, fmt.Errorf("too few columns")
+			return SparseMatrix{}, fmt.Errorf("%w: too few columns at row %d", ErrMalformedRow, row)
 		}
-		// first column is label so skip it.
-		vec := SparseVector{}
-		for c := 1; c < len(tokens); c++ {
-			if len(tokens[c]) == 0 {
-				return SparseMatrix{}, fmt.Errorf("corrupted data format please check for empty spaces")
-			}
-			pair := strings.Split(tokens[c], ":")
-			if len(pair) != 2 {
-				return SparseMatrix{}, fmt.Errorf("wrong data format %s", tokens[c])
-			}
-			colIdx, err := strconv.ParseUint(pair[0], 10, 32)
-			if err != nil {
-				return SparseMatrix{}, fmt.Errorf("cannot parse to int %s: %s", pair[0], err)
-			}
-			val, err := strconv.ParseFloat(pair[1], 64)
-			if err != nil {
-				return SparseMatrix{}, fmt.Errorf("cannot parse to float %s: %s", pair[1], err)
-			}
-			vec[int(colIdx)] = val
+		// first column is label so skip it, as well as the qid of ranking data.
+		tokens = tokens[1:]
+		if strings.HasPrefix(tokens[0], "qid:") {
+			tokens = tokens[1:]
+		}
+		vec, err := parseLibsvmFeatures(tokens, row, &cfg)
+		if err != nil {
+			return SparseMatrix{}, err
 		}
 		sparseMatrix.Vectors = append(sparseMatrix.Vectors, vec)
 	}
+	if len(sparseMatrix.Vectors) == 0 {
+		return SparseMatrix{}, fmt.Errorf("%w: %s has no rows", ErrEmptyInput, name)
+	}
 	return sparseMatrix, nil
 }
 
@@ -140,59 +466,91 @@ func GetSparseMatrixFromSlice(data [][]float64) (SparseMatrix, error) {
 	return sparseMatrix, nil
 }
 
-// ReadCSVFileToDenseMatrix reads CSV file to dense matrix.
-func ReadCSVFileToDenseMatrix(fileName string, delimiter string, defaultVal float64) (Matrix, error) {
-	file, err := os.Open(fileName)
-	if err != nil {
-		return Matrix{}, fmt.Errorf("unable to open %s: %s", fileName, err)
+// GetSparseMatrixFromSliceWithMissing creates sparse matrix from float64 slice like xgboost.DMatrix(data,
+// missing=missing): NaN values and values equal to missing are left out of the rows, zeros are real values.
+func GetSparseMatrixFromSliceWithMissing(data [][]float64, missing float64) (SparseMatrix, error) {
+	sparseMatrix := SparseMatrix{Vectors: make([]SparseVector, 0, len(data))}
+	for i := 0; i < len(data); i++ {
+		vec := SparseVector{}
+		for j, v := range data[i] {
+			if !math.IsNaN(v) && v != missing {
+				vec[j] = v
+			}
+		}
+		sparseMatrix.Vectors = append(sparseMatrix.Vectors, vec)
 	}
-	defer file.Close()
-
-	reader := bufio.NewReader(file)
+	return sparseMatrix, nil
+}
 
-	matrix := Matrix{Vectors: make([]*Vector, 0)}
-	colDim := -1
-	row := 0
-	for {
-		line, err := reader.ReadString('\n')
-		if err != nil && err != io.EOF {
-			return Matrix{}, err
-		}
-		line = strings.TrimSpace(line)
-		if line == "" {
-			break
-		}
-		tokens := strings.Split(line, delimiter)
-		vec := Vector{}
-		for i := 0; i < len(tokens); i++ {
-			var val float64
-			if len(tokens[i]) == 0 {
-				val = defaultVal
-			} else {
-				v, err := strconv.ParseFloat(tokens[i], 64)
-				if err != nil {
-					return Matrix{}, fmt.Errorf("cannot convert to float %s: %s", tokens[i], err)
-				}
-				val = v
+// GetDenseMatrixFromSlice creates dense matrix from float64 slice, values equal to missing are replaced by NaN so
+// that dense prediction treats them as missing. Pass NaN as missing to copy the values as is.
+func GetDenseMatrixFromSlice(data [][]float64, missing float64) (Matrix, error) {
+	matrix := Matrix{Vectors: make([]*Vector, 0, len(data))}
+	for i := 0; i < len(data); i++ {
+		vec := make(Vector, len(data[i]))
+		for j, v := range data[i] {
+			if v == missing {
+				v = math.NaN()
 			}
-			vec = append(vec, val)
-		}
-		if colDim == -1 {
-			colDim = len(vec)
-		} else if colDim != len(vec) {
-			return Matrix{}, fmt.Errorf("row %d has different dimension: %d, please check your file",
-				row, len(vec))
+			vec[j] = v
 		}
 		matrix.Vectors = append(matrix.Vectors, &vec)
-		row++
 	}
 	return matrix, nil
 }
 
+// ReadCSVFileToDenseMatrix reads CSV file to dense matrix, empty cells are defaultVal. gzip and zstd compressed
+// files are decompressed transparently. See ReadCSVFile to read files with a header or a label column.
+func ReadCSVFileToDenseMatrix(fileName string, delimiter string, defaultVal float64) (Matrix, error) {
+	data, err := ReadCSVFile(fileName, WithCSVDelimiter(delimiter), WithCSVDefaultValue(defaultVal))
+	if err != nil {
+		return Matrix{}, err
+	}
+	return data.Features, nil
+}
+
+// ReadCSVToDenseMatrix reads CSV data from r into dense matrix like ReadCSVFileToDenseMatrix.
+func ReadCSVToDenseMatrix(r io.Reader, delimiter string, defaultVal float64) (Matrix, error) {
+	data, err := ReadCSV(r, WithCSVDelimiter(delimiter), WithCSVDefaultValue(defaultVal))
+	if err != nil {
+		return Matrix{}, err
+	}
+	return data.Features, nil
+}
+
+// ReadCSVFileToDenseMatrixWithLabels reads a CSV file like ReadCSVFileToDenseMatrix and returns the values of the
+// 0-based labelColumn as labels instead of a feature column, empty labels are an error.
+func ReadCSVFileToDenseMatrixWithLabels(fileName string, delimiter string, defaultVal float64,
+	labelColumn int) (Matrix, Vector, error) {
+	if labelColumn < 0 {
+		return Matrix{}, nil, fmt.Errorf("%w: label column %d", ErrDimensionMismatch, labelColumn)
+	}
+	data, err := ReadCSVFile(fileName, WithCSVDelimiter(delimiter), WithCSVDefaultValue(defaultVal),
+		WithCSVLabelColumnIndex(labelColumn))
+	if err != nil {
+		return Matrix{}, nil, err
+	}
+	return data.Features, data.Labels, nil
+}
+
+// ReadCSVToDenseMatrixWithLabels reads CSV data from r like ReadCSVFileToDenseMatrixWithLabels.
+func ReadCSVToDenseMatrixWithLabels(r io.Reader, delimiter string, defaultVal float64, labelColumn int) (Matrix,
+	Vector, error) {
+	if labelColumn < 0 {
+		return Matrix{}, nil, fmt.Errorf("%w: label column %d", ErrDimensionMismatch, labelColumn)
+	}
+	data, err := ReadCSV(r, WithCSVDelimiter(delimiter), WithCSVDefaultValue(defaultVal),
+		WithCSVLabelColumnIndex(labelColumn))
+	if err != nil {
+		return Matrix{}, nil, err
+	}
+	return data.Features, data.Labels, nil
+}
+
 // IsEqualVectors compares 2 vectors with a threshold.
 func IsEqualVectors(v1, v2 *Vector, threshold float64) error {
 	if len(*v1) != len(*v2) {
-		return fmt.Errorf("different vector length v1=%d, v2=%d", len(*v1), len(*v2))
+		return fmt.Errorf("%w: different vector length v1=%d, v2=%d", ErrDimensionMismatch, len(*v1), len(*v2))
 	}
 	for i := range *v1 {
 		if math.Abs((*v1)[i]-(*v2)[i]) > threshold {
@@ -221,7 +579,8 @@ func GetVectorMaxIdx(v *Vector) (int, error) {
 // IsEqualMatrices compares 2 matrices with a threshold.
 func IsEqualMatrices(m1, m2 *Matrix, threshold float64) error {
 	if len(m1.Vectors) != len(m2.Vectors) {
-		return fmt.Errorf("row  matrix mismatch: m1 got %d rows, m2 got %d rows", len(m1.Vectors), len(m2.Vectors))
+		return fmt.Errorf("%w: row matrix mismatch: m1 got %d rows, m2 got %d rows", ErrDimensionMismatch,
+			len(m1.Vectors), len(m2.Vectors))
 	}
 	for i := range m1.Vectors {
 		err := IsEqualVectors(m1.Vectors[i], m2.Vectors[i], threshold)
@@ -232,10 +591,37 @@ func IsEqualMatrices(m1, m2 *Matrix, threshold float64) error {
 	return nil
 }
 
+// IsEqualSparseMatrices compares 2 sparse matrices with a threshold, both matrices must have the same indices in
+// every row.
+func IsEqualSparseMatrices(m1, m2 SparseMatrix, threshold float64) error {
+	if len(m1.Vectors) != len(m2.Vectors) {
+		return fmt.Errorf("%w: row matrix mismatch: m1 got %d rows, m2 got %d rows", ErrDimensionMismatch,
+			len(m1.Vectors), len(m2.Vectors))
+	}
+	for i := range m1.Vectors {
+		v1, v2 := m1.Vectors[i], m2.Vectors[i]
+		if len(v1) != len(v2) {
+			return fmt.Errorf("%w: matrix comparison at index %d: different number of elements v1=%d, v2=%d",
+				ErrDimensionMismatch, i, len(v1), len(v2))
+		}
+		for idx, val := range v1 {
+			other, ok := v2[idx]
+			if !ok {
+				return fmt.Errorf("matrix comparison at index %d: %d element missing in v2", i, idx)
+			}
+			if math.Abs(val-other) > threshold {
+				return fmt.Errorf("matrix comparison at index %d: %d element mismatch: v1[%d]=%f, v2[%d]=%f", i, idx,
+					idx, val, idx, other)
+			}
+		}
+	}
+	return nil
+}
+
 // Returns the RSME of the difference between two vectors.
 func GetVectorRMSE(v1, v2 *Vector) (float64, error) {
 	if len(*v1) != len(*v2) {
-		return 0, fmt.Errorf("different vector length v1=%d, v2=%d", len(*v1), len(*v2))
+		return 0, fmt.Errorf("%w: different vector length v1=%d, v2=%d", ErrDimensionMismatch, len(*v1), len(*v2))
 	}
 	sum := 0.0
 	for i := range *v1 {
@@ -244,10 +630,30 @@ func GetVectorRMSE(v1, v2 *Vector) (float64, error) {
 	return math.Sqrt(sum / float64(len(*v1))), nil
 }
 
+// GetVectorWeightedRMSE returns the weighted RMSE of the difference between two vectors which is
+// sqrt(sum(w*(v1-v2)^2)/sum(w)).
+func GetVectorWeightedRMSE(v1, v2, weights *Vector) (float64, error) {
+	if len(*v1) != len(*v2) || len(*v1) != len(*weights) {
+		return 0, fmt.Errorf("%w: different vector length v1=%d, v2=%d, weights=%d", ErrDimensionMismatch,
+			len(*v1), len(*v2), len(*weights))
+	}
+	sum := 0.0
+	sumWeights := 0.0
+	for i := range *v1 {
+		sum += (*weights)[i] * math.Pow((*v1)[i]-(*v2)[i], 2)
+		sumWeights += (*weights)[i]
+	}
+	if sumWeights <= 0 {
+		return 0, fmt.Errorf("sum of weights must be positive: %f", sumWeights)
+	}
+	return math.Sqrt(sum / sumWeights), nil
+}
+
 // Returns the RSME of the difference between two Matrix objects.
 func GetMatrixRMSE(m1, m2 *Matrix) (float64, error) {
 	if len(m1.Vectors) != len(m2.Vectors) {
-		return 0, fmt.Errorf("row  matrix mismatch: m1 got %d rows, m2 got %d rows", len(m1.Vectors), len(m2.Vectors))
+		return 0, fmt.Errorf("%w: row matrix mismatch: m1 got %d rows, m2 got %d rows", ErrDimensionMismatch,
+			len(m1.Vectors), len(m2.Vectors))
 	}
 	sum := 0.0
 	for i := range m1.Vectors {
@@ -260,7 +666,52 @@ func GetMatrixRMSE(m1, m2 *Matrix) (float64, error) {
 	return sum / float64(len(m1.Vectors)), nil
 }
 
-// Write all elements of Matrix to a file
+// GetVectorMAPE returns the mean absolute percentage error mean(|(yTrue-yPred)/yTrue|) between two vectors.
+// Zero values of yTrue are skipped when ignoreZeros is true, otherwise they return an error.
+func GetVectorMAPE(yTrue, yPred *Vector, ignoreZeros bool) (float64, error) {
+	if len(*yTrue) != len(*yPred) {
+		return 0, fmt.Errorf("%w: different vector length yTrue=%d, yPred=%d", ErrDimensionMismatch,
+			len(*yTrue), len(*yPred))
+	}
+	sum := 0.0
+	n := 0
+	for i := range *yTrue {
+		if (*yTrue)[i] == 0 {
+			if ignoreZeros {
+				continue
+			}
+			return 0, fmt.Errorf("zero true value at index %d", i)
+		}
+		sum += math.Abs(((*yTrue)[i] - (*yPred)[i]) / (*yTrue)[i])
+		n++
+	}
+	if n == 0 {
+		return 0, fmt.Errorf("no non zero true values")
+	}
+	return sum / float64(n), nil
+}
+
+// GetMatrixMAPE returns the average of row MAPE between two Matrix objects, see GetVectorMAPE.
+func GetMatrixMAPE(yTrue, yPred *Matrix, ignoreZeros bool) (float64, error) {
+	if len(yTrue.Vectors) != len(yPred.Vectors) {
+		return 0, fmt.Errorf("%w: row matrix mismatch: yTrue got %d rows, yPred got %d rows",
+			ErrDimensionMismatch, len(yTrue.Vectors), len(yPred.Vectors))
+	}
+	if len(yTrue.Vectors) == 0 {
+		return 0, fmt.Errorf("%w: matrices have no rows", ErrEmptyInput)
+	}
+	sum := 0.0
+	for i := range yTrue.Vectors {
+		mape, err := GetVectorMAPE(yTrue.Vectors[i], yPred.Vectors[i], ignoreZeros)
+		if err != nil {
+			return 0, errors.Wrap(err, fmt.Sprintf("matrix comparison at index %d", i))
+		}
+		sum += mape
+	}
+	return sum / float64(len(yTrue.Vectors)), nil
+}
+
+// Write all elements of Matrix to a file, one value per line. Use WriteMatrixToCSV or WriteCSV to keep the rows.
 func WriteMatrixToFile(m *Matrix, fileName string) error {
 	f, err := os.Create(fileName)
 	if err != nil {
@@ -278,3 +729,72 @@ func WriteMatrixToFile(m *Matrix, fileName string) error {
 	}
 	return nil
 }
+
+// WriteSparseMatrixToFile writes sparse matrix to a file in libsvm format with 0 labels, indices are sorted within
+// each row. Rows without any element are not supported by libsvm reader so an error is returned for them.
+func WriteSparseMatrixToFile(m SparseMatrix, fileName string) error {
+	f, err := os.Create(fileName)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return WriteSparseMatrixToLibsvm(f, m, nil)
+}
+
+// WriteSparseMatrixToLibsvm writes sparse matrix to w in libsvm format, the label of each row comes from labels or
+// is 0 when labels is nil. Indices are sorted within each row and values are written with the shortest
+// representation parsing back to the same float64, so that ReadLibsvmToDMatrix reads back the same rows. Rows
+// without any element are not supported by libsvm reader so an error is returned for them.
+func WriteSparseMatrixToLibsvm(w io.Writer, m SparseMatrix, labels Vector) error {
+	if labels != nil && len(labels) != len(m.Vectors) {
+		return fmt.Errorf("%w: %d labels for %d rows", ErrDimensionMismatch, len(labels), len(m.Vectors))
+	}
+	bw := bufio.NewWriter(w)
+	for row, v := range m.Vectors {
+		if len(v) == 0 {
+			return fmt.Errorf("%w: row %d has no elements", ErrMalformedRow, row)
+		}
+		indices := make([]int, 0, len(v))
+		for idx := range v {
+			indices = append(indices, idx)
+		}
+		sort.Ints(indices)
+		label := "0"
+		if labels != nil {
+			label = strconv.FormatFloat(labels[row], 'g', -1, 64)
+		}
+		if _, err := bw.WriteString(label); err != nil {
+			return err
+		}
+		for _, idx := range indices {
+			if _, err := fmt.Fprintf(bw, " %d:%s", idx, strconv.FormatFloat(v[idx], 'g', -1, 64)); err != nil {
+				return err
+			}
+		}
+		if err := bw.WriteByte('\n'); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// WriteMatrixToCSV writes matrix rows to w, values of a row are separated by delimiter.
+func WriteMatrixToCSV(m *Matrix, w io.Writer, delimiter string) error {
+	bw := bufio.NewWriter(w)
+	for _, v := range m.Vectors {
+		for j, val := range *v {
+			if j > 0 {
+				if _, err := bw.WriteString(delimiter); err != nil {
+					return err
+				}
+			}
+			if _, err := bw.WriteString(strconv.FormatFloat(val, 'g', -1, 64)); err != nil {
+				return err
+			}
+		}
+		if err := bw.WriteByte('\n'); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
@@ -1,8 +1,19 @@
 package mat
 
 import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"io"
+	"io/ioutil"
+	"math"
+	"os"
+	"reflect"
+	"strings"
 	"testing"
 
+	"github.com/klauspost/compress/zstd"
 	"gotest.tools/assert"
 )
 
@@ -20,3 +31,881 @@ func TestReadCSVFileToDenseMatrix(t *testing.T) {
 	assert.Check(t, len(m.Vectors) != 0)
 	assert.Equal(t, len(*m.Vectors[0]), 3)
 }
+
+func TestReadFromReader(t *testing.T) {
+	content, err := ioutil.ReadFile("../test/data/iris_test.libsvm")
+	assert.NilError(t, err)
+	fromFile, err := ReadLibsvmFileToSparseMatrix("../test/data/iris_test.libsvm")
+	assert.NilError(t, err)
+	m, err := ReadLibsvmToSparseMatrix(bytes.NewReader(content))
+	assert.NilError(t, err)
+	assert.DeepEqual(t, m, fromFile)
+
+	// the last row does not need a line ending.
+	m, err = ReadLibsvmToSparseMatrix(strings.NewReader("1 1:1\n0 2:2"), WithOneBasedIndex())
+	assert.NilError(t, err)
+	assert.DeepEqual(t, m.Vectors, []SparseVector{{0: 1}, {1: 2}})
+
+	d, err := ReadLibsvmToDMatrix(strings.NewReader("1:2 qid:1 0:1\n0 qid:1 1:2\n"))
+	assert.NilError(t, err)
+	assert.DeepEqual(t, d.Labels, Vector{1, 0})
+	assert.DeepEqual(t, d.Weights, Vector{2, 1})
+	assert.DeepEqual(t, d.GroupPtr, []int{0, 2})
+
+	dense, err := ReadCSVToDenseMatrix(strings.NewReader("1\t2\n3\t\n"), "\t", -1)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, dense.Vectors, []*Vector{{1, 2}, {3, -1}})
+
+	data, err := ReadCSV(strings.NewReader("x,y\n1,0"), WithCSVHeader(), WithCSVLabelColumn("y"))
+	assert.NilError(t, err)
+	assert.DeepEqual(t, data.Labels, Vector{0})
+
+	_, err = ReadLibsvmToSparseMatrix(strings.NewReader(""))
+	assert.Check(t, errors.Is(err, ErrEmptyInput))
+	assert.ErrorContains(t, err, "input has no rows")
+	_, err = ReadCSV(strings.NewReader("\n"))
+	assert.Check(t, errors.Is(err, ErrEmptyInput))
+	_, err = ReadLibsvmToDMatrix(strings.NewReader(""))
+	assert.Check(t, errors.Is(err, ErrEmptyInput))
+}
+
+func TestReadCompressed(t *testing.T) {
+	content, err := ioutil.ReadFile("../test/data/iris_test.libsvm")
+	assert.NilError(t, err)
+	expected, err := ReadLibsvmToSparseMatrix(bytes.NewReader(content))
+	assert.NilError(t, err)
+
+	var gz bytes.Buffer
+	gw := gzip.NewWriter(&gz)
+	_, err = gw.Write(content)
+	assert.NilError(t, err)
+	assert.NilError(t, gw.Close())
+	zw, err := zstd.NewWriter(nil)
+	assert.NilError(t, err)
+	zst := zw.EncodeAll(content, nil)
+
+	for _, compressed := range [][]byte{gz.Bytes(), zst} {
+		m, err := ReadLibsvmFileToSparseMatrix(writeTempFile(t, string(compressed)))
+		assert.NilError(t, err)
+		assert.DeepEqual(t, m, expected)
+		d, err := ReadLibsvmToDMatrix(bytes.NewReader(compressed))
+		assert.NilError(t, err)
+		assert.DeepEqual(t, d.Features, expected)
+	}
+
+	gz.Reset()
+	gw = gzip.NewWriter(&gz)
+	_, err = gw.Write([]byte("x,y\n1,2\n"))
+	assert.NilError(t, err)
+	assert.NilError(t, gw.Close())
+	data, err := ReadCSV(bytes.NewReader(gz.Bytes()), WithCSVHeader())
+	assert.NilError(t, err)
+	assert.DeepEqual(t, data.Features.Vectors, []*Vector{{1, 2}})
+
+	_, err = ReadCSV(bytes.NewReader(gzipMagic))
+	assert.ErrorContains(t, err, "cannot decompress gzip")
+}
+
+func TestReadCSVFile(t *testing.T) {
+	path := writeTempFile(t, `id,"age",income,churn,plan
+a1,31,1200.5,yes,1
+a2,,800,no,NA
+`)
+	data, err := ReadCSVFile(path, WithCSVHeader(), WithCSVLabelColumn("churn"), WithCSVDroppedColumns("id"),
+		WithCSVValueMap(map[string]float64{"yes": 1, "no": 0, "NA": -1}))
+	assert.NilError(t, err)
+	assert.DeepEqual(t, data.FeatureNames, []string{"age", "income", "plan"})
+	assert.DeepEqual(t, data.Labels, Vector{1, 0})
+	assert.Equal(t, len(data.Features.Vectors), 2)
+	assert.DeepEqual(t, *data.Features.Vectors[0], Vector{31, 1200.5, 1})
+	// empty cells are missing by default.
+	assert.Check(t, math.IsNaN((*data.Features.Vectors[1])[0]))
+	assert.DeepEqual(t, (*data.Features.Vectors[1])[1:], Vector{800, -1})
+
+	path = writeTempFile(t, "1\t0.5\t2\n0\t1.5\t\n")
+	data, err = ReadCSVFile(path, WithCSVDelimiter("\t"), WithCSVDefaultValue(0), WithCSVLabelColumnIndex(0),
+		WithCSVDroppedColumnIndices(1))
+	assert.NilError(t, err)
+	assert.Check(t, data.FeatureNames == nil)
+	assert.DeepEqual(t, data.Labels, Vector{1, 0})
+	assert.DeepEqual(t, *data.Features.Vectors[1], Vector{0})
+
+	path = writeTempFile(t, "a,b\n1,x\n")
+	_, err = ReadCSVFile(path, WithCSVHeader())
+	assert.Check(t, errors.Is(err, ErrMalformedRow))
+	assert.ErrorContains(t, err, "at row 0 column 1")
+	_, err = ReadCSVFile(path, WithCSVHeader(), WithCSVLabelColumn("c"))
+	assert.Check(t, errors.Is(err, ErrMalformedRow))
+	_, err = ReadCSVFile(path, WithCSVLabelColumn("a"))
+	assert.Check(t, err != nil)
+	_, err = ReadCSVFile(path, WithCSVLabelColumnIndex(2))
+	assert.Check(t, errors.Is(err, ErrDimensionMismatch))
+	_, err = ReadCSVFile(writeTempFile(t, "a,b\n1,\n"), WithCSVHeader(), WithCSVLabelColumn("b"))
+	assert.Check(t, errors.Is(err, ErrMalformedRow))
+	_, err = ReadCSVFile(writeTempFile(t, "a,b\n"), WithCSVHeader())
+	assert.Check(t, errors.Is(err, ErrEmptyInput))
+}
+
+func TestMatrixShape(t *testing.T) {
+	m, err := ReadCSVFileToDenseMatrix(
+		"../test/data/iris_xgboost_true_prediction_proba.txt", "\t", 0)
+	assert.NilError(t, err)
+	rows, cols, err := m.Shape()
+	assert.NilError(t, err)
+	assert.Equal(t, rows, len(m.Vectors))
+	assert.Equal(t, cols, 3)
+
+	_, err = Matrix{}.Cols()
+	assert.Check(t, err != nil)
+
+	_, err = Matrix{Vectors: []*Vector{{1, 2}, {1}}}.Cols()
+	assert.Check(t, err != nil)
+	for _, vectors := range [][]*Vector{{nil}, {{1, 2}, nil}} {
+		_, _, err = Matrix{Vectors: vectors}.Shape()
+		assert.Check(t, errors.Is(err, ErrMalformedRow))
+	}
+}
+
+func TestSparseMatrixShape(t *testing.T) {
+	m := SparseMatrix{Vectors: []SparseVector{{0: 1, 4: 2}, {2: 3}}}
+	rows, cols, err := m.Shape()
+	assert.NilError(t, err)
+	assert.Equal(t, rows, 2)
+	assert.Equal(t, cols, 5)
+
+	_, err = SparseMatrix{Vectors: []SparseVector{{}}}.Cols()
+	assert.Check(t, err != nil)
+}
+
+func TestClone(t *testing.T) {
+	m := Matrix{Vectors: []*Vector{{1, 2}, {3, 4}}}
+	c := m.Clone()
+	(*c.Vectors[0])[0] = 10
+	assert.Equal(t, (*m.Vectors[0])[0], 1.0)
+	assert.NilError(t, IsEqualVectors(m.Vectors[1], c.Vectors[1], 0))
+
+	sm := SparseMatrix{Vectors: []SparseVector{{0: 1, 3: 2}}}
+	sc := sm.Clone()
+	sc.Vectors[0][0] = 10
+	delete(sc.Vectors[0], 3)
+	assert.Equal(t, sm.Vectors[0][0], 1.0)
+	assert.Equal(t, sm.Vectors[0][3], 2.0)
+}
+
+func TestGetVectorWeightedRMSE(t *testing.T) {
+	v1 := Vector{1, 2, 3}
+	v2 := Vector{1, 4, 0}
+	w := Vector{1, 1, 1}
+	weighted, err := GetVectorWeightedRMSE(&v1, &v2, &w)
+	assert.NilError(t, err)
+	rmse, err := GetVectorRMSE(&v1, &v2)
+	assert.NilError(t, err)
+	assert.Check(t, math.Abs(weighted-rmse) < 1e-12)
+
+	w = Vector{0, 1, 3}
+	weighted, err = GetVectorWeightedRMSE(&v1, &v2, &w)
+	assert.NilError(t, err)
+	assert.Check(t, math.Abs(weighted-math.Sqrt((4+27)/4.0)) < 1e-12)
+
+	_, err = GetVectorWeightedRMSE(&v1, &v2, &Vector{1, 1})
+	assert.Check(t, err != nil)
+	_, err = GetVectorWeightedRMSE(&v1, &v2, &Vector{0, 0, 0})
+	assert.Check(t, err != nil)
+}
+
+// writeTempFile writes content into a temporary file and returns its path.
+func writeTempFile(t *testing.T, content string) string {
+	f, err := ioutil.TempFile("", "mat_test")
+	assert.NilError(t, err)
+	defer f.Close()
+	_, err = f.WriteString(content)
+	assert.NilError(t, err)
+	t.Cleanup(func() { os.Remove(f.Name()) })
+	return f.Name()
+}
+
+func TestReadLibsvmFileDuplicateIndex(t *testing.T) {
+	path := writeTempFile(t, "1 0:1 3:1 3:2\n0 0:2\n")
+	m, err := ReadLibsvmFileToSparseMatrix(path)
+	assert.NilError(t, err)
+	assert.Equal(t, m.Vectors[0][3], 2.0)
+
+	_, err = ReadLibsvmFileToSparseMatrix(path, WithDuplicateIndexError())
+	assert.ErrorContains(t, err, "duplicate feature index 3 at row 0")
+}
+
+func TestReadLibsvmFileOneBased(t *testing.T) {
+	path := writeTempFile(t, "1 1:1.5 4:2\n0 2:3\n")
+	m, err := ReadLibsvmFileToSparseMatrix(path, WithOneBasedIndex())
+	assert.NilError(t, err)
+	assert.DeepEqual(t, m.Vectors, []SparseVector{{0: 1.5, 3: 2}, {1: 3}})
+
+	path = writeTempFile(t, "1 0:1.5\n")
+	_, err = ReadLibsvmFileToSparseMatrix(path, WithOneBasedIndex())
+	assert.Check(t, err != nil)
+}
+
+func TestReadLibsvmFileExpectedNumFeatures(t *testing.T) {
+	path := writeTempFile(t, "1 0:1 2:1\n0 1:2 30:1\n")
+	m, err := ReadLibsvmFileToSparseMatrix(path, WithExpectedNumFeatures(0))
+	assert.NilError(t, err)
+	assert.Equal(t, len(m.Vectors), 2)
+
+	_, err = ReadLibsvmFileToSparseMatrix(path, WithExpectedNumFeatures(3))
+	assert.Check(t, errors.Is(err, ErrDimensionMismatch))
+	assert.ErrorContains(t, err, "feature index 30 at row 1")
+}
+
+func TestWriteSparseMatrixToFile(t *testing.T) {
+	m, err := ReadLibsvmFileToSparseMatrix("../test/data/breast_cancer_test.libsvm")
+	assert.NilError(t, err)
+	path := writeTempFile(t, "")
+	assert.NilError(t, WriteSparseMatrixToFile(m, path))
+	read, err := ReadLibsvmFileToSparseMatrix(path)
+	assert.NilError(t, err)
+	assert.NilError(t, IsEqualSparseMatrices(m, read, 0))
+
+	other := SparseMatrix{Vectors: []SparseVector{{0: 1, 2: 3.5}}}
+	assert.NilError(t, IsEqualSparseMatrices(other, SparseMatrix{Vectors: []SparseVector{{0: 1.01, 2: 3.5}}}, 0.1))
+	assert.ErrorContains(t, IsEqualSparseMatrices(other, SparseMatrix{Vectors: []SparseVector{{0: 1, 2: 4}}}, 0.1),
+		"2 element mismatch")
+	assert.ErrorContains(t, IsEqualSparseMatrices(other, SparseMatrix{Vectors: []SparseVector{{0: 1, 3: 3.5}}}, 0.1),
+		"2 element missing")
+	err = IsEqualSparseMatrices(other, SparseMatrix{Vectors: []SparseVector{{0: 1}}}, 0.1)
+	assert.Check(t, errors.Is(err, ErrDimensionMismatch))
+
+	err = WriteSparseMatrixToFile(SparseMatrix{Vectors: []SparseVector{{}}}, path)
+	assert.Check(t, errors.Is(err, ErrMalformedRow))
+}
+
+func TestWriteRoundTrip(t *testing.T) {
+	d, err := ReadLibsvmFileToDMatrix("../test/data/breast_cancer_test.libsvm")
+	assert.NilError(t, err)
+	var buf bytes.Buffer
+	assert.NilError(t, WriteSparseMatrixToLibsvm(&buf, d.Features, d.Labels))
+	read, err := ReadLibsvmToDMatrix(&buf)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, read.Labels, d.Labels)
+	assert.NilError(t, IsEqualSparseMatrices(read.Features, d.Features, 0))
+	err = WriteSparseMatrixToLibsvm(&buf, d.Features, d.Labels[1:])
+	assert.Check(t, errors.Is(err, ErrDimensionMismatch))
+
+	data := CSVData{
+		Features:     Matrix{Vectors: []*Vector{{1.5, math.NaN()}, {-2, 1e-20}}},
+		Labels:       Vector{1, 0},
+		FeatureNames: []string{"a", "b"},
+		LabelName:    "y",
+	}
+	buf.Reset()
+	assert.NilError(t, WriteCSV(&buf, data, ","))
+	assert.Equal(t, buf.String(), "y,a,b\n1,1.5,\n0,-2,1e-20\n")
+	csv, err := ReadCSV(&buf, WithCSVHeader(), WithCSVLabelColumnIndex(0))
+	assert.NilError(t, err)
+	assert.DeepEqual(t, csv.FeatureNames, data.FeatureNames)
+	assert.Equal(t, csv.LabelName, "y")
+	assert.DeepEqual(t, csv.Labels, data.Labels)
+	assert.Check(t, math.IsNaN((*csv.Features.Vectors[0])[1]))
+	assert.DeepEqual(t, *csv.Features.Vectors[1], *data.Features.Vectors[1])
+
+	data.FeatureNames = []string{"a,b", "c"}
+	assert.Check(t, WriteCSV(&buf, data, ",") != nil)
+	data.FeatureNames = []string{"a"}
+	assert.Check(t, errors.Is(WriteCSV(&buf, data, ","), ErrDimensionMismatch))
+}
+
+func TestVectorArithmetic(t *testing.T) {
+	v := Vector{1, 2, 3}
+	o := Vector{4, 5, 6}
+	sum, err := v.Add(o)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, sum, Vector{5, 7, 9})
+	diff, err := v.Sub(o)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, diff, Vector{-3, -3, -3})
+	assert.DeepEqual(t, v.Scale(2), Vector{2, 4, 6})
+	dot, err := v.Dot(o)
+	assert.NilError(t, err)
+	assert.Equal(t, dot, 32.0)
+	// inputs are not modified.
+	assert.DeepEqual(t, v, Vector{1, 2, 3})
+
+	_, err = v.Add(Vector{1})
+	assert.Check(t, errors.Is(err, ErrDimensionMismatch))
+	_, err = v.Sub(Vector{1})
+	assert.Check(t, errors.Is(err, ErrDimensionMismatch))
+	_, err = v.Dot(Vector{1})
+	assert.Check(t, errors.Is(err, ErrDimensionMismatch))
+}
+
+func TestReadArrowToMatrix(t *testing.T) {
+	expected := Matrix{Vectors: []*Vector{
+		{1.5, -1, 0.25, 1}, {2.5, 7, 0.5, 2}, {math.NaN(), 42, 0.75, 255},
+		{11.5, -1, 0.25, 1}, {12.5, 7, 0.5, 2}, {math.NaN(), 42, 0.75, 255},
+	}}
+	for _, path := range []string{"../test/data/small.arrow", "../test/data/small.arrows"} {
+		m, names, err := ReadArrowToMatrix(path, nil)
+		assert.NilError(t, err, path)
+		assert.DeepEqual(t, names, []string{"f0", "f1", "f2", "f3"})
+		assert.Equal(t, len(m.Vectors), len(expected.Vectors))
+		for i, v := range m.Vectors {
+			assert.Check(t, math.IsNaN((*v)[0]) == math.IsNaN((*expected.Vectors[i])[0]))
+			if !math.IsNaN((*v)[0]) {
+				assert.DeepEqual(t, *v, *expected.Vectors[i])
+			}
+		}
+
+		m, names, err = ReadArrowToMatrix(path, []string{"f3", "f1"})
+		assert.NilError(t, err)
+		assert.DeepEqual(t, names, []string{"f3", "f1"})
+		assert.DeepEqual(t, *m.Vectors[2], Vector{255, 42})
+
+		_, _, err = ReadArrowToMatrix(path, []string{"name"})
+		assert.ErrorContains(t, err, "column name is not numeric")
+		_, _, err = ReadArrowToMatrix(path, []string{"f9"})
+		assert.ErrorContains(t, err, "column f9 not found")
+	}
+
+	_, _, err := ReadArrowToMatrix("../test/data/breast_cancer_test.libsvm", nil)
+	assert.Check(t, errors.Is(err, ErrMalformedRow))
+}
+
+func TestArrowStreamReader(t *testing.T) {
+	for _, path := range []string{"../test/data/small.arrow", "../test/data/small.arrows"} {
+		expected, _, err := ReadArrowToMatrix(path, []string{"f3", "f1"})
+		assert.NilError(t, err)
+		content, err := ioutil.ReadFile(path)
+		assert.NilError(t, err)
+		m, names, err := ReadArrow(bytes.NewReader(content), []string{"f3", "f1"})
+		assert.NilError(t, err)
+		assert.DeepEqual(t, names, []string{"f3", "f1"})
+		assert.DeepEqual(t, m, expected)
+
+		r, err := NewArrowStreamReader(bytes.NewReader(content), []string{"f3", "f1"})
+		assert.NilError(t, err, path)
+		assert.DeepEqual(t, r.Names(), []string{"f3", "f1"})
+		var rows []*Vector
+		batches := 0
+		for {
+			batch, err := r.Next()
+			if err == io.EOF {
+				break
+			}
+			assert.NilError(t, err)
+			rows = append(rows, batch.Vectors...)
+			batches++
+		}
+		assert.Equal(t, batches, 2)
+		assert.DeepEqual(t, rows, expected.Vectors)
+
+		_, err = NewArrowStreamReader(bytes.NewReader(content), []string{"f9"})
+		assert.ErrorContains(t, err, "column f9 not found")
+	}
+	content, err := ioutil.ReadFile("../test/data/small.arrows")
+	assert.NilError(t, err)
+	r, err := NewArrowStreamReader(bytes.NewReader(content[:len(content)-20]), nil)
+	assert.NilError(t, err)
+	for err == nil {
+		_, err = r.Next()
+	}
+	assert.Check(t, errors.Is(err, ErrMalformedRow))
+	_, err = NewArrowStreamReader(strings.NewReader("1 0:1\n"), nil)
+	assert.Check(t, errors.Is(err, ErrMalformedRow))
+
+	// record batch lengths which are negative or exceed the body are rejected before allocating rows.
+	for _, length := range []int64{-1, 1 << 40} {
+		patched := append([]byte(nil), content...)
+		pos := 0
+		for {
+			msg, next, ok, err := readArrowMessage(patched, pos)
+			assert.NilError(t, err)
+			assert.Assert(t, ok)
+			pos = next
+			if msg.headerType == arrowHeaderRecordBatch {
+				binary.LittleEndian.PutUint64(msg.header.buf[msg.header.field(0):], uint64(length))
+				break
+			}
+		}
+		_, _, err = ReadArrow(bytes.NewReader(patched), nil)
+		assert.Check(t, errors.Is(err, ErrMalformedRow), length)
+	}
+}
+
+func TestReadParquetToDenseMatrix(t *testing.T) {
+	expected := Matrix{Vectors: []*Vector{
+		{1.5, -1, 0.25, 1, 1, 12.34}, {2.5, 7, 0.5, 2, 0, -0.05}, {math.NaN(), 42, 0.75, 255, math.NaN(), 0},
+		{11.5, -1, 0.25, 1, 0, 1}, {12.5, 7, 0.5, 2, 1, 0.01}, {math.NaN(), 42, 0.75, 255, 1, 999.99},
+	}}
+	path := "../test/data/small.parquet"
+	m, names, err := ReadParquetToDenseMatrix(path)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, names, []string{"f0", "f1", "f2", "f3", "flag", "price"})
+	assert.Equal(t, len(m.Vectors), len(expected.Vectors))
+	for i, v := range m.Vectors {
+		for j, val := range *v {
+			want := (*expected.Vectors[i])[j]
+			assert.Check(t, math.IsNaN(val) == math.IsNaN(want), "row %d column %d", i, j)
+			if !math.IsNaN(want) {
+				assert.Check(t, math.Abs(val-want) < 1e-9, "row %d column %d: %v != %v", i, j, val, want)
+			}
+		}
+	}
+
+	m, names, err = ReadParquetToDenseMatrix(path, "f3", "f1")
+	assert.NilError(t, err)
+	assert.DeepEqual(t, names, []string{"f3", "f1"})
+	assert.DeepEqual(t, *m.Vectors[2], Vector{255, 42})
+
+	_, _, err = ReadParquetToDenseMatrix(path, "name")
+	assert.ErrorContains(t, err, "column name is not numeric")
+	_, _, err = ReadParquetToDenseMatrix(path, "f9")
+	assert.ErrorContains(t, err, "column f9 not found")
+	_, _, err = ReadParquetToDenseMatrix(path, "x")
+	assert.ErrorContains(t, err, "column x not found")
+
+	_, _, err = ReadParquetToDenseMatrix("../test/data/breast_cancer_test.libsvm")
+	assert.Check(t, errors.Is(err, ErrMalformedRow))
+}
+
+func TestReadNpyToMatrix(t *testing.T) {
+	expected := []Vector{{1.5, -1, 0.25, 1}, {2.5, 7, 0.5, 2}, {math.NaN(), 42, 0.75, 255}}
+	checkRows := func(m Matrix) {
+		assert.Equal(t, len(m.Vectors), len(expected))
+		for i, v := range m.Vectors {
+			assert.Check(t, math.IsNaN((*v)[0]) == math.IsNaN(expected[i][0]))
+			if !math.IsNaN(expected[i][0]) {
+				assert.DeepEqual(t, *v, expected[i])
+			}
+		}
+	}
+	m, err := ReadNpyToMatrix("../test/data/small.npy")
+	assert.NilError(t, err)
+	checkRows(m)
+
+	content, err := ioutil.ReadFile("../test/data/small.npy")
+	assert.NilError(t, err)
+	m, err = ReadNpy(bytes.NewReader(content))
+	assert.NilError(t, err)
+	checkRows(m)
+	_, err = ReadNpy(bytes.NewReader(content[:len(content)-8]))
+	assert.Check(t, errors.Is(err, ErrMalformedRow))
+
+	arrays, err := ReadNpzToMatrices("../test/data/small.npz")
+	assert.NilError(t, err)
+	assert.Equal(t, len(arrays), 4)
+	checkRows(arrays["features"])
+	checkRows(arrays["fortran"])
+	assert.DeepEqual(t, arrays["labels"], Matrix{Vectors: []*Vector{{0}, {1}, {1}}})
+	assert.DeepEqual(t, arrays["mask"], Matrix{Vectors: []*Vector{{1}, {0}, {1}}})
+
+	_, err = ReadNpyToMatrix("../test/data/breast_cancer_test.libsvm")
+	assert.Check(t, errors.Is(err, ErrMalformedRow))
+	_, err = ReadNpzToMatrices("../test/data/small.npy")
+	assert.Check(t, errors.Is(err, ErrMalformedRow))
+}
+
+func TestCSRMatrix(t *testing.T) {
+	m := SparseMatrix{Vectors: []SparseVector{{3: 1.5, 0: -1}, {}, {7: 2, 2: 0, 5: math.Inf(1)}}}
+	csr := m.ToCSR()
+	assert.NilError(t, csr.Validate())
+	assert.DeepEqual(t, csr, CSRMatrix{
+		Indptr:  []int{0, 2, 2, 5},
+		Indices: []int{0, 3, 2, 5, 7},
+		Values:  []float64{-1, 1.5, 0, math.Inf(1), 2},
+	})
+	assert.Equal(t, csr.Rows(), 3)
+	cols, err := csr.Cols()
+	assert.NilError(t, err)
+	assert.Equal(t, cols, 8)
+	indices, values := csr.Row(2)
+	assert.DeepEqual(t, indices, []int{2, 5, 7})
+	assert.DeepEqual(t, values, []float64{0, math.Inf(1), 2})
+	assert.DeepEqual(t, csr.ToSparseMatrix(), m)
+
+	assert.NilError(t, CSRMatrix{}.Validate())
+	assert.Equal(t, CSRMatrix{}.Rows(), 0)
+	for _, wrong := range []CSRMatrix{
+		{Indptr: []int{0, 1}, Indices: []int{1}},
+		{Indptr: []int{1, 1}, Indices: []int{1}, Values: []float64{1}},
+		{Indptr: []int{0, 2, 1}, Indices: []int{1}, Values: []float64{1}},
+	} {
+		assert.Check(t, errors.Is(wrong.Validate(), ErrDimensionMismatch), "%v", wrong)
+	}
+	err = CSRMatrix{Indptr: []int{0, 2}, Indices: []int{1, 1}, Values: []float64{1, 2}}.Validate()
+	assert.Check(t, errors.Is(err, ErrMalformedRow))
+}
+
+func TestReadWithLabels(t *testing.T) {
+	path := writeTempFile(t, "1 qid:3 0:1.5 2:3\n0:0.5 qid:3 1:2\n")
+	m, labels, err := ReadLibsvmFileToSparseMatrixWithLabels(path)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, labels, Vector{1, 0})
+	expected, err := ReadLibsvmFileToSparseMatrix(writeTempFile(t, "1 0:1.5 2:3\n0 1:2\n"))
+	assert.NilError(t, err)
+	assert.DeepEqual(t, m, expected)
+	m, labels, err = ReadLibsvmToSparseMatrixWithLabels(strings.NewReader("2 1:1\n3 2:1\n"), WithOneBasedIndex())
+	assert.NilError(t, err)
+	assert.DeepEqual(t, labels, Vector{2, 3})
+	assert.DeepEqual(t, m, SparseMatrix{Vectors: []SparseVector{{0: 1}, {1: 1}}})
+	_, _, err = ReadLibsvmToSparseMatrixWithLabels(strings.NewReader("a 1:1\n"))
+	assert.Check(t, errors.Is(err, ErrMalformedRow))
+
+	path = writeTempFile(t, "1.5,1,2\n,0,3\n")
+	features, labels, err := ReadCSVFileToDenseMatrixWithLabels(path, ",", 0, 1)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, labels, Vector{1, 0})
+	assert.DeepEqual(t, features, Matrix{Vectors: []*Vector{{1.5, 2}, {0, 3}}})
+	features, labels, err = ReadCSVToDenseMatrixWithLabels(strings.NewReader("1\t5\n0\t6\n"), "\t", 0, 0)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, labels, Vector{1, 0})
+	assert.DeepEqual(t, features, Matrix{Vectors: []*Vector{{5}, {6}}})
+	_, _, err = ReadCSVFileToDenseMatrixWithLabels(path, ",", 0, 0)
+	assert.Check(t, errors.Is(err, ErrMalformedRow))
+	_, _, err = ReadCSVFileToDenseMatrixWithLabels(path, ",", 0, 3)
+	assert.Check(t, errors.Is(err, ErrDimensionMismatch))
+	_, _, err = ReadCSVToDenseMatrixWithLabels(strings.NewReader("1\n"), ",", 0, -1)
+	assert.Check(t, errors.Is(err, ErrDimensionMismatch))
+}
+
+func TestMatrixSlicing(t *testing.T) {
+	m := Matrix{Vectors: []*Vector{{1, 2, 3}, {4, 5, 6}, {7, 8, 9}}}
+	sliced, err := m.Slice([]int{2, 0, 2})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, sliced, Matrix{Vectors: []*Vector{{7, 8, 9}, {1, 2, 3}, {7, 8, 9}}})
+	assert.Check(t, sliced.Vectors[1] == m.Vectors[0])
+	_, err = m.Slice([]int{3})
+	assert.ErrorContains(t, err, "row index 3 out of range of 3 rows")
+	row, err := m.Row(1)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, row, Vector{4, 5, 6})
+	_, err = m.Row(-1)
+	assert.ErrorContains(t, err, "row index -1 out of range")
+	projected, err := m.SelectColumns([]int{2, 0})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, projected, Matrix{Vectors: []*Vector{{3, 1}, {6, 4}, {9, 7}}})
+
+	sparse := SparseMatrix{Vectors: []SparseVector{{0: 1, 3: 2}, {1: 3}, {}}}
+	sparseSliced, err := sparse.Slice([]int{1, 0})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, sparseSliced, SparseMatrix{Vectors: []SparseVector{{1: 3}, {0: 1, 3: 2}}})
+	_, err = sparse.Slice([]int{5})
+	assert.ErrorContains(t, err, "row index 5 out of range of 3 rows")
+	sparseRow, err := sparse.Row(0)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, sparseRow, SparseVector{0: 1, 3: 2})
+	_, err = sparse.Row(3)
+	assert.ErrorContains(t, err, "row index 3 out of range")
+	sparseProjected, err := sparse.SelectColumns([]int{3, 1, 3})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, sparseProjected, SparseMatrix{Vectors: []SparseVector{{0: 2, 2: 2}, {1: 3}, {}}})
+	_, err = sparse.SelectColumns([]int{-1})
+	assert.ErrorContains(t, err, "column index -1 out of range")
+}
+
+func TestSplitAndShuffle(t *testing.T) {
+	m := Matrix{Vectors: make([]*Vector, 10)}
+	labels := make(Vector, 10)
+	for i := range m.Vectors {
+		m.Vectors[i] = &Vector{float64(i)}
+		labels[i] = float64(i)
+	}
+	train, trainLabels, test, testLabels, err := Split(m, labels, 0.75, 42)
+	assert.NilError(t, err)
+	assert.Equal(t, train.Rows(), 8)
+	assert.Equal(t, test.Rows(), 2)
+	seen := map[float64]bool{}
+	for i, v := range append(train.Vectors, test.Vectors...) {
+		assert.Equal(t, (*v)[0], append(trainLabels, testLabels...)[i])
+		seen[(*v)[0]] = true
+	}
+	assert.Equal(t, len(seen), 10)
+	train2, _, _, _, err := Split(m, nil, 0.75, 42)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, train2, train)
+	train3, _, _, _, err := Split(m, nil, 0.75, 7)
+	assert.NilError(t, err)
+	assert.Check(t, !reflect.DeepEqual(train3, train))
+
+	sparse := SparseMatrix{Vectors: make([]SparseVector, 10)}
+	for i := range sparse.Vectors {
+		sparse.Vectors[i] = SparseVector{0: float64(i)}
+	}
+	sparseTrain, sparseLabels, sparseTest, _, err := SplitSparse(sparse, labels, 0.75, 42)
+	assert.NilError(t, err)
+	assert.Equal(t, sparseTest.Rows(), 2)
+	assert.DeepEqual(t, sparseLabels, trainLabels)
+	for i, v := range sparseTrain.Vectors {
+		assert.Equal(t, v[0], (*train.Vectors[i])[0])
+	}
+
+	shuffled, shuffledLabels, err := Shuffle(m, labels, 42)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, shuffledLabels[:8], trainLabels)
+	assert.Equal(t, shuffled.Rows(), 10)
+	sparseShuffled, _, err := ShuffleSparse(sparse, nil, 42)
+	assert.NilError(t, err)
+	assert.Equal(t, sparseShuffled.Vectors[9][0], (*shuffled.Vectors[9])[0])
+	assert.DeepEqual(t, Permutation(5, 1), Permutation(5, 1))
+
+	_, _, _, _, err = Split(m, labels[1:], 0.5, 1)
+	assert.Check(t, errors.Is(err, ErrDimensionMismatch))
+	_, _, _, _, err = Split(m, labels, 1.5, 1)
+	assert.ErrorContains(t, err, "split ratio must be in [0, 1]")
+	_, _, err = Shuffle(m, labels[1:], 1)
+	assert.Check(t, errors.Is(err, ErrDimensionMismatch))
+}
+
+func TestStack(t *testing.T) {
+	a := Matrix{Vectors: []*Vector{{1, 2}, {3, 4}}}
+	b := Matrix{Vectors: []*Vector{{5, 6}}}
+	stacked, err := VStack(a, b, Matrix{})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, stacked, Matrix{Vectors: []*Vector{{1, 2}, {3, 4}, {5, 6}}})
+	_, err = VStack(a, Matrix{Vectors: []*Vector{{1}}})
+	assert.Check(t, errors.Is(err, ErrDimensionMismatch))
+
+	stacked, err = HStack(a, Matrix{Vectors: []*Vector{{7}, {8}}}, a)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, stacked, Matrix{Vectors: []*Vector{{1, 2, 7, 1, 2}, {3, 4, 8, 3, 4}}})
+	_, err = HStack(a, b)
+	assert.Check(t, errors.Is(err, ErrDimensionMismatch))
+	_, err = HStack(a, Matrix{Vectors: []*Vector{{1}, {2, 3}}})
+	assert.Check(t, errors.Is(err, ErrDimensionMismatch))
+	stacked, err = HStack()
+	assert.NilError(t, err)
+	assert.Equal(t, stacked.Rows(), 0)
+
+	s1 := SparseMatrix{Vectors: []SparseVector{{0: 1}, {2: 2}}}
+	s2 := SparseMatrix{Vectors: []SparseVector{{1: 3}, {}}}
+	assert.DeepEqual(t, VStackSparse(s1, s2), SparseMatrix{Vectors: []SparseVector{{0: 1}, {2: 2}, {1: 3}, {}}})
+	sparse, err := HStackSparse([]SparseMatrix{s1, s2}, []int{4, 2})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, sparse, SparseMatrix{Vectors: []SparseVector{{0: 1, 5: 3}, {2: 2}}})
+	_, err = HStackSparse([]SparseMatrix{s1, s2}, []int{2, 2})
+	assert.Check(t, errors.Is(err, ErrFeatureIndexOutOfRange))
+	_, err = HStackSparse([]SparseMatrix{s1, {Vectors: []SparseVector{{}}}}, []int{4, 2})
+	assert.Check(t, errors.Is(err, ErrDimensionMismatch))
+	_, err = HStackSparse([]SparseMatrix{s1}, nil)
+	assert.Check(t, errors.Is(err, ErrDimensionMismatch))
+}
+
+func TestReadLibsvmFileMaxFeatureIndex(t *testing.T) {
+	path := writeTempFile(t, "1 0:1 2:1\n0 1:2 4000000000:1\n")
+	_, err := ReadLibsvmFileToSparseMatrix(path)
+	assert.Check(t, errors.Is(err, ErrMalformedRow))
+	assert.ErrorContains(t, err, "feature index 4000000000 at row 1 overflows int32")
+
+	path = writeTempFile(t, "1 0:1 2:1\n0 1:2 1000:1\n")
+	m, err := ReadLibsvmFileToSparseMatrix(path, WithMaxFeatureIndex(1000))
+	assert.NilError(t, err)
+	assert.Equal(t, m.Vectors[1][1000], 1.0)
+	_, err = ReadLibsvmFileToSparseMatrix(path, WithMaxFeatureIndex(999))
+	assert.Check(t, errors.Is(err, ErrMalformedRow))
+	assert.ErrorContains(t, err, "feature index 1000 at row 1 exceeds maximum 999")
+}
+
+func TestHistogram(t *testing.T) {
+	counts, edges, err := Histogram(Vector{0, 1, 2, 3, 4, 4}, 4)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, edges, []float64{0, 1, 2, 3, 4})
+	assert.DeepEqual(t, counts, []int{1, 1, 1, 3})
+
+	counts, edges, err = Histogram(Vector{2, 2}, 2)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, edges, []float64{1.5, 2, 2.5})
+	assert.DeepEqual(t, counts, []int{0, 2})
+
+	_, _, err = Histogram(Vector{}, 2)
+	assert.Check(t, err != nil)
+	_, _, err = Histogram(Vector{1}, 0)
+	assert.Check(t, err != nil)
+	_, _, err = Histogram(Vector{1, math.NaN()}, 2)
+	assert.Check(t, err != nil)
+
+	// values too close to have distinct edges are binned like equal values.
+	tiny := math.Nextafter(1, 2)
+	counts, edges, err = Histogram(Vector{1, tiny}, 4)
+	assert.NilError(t, err)
+	assert.Equal(t, len(edges), 5)
+	assert.Equal(t, edges[0], 0.5)
+	assert.Equal(t, edges[4], tiny+0.5)
+	assert.DeepEqual(t, counts, []int{0, 0, 2, 0})
+	_, _, err = Histogram(Vector{1e300, math.Nextafter(1e300, math.Inf(1))}, 2)
+	assert.ErrorContains(t, err, "too large to have 2 distinct bins")
+	_, _, err = Histogram(Vector{-math.MaxFloat64, math.MaxFloat64}, 2)
+	assert.ErrorContains(t, err, "too large to build a histogram")
+	_, _, err = Histogram(Vector{1, math.Inf(1)}, 2)
+	assert.Check(t, err != nil)
+}
+
+func TestDigitize(t *testing.T) {
+	idx, err := Digitize(Vector{-1, 0, 0.5, 1, 2, 3, 5, math.NaN()}, []float64{0, 1, 2, 3})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, idx, []int{-1, 0, 0, 1, 2, 2, 3, -1})
+
+	idx, err = Digitize(Vector{}, []float64{0, 1})
+	assert.NilError(t, err)
+	assert.Equal(t, len(idx), 0)
+
+	_, err = Digitize(Vector{1}, []float64{1})
+	assert.Check(t, err != nil)
+	_, err = Digitize(Vector{1}, []float64{1, 1})
+	assert.Check(t, err != nil)
+}
+
+func TestSelectColumns(t *testing.T) {
+	m := Matrix{Vectors: []*Vector{{1, 2, 3}, {4, 5, 6}}}
+	s, err := m.SelectColumns([]int{2, 0})
+	assert.NilError(t, err)
+	expected := Matrix{Vectors: []*Vector{{3, 1}, {6, 4}}}
+	assert.NilError(t, IsEqualMatrices(&s, &expected, 0))
+
+	_, err = m.SelectColumns([]int{3})
+	assert.Check(t, err != nil)
+	_, err = m.SelectColumns([]int{-1})
+	assert.Check(t, err != nil)
+}
+
+func TestWriteMatrixToJSONL(t *testing.T) {
+	m := Matrix{Vectors: []*Vector{{0.25, 0.75}, {1, 0}}}
+	var buf bytes.Buffer
+	assert.NilError(t, WriteMatrixToJSONL(&m, &buf))
+	assert.Equal(t, buf.String(), "[0.25,0.75]\n[1,0]\n")
+
+	buf.Reset()
+	assert.NilError(t, WritePredictionsJSON(&m, []string{"no", "yes"}, &buf))
+	assert.Equal(t, buf.String(), "{\"no\":0.25,\"yes\":0.75}\n{\"no\":1,\"yes\":0}\n")
+
+	assert.Check(t, WritePredictionsJSON(&m, []string{"no"}, &buf) != nil)
+	m = Matrix{Vectors: []*Vector{{math.NaN()}}}
+	assert.Check(t, WriteMatrixToJSONL(&m, &buf) != nil)
+}
+
+func TestGetMAPE(t *testing.T) {
+	yTrue := Vector{1, 2, 0, 4}
+	yPred := Vector{1.5, 1, 1, 4}
+	_, err := GetVectorMAPE(&yTrue, &yPred, false)
+	assert.ErrorContains(t, err, "zero true value at index 2")
+	mape, err := GetVectorMAPE(&yTrue, &yPred, true)
+	assert.NilError(t, err)
+	assert.Check(t, math.Abs(mape-(0.5+0.5+0)/3) < 1e-12)
+
+	_, err = GetVectorMAPE(&Vector{0}, &Vector{1}, true)
+	assert.Check(t, err != nil)
+	_, err = GetVectorMAPE(&yTrue, &Vector{1}, true)
+	assert.Check(t, err != nil)
+
+	m1 := Matrix{Vectors: []*Vector{{1, 2}, {4}}}
+	m2 := Matrix{Vectors: []*Vector{{2, 2}, {3}}}
+	mape, err = GetMatrixMAPE(&m1, &m2, false)
+	assert.NilError(t, err)
+	assert.Check(t, math.Abs(mape-(0.5+0.25)/2) < 1e-12)
+	_, err = GetMatrixMAPE(&Matrix{}, &Matrix{}, false)
+	assert.Check(t, errors.Is(err, ErrEmptyInput))
+}
+
+func TestSparseMatrixBatches(t *testing.T) {
+	m, err := ReadLibsvmFileToSparseMatrix("../test/data/iris_test.libsvm")
+	assert.NilError(t, err)
+
+	next := m.Batches(7)
+	rows := make([]SparseVector, 0)
+	sizes := make([]int, 0)
+	for batch, ok := next(); ok; batch, ok = next() {
+		rows = append(rows, batch.Vectors...)
+		sizes = append(sizes, batch.Rows())
+	}
+	assert.DeepEqual(t, rows, m.Vectors)
+	assert.DeepEqual(t, sizes, []int{7, 7, 7, 7, 2})
+
+	next = SparseMatrix{}.Batches(3)
+	_, ok := next()
+	assert.Check(t, !ok)
+}
+
+func TestErrors(t *testing.T) {
+	_, err := ReadLibsvmFileToSparseMatrix("../test/data/does_not_exist.libsvm")
+	assert.Check(t, errors.Is(err, os.ErrNotExist))
+
+	path := writeTempFile(t, "1 0:1\n1 0:abc\n")
+	_, err = ReadLibsvmFileToSparseMatrix(path)
+	assert.Check(t, errors.Is(err, ErrMalformedRow))
+	assert.ErrorContains(t, err, "at row 1")
+
+	path = writeTempFile(t, "1,2\n3\n")
+	_, err = ReadCSVFileToDenseMatrix(path, ",", 0)
+	assert.Check(t, errors.Is(err, ErrDimensionMismatch))
+
+	m1 := Matrix{Vectors: []*Vector{{1, 2}}}
+	m2 := Matrix{Vectors: []*Vector{{1}}}
+	assert.Check(t, errors.Is(IsEqualMatrices(&m1, &m2, 0), ErrDimensionMismatch))
+
+	for _, content := range []string{"", " \n\t\n"} {
+		path = writeTempFile(t, content)
+		_, err = ReadLibsvmFileToSparseMatrix(path)
+		assert.Check(t, errors.Is(err, ErrEmptyInput))
+		_, err = ReadCSVFileToDenseMatrix(path, ",", 0)
+		assert.Check(t, errors.Is(err, ErrEmptyInput))
+	}
+}
+
+func TestGetMatrixFromSliceWithMissing(t *testing.T) {
+	data := [][]float64{{0, -999, 1.5}, {math.NaN(), 2, -999}}
+	sparse, err := GetSparseMatrixFromSliceWithMissing(data, -999)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, sparse.Vectors, []SparseVector{{0: 0, 2: 1.5}, {1: 2}})
+
+	dense, err := GetDenseMatrixFromSlice(data, -999)
+	assert.NilError(t, err)
+	assert.Equal(t, dense.Rows(), 2)
+	assert.Equal(t, (*dense.Vectors[0])[0], 0.0)
+	assert.Check(t, math.IsNaN((*dense.Vectors[0])[1]))
+	assert.Check(t, math.IsNaN((*dense.Vectors[1])[0]))
+	assert.Check(t, math.IsNaN((*dense.Vectors[1])[2]))
+	// the input is not modified.
+	assert.Equal(t, data[0][1], -999.0)
+
+	dense, err = GetDenseMatrixFromSlice(data, math.NaN())
+	assert.NilError(t, err)
+	assert.Equal(t, (*dense.Vectors[0])[1], -999.0)
+}
+
+func TestReadLibsvmFileToDMatrix(t *testing.T) {
+	path := writeTempFile(t, "1:2 qid:1 0:1 3:1\n0 qid:1 0:2\n2:0.5 qid:7 1:3\n")
+	d, err := ReadLibsvmFileToDMatrix(path, WithDuplicateIndexError())
+	assert.NilError(t, err)
+	assert.DeepEqual(t, d.Features.Vectors, []SparseVector{{0: 1, 3: 1}, {0: 2}, {1: 3}})
+	assert.DeepEqual(t, d.Labels, Vector{1, 0, 2})
+	assert.DeepEqual(t, d.Weights, Vector{2, 1, 0.5})
+	assert.DeepEqual(t, d.GroupPtr, []int{0, 2, 3})
+	assert.Equal(t, d.NumGroups(), 2)
+	assert.NilError(t, d.Validate())
+	assert.DeepEqual(t, d.GroupSizes(), []int{2, 1})
+	m, err := ReadLibsvmFileToSparseMatrix(path)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, m, d.Features)
+
+	d, err = ReadLibsvmFileToDMatrix("../test/data/iris_test.libsvm")
+	assert.NilError(t, err)
+	m, err = ReadLibsvmFileToSparseMatrix("../test/data/iris_test.libsvm")
+	assert.NilError(t, err)
+	assert.DeepEqual(t, d.Features, m)
+	assert.Equal(t, len(d.Labels), d.Rows())
+	assert.Check(t, d.Weights == nil)
+	assert.Check(t, d.GroupPtr == nil)
+	assert.Check(t, d.GroupSizes() == nil)
+	assert.NilError(t, d.SetGroupSizes([]int{d.Rows() - 1, 1}))
+	assert.DeepEqual(t, d.GroupPtr, []int{0, d.Rows() - 1, d.Rows()})
+	assert.Check(t, errors.Is(d.SetGroupSizes([]int{1}), ErrDimensionMismatch))
+
+	d.Labels = d.Labels[1:]
+	assert.Check(t, errors.Is(d.Validate(), ErrDimensionMismatch))
+
+	_, err = ReadLibsvmFileToDMatrix(writeTempFile(t, "1 qid:1 0:1\n0 0:2\n"))
+	assert.Check(t, errors.Is(err, ErrMalformedRow))
+	_, err = ReadLibsvmFileToDMatrix(writeTempFile(t, "1 qid:1 0:1\n0 qid:2 0:2\n1 qid:1 0:3\n"))
+	assert.ErrorContains(t, err, "rows of qid 1 are not contiguous")
+	_, err = ReadLibsvmFileToDMatrix(writeTempFile(t, "a 0:1\n"))
+	assert.Check(t, errors.Is(err, ErrMalformedRow))
+	_, err = ReadLibsvmFileToDMatrix(writeTempFile(t, ""))
+	assert.Check(t, errors.Is(err, ErrEmptyInput))
+}
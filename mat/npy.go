@@ -0,0 +1,240 @@
+package mat
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// npyMagic starts every .npy array, it is followed by the major and minor version of the format.
+const npyMagic = "\x93NUMPY"
+
+// npyHeader is the description of an array stored in the python dict literal of a .npy header.
+type npyHeader struct {
+	order binary.ByteOrder
+	kind  byte
+	size  int
+	// fortran is true when the array is stored in column-major order.
+	fortran bool
+	shape   []int
+}
+
+// parseNpyHeader parses a header like {'descr': '<f8', 'fortran_order': False, 'shape': (3, 4), }.
+func parseNpyHeader(header, name string) (npyHeader, error) {
+	h := npyHeader{order: binary.LittleEndian}
+	entry := func(key string) (string, error) {
+		i := strings.Index(header, "'"+key+"'")
+		if i < 0 {
+			return "", fmt.Errorf("%w: no %s in npy header of %s", ErrMalformedRow, key, name)
+		}
+		v := strings.TrimSpace(header[i+len(key)+2:])
+		if !strings.HasPrefix(v, ":") {
+			return "", fmt.Errorf("%w: wrong %s in npy header of %s", ErrMalformedRow, key, name)
+		}
+		return strings.TrimSpace(v[1:]), nil
+	}
+
+	descr, err := entry("descr")
+	if err != nil {
+		return h, err
+	}
+	if len(descr) < 5 || descr[0] != '\'' || strings.IndexByte(descr[1:], '\'') < 0 {
+		return h, fmt.Errorf("%w: unsupported dtype in npy header of %s, only simple numeric dtypes are supported",
+			ErrMalformedRow, name)
+	}
+	dtype := descr[1 : strings.IndexByte(descr[1:], '\'')+1]
+	switch dtype[0] {
+	case '>':
+		h.order = binary.BigEndian
+	case '<', '|', '=':
+	default:
+		return h, fmt.Errorf("%w: unsupported dtype %s in %s", ErrMalformedRow, dtype, name)
+	}
+	h.kind = dtype[1]
+	if h.size, err = strconv.Atoi(dtype[2:]); err != nil {
+		return h, fmt.Errorf("%w: unsupported dtype %s in %s", ErrMalformedRow, dtype, name)
+	}
+	switch {
+	case h.kind == 'f' && (h.size == 4 || h.size == 8):
+	case (h.kind == 'i' || h.kind == 'u') && (h.size == 1 || h.size == 2 || h.size == 4 || h.size == 8):
+	case h.kind == 'b' && h.size == 1:
+	default:
+		return h, fmt.Errorf("%w: unsupported dtype %s in %s", ErrMalformedRow, dtype, name)
+	}
+
+	fortran, err := entry("fortran_order")
+	if err != nil {
+		return h, err
+	}
+	h.fortran = strings.HasPrefix(fortran, "True")
+
+	shape, err := entry("shape")
+	if err != nil {
+		return h, err
+	}
+	end := strings.IndexByte(shape, ')')
+	if !strings.HasPrefix(shape, "(") || end < 0 {
+		return h, fmt.Errorf("%w: wrong shape in npy header of %s", ErrMalformedRow, name)
+	}
+	for _, dim := range strings.Split(shape[1:end], ",") {
+		if dim = strings.TrimSpace(dim); dim == "" {
+			continue
+		}
+		n, err := strconv.Atoi(dim)
+		if err != nil || n < 0 {
+			return h, fmt.Errorf("%w: wrong shape %s in npy header of %s", ErrMalformedRow, shape[:end+1], name)
+		}
+		h.shape = append(h.shape, n)
+	}
+	if len(h.shape) > 2 {
+		return h, fmt.Errorf("%w: %s has %d dimensions, only 1 and 2 dimensional arrays are supported",
+			ErrDimensionMismatch, name, len(h.shape))
+	}
+	return h, nil
+}
+
+// value returns the element starting at b.
+func (h npyHeader) value(b []byte) float64 {
+	switch h.kind {
+	case 'f':
+		if h.size == 4 {
+			return float64(math.Float32frombits(h.order.Uint32(b)))
+		}
+		return math.Float64frombits(h.order.Uint64(b))
+	case 'i':
+		switch h.size {
+		case 1:
+			return float64(int8(b[0]))
+		case 2:
+			return float64(int16(h.order.Uint16(b)))
+		case 4:
+			return float64(int32(h.order.Uint32(b)))
+		}
+		return float64(int64(h.order.Uint64(b)))
+	case 'u':
+		switch h.size {
+		case 1:
+			return float64(b[0])
+		case 2:
+			return float64(h.order.Uint16(b))
+		case 4:
+			return float64(h.order.Uint32(b))
+		}
+		return float64(h.order.Uint64(b))
+	}
+	if b[0] != 0 {
+		return 1
+	}
+	return 0
+}
+
+// readNpy reads a .npy array from r, name identifies the data in errors.
+func readNpy(r io.Reader, name string) (Matrix, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return Matrix{}, fmt.Errorf("unable to read %s: %w", name, err)
+	}
+	if len(data) < len(npyMagic)+4 || string(data[:len(npyMagic)]) != npyMagic {
+		return Matrix{}, fmt.Errorf("%w: %s is not a npy array", ErrMalformedRow, name)
+	}
+	// version 1 stores the header length on 2 bytes, versions 2 and 3 on 4 bytes.
+	offset := len(npyMagic) + 2
+	var headerLen int
+	switch data[len(npyMagic)] {
+	case 1:
+		headerLen = int(binary.LittleEndian.Uint16(data[offset:]))
+		offset += 2
+	case 2, 3:
+		if len(data) < offset+4 {
+			return Matrix{}, fmt.Errorf("%w: %s is truncated", ErrMalformedRow, name)
+		}
+		headerLen = int(binary.LittleEndian.Uint32(data[offset:]))
+		offset += 4
+	default:
+		return Matrix{}, fmt.Errorf("%w: unsupported npy version %d in %s", ErrMalformedRow, data[len(npyMagic)], name)
+	}
+	if headerLen > len(data)-offset {
+		return Matrix{}, fmt.Errorf("%w: %s is truncated", ErrMalformedRow, name)
+	}
+	h, err := parseNpyHeader(string(data[offset:offset+headerLen]), name)
+	if err != nil {
+		return Matrix{}, err
+	}
+	data = data[offset+headerLen:]
+
+	// scalars are a single row of one value and 1-D arrays a single column, like labels or predictions.
+	rows, cols := 1, 1
+	switch len(h.shape) {
+	case 1:
+		rows = h.shape[0]
+	case 2:
+		rows, cols = h.shape[0], h.shape[1]
+	}
+	if len(data) < rows*cols*h.size {
+		return Matrix{}, fmt.Errorf("%w: %s has %d bytes of data for shape %v", ErrMalformedRow, name, len(data),
+			h.shape)
+	}
+	m := Matrix{Vectors: make([]*Vector, rows)}
+	for i := 0; i < rows; i++ {
+		vec := make(Vector, cols)
+		for j := range vec {
+			k := i*cols + j
+			if h.fortran {
+				k = j*rows + i
+			}
+			vec[j] = h.value(data[k*h.size:])
+		}
+		m.Vectors[i] = &vec
+	}
+	return m, nil
+}
+
+// ReadNpyToMatrix reads a NumPy .npy array into a matrix, so that fixtures and feature dumps saved by numpy.save
+// can be used directly in Go tests. 2-D arrays become one row per row of the array, 1-D arrays a single column and
+// scalars a single value. Boolean, integer and floating point dtypes of either byte order are supported, in C or
+// Fortran order; NaN values are kept so they are treated as missing by dense prediction.
+func ReadNpyToMatrix(fileName string) (Matrix, error) {
+	data, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		return Matrix{}, fmt.Errorf("unable to open %s: %w", fileName, err)
+	}
+	return readNpy(bytes.NewReader(data), fileName)
+}
+
+// ReadNpy reads a NumPy .npy array from r like ReadNpyToMatrix.
+func ReadNpy(r io.Reader) (Matrix, error) {
+	return readNpy(r, "input")
+}
+
+// ReadNpzToMatrices reads the arrays of a NumPy .npz archive, saved by numpy.savez or numpy.savez_compressed,
+// into matrices like ReadNpyToMatrix. The arrays are keyed by their name in the archive, without the .npy extension.
+func ReadNpzToMatrices(fileName string) (map[string]Matrix, error) {
+	archive, err := zip.OpenReader(fileName)
+	if err != nil {
+		return nil, fmt.Errorf("%w: unable to open %s as npz: %s", ErrMalformedRow, fileName, err)
+	}
+	defer archive.Close()
+	arrays := make(map[string]Matrix, len(archive.File))
+	for _, f := range archive.File {
+		if !strings.HasSuffix(f.Name, ".npy") {
+			continue
+		}
+		r, err := f.Open()
+		if err != nil {
+			return nil, fmt.Errorf("unable to read %s in %s: %w", f.Name, fileName, err)
+		}
+		m, err := readNpy(r, fileName+"/"+f.Name)
+		r.Close()
+		if err != nil {
+			return nil, err
+		}
+		arrays[strings.TrimSuffix(f.Name, ".npy")] = m
+	}
+	return arrays, nil
+}
@@ -0,0 +1,762 @@
+package mat
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+	"math"
+
+	"github.com/klauspost/compress/s2"
+	"github.com/klauspost/compress/zstd"
+)
+
+// Parquet format constants, see https://github.com/apache/parquet-format for details.
+const (
+	parquetMagic = "PAR1"
+
+	parquetTypeBoolean = 0
+	parquetTypeInt32   = 1
+	parquetTypeInt64   = 2
+	parquetTypeFloat   = 4
+	parquetTypeDouble  = 5
+
+	parquetRequired = 0
+	parquetOptional = 1
+
+	parquetConvertedDecimal = 5
+	parquetConvertedUint32  = 13
+	parquetConvertedUint64  = 14
+
+	parquetEncodingPlain           = 0
+	parquetEncodingPlainDictionary = 2
+	parquetEncodingRLE             = 3
+	parquetEncodingRLEDictionary   = 8
+	parquetEncodingByteStreamSplit = 9
+
+	parquetCodecUncompressed = 0
+	parquetCodecSnappy       = 1
+	parquetCodecGzip         = 2
+	parquetCodecZstd         = 6
+
+	parquetPageData       = 0
+	parquetPageDictionary = 2
+	parquetPageDataV2     = 3
+)
+
+// Thrift compact protocol types, Parquet metadata is serialized with this protocol.
+const (
+	thriftStop    = 0
+	thriftTrue    = 1
+	thriftFalse   = 2
+	thriftByte    = 3
+	thriftI16     = 4
+	thriftI32     = 5
+	thriftI64     = 6
+	thriftDouble  = 7
+	thriftBinary  = 8
+	thriftList    = 9
+	thriftSet     = 10
+	thriftMap     = 11
+	thriftStruct  = 12
+	thriftMaxNest = 64
+)
+
+// thriftFields is a decoded thrift struct, keyed by field id. Integers are int64, binaries []byte, lists
+// []interface{} and structs thriftFields.
+type thriftFields map[int16]interface{}
+
+// int returns integer field id, ok is false if it is absent.
+func (f thriftFields) int(id int16) (int64, bool) {
+	v, ok := f[id].(int64)
+	return v, ok
+}
+
+// bool returns boolean field id or def if it is absent.
+func (f thriftFields) bool(id int16, def bool) bool {
+	if v, ok := f[id].(bool); ok {
+		return v
+	}
+	return def
+}
+
+// string returns binary field id as a string.
+func (f thriftFields) string(id int16) string {
+	v, _ := f[id].([]byte)
+	return string(v)
+}
+
+// list returns list field id, nil if it is absent.
+func (f thriftFields) list(id int16) []interface{} {
+	v, _ := f[id].([]interface{})
+	return v
+}
+
+// child returns struct field id, nil if it is absent.
+func (f thriftFields) child(id int16) thriftFields {
+	v, _ := f[id].(thriftFields)
+	return v
+}
+
+// thriftDecoder decodes thrift compact protocol data.
+type thriftDecoder struct {
+	buf []byte
+	pos int
+}
+
+func (d *thriftDecoder) byte() (byte, error) {
+	if d.pos >= len(d.buf) {
+		return 0, fmt.Errorf("%w: truncated parquet metadata", ErrMalformedRow)
+	}
+	d.pos++
+	return d.buf[d.pos-1], nil
+}
+
+func (d *thriftDecoder) uvarint() (uint64, error) {
+	v, n := binary.Uvarint(d.buf[d.pos:])
+	if n <= 0 {
+		return 0, fmt.Errorf("%w: truncated parquet metadata", ErrMalformedRow)
+	}
+	d.pos += n
+	return v, nil
+}
+
+func (d *thriftDecoder) varint() (int64, error) {
+	v, err := d.uvarint()
+	return int64(v>>1) ^ -int64(v&1), err
+}
+
+// value decodes a value of compact type t.
+func (d *thriftDecoder) value(t byte, depth int) (interface{}, error) {
+	if depth > thriftMaxNest {
+		return nil, fmt.Errorf("%w: parquet metadata is nested too deeply", ErrMalformedRow)
+	}
+	switch t {
+	case thriftTrue:
+		return true, nil
+	case thriftFalse:
+		return false, nil
+	case thriftByte:
+		b, err := d.byte()
+		return int64(int8(b)), err
+	case thriftI16, thriftI32, thriftI64:
+		return d.varint()
+	case thriftDouble:
+		if d.pos+8 > len(d.buf) {
+			return nil, fmt.Errorf("%w: truncated parquet metadata", ErrMalformedRow)
+		}
+		d.pos += 8
+		return math.Float64frombits(binary.LittleEndian.Uint64(d.buf[d.pos-8:])), nil
+	case thriftBinary:
+		n, err := d.uvarint()
+		if err != nil {
+			return nil, err
+		}
+		if n > uint64(len(d.buf)-d.pos) {
+			return nil, fmt.Errorf("%w: truncated parquet metadata", ErrMalformedRow)
+		}
+		d.pos += int(n)
+		return d.buf[d.pos-int(n) : d.pos], nil
+	case thriftList, thriftSet:
+		header, err := d.byte()
+		if err != nil {
+			return nil, err
+		}
+		size := uint64(header >> 4)
+		if size == 15 {
+			if size, err = d.uvarint(); err != nil {
+				return nil, err
+			}
+		}
+		// every element takes at least one byte.
+		if size > uint64(len(d.buf)-d.pos) {
+			return nil, fmt.Errorf("%w: truncated parquet metadata", ErrMalformedRow)
+		}
+		list := make([]interface{}, size)
+		for i := range list {
+			elemType := header & 0x0f
+			if elemType == thriftTrue || elemType == thriftFalse {
+				// booleans of lists are encoded as one byte.
+				b, err := d.byte()
+				if err != nil {
+					return nil, err
+				}
+				list[i] = b == thriftTrue
+				continue
+			}
+			if list[i], err = d.value(elemType, depth+1); err != nil {
+				return nil, err
+			}
+		}
+		return list, nil
+	case thriftMap:
+		size, err := d.uvarint()
+		if err != nil {
+			return nil, err
+		}
+		if size == 0 {
+			return nil, nil
+		}
+		types, err := d.byte()
+		if err != nil {
+			return nil, err
+		}
+		if size > uint64(len(d.buf)-d.pos) {
+			return nil, fmt.Errorf("%w: truncated parquet metadata", ErrMalformedRow)
+		}
+		// maps are not used by the metadata this reader needs, their pairs are skipped.
+		for i := uint64(0); i < 2*size; i++ {
+			t := types >> 4
+			if i%2 == 1 {
+				t = types & 0x0f
+			}
+			if _, err := d.value(t, depth+1); err != nil {
+				return nil, err
+			}
+		}
+		return nil, nil
+	case thriftStruct:
+		return d.fields(depth + 1)
+	}
+	return nil, fmt.Errorf("%w: unknown thrift type %d in parquet metadata", ErrMalformedRow, t)
+}
+
+// fields decodes a struct.
+func (d *thriftDecoder) fields(depth int) (thriftFields, error) {
+	r := thriftFields{}
+	id := int16(0)
+	for {
+		header, err := d.byte()
+		if err != nil {
+			return nil, err
+		}
+		t := header & 0x0f
+		if t == thriftStop {
+			return r, nil
+		}
+		if delta := int16(header >> 4); delta != 0 {
+			id += delta
+		} else {
+			v, err := d.varint()
+			if err != nil {
+				return nil, err
+			}
+			id = int16(v)
+		}
+		if r[id], err = d.value(t, depth); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// parquetColumn is a flat leaf column of a Parquet schema.
+type parquetColumn struct {
+	name          string
+	physicalType  int64
+	optional      bool
+	convertedType int64
+	scale         int64
+}
+
+// numeric returns whether the column can be read as float64 values.
+func (c parquetColumn) numeric() bool {
+	switch c.physicalType {
+	case parquetTypeBoolean, parquetTypeInt32, parquetTypeInt64, parquetTypeFloat, parquetTypeDouble:
+		return true
+	}
+	return false
+}
+
+// width returns the number of bytes of a plain encoded value, 0 for bit packed booleans.
+func (c parquetColumn) width() int {
+	switch c.physicalType {
+	case parquetTypeInt32, parquetTypeFloat:
+		return 4
+	case parquetTypeInt64, parquetTypeDouble:
+		return 8
+	}
+	return 0
+}
+
+// parquetColumns returns the flat columns of a schema, the root followed by its depth first children. Nested and
+// repeated fields are not returned.
+func parquetColumns(schema []interface{}) ([]parquetColumn, error) {
+	var columns []parquetColumn
+	if len(schema) == 0 {
+		return nil, fmt.Errorf("%w: empty parquet schema", ErrMalformedRow)
+	}
+	root, _ := schema[0].(thriftFields)
+	numChildren, _ := root.int(5)
+	pos := 1
+	// skip returns the position after the subtree of the element at pos.
+	var skip func(pos, depth int) (int, error)
+	skip = func(pos, depth int) (int, error) {
+		if pos >= len(schema) || depth > thriftMaxNest {
+			return 0, fmt.Errorf("%w: truncated parquet schema", ErrMalformedRow)
+		}
+		e, _ := schema[pos].(thriftFields)
+		n, _ := e.int(5)
+		pos++
+		for i := int64(0); i < n; i++ {
+			var err error
+			if pos, err = skip(pos, depth+1); err != nil {
+				return 0, err
+			}
+		}
+		return pos, nil
+	}
+	for i := int64(0); i < numChildren; i++ {
+		if pos >= len(schema) {
+			return nil, fmt.Errorf("%w: truncated parquet schema", ErrMalformedRow)
+		}
+		e, _ := schema[pos].(thriftFields)
+		next, err := skip(pos, 0)
+		if err != nil {
+			return nil, err
+		}
+		pos = next
+		repetition, _ := e.int(3)
+		if n, _ := e.int(5); n > 0 || repetition > parquetOptional {
+			continue
+		}
+		c := parquetColumn{name: e.string(4), optional: repetition == parquetOptional}
+		c.physicalType, _ = e.int(1)
+		c.convertedType, _ = e.int(6)
+		c.scale, _ = e.int(7)
+		columns = append(columns, c)
+	}
+	return columns, nil
+}
+
+// parquetDecompress returns the uncompressed content of a page.
+func parquetDecompress(codec int64, data []byte, size int) ([]byte, error) {
+	var r []byte
+	var err error
+	switch codec {
+	case parquetCodecUncompressed:
+		r = data
+	case parquetCodecSnappy:
+		var n int
+		if n, err = s2.DecodedLen(data); err == nil && n != size {
+			return nil, fmt.Errorf("%w: parquet page has %d bytes instead of %d", ErrMalformedRow, n, size)
+		}
+		if err == nil {
+			r, err = s2.Decode(make([]byte, size), data)
+		}
+	case parquetCodecGzip:
+		var gz *gzip.Reader
+		if gz, err = gzip.NewReader(bytes.NewReader(data)); err == nil {
+			r, err = ioutil.ReadAll(gz)
+		}
+	case parquetCodecZstd:
+		var zr *zstd.Decoder
+		if zr, err = zstd.NewReader(nil, zstd.WithDecoderConcurrency(1)); err == nil {
+			r, err = zr.DecodeAll(data, make([]byte, 0, size))
+			zr.Close()
+		}
+	default:
+		return nil, fmt.Errorf("parquet compression codec %d is not supported", codec)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: cannot decompress parquet page: %s", ErrMalformedRow, err)
+	}
+	if len(r) != size {
+		return nil, fmt.Errorf("%w: parquet page has %d bytes instead of %d", ErrMalformedRow, len(r), size)
+	}
+	return r, nil
+}
+
+// parquetHybrid decodes n values of bitWidth bits encoded with the RLE/bit-packing hybrid encoding.
+func parquetHybrid(data []byte, bitWidth, n int) ([]uint32, error) {
+	if bitWidth < 0 || bitWidth > 32 {
+		return nil, fmt.Errorf("%w: invalid parquet bit width %d", ErrMalformedRow, bitWidth)
+	}
+	r := make([]uint32, 0, n)
+	pos := 0
+	for len(r) < n {
+		header, k := binary.Uvarint(data[pos:])
+		if k <= 0 {
+			return nil, fmt.Errorf("%w: truncated parquet levels or indices", ErrMalformedRow)
+		}
+		pos += k
+		if header&1 == 0 {
+			count := int(header >> 1)
+			width := (bitWidth + 7) / 8
+			if pos+width > len(data) || count > n-len(r) {
+				return nil, fmt.Errorf("%w: truncated parquet levels or indices", ErrMalformedRow)
+			}
+			v := uint32(0)
+			for i := 0; i < width; i++ {
+				v |= uint32(data[pos+i]) << (8 * i)
+			}
+			pos += width
+			for i := 0; i < count; i++ {
+				r = append(r, v)
+			}
+			continue
+		}
+		count := int(header>>1) * 8
+		if pos+count*bitWidth/8 > len(data) {
+			return nil, fmt.Errorf("%w: truncated parquet levels or indices", ErrMalformedRow)
+		}
+		for i := 0; i < count; i++ {
+			v := uint32(0)
+			for b := 0; b < bitWidth; b++ {
+				bit := i*bitWidth + b
+				v |= uint32(data[pos+bit/8]>>(bit%8)&1) << b
+			}
+			// the last group is padded to 8 values.
+			if len(r) < n {
+				r = append(r, v)
+			}
+		}
+		pos += count * bitWidth / 8
+	}
+	return r, nil
+}
+
+// plainValues decodes n plain encoded values.
+func (c parquetColumn) plainValues(data []byte, n int) ([]float64, error) {
+	r := make([]float64, n)
+	if c.physicalType == parquetTypeBoolean {
+		if (n+7)/8 > len(data) {
+			return nil, fmt.Errorf("%w: truncated parquet values of %s", ErrMalformedRow, c.name)
+		}
+		for i := range r {
+			r[i] = float64(data[i/8] >> (i % 8) & 1)
+		}
+		return r, nil
+	}
+	width := c.width()
+	if n*width > len(data) {
+		return nil, fmt.Errorf("%w: truncated parquet values of %s", ErrMalformedRow, c.name)
+	}
+	for i := range r {
+		r[i] = c.value(data[i*width : (i+1)*width])
+	}
+	return r, nil
+}
+
+// value decodes a little endian value of the column type.
+func (c parquetColumn) value(b []byte) float64 {
+	var v float64
+	switch c.physicalType {
+	case parquetTypeInt32:
+		u := binary.LittleEndian.Uint32(b)
+		v = float64(int32(u))
+		if c.convertedType == parquetConvertedUint32 {
+			v = float64(u)
+		}
+	case parquetTypeInt64:
+		u := binary.LittleEndian.Uint64(b)
+		v = float64(int64(u))
+		if c.convertedType == parquetConvertedUint64 {
+			v = float64(u)
+		}
+	case parquetTypeFloat:
+		return float64(math.Float32frombits(binary.LittleEndian.Uint32(b)))
+	case parquetTypeDouble:
+		return math.Float64frombits(binary.LittleEndian.Uint64(b))
+	}
+	if c.convertedType == parquetConvertedDecimal && c.scale > 0 {
+		v /= math.Pow10(int(c.scale))
+	}
+	return v
+}
+
+// pageValues decodes n non null values of a data page with the given encoding.
+func (c parquetColumn) pageValues(data []byte, encoding int64, n int, dictionary []float64) ([]float64, error) {
+	switch encoding {
+	case parquetEncodingPlain:
+		return c.plainValues(data, n)
+	case parquetEncodingPlainDictionary, parquetEncodingRLEDictionary:
+		if dictionary == nil {
+			return nil, fmt.Errorf("%w: dictionary encoded page of %s without dictionary", ErrMalformedRow, c.name)
+		}
+		if n == 0 {
+			return nil, nil
+		}
+		if len(data) == 0 {
+			return nil, fmt.Errorf("%w: truncated parquet values of %s", ErrMalformedRow, c.name)
+		}
+		indices, err := parquetHybrid(data[1:], int(data[0]), n)
+		if err != nil {
+			return nil, err
+		}
+		r := make([]float64, n)
+		for i, idx := range indices {
+			if int(idx) >= len(dictionary) {
+				return nil, fmt.Errorf("%w: dictionary index %d of %s is out of range", ErrMalformedRow, idx, c.name)
+			}
+			r[i] = dictionary[idx]
+		}
+		return r, nil
+	case parquetEncodingRLE:
+		if c.physicalType != parquetTypeBoolean || len(data) < 4 {
+			return nil, fmt.Errorf("%w: invalid RLE encoded values of %s", ErrMalformedRow, c.name)
+		}
+		bits, err := parquetHybrid(data[4:], 1, n)
+		if err != nil {
+			return nil, err
+		}
+		r := make([]float64, n)
+		for i, b := range bits {
+			r[i] = float64(b)
+		}
+		return r, nil
+	case parquetEncodingByteStreamSplit:
+		width := c.width()
+		if width == 0 || n*width > len(data) {
+			return nil, fmt.Errorf("%w: invalid byte stream split values of %s", ErrMalformedRow, c.name)
+		}
+		r := make([]float64, n)
+		b := make([]byte, width)
+		for i := range r {
+			for j := range b {
+				b[j] = data[j*n+i]
+			}
+			r[i] = c.value(b)
+		}
+		return r, nil
+	}
+	return nil, fmt.Errorf("parquet encoding %d of %s is not supported", encoding, c.name)
+}
+
+// readChunk decodes the values of a column chunk, null values are NaN.
+func (c parquetColumn) readChunk(data []byte, meta thriftFields) (Vector, error) {
+	codec, _ := meta.int(4)
+	numValues, _ := meta.int(5)
+	pos, _ := meta.int(9)
+	if dictPos, ok := meta.int(11); ok && dictPos > 0 && dictPos < pos {
+		pos = dictPos
+	}
+	if numValues < 0 {
+		return nil, fmt.Errorf("%w: invalid number of values %d of %s", ErrMalformedRow, numValues, c.name)
+	}
+	// runs of nulls take a few bytes, the capacity is bounded to not trust the metadata for allocations.
+	capacity := int64(len(data))
+	if numValues < capacity {
+		capacity = numValues
+	}
+	r := make(Vector, 0, capacity)
+	var dictionary []float64
+	for int64(len(r)) < numValues {
+		if pos < 0 || pos >= int64(len(data)) {
+			return nil, fmt.Errorf("%w: parquet page of %s is out of the file", ErrMalformedRow, c.name)
+		}
+		d := &thriftDecoder{buf: data[pos:]}
+		header, err := d.fields(0)
+		if err != nil {
+			return nil, err
+		}
+		pageType, _ := header.int(1)
+		size, _ := header.int(2)
+		compressedSize, _ := header.int(3)
+		start := pos + int64(d.pos)
+		if size < 0 || compressedSize < 0 || start+compressedSize > int64(len(data)) {
+			return nil, fmt.Errorf("%w: parquet page of %s is out of the file", ErrMalformedRow, c.name)
+		}
+		page := data[start : start+compressedSize]
+		pos = start + compressedSize
+
+		switch pageType {
+		case parquetPageDictionary:
+			h := header.child(7)
+			n, _ := h.int(1)
+			content, err := parquetDecompress(codec, page, int(size))
+			if err != nil {
+				return nil, err
+			}
+			if n < 0 || n > int64(len(content))*8 {
+				return nil, fmt.Errorf("%w: invalid dictionary size %d of %s", ErrMalformedRow, n, c.name)
+			}
+			if dictionary, err = c.plainValues(content, int(n)); err != nil {
+				return nil, err
+			}
+		case parquetPageData:
+			h := header.child(5)
+			n, _ := h.int(1)
+			encoding, _ := h.int(2)
+			content, err := parquetDecompress(codec, page, int(size))
+			if err != nil {
+				return nil, err
+			}
+			var levels []byte
+			if c.optional {
+				if len(content) < 4 {
+					return nil, fmt.Errorf("%w: truncated definition levels of %s", ErrMalformedRow, c.name)
+				}
+				length := int(binary.LittleEndian.Uint32(content))
+				if length < 0 || 4+length > len(content) {
+					return nil, fmt.Errorf("%w: truncated definition levels of %s", ErrMalformedRow, c.name)
+				}
+				levels, content = content[4:4+length], content[4+length:]
+			}
+			if r, err = c.appendPage(r, content, levels, encoding, n, numValues, dictionary); err != nil {
+				return nil, err
+			}
+		case parquetPageDataV2:
+			h := header.child(8)
+			n, _ := h.int(1)
+			encoding, _ := h.int(4)
+			defLength, _ := h.int(5)
+			repLength, _ := h.int(6)
+			if defLength < 0 || repLength < 0 || defLength+repLength > int64(len(page)) {
+				return nil, fmt.Errorf("%w: truncated levels of %s", ErrMalformedRow, c.name)
+			}
+			// levels are never compressed in version 2 pages.
+			levels := page[repLength : repLength+defLength]
+			content := page[repLength+defLength:]
+			if h.bool(7, true) {
+				if content, err = parquetDecompress(codec, content, int(size-defLength-repLength)); err != nil {
+					return nil, err
+				}
+			}
+			if !c.optional {
+				levels = nil
+			}
+			if r, err = c.appendPage(r, content, levels, encoding, n, numValues, dictionary); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return r, nil
+}
+
+// appendPage appends the n values of a data page to r, levels are the RLE encoded definition levels of optional
+// columns.
+func (c parquetColumn) appendPage(r Vector, content, levels []byte, encoding, n, numValues int64,
+	dictionary []float64) (Vector, error) {
+	if n < 0 || int64(len(r))+n > numValues {
+		return nil, fmt.Errorf("%w: too many values in parquet pages of %s", ErrMalformedRow, c.name)
+	}
+	defined := make([]uint32, n)
+	nonNull := int(n)
+	if levels != nil {
+		var err error
+		if defined, err = parquetHybrid(levels, 1, int(n)); err != nil {
+			return nil, err
+		}
+		nonNull = 0
+		for _, d := range defined {
+			nonNull += int(d)
+		}
+	} else {
+		for i := range defined {
+			defined[i] = 1
+		}
+	}
+	values, err := c.pageValues(content, encoding, nonNull, dictionary)
+	if err != nil {
+		return nil, err
+	}
+	j := 0
+	for _, d := range defined {
+		if d == 0 {
+			r = append(r, math.NaN())
+			continue
+		}
+		r = append(r, values[j])
+		j++
+	}
+	return r, nil
+}
+
+// ReadParquetToDenseMatrix reads flat numeric columns of a Parquet file into a dense matrix. columns selects the
+// columns to read in the given order, if empty all boolean, integer and floating point columns are read. Null
+// values become NaN so they are treated as missing by dense prediction, booleans are 0 or 1 and decimals are
+// scaled. It returns the names of the read columns which can be used to align them with a feature map.
+// Uncompressed, Snappy, gzip and zstd pages with plain, dictionary, RLE and byte stream split encodings are
+// supported; nested and repeated columns, encrypted files and delta encodings are not.
+func ReadParquetToDenseMatrix(fileName string, columns ...string) (Matrix, []string, error) {
+	data, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		return Matrix{}, nil, fmt.Errorf("unable to open %s: %w", fileName, err)
+	}
+	if len(data) < 2*len(parquetMagic)+4 || string(data[:4]) != parquetMagic ||
+		string(data[len(data)-4:]) != parquetMagic {
+		return Matrix{}, nil, fmt.Errorf("%w: %s is not a parquet file", ErrMalformedRow, fileName)
+	}
+	footerLen := int(int32(binary.LittleEndian.Uint32(data[len(data)-8:])))
+	if footerLen <= 0 || footerLen > len(data)-12 {
+		return Matrix{}, nil, fmt.Errorf("%w: wrong parquet footer length %d", ErrMalformedRow, footerLen)
+	}
+	d := &thriftDecoder{buf: data[len(data)-8-footerLen : len(data)-8]}
+	meta, err := d.fields(0)
+	if err != nil {
+		return Matrix{}, nil, err
+	}
+	schema, err := parquetColumns(meta.list(2))
+	if err != nil {
+		return Matrix{}, nil, err
+	}
+
+	var selected []parquetColumn
+	if len(columns) == 0 {
+		for _, c := range schema {
+			if c.numeric() {
+				selected = append(selected, c)
+			}
+		}
+	} else {
+		index := make(map[string]int, len(schema))
+		for i, c := range schema {
+			index[c.name] = i
+		}
+		for _, name := range columns {
+			i, ok := index[name]
+			if !ok {
+				return Matrix{}, nil, fmt.Errorf("column %s not found in %s", name, fileName)
+			}
+			if !schema[i].numeric() {
+				return Matrix{}, nil, fmt.Errorf("column %s is not numeric", name)
+			}
+			selected = append(selected, schema[i])
+		}
+	}
+	names := make([]string, len(selected))
+	for j, c := range selected {
+		names[j] = c.name
+	}
+
+	matrix := Matrix{Vectors: make([]*Vector, 0)}
+	for g, v := range meta.list(4) {
+		group, _ := v.(thriftFields)
+		rows, _ := group.int(3)
+		chunks := map[string]thriftFields{}
+		for _, v := range group.list(1) {
+			chunk, _ := v.(thriftFields)
+			if chunk.string(1) != "" {
+				return Matrix{}, nil, fmt.Errorf("parquet columns stored in other files are not supported")
+			}
+			meta := chunk.child(3)
+			if path := meta.list(3); len(path) == 1 {
+				name, _ := path[0].([]byte)
+				chunks[string(name)] = meta
+			}
+		}
+		values := make([]Vector, len(selected))
+		for j, c := range selected {
+			meta, ok := chunks[c.name]
+			if !ok {
+				return Matrix{}, nil, fmt.Errorf("%w: row group %d has no column %s", ErrMalformedRow, g, c.name)
+			}
+			if values[j], err = c.readChunk(data, meta); err != nil {
+				return Matrix{}, nil, fmt.Errorf("error while reading row group %d of %s: %w", g, fileName, err)
+			}
+			if int64(len(values[j])) != rows {
+				return Matrix{}, nil, fmt.Errorf("%w: column %s has %d rows, row group %d has %d",
+					ErrDimensionMismatch, c.name, len(values[j]), g, rows)
+			}
+		}
+		for r := 0; r < int(rows); r++ {
+			vec := make(Vector, len(selected))
+			for j := range selected {
+				vec[j] = values[j][r]
+			}
+			matrix.Vectors = append(matrix.Vectors, &vec)
+		}
+	}
+	return matrix, names, nil
+}
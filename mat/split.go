@@ -0,0 +1,96 @@
+package mat
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+)
+
+// Permutation returns a random permutation of the row indices [0, n) which only depends on seed, so that
+// shuffles and splits are reproducible.
+func Permutation(n int, seed int64) []int {
+	return rand.New(rand.NewSource(seed)).Perm(n)
+}
+
+// splitIndices returns the shuffled row indices of the train and test sets of n rows, the train set having
+// ratio * n rows rounded to the nearest integer.
+func splitIndices(n int, labels Vector, ratio float64, seed int64) ([]int, []int, error) {
+	if err := checkLabels(labels, n); err != nil {
+		return nil, nil, err
+	}
+	if math.IsNaN(ratio) || ratio < 0 || ratio > 1 {
+		return nil, nil, fmt.Errorf("split ratio must be in [0, 1], got %v", ratio)
+	}
+	perm := Permutation(n, seed)
+	size := int(math.Round(ratio * float64(n)))
+	return perm[:size:size], perm[size:], nil
+}
+
+// selectLabels returns the labels of the given rows, nil if labels is nil.
+func selectLabels(labels Vector, rows []int) Vector {
+	if labels == nil {
+		return nil
+	}
+	r := make(Vector, len(rows))
+	for i, row := range rows {
+		r[i] = labels[row]
+	}
+	return r
+}
+
+// checkLabels returns an error wrapping ErrDimensionMismatch if labels is not nil and does not have one value per
+// row.
+func checkLabels(labels Vector, rows int) error {
+	if labels != nil && len(labels) != rows {
+		return fmt.Errorf("%w: %d labels for %d rows", ErrDimensionMismatch, len(labels), rows)
+	}
+	return nil
+}
+
+// Split shuffles the rows of m and their labels with seed and splits them into a train set of ratio * len(rows)
+// rows, rounded to the nearest integer, and a test set of the other rows. The same seed always gives the same
+// split. labels may be nil, the returned labels are then nil too. Rows are shared with m.
+func Split(m Matrix, labels Vector, ratio float64, seed int64) (train Matrix, trainLabels Vector, test Matrix,
+	testLabels Vector, err error) {
+	trainRows, testRows, err := splitIndices(m.Rows(), labels, ratio, seed)
+	if err != nil {
+		return Matrix{}, nil, Matrix{}, nil, err
+	}
+	// permuted indices are always in range.
+	train, _ = m.Slice(trainRows)
+	test, _ = m.Slice(testRows)
+	return train, selectLabels(labels, trainRows), test, selectLabels(labels, testRows), nil
+}
+
+// SplitSparse splits the rows of a sparse matrix and their labels like Split.
+func SplitSparse(m SparseMatrix, labels Vector, ratio float64, seed int64) (train SparseMatrix,
+	trainLabels Vector, test SparseMatrix, testLabels Vector, err error) {
+	trainRows, testRows, err := splitIndices(m.Rows(), labels, ratio, seed)
+	if err != nil {
+		return SparseMatrix{}, nil, SparseMatrix{}, nil, err
+	}
+	train, _ = m.Slice(trainRows)
+	test, _ = m.Slice(testRows)
+	return train, selectLabels(labels, trainRows), test, selectLabels(labels, testRows), nil
+}
+
+// Shuffle returns the rows of m and their labels in the random order given by Permutation with seed. labels may
+// be nil, the returned labels are then nil too. Rows are shared with m.
+func Shuffle(m Matrix, labels Vector, seed int64) (Matrix, Vector, error) {
+	if err := checkLabels(labels, m.Rows()); err != nil {
+		return Matrix{}, nil, err
+	}
+	perm := Permutation(m.Rows(), seed)
+	shuffled, _ := m.Slice(perm)
+	return shuffled, selectLabels(labels, perm), nil
+}
+
+// ShuffleSparse shuffles the rows of a sparse matrix and their labels like Shuffle.
+func ShuffleSparse(m SparseMatrix, labels Vector, seed int64) (SparseMatrix, Vector, error) {
+	if err := checkLabels(labels, m.Rows()); err != nil {
+		return SparseMatrix{}, nil, err
+	}
+	perm := Permutation(m.Rows(), seed)
+	shuffled, _ := m.Slice(perm)
+	return shuffled, selectLabels(labels, perm), nil
+}
@@ -0,0 +1,105 @@
+package mat
+
+import "fmt"
+
+// VStack returns the rows of the given matrices one matrix after the other, every row must have the same number
+// of columns. Rows are shared with the given matrices, use Clone on the result to modify them independently.
+func VStack(ms ...Matrix) (Matrix, error) {
+	result := Matrix{Vectors: make([]*Vector, 0)}
+	cols := -1
+	for i, m := range ms {
+		for j, v := range m.Vectors {
+			if cols == -1 {
+				cols = len(*v)
+			}
+			if len(*v) != cols {
+				return Matrix{}, fmt.Errorf("%w: row %d of matrix %d has %d columns, expected %d",
+					ErrDimensionMismatch, j, i, len(*v), cols)
+			}
+		}
+		result.Vectors = append(result.Vectors, m.Vectors...)
+	}
+	return result, nil
+}
+
+// HStack returns the matrix whose row i is made of the rows i of the given matrices in order, like feature blocks
+// computed by separate pipelines. Every matrix must have the same number of rows and each of them must have rows
+// of the same length.
+func HStack(ms ...Matrix) (Matrix, error) {
+	if len(ms) == 0 {
+		return Matrix{Vectors: make([]*Vector, 0)}, nil
+	}
+	rows := ms[0].Rows()
+	width := 0
+	for i, m := range ms {
+		if m.Rows() != rows {
+			return Matrix{}, fmt.Errorf("%w: matrix %d has %d rows, expected %d", ErrDimensionMismatch, i,
+				m.Rows(), rows)
+		}
+		if rows == 0 {
+			continue
+		}
+		cols, err := m.Cols()
+		if err != nil {
+			return Matrix{}, fmt.Errorf("matrix %d: %w", i, err)
+		}
+		width += cols
+	}
+	result := Matrix{Vectors: make([]*Vector, rows)}
+	for r := range result.Vectors {
+		vec := make(Vector, 0, width)
+		for _, m := range ms {
+			vec = append(vec, *m.Vectors[r]...)
+		}
+		result.Vectors[r] = &vec
+	}
+	return result, nil
+}
+
+// VStackSparse returns the rows of the given sparse matrices one matrix after the other like VStack. Rows are
+// shared with the given matrices.
+func VStackSparse(ms ...SparseMatrix) SparseMatrix {
+	result := SparseMatrix{Vectors: make([]SparseVector, 0)}
+	for _, m := range ms {
+		result.Vectors = append(result.Vectors, m.Vectors...)
+	}
+	return result
+}
+
+// HStackSparse returns the sparse matrix whose row i is made of the rows i of the given matrices like HStack.
+// widths[k] is the number of columns of ms[k], which cannot be inferred from absent features: feature j of ms[k]
+// becomes feature j plus the sum of the previous widths. Every matrix must have the same number of rows and their
+// feature indices must be in [0, widths[k]).
+func HStackSparse(ms []SparseMatrix, widths []int) (SparseMatrix, error) {
+	if len(ms) != len(widths) {
+		return SparseMatrix{}, fmt.Errorf("%w: %d matrices but %d widths", ErrDimensionMismatch, len(ms),
+			len(widths))
+	}
+	if len(ms) == 0 {
+		return SparseMatrix{Vectors: make([]SparseVector, 0)}, nil
+	}
+	rows := ms[0].Rows()
+	for i, m := range ms {
+		if m.Rows() != rows {
+			return SparseMatrix{}, fmt.Errorf("%w: matrix %d has %d rows, expected %d", ErrDimensionMismatch, i,
+				m.Rows(), rows)
+		}
+	}
+	result := SparseMatrix{Vectors: make([]SparseVector, rows)}
+	for r := range result.Vectors {
+		vec := SparseVector{}
+		offset := 0
+		for i, m := range ms {
+			for idx, v := range m.Vectors[r] {
+				if idx < 0 || idx >= widths[i] {
+					return SparseMatrix{}, fmt.Errorf("%w: feature index %d at row %d of matrix %d of width %d",
+						ErrFeatureIndexOutOfRange, idx, r, i, widths[i])
+				}
+				vec[offset+idx] = v
+			}
+			offset += widths[i]
+		}
+		result.Vectors[r] = vec
+	}
+	return result, nil
+}
@@ -0,0 +1,48 @@
+package mat
+
+import "fmt"
+
+// Add returns the element-wise sum of v and o.
+func (v Vector) Add(o Vector) (Vector, error) {
+	if len(v) != len(o) {
+		return Vector{}, fmt.Errorf("%w: different vector length v=%d, o=%d", ErrDimensionMismatch, len(v), len(o))
+	}
+	r := make(Vector, len(v))
+	for i := range v {
+		r[i] = v[i] + o[i]
+	}
+	return r, nil
+}
+
+// Sub returns the element-wise difference of v and o.
+func (v Vector) Sub(o Vector) (Vector, error) {
+	if len(v) != len(o) {
+		return Vector{}, fmt.Errorf("%w: different vector length v=%d, o=%d", ErrDimensionMismatch, len(v), len(o))
+	}
+	r := make(Vector, len(v))
+	for i := range v {
+		r[i] = v[i] - o[i]
+	}
+	return r, nil
+}
+
+// Scale returns v multiplied by f.
+func (v Vector) Scale(f float64) Vector {
+	r := make(Vector, len(v))
+	for i := range v {
+		r[i] = v[i] * f
+	}
+	return r
+}
+
+// Dot returns the dot product of v and o.
+func (v Vector) Dot(o Vector) (float64, error) {
+	if len(v) != len(o) {
+		return 0, fmt.Errorf("%w: different vector length v=%d, o=%d", ErrDimensionMismatch, len(v), len(o))
+	}
+	sum := 0.0
+	for i := range v {
+		sum += v[i] * o[i]
+	}
+	return sum, nil
+}
@@ -0,0 +1,80 @@
+package metrics
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/lordberre/xgboost-go/mat"
+)
+
+// AUC returns the area under the ROC curve of scores preds for binary labels, like the auc metric. Rows with a
+// label greater than 0.5 are positives. Tied scores count as half ordered, so any monotonic transformation of preds,
+// e.g. margins instead of probabilities, gives the same AUC. An error is returned if labels contain a single class.
+func AUC(preds, labels, weights mat.Vector) (float64, error) {
+	if err := checkInput(preds, labels, weights); err != nil {
+		return 0, err
+	}
+	order := make([]int, len(preds))
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(i, j int) bool { return preds[order[i]] > preds[order[j]] })
+
+	// area sums the positives ranked above each negative, ties counting for half.
+	area, totalPos, totalNeg := 0.0, 0.0, 0.0
+	for begin := 0; begin < len(order); {
+		end := begin
+		pos, neg := 0.0, 0.0
+		for ; end < len(order) && preds[order[end]] == preds[order[begin]]; end++ {
+			w := weight(weights, order[end])
+			if labels[order[end]] > 0.5 {
+				pos += w
+			} else {
+				neg += w
+			}
+		}
+		area += neg * (totalPos + pos/2)
+		totalPos += pos
+		totalNeg += neg
+		begin = end
+	}
+	if totalPos <= 0 || totalNeg <= 0 {
+		return 0, fmt.Errorf("AUC needs positive and negative labels, got %f positives and %f negatives",
+			totalPos, totalNeg)
+	}
+	return area / (totalPos * totalNeg), nil
+}
+
+// PrecisionRecallF1 returns the precision, recall and F1 score of the binary classification predicting positive
+// the rows with a score greater or equal to threshold, like PredictClassWithThreshold. Rows with a label greater
+// than 0.5 are positives. Precision is 0 when no row is predicted positive and recall is 0 without positive labels.
+func PrecisionRecallF1(preds, labels, weights mat.Vector, threshold float64) (float64, float64, float64, error) {
+	if err := checkInput(preds, labels, weights); err != nil {
+		return 0, 0, 0, err
+	}
+	truePos, predPos, pos := 0.0, 0.0, 0.0
+	for i := range preds {
+		w := weight(weights, i)
+		predicted, actual := preds[i] >= threshold, labels[i] > 0.5
+		if predicted {
+			predPos += w
+		}
+		if actual {
+			pos += w
+		}
+		if predicted && actual {
+			truePos += w
+		}
+	}
+	var precision, recall, f1 float64
+	if predPos > 0 {
+		precision = truePos / predPos
+	}
+	if pos > 0 {
+		recall = truePos / pos
+	}
+	if precision+recall > 0 {
+		f1 = 2 * precision * recall / (precision + recall)
+	}
+	return precision, recall, f1, nil
+}
@@ -0,0 +1,80 @@
+// Package metrics implements the evaluation metrics of XGBoost eval_metric, so that predictions made in Go can be
+// validated against the metrics reported during training. Metrics take one prediction and one label per row and
+// optional instance weights, nil weights give every row a weight of 1.
+package metrics
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/lordberre/xgboost-go/mat"
+)
+
+// logLossEps bounds probabilities away from 0 and 1 like the logloss metric of XGBoost.
+const logLossEps = 1e-16
+
+// checkInput returns an error if preds, labels and weights do not have the same non zero length.
+func checkInput(preds, labels, weights mat.Vector) error {
+	if len(preds) != len(labels) {
+		return fmt.Errorf("%w: %d predictions but %d labels", mat.ErrDimensionMismatch, len(preds), len(labels))
+	}
+	if weights != nil && len(weights) != len(labels) {
+		return fmt.Errorf("%w: %d weights but %d labels", mat.ErrDimensionMismatch, len(weights), len(labels))
+	}
+	if len(preds) == 0 {
+		return fmt.Errorf("cannot evaluate empty input")
+	}
+	return nil
+}
+
+// weight returns the weight of row i, 1 if weights is nil.
+func weight(weights mat.Vector, i int) float64 {
+	if weights == nil {
+		return 1
+	}
+	return weights[i]
+}
+
+// weightedMean returns the weighted mean of loss over the rows.
+func weightedMean(preds, labels, weights mat.Vector, loss func(pred, label float64) float64) (float64, error) {
+	if err := checkInput(preds, labels, weights); err != nil {
+		return 0, err
+	}
+	sum, sumWeights := 0.0, 0.0
+	for i := range preds {
+		w := weight(weights, i)
+		sum += w * loss(preds[i], labels[i])
+		sumWeights += w
+	}
+	if sumWeights <= 0 {
+		return 0, fmt.Errorf("sum of weights must be positive: %f", sumWeights)
+	}
+	return sum / sumWeights, nil
+}
+
+// RMSE returns the root mean square error of preds, like the rmse metric.
+func RMSE(preds, labels, weights mat.Vector) (float64, error) {
+	mse, err := weightedMean(preds, labels, weights, func(pred, label float64) float64 {
+		return (pred - label) * (pred - label)
+	})
+	if err != nil {
+		return 0, err
+	}
+	return math.Sqrt(mse), nil
+}
+
+// MAE returns the mean absolute error of preds, like the mae metric.
+func MAE(preds, labels, weights mat.Vector) (float64, error) {
+	return weightedMean(preds, labels, weights, func(pred, label float64) float64 {
+		return math.Abs(pred - label)
+	})
+}
+
+// LogLoss returns the negative log likelihood of probabilities preds of labels in [0, 1], like the logloss
+// metric. Probabilities are clipped to [1e-16, 1-1e-16].
+func LogLoss(preds, labels, weights mat.Vector) (float64, error) {
+	return weightedMean(preds, labels, weights, func(pred, label float64) float64 {
+		p := math.Min(math.Max(pred, logLossEps), 1-logLossEps)
+		return -label*math.Log(p) - (1-label)*math.Log(1-p)
+	})
+}
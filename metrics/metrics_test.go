@@ -0,0 +1,89 @@
+package metrics
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"gotest.tools/assert"
+
+	"github.com/lordberre/xgboost-go/mat"
+)
+
+func TestMetrics(t *testing.T) {
+	near := func(got, expected float64) {
+		t.Helper()
+		assert.Check(t, math.Abs(got-expected) < 1e-12, "%v != %v", got, expected)
+	}
+	rmse, err := RMSE(mat.Vector{1, 2, 3}, mat.Vector{1, 2, 5}, nil)
+	assert.NilError(t, err)
+	near(rmse, math.Sqrt(4.0/3))
+	rmse, err = RMSE(mat.Vector{1, 2, 3}, mat.Vector{1, 2, 5}, mat.Vector{1, 1, 2})
+	assert.NilError(t, err)
+	near(rmse, math.Sqrt(2))
+	mae, err := MAE(mat.Vector{1, 2, 3}, mat.Vector{1, 2, 5}, nil)
+	assert.NilError(t, err)
+	near(mae, 2.0/3)
+	logLoss, err := LogLoss(mat.Vector{0.8, 0.4, 0}, mat.Vector{1, 0, 0}, nil)
+	assert.NilError(t, err)
+	near(logLoss, -(math.Log(0.8)+math.Log(0.6))/3)
+	_, err = MAE(mat.Vector{1}, mat.Vector{1, 2}, nil)
+	assert.Check(t, errors.Is(err, mat.ErrDimensionMismatch))
+
+	auc, err := AUC(mat.Vector{0.1, 0.4, 0.35, 0.8}, mat.Vector{0, 0, 1, 1}, nil)
+	assert.NilError(t, err)
+	near(auc, 0.75)
+	auc, err = AUC(mat.Vector{0.5, 0.5, 0.1}, mat.Vector{0, 1, 0}, nil)
+	assert.NilError(t, err)
+	near(auc, 0.75)
+	auc, err = AUC(mat.Vector{0.1, 0.4, 0.35, 0.8}, mat.Vector{0, 0, 1, 1}, mat.Vector{1, 3, 1, 1})
+	assert.NilError(t, err)
+	near(auc, 5.0/8)
+	_, err = AUC(mat.Vector{0.1, 0.4}, mat.Vector{1, 1}, nil)
+	assert.ErrorContains(t, err, "positive and negative labels")
+
+	precision, recall, f1, err := PrecisionRecallF1(mat.Vector{0.9, 0.6, 0.4, 0.2}, mat.Vector{1, 0, 1, 0},
+		nil, 0.5)
+	assert.NilError(t, err)
+	near(precision, 0.5)
+	near(recall, 0.5)
+	near(f1, 0.5)
+	precision, recall, f1, err = PrecisionRecallF1(mat.Vector{0.9, 0.6, 0.4, 0.2}, mat.Vector{1, 0, 1, 0},
+		nil, 0.3)
+	assert.NilError(t, err)
+	near(precision, 2.0/3)
+	near(recall, 1)
+	near(f1, 0.8)
+
+	ndcg, err := NDCG(mat.Vector{3, 2, 1, 1, 2}, mat.Vector{0, 1, 2, 0, 0}, []int{0, 3, 5}, 0)
+	assert.NilError(t, err)
+	first := (1/math.Log2(3) + 3/math.Log2(4)) / (3 + 1/math.Log2(3))
+	near(ndcg, (first+1)/2)
+	ndcg, err = NDCG(mat.Vector{3, 2, 1}, mat.Vector{0, 1, 2}, nil, 1)
+	assert.NilError(t, err)
+	near(ndcg, 0)
+	_, err = NDCG(mat.Vector{3, 2, 1}, mat.Vector{0, 1, 2}, []int{0, 2}, 1)
+	assert.Check(t, errors.Is(err, mat.ErrDimensionMismatch))
+
+	averagePrecision, err := MAP(mat.Vector{3, 2, 1, 1, 2}, mat.Vector{0, 1, 2, 0, 0}, []int{0, 3, 5}, 0)
+	assert.NilError(t, err)
+	near(averagePrecision, (7.0/12+1)/2)
+	averagePrecision, err = MAP(mat.Vector{3, 2, 1, 1, 2}, mat.Vector{0, 1, 2, 0, 0}, []int{0, 3, 5}, 2)
+	assert.NilError(t, err)
+	near(averagePrecision, (1.0/4+1)/2)
+	_, err = MAP(mat.Vector{3, 2, 1}, mat.Vector{0, 1, 2}, []int{0, 2}, 1)
+	assert.Check(t, errors.Is(err, mat.ErrDimensionMismatch))
+
+	// the AUC of margins is the one of probabilities.
+	margins := mat.Vector{-2.5, 0.3, 1.2, -0.7, 4, 0.1}
+	labels := mat.Vector{0, 1, 1, 0, 1, 0}
+	proba := make(mat.Vector, len(margins))
+	for i, m := range margins {
+		proba[i] = 1 / (1 + math.Exp(-m))
+	}
+	auc, err = AUC(proba, labels, nil)
+	assert.NilError(t, err)
+	marginAUC, err := AUC(margins, labels, nil)
+	assert.NilError(t, err)
+	near(marginAUC, auc)
+}
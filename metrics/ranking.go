@@ -0,0 +1,92 @@
+package metrics
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/lordberre/xgboost-go/mat"
+)
+
+// NDCG returns the mean normalized discounted cumulative gain at k of the ranking groups, like the ndcg@k metric.
+// groupPtr contains the group boundaries like mat.DMatrix GroupPtr, nil ranks all rows as a single group. Within a
+// group rows are ranked by decreasing scores, a row of relevance label l has a gain of 2^l - 1 discounted by
+// log2(rank + 1). k <= 0 uses every row of the groups. Groups without relevant rows have an NDCG of 1 like in
+// XGBoost.
+func NDCG(preds, labels mat.Vector, groupPtr []int, k int) (float64, error) {
+	if err := checkInput(preds, labels, nil); err != nil {
+		return 0, err
+	}
+	return groupMean(preds, labels, groupPtr, func(byPred []int) float64 {
+		byLabel := append([]int(nil), byPred...)
+		sort.SliceStable(byLabel, func(i, j int) bool { return labels[byLabel[i]] > labels[byLabel[j]] })
+		idcg := dcg(labels, byLabel, k)
+		if idcg == 0 {
+			return 1
+		}
+		return dcg(labels, byPred, k) / idcg
+	})
+}
+
+// MAP returns the mean average precision at k of the ranking groups, like the map@k metric. groupPtr contains the
+// group boundaries like for NDCG and rows with a label different from 0 are relevant. The precision at the rank
+// of each relevant row within the first k rows is averaged over all the relevant rows of the group like XGBoost
+// does. k <= 0 uses every row of the groups. Groups without relevant rows have an average precision of 1.
+func MAP(preds, labels mat.Vector, groupPtr []int, k int) (float64, error) {
+	if err := checkInput(preds, labels, nil); err != nil {
+		return 0, err
+	}
+	return groupMean(preds, labels, groupPtr, func(byPred []int) float64 {
+		hits, sum := 0, 0.0
+		for rank, i := range byPred {
+			if labels[i] == 0 {
+				continue
+			}
+			hits++
+			if k <= 0 || rank < k {
+				sum += float64(hits) / float64(rank+1)
+			}
+		}
+		if hits == 0 {
+			return 1
+		}
+		return sum / float64(hits)
+	})
+}
+
+// groupMean returns the mean of metric over the ranking groups of groupPtr, nil being a single group of every row.
+// metric gets the rows of a group ranked by decreasing predictions.
+func groupMean(preds, labels mat.Vector, groupPtr []int, metric func(byPred []int) float64) (float64, error) {
+	if groupPtr == nil {
+		groupPtr = []int{0, len(preds)}
+	}
+	if len(groupPtr) < 2 || groupPtr[0] != 0 || groupPtr[len(groupPtr)-1] != len(preds) {
+		return 0, fmt.Errorf("%w: group boundaries must go from 0 to %d rows", mat.ErrDimensionMismatch, len(preds))
+	}
+	sum := 0.0
+	for g := 1; g < len(groupPtr); g++ {
+		begin, end := groupPtr[g-1], groupPtr[g]
+		if end < begin {
+			return 0, fmt.Errorf("group boundaries must be increasing, got %d after %d", end, begin)
+		}
+		byPred := make([]int, 0, end-begin)
+		for i := begin; i < end; i++ {
+			byPred = append(byPred, i)
+		}
+		sort.SliceStable(byPred, func(i, j int) bool { return preds[byPred[i]] > preds[byPred[j]] })
+		sum += metric(byPred)
+	}
+	return sum / float64(len(groupPtr)-1), nil
+}
+
+// dcg returns the discounted cumulative gain of the first k rows of order.
+func dcg(labels mat.Vector, order []int, k int) float64 {
+	if k <= 0 || k > len(order) {
+		k = len(order)
+	}
+	r := 0.0
+	for rank, i := range order[:k] {
+		r += (math.Exp2(labels[i]) - 1) / math.Log2(float64(rank)+2)
+	}
+	return r
+}
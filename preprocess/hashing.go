@@ -0,0 +1,130 @@
+package preprocess
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"math/bits"
+
+	"github.com/lordberre/xgboost-go/mat"
+)
+
+// HashingVectorizer maps named features and string tokens to a fixed number of feature indices with the hashing
+// trick, like scikit-learn FeatureHasher, so that models trained on hashed features can be served without a
+// vocabulary. The index of a name is the absolute value of its signed 32-bit MurmurHash3 with seed 0 modulo
+// NumFeatures, values of names hashed to the same index are summed.
+type HashingVectorizer struct {
+	// NumFeatures is the number of feature indices, the n_features of FeatureHasher.
+	NumFeatures int `json:"num_features"`
+	// AlternateSign negates the values of names with a negative hash so that collisions cancel out on average.
+	// It is the default of FeatureHasher and must be set to match it.
+	AlternateSign bool `json:"alternate_sign"`
+}
+
+// Transform returns the hashed sparse row of features keyed by name, like FeatureHasher with input_type="dict".
+// Indices whose values cancel out are kept with a value of 0 like the explicit zeros of scikit-learn.
+func (h *HashingVectorizer) Transform(features map[string]float64) (mat.SparseVector, error) {
+	if h.NumFeatures <= 0 {
+		return nil, fmt.Errorf("hashing vectorizer needs a positive number of features, got %d", h.NumFeatures)
+	}
+	row := make(mat.SparseVector, len(features))
+	for name, v := range features {
+		h.add(row, name, v)
+	}
+	return row, nil
+}
+
+// TransformCategorical returns the hashed sparse row of categorical features, feature name with value v being
+// hashed as "name=v" with a value of 1 like string values of FeatureHasher with input_type="dict".
+func (h *HashingVectorizer) TransformCategorical(features map[string]string) (mat.SparseVector, error) {
+	if h.NumFeatures <= 0 {
+		return nil, fmt.Errorf("hashing vectorizer needs a positive number of features, got %d", h.NumFeatures)
+	}
+	row := make(mat.SparseVector, len(features))
+	for name, v := range features {
+		h.add(row, name+"="+v, 1)
+	}
+	return row, nil
+}
+
+// TransformTokens returns the hashed sparse row of tokens, each occurrence of a token adding 1 like
+// FeatureHasher with input_type="string".
+func (h *HashingVectorizer) TransformTokens(tokens []string) (mat.SparseVector, error) {
+	if h.NumFeatures <= 0 {
+		return nil, fmt.Errorf("hashing vectorizer needs a positive number of features, got %d", h.NumFeatures)
+	}
+	row := make(mat.SparseVector, len(tokens))
+	for _, token := range tokens {
+		h.add(row, token, 1)
+	}
+	return row, nil
+}
+
+// Index returns the feature index of name and the sign applied to its values.
+func (h *HashingVectorizer) Index(name string) (int, float64) {
+	hash := int32(murmur3([]byte(name), 0))
+	var idx int
+	if hash == math.MinInt32 {
+		// abs overflows, scikit-learn maps it like this.
+		idx = int((math.MaxInt32 - (int64(h.NumFeatures) - 1)) % int64(h.NumFeatures))
+	} else {
+		if hash < 0 {
+			idx = int(-hash)
+		} else {
+			idx = int(hash)
+		}
+		idx %= h.NumFeatures
+	}
+	if h.AlternateSign && hash < 0 {
+		return idx, -1
+	}
+	return idx, 1
+}
+
+// add adds the value v of name to row.
+func (h *HashingVectorizer) add(row mat.SparseVector, name string, v float64) {
+	idx, sign := h.Index(name)
+	row[idx] += sign * v
+}
+
+// murmur3 returns the 32-bit MurmurHash3 x86 hash of data.
+func murmur3(data []byte, seed uint32) uint32 {
+	const (
+		c1 = 0xcc9e2d51
+		c2 = 0x1b873593
+	)
+	h := seed
+	n := len(data) / 4
+	for i := 0; i < n; i++ {
+		k := binary.LittleEndian.Uint32(data[4*i:])
+		k *= c1
+		k = bits.RotateLeft32(k, 15)
+		k *= c2
+		h ^= k
+		h = bits.RotateLeft32(h, 13)
+		h = h*5 + 0xe6546b64
+	}
+	var k uint32
+	tail := data[4*n:]
+	switch len(tail) {
+	case 3:
+		k ^= uint32(tail[2]) << 16
+		fallthrough
+	case 2:
+		k ^= uint32(tail[1]) << 8
+		fallthrough
+	case 1:
+		k ^= uint32(tail[0])
+		k *= c1
+		k = bits.RotateLeft32(k, 15)
+		k *= c2
+		h ^= k
+	}
+	h ^= uint32(len(data))
+	h ^= h >> 16
+	h *= 0x85ebca6b
+	h ^= h >> 13
+	h *= 0xc2b2ae35
+	h ^= h >> 16
+	return h
+}
@@ -0,0 +1,44 @@
+package preprocess
+
+import (
+	"testing"
+
+	"gotest.tools/assert"
+
+	"github.com/lordberre/xgboost-go/mat"
+)
+
+func TestHashingVectorizer(t *testing.T) {
+	// hashes of scikit-learn murmurhash3_32: "foo" is -156908512 and "hello" 613153351.
+	h := &HashingVectorizer{NumFeatures: 1 << 20, AlternateSign: true}
+	idx, sign := h.Index("foo")
+	assert.Equal(t, idx, 156908512%(1<<20))
+	assert.Equal(t, sign, -1.0)
+	idx, sign = h.Index("hello")
+	assert.Equal(t, idx, 613153351%(1<<20))
+	assert.Equal(t, sign, 1.0)
+
+	row, err := h.Transform(map[string]float64{"foo": 2, "hello": 0.5})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, row, mat.SparseVector{156908512 % (1 << 20): -2, 613153351 % (1 << 20): 0.5})
+	row, err = h.TransformTokens([]string{"hello", "foo", "hello"})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, row, mat.SparseVector{156908512 % (1 << 20): -1, 613153351 % (1 << 20): 2})
+	row, err = h.TransformCategorical(map[string]string{"color": "red"})
+	assert.NilError(t, err)
+	idx, sign = h.Index("color=red")
+	assert.DeepEqual(t, row, mat.SparseVector{idx: sign})
+
+	// collisions are summed and kept when they cancel out.
+	small := &HashingVectorizer{NumFeatures: 1}
+	row, err = small.TransformTokens([]string{"a", "b", "c"})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, row, mat.SparseVector{0: 3})
+	small.AlternateSign = true
+	row, err = small.Transform(map[string]float64{"foo": 1, "hello": 1})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, row, mat.SparseVector{0: 0})
+
+	_, err = (&HashingVectorizer{}).TransformTokens([]string{"a"})
+	assert.ErrorContains(t, err, "positive number of features")
+}
@@ -0,0 +1,110 @@
+package preprocess
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/lordberre/xgboost-go/mat"
+)
+
+// Imputation strategies of Imputer, named like the strategies of scikit-learn SimpleImputer.
+const (
+	ImputeMean         = "mean"
+	ImputeMedian       = "median"
+	ImputeMostFrequent = "most_frequent"
+	ImputeConstant     = "constant"
+)
+
+// Imputer replaces missing values by a statistic of their column computed by Fit, like scikit-learn
+// SimpleImputer. Columns which only contain missing values are filled with FillValue whatever the strategy.
+type Imputer struct {
+	// Strategy is one of ImputeMean, ImputeMedian, ImputeMostFrequent and ImputeConstant, mean if empty.
+	Strategy string `json:"strategy"`
+	// FillValue replaces missing values with the constant strategy.
+	FillValue float64 `json:"fill_value"`
+	// Statistics contains the value replacing the missing values of each column, set by Fit.
+	Statistics []float64 `json:"statistics"`
+}
+
+// Name returns transformer name.
+func (p *Imputer) Name() string {
+	return imputerName
+}
+
+// Fit computes the replacement value of each column.
+func (p *Imputer) Fit(m mat.Matrix) error {
+	cols, err := checkFitInput(m, p.Name())
+	if err != nil {
+		return err
+	}
+	statistic, err := p.statistic()
+	if err != nil {
+		return err
+	}
+	p.Statistics = make([]float64, cols)
+	for j := range p.Statistics {
+		values := columnValues(m, j)
+		if len(values) == 0 {
+			p.Statistics[j] = p.FillValue
+			continue
+		}
+		p.Statistics[j] = statistic(values)
+	}
+	return nil
+}
+
+// statistic returns the function computing the replacement value of the non missing values of a column.
+func (p *Imputer) statistic() (func(values []float64) float64, error) {
+	switch p.Strategy {
+	case ImputeMean, "":
+		return func(values []float64) float64 {
+			sum := 0.0
+			for _, v := range values {
+				sum += v
+			}
+			return sum / float64(len(values))
+		}, nil
+	case ImputeMedian:
+		return func(values []float64) float64 {
+			sorted := append([]float64(nil), values...)
+			sort.Float64s(sorted)
+			n := len(sorted)
+			if n%2 == 1 {
+				return sorted[n/2]
+			}
+			return (sorted[n/2-1] + sorted[n/2]) / 2
+		}, nil
+	case ImputeMostFrequent:
+		// ties are broken by the smallest value like scikit-learn.
+		return func(values []float64) float64 {
+			counts := map[float64]int{}
+			best, bestCount := math.Inf(1), 0
+			for _, v := range values {
+				counts[v]++
+				if c := counts[v]; c > bestCount || (c == bestCount && v < best) {
+					best, bestCount = v, c
+				}
+			}
+			return best
+		}, nil
+	case ImputeConstant:
+		return func([]float64) float64 {
+			return p.FillValue
+		}, nil
+	}
+	return nil, fmt.Errorf("unknown imputation strategy %q", p.Strategy)
+}
+
+// Transform returns the rows with missing values replaced.
+func (p *Imputer) Transform(m mat.Matrix) (mat.Matrix, error) {
+	if err := checkWidth(m, len(p.Statistics), p.Name()); err != nil {
+		return mat.Matrix{}, err
+	}
+	return mapValues(m, func(j int, v float64) float64 {
+		if math.IsNaN(v) {
+			return p.Statistics[j]
+		}
+		return v
+	}), nil
+}
@@ -0,0 +1,102 @@
+package preprocess
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/lordberre/xgboost-go/mat"
+)
+
+// OneHotEncoder replaces categorical columns, holding numeric category codes, by one indicator column per
+// category seen by Fit like scikit-learn OneHotEncoder(handle_unknown="ignore"). The indicators of a column take
+// its place in the row, in increasing order of category, and the other columns keep their order. Unknown
+// categories have every indicator at 0 and missing values every indicator missing.
+type OneHotEncoder struct {
+	// Columns contains the indices of the categorical columns, set before Fit.
+	Columns []int `json:"columns"`
+	// Categories contains the sorted categories of each categorical column, set by Fit.
+	Categories [][]float64 `json:"categories"`
+	// NumInputs is the number of columns of the fitted rows.
+	NumInputs int `json:"num_inputs"`
+}
+
+// Name returns transformer name.
+func (e *OneHotEncoder) Name() string {
+	return oneHotEncoderName
+}
+
+// Fit collects the categories of the categorical columns.
+func (e *OneHotEncoder) Fit(m mat.Matrix) error {
+	cols, err := checkFitInput(m, e.Name())
+	if err != nil {
+		return err
+	}
+	seen := make(map[int]bool, len(e.Columns))
+	for _, col := range e.Columns {
+		if col < 0 || col >= cols {
+			return fmt.Errorf("%w: categorical column %d of rows with %d columns", mat.ErrDimensionMismatch, col,
+				cols)
+		}
+		if seen[col] {
+			return fmt.Errorf("duplicate categorical column %d", col)
+		}
+		seen[col] = true
+	}
+	e.NumInputs = cols
+	e.Categories = make([][]float64, len(e.Columns))
+	for k, col := range e.Columns {
+		distinct := map[float64]bool{}
+		for _, v := range columnValues(m, col) {
+			distinct[v] = true
+		}
+		categories := make([]float64, 0, len(distinct))
+		for v := range distinct {
+			categories = append(categories, v)
+		}
+		sort.Float64s(categories)
+		e.Categories[k] = categories
+	}
+	return nil
+}
+
+// Transform returns the rows with the categorical columns replaced by their indicators.
+func (e *OneHotEncoder) Transform(m mat.Matrix) (mat.Matrix, error) {
+	if err := checkWidth(m, e.NumInputs, e.Name()); err != nil {
+		return mat.Matrix{}, err
+	}
+	if len(e.Categories) != len(e.Columns) {
+		return mat.Matrix{}, fmt.Errorf("%w: %d categorical columns but categories of %d", mat.ErrDimensionMismatch,
+			len(e.Columns), len(e.Categories))
+	}
+	encoded := make(map[int][]float64, len(e.Columns))
+	width := e.NumInputs
+	for k, col := range e.Columns {
+		encoded[col] = e.Categories[k]
+		width += len(e.Categories[k]) - 1
+	}
+	result := mat.Matrix{Vectors: make([]*mat.Vector, len(m.Vectors))}
+	for i, v := range m.Vectors {
+		vec := make(mat.Vector, 0, width)
+		for j, val := range *v {
+			categories, ok := encoded[j]
+			if !ok {
+				vec = append(vec, val)
+				continue
+			}
+			idx := sort.SearchFloat64s(categories, val)
+			for c := range categories {
+				switch {
+				case math.IsNaN(val):
+					vec = append(vec, math.NaN())
+				case c == idx && categories[c] == val:
+					vec = append(vec, 1)
+				default:
+					vec = append(vec, 0)
+				}
+			}
+		}
+		result.Vectors[i] = &vec
+	}
+	return result, nil
+}
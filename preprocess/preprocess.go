@@ -0,0 +1,203 @@
+// Package preprocess implements fit/transform feature preprocessing steps like the transformers of scikit-learn
+// pipelines, so that raw rows can be scaled, encoded and imputed in Go the same way as during training. Fitted
+// steps are saved as JSON alongside the model and read back before prediction. Steps transform dense rows where
+// NaN values are missing.
+package preprocess
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/lordberre/xgboost-go/mat"
+)
+
+// Transformer is an interface that a preprocessing step needs to implement. It is fitted on the rows of a
+// training set and then transforms rows of the same width, leaving its input unmodified.
+type Transformer interface {
+	Fit(m mat.Matrix) error
+	Transform(m mat.Matrix) (mat.Matrix, error)
+	Name() string
+}
+
+// transformer types used for JSON persistence.
+const (
+	standardScalerName = "standard_scaler"
+	minMaxScalerName   = "min_max_scaler"
+	oneHotEncoderName  = "one_hot_encoder"
+	imputerName        = "imputer"
+	pipelineName       = "pipeline"
+)
+
+type transformerJSON struct {
+	Type   string          `json:"type"`
+	Params json.RawMessage `json:"params"`
+}
+
+// marshalTransformer returns the JSON of t with its type.
+func marshalTransformer(t Transformer) (transformerJSON, error) {
+	params, err := json.Marshal(t)
+	if err != nil {
+		return transformerJSON{}, fmt.Errorf("cannot encode %s transformer: %s", t.Name(), err)
+	}
+	return transformerJSON{Type: t.Name(), Params: params}, nil
+}
+
+// unmarshalTransformer returns the transformer of tj.
+func unmarshalTransformer(tj transformerJSON) (Transformer, error) {
+	var t Transformer
+	switch tj.Type {
+	case standardScalerName:
+		t = &StandardScaler{}
+	case minMaxScalerName:
+		t = &MinMaxScaler{}
+	case oneHotEncoderName:
+		t = &OneHotEncoder{}
+	case imputerName:
+		t = &Imputer{}
+	case pipelineName:
+		t = &Pipeline{}
+	default:
+		return nil, fmt.Errorf("unknown transformer type %q", tj.Type)
+	}
+	if err := json.Unmarshal(tj.Params, t); err != nil {
+		return nil, fmt.Errorf("cannot decode %s transformer: %s", tj.Type, err)
+	}
+	return t, nil
+}
+
+// WriteTransformerJSON writes a fitted transformer to w as JSON.
+func WriteTransformerJSON(t Transformer, w io.Writer) error {
+	tj, err := marshalTransformer(t)
+	if err != nil {
+		return err
+	}
+	return json.NewEncoder(w).Encode(tj)
+}
+
+// ReadTransformerJSON reads a transformer written by WriteTransformerJSON.
+func ReadTransformerJSON(r io.Reader) (Transformer, error) {
+	var tj transformerJSON
+	if err := json.NewDecoder(r).Decode(&tj); err != nil {
+		return nil, fmt.Errorf("cannot decode transformer: %s", err)
+	}
+	return unmarshalTransformer(tj)
+}
+
+// Pipeline chains transformers, each step being fitted on and transforming the output of the previous one like
+// scikit-learn Pipeline.
+type Pipeline struct {
+	Steps []Transformer
+}
+
+// Name returns transformer name.
+func (p *Pipeline) Name() string {
+	return pipelineName
+}
+
+// Fit fits the steps in order, each one on the rows transformed by the previous steps.
+func (p *Pipeline) Fit(m mat.Matrix) error {
+	for i, step := range p.Steps {
+		if err := step.Fit(m); err != nil {
+			return fmt.Errorf("cannot fit step %d of pipeline: %w", i, err)
+		}
+		if i == len(p.Steps)-1 {
+			break
+		}
+		var err error
+		if m, err = step.Transform(m); err != nil {
+			return fmt.Errorf("cannot transform step %d of pipeline: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// Transform applies the steps in order.
+func (p *Pipeline) Transform(m mat.Matrix) (mat.Matrix, error) {
+	for i, step := range p.Steps {
+		var err error
+		if m, err = step.Transform(m); err != nil {
+			return mat.Matrix{}, fmt.Errorf("cannot transform step %d of pipeline: %w", i, err)
+		}
+	}
+	return m, nil
+}
+
+// MarshalJSON encodes the steps with their type.
+func (p *Pipeline) MarshalJSON() ([]byte, error) {
+	steps := make([]transformerJSON, len(p.Steps))
+	for i, step := range p.Steps {
+		var err error
+		if steps[i], err = marshalTransformer(step); err != nil {
+			return nil, err
+		}
+	}
+	return json.Marshal(struct {
+		Steps []transformerJSON `json:"steps"`
+	}{steps})
+}
+
+// UnmarshalJSON decodes steps encoded by MarshalJSON.
+func (p *Pipeline) UnmarshalJSON(data []byte) error {
+	var pj struct {
+		Steps []transformerJSON `json:"steps"`
+	}
+	if err := json.Unmarshal(data, &pj); err != nil {
+		return err
+	}
+	p.Steps = make([]Transformer, len(pj.Steps))
+	for i, step := range pj.Steps {
+		var err error
+		if p.Steps[i], err = unmarshalTransformer(step); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkFitInput returns the number of columns of m, an error if it has no rows or rows of different lengths.
+func checkFitInput(m mat.Matrix, name string) (int, error) {
+	if m.Rows() == 0 {
+		return 0, fmt.Errorf("cannot fit %s on empty input", name)
+	}
+	return m.Cols()
+}
+
+// checkWidth returns an error wrapping mat.ErrDimensionMismatch if a row of m does not have cols values.
+func checkWidth(m mat.Matrix, cols int, name string) error {
+	if cols == 0 {
+		return fmt.Errorf("%s is not fitted", name)
+	}
+	for i, v := range m.Vectors {
+		if len(*v) != cols {
+			return fmt.Errorf("%w: row %d has %d columns, %s was fitted on %d", mat.ErrDimensionMismatch, i,
+				len(*v), name, cols)
+		}
+	}
+	return nil
+}
+
+// mapValues returns a copy of m with f applied to every value of column j.
+func mapValues(m mat.Matrix, f func(j int, v float64) float64) mat.Matrix {
+	result := mat.Matrix{Vectors: make([]*mat.Vector, len(m.Vectors))}
+	for i, v := range m.Vectors {
+		vec := make(mat.Vector, len(*v))
+		for j, val := range *v {
+			vec[j] = f(j, val)
+		}
+		result.Vectors[i] = &vec
+	}
+	return result
+}
+
+// columnValues returns the non missing values of column j of m.
+func columnValues(m mat.Matrix, j int) []float64 {
+	values := make([]float64, 0, len(m.Vectors))
+	for _, v := range m.Vectors {
+		if !math.IsNaN((*v)[j]) {
+			values = append(values, (*v)[j])
+		}
+	}
+	return values
+}
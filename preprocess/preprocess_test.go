@@ -0,0 +1,99 @@
+package preprocess
+
+import (
+	"bytes"
+	"errors"
+	"math"
+	"strings"
+	"testing"
+
+	"gotest.tools/assert"
+
+	"github.com/lordberre/xgboost-go/mat"
+)
+
+// testData returns rows with missing values used to fit the transformers.
+func testData() mat.Matrix {
+	nan := math.NaN()
+	return mat.Matrix{Vectors: []*mat.Vector{{1, 0, nan}, {3, 2, 10}, {5, 1, 20}, {nan, 2, 30}}}
+}
+
+func TestImputer(t *testing.T) {
+	data := testData()
+	imputer := &Imputer{}
+	assert.NilError(t, imputer.Fit(data))
+	assert.DeepEqual(t, imputer.Statistics, []float64{3, 1.25, 20})
+	imputed, err := imputer.Transform(data)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, imputed, mat.Matrix{Vectors: []*mat.Vector{{1, 0, 20}, {3, 2, 10}, {5, 1, 20}, {3, 2, 30}}})
+	assert.Check(t, math.IsNaN((*data.Vectors[0])[2]))
+	for strategy, expected := range map[string][]float64{
+		ImputeMedian:       {3, 1.5, 20},
+		ImputeMostFrequent: {1, 2, 10},
+		ImputeConstant:     {-1, -1, -1},
+	} {
+		imputer := &Imputer{Strategy: strategy, FillValue: -1}
+		assert.NilError(t, imputer.Fit(data))
+		assert.DeepEqual(t, imputer.Statistics, expected)
+	}
+	assert.ErrorContains(t, (&Imputer{Strategy: "mode"}).Fit(data), "unknown imputation strategy")
+}
+
+func TestStandardScaler(t *testing.T) {
+	imputed := mat.Matrix{Vectors: []*mat.Vector{{1, 0, 20}, {3, 2, 10}, {5, 1, 20}, {3, 2, 30}}}
+	standard := &StandardScaler{}
+	assert.NilError(t, standard.Fit(imputed))
+	assert.DeepEqual(t, standard.Mean, []float64{3, 1.25, 20})
+	scaled, err := standard.Transform(mat.Matrix{Vectors: []*mat.Vector{{5, 1.25, math.NaN()}}})
+	assert.NilError(t, err)
+	assert.Equal(t, (*scaled.Vectors[0])[0], 2/math.Sqrt(2))
+	assert.Equal(t, (*scaled.Vectors[0])[1], 0.0)
+	assert.Check(t, math.IsNaN((*scaled.Vectors[0])[2]))
+	_, err = (&StandardScaler{}).Transform(imputed)
+	assert.ErrorContains(t, err, "not fitted")
+}
+
+func TestMinMaxScaler(t *testing.T) {
+	minMax := &MinMaxScaler{}
+	assert.NilError(t, minMax.Fit(testData()))
+	scaled, err := minMax.Transform(mat.Matrix{Vectors: []*mat.Vector{{3, 2, 40}}})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, scaled, mat.Matrix{Vectors: []*mat.Vector{{0.5, 1, 1.5}}})
+}
+
+func TestOneHotEncoder(t *testing.T) {
+	data := testData()
+	encoder := &OneHotEncoder{Columns: []int{1}}
+	assert.NilError(t, encoder.Fit(data))
+	assert.DeepEqual(t, encoder.Categories, [][]float64{{0, 1, 2}})
+	encoded, err := encoder.Transform(mat.Matrix{Vectors: []*mat.Vector{{1, 2, 3}, {4, 7, 5}}})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, encoded, mat.Matrix{Vectors: []*mat.Vector{{1, 0, 0, 1, 3}, {4, 0, 0, 0, 5}}})
+	assert.Check(t, errors.Is((&OneHotEncoder{Columns: []int{3}}).Fit(data), mat.ErrDimensionMismatch))
+}
+
+func TestPipeline(t *testing.T) {
+	data := testData()
+	pipeline := &Pipeline{Steps: []Transformer{
+		&Imputer{}, &OneHotEncoder{Columns: []int{1}}, &MinMaxScaler{},
+	}}
+	assert.NilError(t, pipeline.Fit(data))
+	transformed, err := pipeline.Transform(data)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, *transformed.Vectors[0], mat.Vector{0, 1, 0, 0, 0.5})
+	assert.DeepEqual(t, *transformed.Vectors[3], mat.Vector{0.5, 0, 0, 1, 1})
+
+	var buf bytes.Buffer
+	assert.NilError(t, WriteTransformerJSON(pipeline, &buf))
+	read, err := ReadTransformerJSON(&buf)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, read, Transformer(pipeline))
+	readTransformed, err := read.Transform(data)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, readTransformed, transformed)
+
+	_, err = pipeline.Transform(mat.Matrix{Vectors: []*mat.Vector{{1, 2}}})
+	assert.Check(t, errors.Is(err, mat.ErrDimensionMismatch))
+	_, err = ReadTransformerJSON(strings.NewReader(`{"type": "pca", "params": {}}`))
+	assert.ErrorContains(t, err, "unknown transformer type")
+}
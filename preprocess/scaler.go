@@ -0,0 +1,104 @@
+package preprocess
+
+import (
+	"math"
+
+	"github.com/lordberre/xgboost-go/mat"
+)
+
+// StandardScaler centers every column on its mean and divides it by its standard deviation like scikit-learn
+// StandardScaler. Missing values are ignored by Fit and stay missing, columns of constant values are only
+// centered.
+type StandardScaler struct {
+	Mean  []float64 `json:"mean"`
+	Scale []float64 `json:"scale"`
+}
+
+// Name returns transformer name.
+func (s *StandardScaler) Name() string {
+	return standardScalerName
+}
+
+// Fit computes the mean and the population standard deviation of each column.
+func (s *StandardScaler) Fit(m mat.Matrix) error {
+	cols, err := checkFitInput(m, s.Name())
+	if err != nil {
+		return err
+	}
+	s.Mean, s.Scale = make([]float64, cols), make([]float64, cols)
+	for j := 0; j < cols; j++ {
+		values := columnValues(m, j)
+		mean, variance := 0.0, 0.0
+		for _, v := range values {
+			mean += v
+		}
+		if len(values) > 0 {
+			mean /= float64(len(values))
+		}
+		for _, v := range values {
+			variance += (v - mean) * (v - mean)
+		}
+		s.Mean[j], s.Scale[j] = mean, 1
+		if variance > 0 {
+			s.Scale[j] = math.Sqrt(variance / float64(len(values)))
+		}
+	}
+	return nil
+}
+
+// Transform returns the standardized rows.
+func (s *StandardScaler) Transform(m mat.Matrix) (mat.Matrix, error) {
+	if err := checkWidth(m, len(s.Mean), s.Name()); err != nil {
+		return mat.Matrix{}, err
+	}
+	return mapValues(m, func(j int, v float64) float64 {
+		return (v - s.Mean[j]) / s.Scale[j]
+	}), nil
+}
+
+// MinMaxScaler scales every column to [0, 1] using the minimum and maximum seen by Fit like scikit-learn
+// MinMaxScaler, values out of the fitted range are not clipped. Missing values are ignored by Fit and stay
+// missing, columns of constant values are only shifted.
+type MinMaxScaler struct {
+	Min []float64 `json:"data_min"`
+	Max []float64 `json:"data_max"`
+}
+
+// Name returns transformer name.
+func (s *MinMaxScaler) Name() string {
+	return minMaxScalerName
+}
+
+// Fit computes the minimum and maximum of each column.
+func (s *MinMaxScaler) Fit(m mat.Matrix) error {
+	cols, err := checkFitInput(m, s.Name())
+	if err != nil {
+		return err
+	}
+	s.Min, s.Max = make([]float64, cols), make([]float64, cols)
+	for j := 0; j < cols; j++ {
+		min, max := math.Inf(1), math.Inf(-1)
+		for _, v := range columnValues(m, j) {
+			min, max = math.Min(min, v), math.Max(max, v)
+		}
+		if min > max {
+			// only missing values.
+			min, max = 0, 0
+		}
+		s.Min[j], s.Max[j] = min, max
+	}
+	return nil
+}
+
+// Transform returns the scaled rows.
+func (s *MinMaxScaler) Transform(m mat.Matrix) (mat.Matrix, error) {
+	if err := checkWidth(m, len(s.Min), s.Name()); err != nil {
+		return mat.Matrix{}, err
+	}
+	return mapValues(m, func(j int, v float64) float64 {
+		if r := s.Max[j] - s.Min[j]; r > 0 {
+			return (v - s.Min[j]) / r
+		}
+		return v - s.Min[j]
+	}), nil
+}
@@ -0,0 +1,9 @@
+//go:build race
+// +build race
+
+package xgboost
+
+func init() {
+	// the race detector randomly drops items put in a sync.Pool.
+	raceEnabled = true
+}
@@ -0,0 +1,103 @@
+//go:build reference
+// +build reference
+
+package xgboost
+
+import (
+	"testing"
+
+	"gotest.tools/assert"
+
+	"github.com/lordberre/xgboost-go/mat"
+)
+
+// The tests of this file check the loaders against the predictions of the libraries the models come from. Their
+// models and predictions are generated by the scripts of test/scripts, run them before `make reference`.
+
+// readReferencePredictions reads the predictions written by script of test/scripts.
+func readReferencePredictions(t *testing.T, path, script string) mat.Matrix {
+	predictions, err := mat.ReadCSVFileToDenseMatrix(path, "\t", 0.0)
+	assert.NilError(t, err, "run test/scripts/%s to generate %s", script, path)
+	return predictions
+}
+
+func TestLoadXGBoostFromModelJSON_RandomForestReferencePredictions(t *testing.T) {
+	const script = "breast_cancer_xgboost_rf.py"
+	expected := readReferencePredictions(t, "test/data/breast_cancer_xgboost_rf_true_prediction.txt", script)
+	firstRound := readReferencePredictions(t,
+		"test/data/breast_cancer_xgboost_rf_true_prediction_first_round.txt", script)
+	input, err := mat.ReadLibsvmFileToSparseMatrix("test/data/breast_cancer_test.libsvm")
+	assert.NilError(t, err)
+
+	ensemble, err := LoadXGBoostFromModelJSON("test/data/breast_cancer_xgboost_rf_model.json", nil)
+	assert.NilError(t, err)
+	assert.Equal(t, ensemble.NumTrees(), 12)
+	predictions, err := ensemble.PredictProba(input)
+	assert.NilError(t, err)
+	assert.NilError(t, mat.IsEqualMatrices(&predictions, &expected, 1e-6))
+
+	ensemble, err = LoadXGBoostFromModelJSON("test/data/breast_cancer_xgboost_rf_model.json", nil,
+		WithIterationRange(0, 1))
+	assert.NilError(t, err)
+	assert.Equal(t, ensemble.NumTrees(), 4)
+	predictions, err = ensemble.PredictProba(input)
+	assert.NilError(t, err)
+	assert.NilError(t, mat.IsEqualMatrices(&predictions, &firstRound, 1e-6))
+}
+
+func TestLoadPMML_ReferencePredictions(t *testing.T) {
+	for _, c := range []struct{ pmml, input, predictions string }{
+		{"test/data/breast_cancer_xgboost_generated.pmml", "test/data/breast_cancer_test.libsvm",
+			"test/data/breast_cancer_xgboost_pmml_true_prediction.txt"},
+		{"test/data/iris_xgboost_generated.pmml", "test/data/iris_test.libsvm",
+			"test/data/iris_xgboost_pmml_true_prediction.txt"},
+	} {
+		expected := readReferencePredictions(t, c.predictions, "xgboost_pmml.py")
+		input, err := mat.ReadLibsvmFileToSparseMatrix(c.input)
+		assert.NilError(t, err)
+		ensemble, err := LoadPMML(c.pmml, nil)
+		assert.NilError(t, err)
+		predictions, err := ensemble.PredictProba(input)
+		assert.NilError(t, err)
+		// JPMML-Evaluator computes in float32 like the PMML fields.
+		assert.NilError(t, mat.IsEqualMatrices(&predictions, &expected, 1e-5), c.pmml)
+	}
+}
+
+func TestLoadTreelite_ReferencePredictions(t *testing.T) {
+	for _, c := range []struct{ model, input, predictions string }{
+		{"test/data/small_treelite_generated.bin", "test/data/small_treelite_test.libsvm",
+			"test/data/small_treelite_true_prediction.txt"},
+		{"test/data/breast_cancer_treelite_generated.bin", "test/data/breast_cancer_test.libsvm",
+			"test/data/breast_cancer_treelite_true_prediction.txt"},
+		{"test/data/iris_treelite_generated.bin", "test/data/iris_test.libsvm",
+			"test/data/iris_treelite_true_prediction.txt"},
+	} {
+		expected := readReferencePredictions(t, c.predictions, "treelite_checkpoints.py")
+		input, err := mat.ReadLibsvmFileToSparseMatrix(c.input)
+		assert.NilError(t, err)
+		ensemble, err := LoadTreelite(c.model, nil)
+		assert.NilError(t, err)
+		predictions, err := ensemble.PredictProba(input)
+		assert.NilError(t, err)
+		assert.NilError(t, mat.IsEqualMatrices(&predictions, &expected, 1e-6), c.model)
+	}
+}
+
+func TestLoadLightGBMModel_ReferencePredictions(t *testing.T) {
+	for _, c := range []struct{ model, input, predictions, script string }{
+		{"test/data/breast_cancer_lightgbm_generated_model.txt", "test/data/breast_cancer_test.libsvm",
+			"test/data/breast_cancer_lightgbm_true_prediction.txt", "breast_cancer_lightgbm.py"},
+		{"test/data/iris_lightgbm_generated_model.txt", "test/data/iris_test.libsvm",
+			"test/data/iris_lightgbm_true_prediction_proba.txt", "iris_lightgbm.py"},
+	} {
+		expected := readReferencePredictions(t, c.predictions, c.script)
+		input, err := mat.ReadLibsvmFileToSparseMatrix(c.input)
+		assert.NilError(t, err)
+		ensemble, err := LoadXGBoostModel(c.model, "", 0, 0, nil)
+		assert.NilError(t, err)
+		predictions, err := ensemble.PredictProba(input)
+		assert.NilError(t, err)
+		assert.NilError(t, mat.IsEqualMatrices(&predictions, &expected, 1e-6), c.model)
+	}
+}
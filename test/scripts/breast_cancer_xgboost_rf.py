@@ -0,0 +1,25 @@
+import numpy as np
+import xgboost as xgb
+from sklearn import datasets
+from sklearn.model_selection import train_test_split
+
+X, y = datasets.load_breast_cancer(return_X_y=True)
+X_train, X_test, y_train, y_test = train_test_split(X, y, test_size=0.2, random_state=0)
+
+# Boosted random forest: each round grows num_parallel_tree trees. The outputs are checked by `make reference`.
+dtrain = xgb.DMatrix(X_train, label=y_train)
+param = {'max_depth': 4, 'eta': 0.5, 'objective': 'binary:logistic', 'nthread': 4, 'num_parallel_tree': 4,
+         'subsample': 0.8, 'colsample_bynode': 0.8, 'seed': 0}
+
+num_round = 3
+bst = xgb.train(param, dtrain, num_round)
+bst.save_model('../data/breast_cancer_xgboost_rf_model.json')
+
+# Predict the libsvm rows of breast_cancer_xgboost.py, whose absent features are missing.
+dtest = xgb.DMatrix('../data/breast_cancer_test.libsvm?format=libsvm')
+y_pred = bst.predict(dtest)
+np.savetxt('../data/breast_cancer_xgboost_rf_true_prediction.txt', y_pred, delimiter='\t')
+
+# Predictions of the first round only, the 4 parallel trees of a round belong to a single iteration.
+y_pred = bst.predict(dtest, iteration_range=(0, 1))
+np.savetxt('../data/breast_cancer_xgboost_rf_true_prediction_first_round.txt', y_pred, delimiter='\t')
@@ -0,0 +1,23 @@
+import lightgbm as lgb
+import numpy as np
+from sklearn import datasets
+from sklearn.model_selection import train_test_split
+
+X, y = datasets.load_iris(return_X_y=True)
+X_train, X_test, y_train, y_test = train_test_split(X, y, test_size=0.2, random_state=0)
+
+# Same split as iris_xgboost.py, whose iris_test.libsvm is the test input.
+dtrain = lgb.Dataset(X_train, label=y_train)
+param = {'objective': 'multiclass', 'num_class': 3, 'num_leaves': 7, 'learning_rate': 0.5, 'num_threads': 4,
+         'verbose': -1}
+
+num_round = 10
+bst = lgb.train(param, dtrain, num_round)
+
+# Features absent from libsvm rows are missing, predict them as NaN.
+X_pred = np.where(X_test == 0, np.nan, X_test)
+y_pred_proba = bst.predict(X_pred)
+
+np.savetxt('../data/iris_lightgbm_true_prediction_proba.txt', y_pred_proba, delimiter='\t')
+# iris_lightgbm_model.txt is converted by hand from the XGBoost model, keep it.
+bst.save_model('../data/iris_lightgbm_generated_model.txt')
@@ -0,0 +1,75 @@
+# Writes the Treelite checkpoints of the tests with treelite 4 and the predictions of treelite GTIL:
+# the XGBoost models of breast_cancer_xgboost.py and iris_xgboost.py converted by treelite, and a small model built
+# with the model builder covering numerical and categorical splits. The checkpoints are written next to the ones
+# converted by hand, which the tests keep, and are checked by `make reference`.
+import numpy as np
+import treelite
+from sklearn.datasets import load_svmlight_file
+from treelite.model_builder import Metadata, ModelBuilder, PostProcessorFunc, TreeAnnotation
+
+
+def predict(model, libsvm, num_features, path):
+    # Features absent from libsvm rows are missing.
+    X, _ = load_svmlight_file(libsvm, n_features=num_features)
+    X = X.toarray()
+    X[X == 0] = np.nan
+    y_pred = treelite.gtil.predict(model, X)
+    np.savetxt(path, y_pred.reshape((X.shape[0], -1)), delimiter='\t')
+
+
+for name, num_features in [('breast_cancer', 30), ('iris', 4)]:
+    model = treelite.frontend.load_xgboost('../data/{}_xgboost_model.json'.format(name))
+    model.serialize('../data/{}_treelite_generated.bin'.format(name))
+    predict(model, '../data/{}_test.libsvm'.format(name), num_features,
+            '../data/{}_treelite_true_prediction.txt'.format(name))
+
+
+def leaf(builder, key, value):
+    builder.start_node(key)
+    builder.leaf(value)
+    builder.sum_hess(10.0)
+    builder.end_node()
+
+
+# Two classes with one tree each and a base score of 0.5, see TestLoadTreelite.
+builder = ModelBuilder(
+    threshold_type='float64',
+    leaf_output_type='float64',
+    metadata=Metadata(num_feature=3, task_type='kMultiClf', average_tree_output=False, num_target=1, num_class=[2],
+                      leaf_vector_shape=(1, 1)),
+    tree_annotation=TreeAnnotation(num_tree=2, target_id=[0, 0], class_id=[0, 1]),
+    postprocessor=PostProcessorFunc(name='identity'),
+    base_scores=[0.5, 0.5],
+)
+
+# f0 <= 1 goes left, categories 1 and 3 of f1 go right, missing values go right then left.
+builder.start_tree()
+builder.start_node(0)
+builder.numerical_test(feature_id=0, threshold=1.0, default_left=False, opname='<=', left_child_key=1,
+                       right_child_key=2)
+builder.sum_hess(10.0)
+builder.end_node()
+leaf(builder, 1, 10.0)
+builder.start_node(2)
+builder.categorical_test(feature_id=1, default_left=True, category_list=[1, 3], category_list_right_child=True,
+                         left_child_key=3, right_child_key=4)
+builder.sum_hess(10.0)
+builder.end_node()
+leaf(builder, 3, 20.0)
+leaf(builder, 4, 30.0)
+builder.end_tree()
+
+# f2 > 2 goes left, missing values go left.
+builder.start_tree()
+builder.start_node(0)
+builder.numerical_test(feature_id=2, threshold=2.0, default_left=True, opname='>', left_child_key=1,
+                       right_child_key=2)
+builder.sum_hess(10.0)
+builder.end_node()
+leaf(builder, 1, 2.0)
+leaf(builder, 2, 3.0)
+builder.end_tree()
+
+model = builder.commit()
+model.serialize('../data/small_treelite_generated.bin')
+predict(model, '../data/small_treelite_test.libsvm', 3, '../data/small_treelite_true_prediction.txt')
@@ -0,0 +1,34 @@
+# Converts the XGBoost models of breast_cancer_xgboost.py and iris_xgboost.py to PMML with JPMML-XGBoost and
+# writes the predictions of the converted models computed by JPMML-Evaluator, checked by `make reference`.
+#
+# Requires java, the JPMML-XGBoost executable jar whose path is given by the JPMML_XGBOOST_JAR environment
+# variable (https://github.com/jpmml/jpmml-xgboost) and the jpmml_evaluator python package.
+import os
+import subprocess
+
+import numpy as np
+import pandas as pd
+from jpmml_evaluator import make_evaluator
+from sklearn.datasets import load_svmlight_file
+
+jar = os.environ['JPMML_XGBOOST_JAR']
+
+for name, num_features in [('breast_cancer', 30), ('iris', 4)]:
+    model = '../data/{}_xgboost_model.json'.format(name)
+    # {}_xgboost.pmml is converted by hand, keep it.
+    pmml = '../data/{}_xgboost_generated.pmml'.format(name)
+    subprocess.run(['java', '-jar', jar, '--model-input', model, '--pmml-output', pmml], check=True)
+
+    # Features absent from libsvm rows are missing, JPMML-XGBoost names feature i x{i+1}.
+    X_test, _ = load_svmlight_file('../data/{}_test.libsvm'.format(name), n_features=num_features)
+    X_test = X_test.toarray()
+    X_test[X_test == 0] = np.nan
+    rows = pd.DataFrame(X_test, columns=['x{}'.format(i + 1) for i in range(num_features)])
+
+    evaluator = make_evaluator(pmml).verify()
+    results = evaluator.evaluateAll(rows)
+    if name == 'breast_cancer':
+        y_pred = results[['probability(1)']].values
+    else:
+        y_pred = results[['probability(0)', 'probability(1)', 'probability(2)']].values
+    np.savetxt('../data/{}_xgboost_pmml_true_prediction.txt'.format(name), y_pred, delimiter='\t')
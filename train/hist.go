@@ -0,0 +1,203 @@
+package train
+
+import (
+	"math"
+	"sort"
+
+	"github.com/lordberre/xgboost-go/mat"
+)
+
+// binnedValue is a present feature value replaced by the index of its histogram bin.
+type binnedValue struct {
+	feature int32
+	bin     int32
+}
+
+// binnedMatrix contains the rows of the training data as histogram bins, like the quantile sketch of the XGBoost
+// hist tree method. A value v of feature f goes to bin b, the number of cuts[f] lower or equal to v, so that
+// splitting at cuts[f][j] sends bins <= j to the left child exactly like v < cuts[f][j].
+type binnedMatrix struct {
+	numFeat int
+	// cuts contains the sorted candidate split thresholds of each feature, rounded to float32 like XGBoost stores
+	// them.
+	cuts [][]float64
+	// rows contains the bins of the present features of each row sorted by feature, features without any cut are
+	// left out.
+	rows [][]binnedValue
+}
+
+// present returns whether v is a present value, absent features and NaN values are missing.
+func present(v float64) bool {
+	return !math.IsNaN(v)
+}
+
+// sketchCuts returns at most maxBin-1 cuts of the sorted values of a feature, taken at their quantiles.
+func sketchCuts(values []float64, maxBin int) []float64 {
+	sort.Float64s(values)
+	var candidates []float64
+	for i, v := range values {
+		if i == 0 || v != values[i-1] {
+			candidates = append(candidates, float64(float32(v)))
+		}
+	}
+	var cuts []float64
+	if len(candidates) <= maxBin {
+		// every distinct value but the smallest one separates two values.
+		cuts = candidates[1:]
+	} else {
+		for b := 1; b < maxBin; b++ {
+			cuts = append(cuts, float64(float32(values[b*len(values)/maxBin])))
+		}
+	}
+	// thresholds are deduplicated after float32 rounding and cannot be the minimum, which separates nothing.
+	r := cuts[:0]
+	for _, c := range cuts {
+		if c > float64(float32(values[0])) && (len(r) == 0 || c > r[len(r)-1]) {
+			r = append(r, c)
+		}
+	}
+	return r
+}
+
+// newBinnedMatrix computes the cuts of every feature of features and bins their values.
+func newBinnedMatrix(features mat.SparseMatrix, maxBin int) *binnedMatrix {
+	m := &binnedMatrix{}
+	values := map[int][]float64{}
+	for _, row := range features.Vectors {
+		for f, v := range row {
+			if present(v) {
+				values[f] = append(values[f], v)
+			}
+			if f+1 > m.numFeat {
+				m.numFeat = f + 1
+			}
+		}
+	}
+	m.cuts = make([][]float64, m.numFeat)
+	for f, v := range values {
+		m.cuts[f] = sketchCuts(v, maxBin)
+	}
+	m.rows = make([][]binnedValue, len(features.Vectors))
+	for i, row := range features.Vectors {
+		binned := make([]binnedValue, 0, len(row))
+		for f, v := range row {
+			if !present(v) || len(m.cuts[f]) == 0 {
+				continue
+			}
+			binned = append(binned, binnedValue{feature: int32(f), bin: int32(m.bin(f, v))})
+		}
+		sort.Slice(binned, func(a, b int) bool { return binned[a].feature < binned[b].feature })
+		m.rows[i] = binned
+	}
+	return m
+}
+
+// bin returns the bin of value v of feature f.
+func (m *binnedMatrix) bin(f int, v float64) int {
+	cuts := m.cuts[f]
+	return sort.Search(len(cuts), func(i int) bool { return cuts[i] > v })
+}
+
+// rowBin returns the bin of feature f in row i and false if the feature is missing.
+func (m *binnedMatrix) rowBin(i, f int) (int, bool) {
+	row := m.rows[i]
+	j := sort.Search(len(row), func(j int) bool { return int(row[j].feature) >= f })
+	if j < len(row) && int(row[j].feature) == f {
+		return int(row[j].bin), true
+	}
+	return 0, false
+}
+
+// gradPair is a sum of gradients and hessians.
+type gradPair struct {
+	g, h float64
+}
+
+func (p *gradPair) add(o gradPair) {
+	p.g += o.g
+	p.h += o.h
+}
+
+func (p gradPair) sub(o gradPair) gradPair {
+	return gradPair{g: p.g - o.g, h: p.h - o.h}
+}
+
+// split is the best split of a node.
+type split struct {
+	feature     int
+	cut         int
+	defaultLeft bool
+	gain        float64
+	left, right gradPair
+}
+
+// histogram returns the gradient histogram of every sampled feature over rows.
+func (m *binnedMatrix) histogram(rows []int, grads []gradPair, sampled []bool) [][]gradPair {
+	hist := make([][]gradPair, m.numFeat)
+	for f, cuts := range m.cuts {
+		if len(cuts) != 0 && sampled[f] {
+			hist[f] = make([]gradPair, len(cuts)+1)
+		}
+	}
+	for _, i := range rows {
+		for _, v := range m.rows[i] {
+			if h := hist[v.feature]; h != nil {
+				h[v.bin].add(grads[i])
+			}
+		}
+	}
+	return hist
+}
+
+// bestSplit returns the split of a node of gradient sum total with the highest gain, ok is false if no split
+// gains more than gamma. Missing values are tried on both sides like the default direction of XGBoost.
+func (p *Params) bestSplit(hist [][]gradPair, total gradPair) (split, bool) {
+	best := split{gain: math.Max(p.Gamma, splitEps)}
+	found := false
+	parentGain := p.gain(total)
+	for f, h := range hist {
+		if h == nil {
+			continue
+		}
+		var presentSum gradPair
+		for _, b := range h {
+			presentSum.add(b)
+		}
+		missing := total.sub(presentSum)
+		var left gradPair
+		for cut := 0; cut < len(h)-1; cut++ {
+			left.add(h[cut])
+			for _, defaultLeft := range []bool{false, true} {
+				l := left
+				if defaultLeft {
+					l.add(missing)
+				}
+				r := total.sub(l)
+				if l.h < p.MinChildWeight || r.h < p.MinChildWeight {
+					continue
+				}
+				if gain := p.gain(l) + p.gain(r) - parentGain; gain > best.gain {
+					best = split{feature: f, cut: cut, defaultLeft: defaultLeft, gain: gain, left: l, right: r}
+					found = true
+				}
+			}
+		}
+	}
+	return best, found
+}
+
+// gain returns the structure score of a node like CalcGain of XGBoost.
+func (p *Params) gain(s gradPair) float64 {
+	if s.h < p.MinChildWeight || s.h <= 0 {
+		return 0
+	}
+	return s.g * s.g / (s.h + p.Lambda)
+}
+
+// weight returns the optimal leaf weight of a node before shrinkage like CalcWeight of XGBoost.
+func (p *Params) weight(s gradPair) float64 {
+	if s.h < p.MinChildWeight || s.h <= 0 {
+		return 0
+	}
+	return -s.g / (s.h + p.Lambda)
+}
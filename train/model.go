@@ -0,0 +1,243 @@
+package train
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+)
+
+// xgboostVersion is the XGBoost version written to saved models, whose schema they follow.
+var xgboostVersion = []int{1, 7, 6}
+
+// rootParent is the parent of root nodes in XGBoost models.
+const rootParent = math.MaxInt32
+
+// The following types are the parts of the XGBoost JSON model schema written by SaveModel, fields are declared
+// in the order XGBoost writes them.
+
+type modelJSON struct {
+	Learner learnerJSON `json:"learner"`
+	Version []int       `json:"version"`
+}
+
+type learnerJSON struct {
+	Attributes        map[string]string `json:"attributes"`
+	FeatureNames      []string          `json:"feature_names"`
+	FeatureTypes      []string          `json:"feature_types"`
+	GradientBooster   boosterJSON       `json:"gradient_booster"`
+	LearnerModelParam map[string]string `json:"learner_model_param"`
+	Objective         objectiveJSON     `json:"objective"`
+}
+
+type boosterJSON struct {
+	Model gbtreeJSON `json:"model"`
+	Name  string     `json:"name"`
+}
+
+type gbtreeJSON struct {
+	GBTreeModelParam map[string]string `json:"gbtree_model_param"`
+	TreeInfo         []int             `json:"tree_info"`
+	Trees            []treeJSON        `json:"trees"`
+}
+
+type objectiveJSON struct {
+	Name         string            `json:"name"`
+	RegLossParam map[string]string `json:"reg_loss_param"`
+}
+
+type treeJSON struct {
+	BaseWeights        []float64         `json:"base_weights"`
+	DefaultLeft        []int             `json:"default_left"`
+	LeftChildren       []int             `json:"left_children"`
+	LossChanges        []float64         `json:"loss_changes"`
+	Parents            []int             `json:"parents"`
+	RightChildren      []int             `json:"right_children"`
+	SplitConditions    []float64         `json:"split_conditions"`
+	SplitIndices       []int             `json:"split_indices"`
+	SplitType          []int             `json:"split_type"`
+	SumHessian         []float64         `json:"sum_hessian"`
+	Categories         []int             `json:"categories"`
+	CategoriesNodes    []int             `json:"categories_nodes"`
+	CategoriesSegments []int             `json:"categories_segments"`
+	CategoriesSizes    []int             `json:"categories_sizes"`
+	ID                 int               `json:"id"`
+	TreeParam          map[string]string `json:"tree_param"`
+}
+
+// treeModel returns the JSON model of tree id.
+func (t *tree) treeModel(id, numFeat int) treeJSON {
+	n := len(t.nodes)
+	r := treeJSON{
+		BaseWeights:        make([]float64, n),
+		DefaultLeft:        make([]int, n),
+		LeftChildren:       make([]int, n),
+		LossChanges:        make([]float64, n),
+		Parents:            make([]int, n),
+		RightChildren:      make([]int, n),
+		SplitConditions:    make([]float64, n),
+		SplitIndices:       make([]int, n),
+		SplitType:          make([]int, n),
+		SumHessian:         make([]float64, n),
+		Categories:         []int{},
+		CategoriesNodes:    []int{},
+		CategoriesSegments: []int{},
+		CategoriesSizes:    []int{},
+		ID:                 id,
+		TreeParam: map[string]string{
+			"num_deleted":      "0",
+			"num_feature":      strconv.Itoa(numFeat),
+			"num_nodes":        strconv.Itoa(n),
+			"size_leaf_vector": "1",
+		},
+	}
+	for i, node := range t.nodes {
+		r.BaseWeights[i] = node.weight
+		r.LeftChildren[i], r.RightChildren[i] = node.left, node.right
+		r.LossChanges[i] = node.gain
+		r.Parents[i] = node.parent
+		if node.parent < 0 {
+			r.Parents[i] = rootParent
+		}
+		r.SumHessian[i] = node.hess
+		if node.left < 0 {
+			// leaves store their value as split condition.
+			r.SplitConditions[i] = node.weight
+			continue
+		}
+		r.SplitConditions[i] = node.threshold
+		r.SplitIndices[i] = node.feature
+		if node.defaultLeft {
+			r.DefaultLeft[i] = 1
+		}
+	}
+	return r
+}
+
+// SaveModel writes the booster in the JSON model format of XGBoost Booster.save_model, which the xgboost package
+// loads with LoadXGBoostFromModelJSON. The model can also be loaded by XGBoost, which reads thresholds and leaf
+// values as float32 like they are trained here.
+func (b *Booster) SaveModel(w io.Writer) error {
+	numFeat := strconv.Itoa(b.numFeat)
+	model := modelJSON{
+		Learner: learnerJSON{
+			Attributes:   map[string]string{},
+			FeatureNames: []string{},
+			FeatureTypes: []string{},
+			GradientBooster: boosterJSON{
+				Model: gbtreeJSON{
+					GBTreeModelParam: map[string]string{
+						"num_parallel_tree": "1",
+						"num_trees":         strconv.Itoa(len(b.trees)),
+					},
+					TreeInfo: make([]int, len(b.trees)),
+					Trees:    make([]treeJSON, len(b.trees)),
+				},
+				Name: "gbtree",
+			},
+			LearnerModelParam: map[string]string{
+				"base_score":         strconv.FormatFloat(b.baseScore, 'E', -1, 64),
+				"boost_from_average": "1",
+				"num_class":          "0",
+				"num_feature":        numFeat,
+				"num_target":         "1",
+			},
+			Objective: objectiveJSON{
+				Name:         b.objective,
+				RegLossParam: map[string]string{"scale_pos_weight": "1"},
+			},
+		},
+		Version: xgboostVersion,
+	}
+	for i, t := range b.trees {
+		model.Learner.GradientBooster.Model.Trees[i] = t.treeModel(i, b.numFeat)
+	}
+	return json.NewEncoder(w).Encode(model)
+}
+
+// LoadModel reads a booster saved by SaveModel or by XGBoost Booster.save_model in JSON format, so that its
+// training can be continued with Continue. Only gbtree boosters of a single output with numerical splits and a
+// supported objective can be loaded.
+func LoadModel(r io.Reader) (*Booster, error) {
+	var model modelJSON
+	if err := json.NewDecoder(r).Decode(&model); err != nil {
+		return nil, fmt.Errorf("cannot decode model: %w", err)
+	}
+	learner := &model.Learner
+	objective := learner.Objective.Name
+	if objective != ObjectiveSquaredError && objective != ObjectiveBinaryLogistic {
+		return nil, fmt.Errorf("unsupported objective %q", objective)
+	}
+	if learner.GradientBooster.Name != "gbtree" {
+		return nil, fmt.Errorf("%s booster is not supported", learner.GradientBooster.Name)
+	}
+	param := learner.LearnerModelParam
+	for _, key := range []string{"num_class", "num_target"} {
+		if v, ok := param[key]; ok && v != "0" && v != "1" {
+			return nil, fmt.Errorf("multi output models are not supported, %s is %s", key, v)
+		}
+	}
+	p := Params{Objective: objective}
+	var err error
+	if p.BaseScore, err = strconv.ParseFloat(param["base_score"], 64); err != nil {
+		return nil, fmt.Errorf("invalid base_score: %w", err)
+	}
+	b := &Booster{objective: objective, baseScore: p.BaseScore, baseMargin: p.baseMargin()}
+	if b.numFeat, err = strconv.Atoi(param["num_feature"]); err != nil {
+		return nil, fmt.Errorf("invalid num_feature: %w", err)
+	}
+	for i, tj := range learner.GradientBooster.Model.Trees {
+		t, err := tj.tree()
+		if err != nil {
+			return nil, fmt.Errorf("error while reading %d tree: %w", i, err)
+		}
+		b.trees = append(b.trees, t)
+	}
+	return b, nil
+}
+
+// tree returns the trained tree of a JSON model tree.
+func (tj *treeJSON) tree() (*tree, error) {
+	n := len(tj.LeftChildren)
+	if n == 0 {
+		return nil, fmt.Errorf("tree without nodes")
+	}
+	for _, arr := range [][]int{tj.RightChildren, tj.Parents, tj.SplitIndices, tj.DefaultLeft} {
+		if len(arr) != n {
+			return nil, fmt.Errorf("tree arrays have different lengths")
+		}
+	}
+	if len(tj.SplitConditions) != n || len(tj.BaseWeights) != n {
+		return nil, fmt.Errorf("tree arrays have different lengths")
+	}
+	t := &tree{nodes: make([]node, n)}
+	for i := range t.nodes {
+		nd := node{left: tj.LeftChildren[i], right: tj.RightChildren[i], parent: tj.Parents[i]}
+		if i == 0 {
+			nd.parent = -1
+		}
+		if len(tj.SumHessian) == n {
+			nd.hess = tj.SumHessian[i]
+		}
+		if nd.left < 0 {
+			nd.left, nd.right, nd.weight = -1, -1, tj.SplitConditions[i]
+			t.nodes[i] = nd
+			continue
+		}
+		if len(tj.SplitType) == n && tj.SplitType[i] != 0 {
+			return nil, fmt.Errorf("node %d is a categorical split which is not supported", i)
+		}
+		// children are always created after their parent which guarantees traversal ends.
+		if nd.left <= i || nd.right <= i || nd.left >= n || nd.right >= n || tj.SplitIndices[i] < 0 {
+			return nil, fmt.Errorf("node %d has invalid children %d and %d", i, nd.left, nd.right)
+		}
+		nd.feature, nd.threshold = tj.SplitIndices[i], tj.SplitConditions[i]
+		nd.defaultLeft, nd.weight = tj.DefaultLeft[i] != 0, tj.BaseWeights[i]
+		if len(tj.LossChanges) == n {
+			nd.gain = tj.LossChanges[i]
+		}
+		t.nodes[i] = nd
+	}
+	return t, nil
+}
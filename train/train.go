@@ -0,0 +1,344 @@
+// Package train implements a limited pure Go gradient boosting trainer, so that pipelines can be trained and
+// served without leaving Go. It follows the hist tree method of XGBoost: feature values are sketched into at most
+// MaxBin bins and trees are grown depth-wise from gradient histograms. Trained boosters are saved in the XGBoost
+// JSON model format, which the xgboost package loads like models saved by XGBoost Booster.save_model.
+package train
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+
+	"github.com/lordberre/xgboost-go/mat"
+)
+
+// Supported objectives, named like the XGBoost objective parameter.
+const (
+	// ObjectiveSquaredError is the reg:squarederror regression objective.
+	ObjectiveSquaredError = "reg:squarederror"
+	// ObjectiveBinaryLogistic is the binary:logistic classification objective predicting probabilities.
+	ObjectiveBinaryLogistic = "binary:logistic"
+)
+
+// splitEps is the minimum gain of a split like kRtEps of XGBoost.
+const splitEps = 1e-6
+
+// minHessian bounds the hessian of the logistic loss away from 0 like XGBoost.
+const minHessian = 1e-16
+
+// Params contains the training parameters, named like the XGBoost ones. DefaultParams returns the XGBoost
+// defaults.
+type Params struct {
+	Objective string
+	// NumRounds is the number of boosting rounds, one tree is grown per round.
+	NumRounds int
+	// LearningRate is the shrinkage eta applied to leaf values.
+	LearningRate float64
+	MaxDepth     int
+	// MinChildWeight is the minimum sum of hessians of a child.
+	MinChildWeight float64
+	// Lambda is the L2 regularization of leaf weights.
+	Lambda float64
+	// Gamma is the minimum gain of a split, min_split_loss in XGBoost.
+	Gamma float64
+	// Subsample is the fraction of rows sampled for each tree.
+	Subsample float64
+	// ColsampleByTree is the fraction of features sampled for each tree.
+	ColsampleByTree float64
+	// MaxBin is the maximum number of histogram bins of a feature.
+	MaxBin int
+	// BaseScore is the initial prediction of every row, a probability for binary:logistic.
+	BaseScore float64
+	// Seed seeds the row and feature sampling.
+	Seed int64
+}
+
+// DefaultParams returns the default parameters of XGBoost for objective with 10 boosting rounds.
+func DefaultParams(objective string) Params {
+	return Params{
+		Objective:       objective,
+		NumRounds:       10,
+		LearningRate:    0.3,
+		MaxDepth:        6,
+		MinChildWeight:  1,
+		Lambda:          1,
+		Subsample:       1,
+		ColsampleByTree: 1,
+		MaxBin:          256,
+		BaseScore:       0.5,
+	}
+}
+
+// validate returns an error if p cannot be used for training.
+func (p *Params) validate() error {
+	switch p.Objective {
+	case ObjectiveSquaredError:
+	case ObjectiveBinaryLogistic:
+		if p.BaseScore <= 0 || p.BaseScore >= 1 {
+			return fmt.Errorf("base score must be in (0, 1) for %s, got %v", p.Objective, p.BaseScore)
+		}
+	default:
+		return fmt.Errorf("unsupported objective %q", p.Objective)
+	}
+	switch {
+	case p.NumRounds <= 0:
+		return fmt.Errorf("number of rounds must be positive, got %d", p.NumRounds)
+	case p.LearningRate <= 0:
+		return fmt.Errorf("learning rate must be positive, got %v", p.LearningRate)
+	case p.MaxDepth <= 0:
+		return fmt.Errorf("max depth must be positive, got %d", p.MaxDepth)
+	case p.MinChildWeight < 0 || p.Lambda < 0 || p.Gamma < 0:
+		return fmt.Errorf("min child weight, lambda and gamma must not be negative")
+	case p.Subsample <= 0 || p.Subsample > 1:
+		return fmt.Errorf("subsample must be in (0, 1], got %v", p.Subsample)
+	case p.ColsampleByTree <= 0 || p.ColsampleByTree > 1:
+		return fmt.Errorf("colsample by tree must be in (0, 1], got %v", p.ColsampleByTree)
+	case p.MaxBin < 2:
+		return fmt.Errorf("max bin must be at least 2, got %d", p.MaxBin)
+	}
+	return nil
+}
+
+// baseMargin returns the margin of the base score.
+func (p *Params) baseMargin() float64 {
+	if p.Objective == ObjectiveBinaryLogistic {
+		return -math.Log(1/p.BaseScore - 1)
+	}
+	return p.BaseScore
+}
+
+// gradient returns the gradient and hessian of the loss of a row of margin and label.
+func (p *Params) gradient(margin, label, weight float64) gradPair {
+	if p.Objective == ObjectiveBinaryLogistic {
+		prob := 1 / (1 + math.Exp(-margin))
+		return gradPair{g: (prob - label) * weight, h: math.Max(prob*(1-prob), minHessian) * weight}
+	}
+	return gradPair{g: (margin - label) * weight, h: weight}
+}
+
+// node is a node of a trained tree, children are created after their parent.
+type node struct {
+	// left and right are -1 for leaves.
+	left, right int
+	parent      int
+	feature     int
+	threshold   float64
+	defaultLeft bool
+	gain        float64
+	// weight is the shrunk weight of the node, the value of leaves.
+	weight float64
+	hess   float64
+}
+
+// tree is a trained regression tree.
+type tree struct {
+	nodes []node
+}
+
+// predict returns the leaf value reached by row, values are compared in float64 like the xgboost package does.
+func (t *tree) predict(row mat.SparseVector) float64 {
+	idx := 0
+	for {
+		n := &t.nodes[idx]
+		if n.left < 0 {
+			return n.weight
+		}
+		v, ok := row[n.feature]
+		switch {
+		case !ok || !present(v):
+			if n.defaultLeft {
+				idx = n.left
+			} else {
+				idx = n.right
+			}
+		case v < n.threshold:
+			idx = n.left
+		default:
+			idx = n.right
+		}
+	}
+}
+
+// Booster is a trained gradient boosting model.
+type Booster struct {
+	objective  string
+	baseScore  float64
+	baseMargin float64
+	numFeat    int
+	trees      []*tree
+}
+
+// NumTrees returns the number of trees of the booster, one per boosting round.
+func (b *Booster) NumTrees() int {
+	return len(b.trees)
+}
+
+// NumFeatures returns the number of features of the training data.
+func (b *Booster) NumFeatures() int {
+	return b.numFeat
+}
+
+// PredictMargin returns the raw score of a row, the base margin plus the leaf values of every tree.
+func (b *Booster) PredictMargin(row mat.SparseVector) float64 {
+	margin := b.baseMargin
+	for _, t := range b.trees {
+		margin += t.predict(row)
+	}
+	return margin
+}
+
+// Train grows a booster on the rows and labels of d, weighted by the instance weights of d if it has some.
+// Absent features and NaN values are missing, each split learns the child missing values go to.
+func Train(d mat.DMatrix, p Params) (*Booster, error) {
+	if err := p.check(d); err != nil {
+		return nil, err
+	}
+	// the base score is rounded to float32 like XGBoost stores it, so that loaded models predict like the booster.
+	p.BaseScore = float64(float32(p.BaseScore))
+	b := &Booster{objective: p.Objective, baseScore: p.BaseScore, baseMargin: p.baseMargin()}
+	p.boost(b, d)
+	return b, nil
+}
+
+// check returns an error if p is invalid or d cannot be trained on with p.
+func (p *Params) check(d mat.DMatrix) error {
+	if err := p.validate(); err != nil {
+		return err
+	}
+	if err := d.Validate(); err != nil {
+		return err
+	}
+	if d.Rows() == 0 {
+		return fmt.Errorf("cannot train on empty input")
+	}
+	if d.Labels == nil {
+		return fmt.Errorf("training data has no labels")
+	}
+	if d.BaseMargins.Vectors != nil {
+		return fmt.Errorf("training from base margins is not supported")
+	}
+	if p.Objective == ObjectiveBinaryLogistic {
+		for i, l := range d.Labels {
+			if l < 0 || l > 1 {
+				return fmt.Errorf("label of row %d must be in [0, 1] for %s, got %v", i, p.Objective, l)
+			}
+		}
+	}
+	return nil
+}
+
+// Continue returns a copy of b with p.NumRounds more boosting rounds trained on d, like the xgb_model parameter of
+// XGBoost train. It refreshes a loaded model on new data, e.g. in an online service, without retraining the
+// previous trees. The objective of p must be the one of b and the base score of b is kept, b is not modified.
+func Continue(b *Booster, d mat.DMatrix, p Params) (*Booster, error) {
+	if p.Objective != b.objective {
+		return nil, fmt.Errorf("booster objective is %s, got %s", b.objective, p.Objective)
+	}
+	if err := p.check(d); err != nil {
+		return nil, err
+	}
+	r := &Booster{objective: b.objective, baseScore: b.baseScore, baseMargin: b.baseMargin, numFeat: b.numFeat,
+		trees: append([]*tree(nil), b.trees...)}
+	p.boost(r, d)
+	return r, nil
+}
+
+// boost appends p.NumRounds trees trained on d to b.
+func (p *Params) boost(b *Booster, d mat.DMatrix) {
+	m := newBinnedMatrix(d.Features, p.MaxBin)
+	if m.numFeat > b.numFeat {
+		b.numFeat = m.numFeat
+	}
+	rng := rand.New(rand.NewSource(p.Seed))
+	margins := make([]float64, d.Rows())
+	for i, row := range d.Features.Vectors {
+		margins[i] = b.PredictMargin(row)
+	}
+	grads := make([]gradPair, d.Rows())
+	for round := 0; round < p.NumRounds; round++ {
+		for i := range grads {
+			w := 1.0
+			if d.Weights != nil {
+				w = d.Weights[i]
+			}
+			grads[i] = p.gradient(margins[i], d.Labels[i], w)
+		}
+		rows := make([]int, 0, d.Rows())
+		for i := range grads {
+			if p.Subsample == 1 || rng.Float64() < p.Subsample {
+				rows = append(rows, i)
+			}
+		}
+		t := p.grow(m, grads, rows, sampleFeatures(rng, m.numFeat, p.ColsampleByTree))
+		for i, row := range d.Features.Vectors {
+			margins[i] += t.predict(row)
+		}
+		b.trees = append(b.trees, t)
+	}
+}
+
+// sampleFeatures returns which of the numFeat features a tree may split on.
+func sampleFeatures(rng *rand.Rand, numFeat int, fraction float64) []bool {
+	sampled := make([]bool, numFeat)
+	n := numFeat
+	if fraction < 1 {
+		n = int(fraction * float64(numFeat))
+		if n < 1 {
+			n = 1
+		}
+	}
+	for _, f := range rng.Perm(numFeat)[:n] {
+		sampled[f] = true
+	}
+	return sampled
+}
+
+// grow grows a tree depth-wise on rows.
+func (p *Params) grow(m *binnedMatrix, grads []gradPair, rows []int, sampled []bool) *tree {
+	var total gradPair
+	for _, i := range rows {
+		total.add(grads[i])
+	}
+	t := &tree{nodes: []node{p.leaf(-1, total)}}
+	level := map[int][]int{0: rows}
+	sums := map[int]gradPair{0: total}
+	for depth := 0; depth < p.MaxDepth && len(level) > 0; depth++ {
+		next := map[int][]int{}
+		// nodes of a level are split in creation order so that node ids only depend on the data.
+		for idx := range t.nodes {
+			nodeRows, ok := level[idx]
+			if !ok {
+				continue
+			}
+			s, ok := p.bestSplit(m.histogram(nodeRows, grads, sampled), sums[idx])
+			if !ok {
+				continue
+			}
+			left, right := len(t.nodes), len(t.nodes)+1
+			t.nodes = append(t.nodes, p.leaf(idx, s.left), p.leaf(idx, s.right))
+			n := &t.nodes[idx]
+			n.left, n.right = left, right
+			n.feature, n.threshold = s.feature, m.cuts[s.feature][s.cut]
+			n.defaultLeft, n.gain = s.defaultLeft, s.gain
+			var leftRows, rightRows []int
+			for _, i := range nodeRows {
+				bin, ok := m.rowBin(i, s.feature)
+				if (ok && bin <= s.cut) || (!ok && s.defaultLeft) {
+					leftRows = append(leftRows, i)
+				} else {
+					rightRows = append(rightRows, i)
+				}
+			}
+			next[left], next[right] = leftRows, rightRows
+			sums[left], sums[right] = s.left, s.right
+		}
+		level = next
+	}
+	return t
+}
+
+// leaf returns a leaf node of gradient sum s, leaf values are rounded to float32 like XGBoost stores them.
+func (p *Params) leaf(parent int, s gradPair) node {
+	w := float64(float32(p.weight(s) * p.LearningRate))
+	return node{left: -1, right: -1, parent: parent, weight: w, hess: s.h}
+}
@@ -0,0 +1,231 @@
+package train
+
+import (
+	"bytes"
+	"io/ioutil"
+	"math"
+	"os"
+	"reflect"
+	"testing"
+
+	"gotest.tools/assert"
+
+	"github.com/lordberre/xgboost-go"
+	"github.com/lordberre/xgboost-go/inference"
+	"github.com/lordberre/xgboost-go/mat"
+	"github.com/lordberre/xgboost-go/metrics"
+)
+
+// writeTempFile writes content into a temporary file and returns its path.
+func writeTempFile(t *testing.T, content string) string {
+	f, err := ioutil.TempFile("", "xgboost_train_test")
+	assert.NilError(t, err)
+	defer f.Close()
+	_, err = f.WriteString(content)
+	assert.NilError(t, err)
+	t.Cleanup(func() { os.Remove(f.Name()) })
+	return f.Name()
+}
+
+// trainAndLoad trains a booster on d and loads its saved JSON model.
+func trainAndLoad(t *testing.T, d mat.DMatrix, params Params) (*Booster, *inference.Ensemble) {
+	t.Helper()
+	booster, err := Train(d, params)
+	assert.NilError(t, err)
+	var buf bytes.Buffer
+	assert.NilError(t, booster.SaveModel(&buf))
+	ensemble, err := xgboost.LoadXGBoostFromModelJSON(writeTempFile(t, buf.String()), nil)
+	assert.NilError(t, err)
+	return booster, ensemble
+}
+
+func TestTrain(t *testing.T) {
+	d, err := mat.ReadLibsvmFileToDMatrix("../test/data/breast_cancer_test.libsvm")
+	assert.NilError(t, err)
+	params := DefaultParams(ObjectiveBinaryLogistic)
+	params.NumRounds = 20
+	booster, ensemble := trainAndLoad(t, d, params)
+	assert.Equal(t, booster.NumTrees(), 20)
+	assert.Equal(t, ensemble.NumTrees(), 20)
+	assert.Equal(t, ensemble.Objective(), ObjectiveBinaryLogistic)
+	assert.Equal(t, ensemble.NumFeatures(), booster.NumFeatures())
+	margins, err := ensemble.PredictMargin(d.Features)
+	assert.NilError(t, err)
+	for i, row := range d.Features.Vectors {
+		assert.Check(t, math.Abs((*margins.Vectors[i])[0]-booster.PredictMargin(row)) < 1e-9, "row %d", i)
+	}
+	proba, err := ensemble.PredictProba(d.Features)
+	assert.NilError(t, err)
+	auc, err := metrics.AUC(proba.Flatten(), d.Labels, nil)
+	assert.NilError(t, err)
+	assert.Check(t, auc > 0.99, auc)
+	logLoss, err := metrics.LogLoss(proba.Flatten(), d.Labels, nil)
+	assert.NilError(t, err)
+	assert.Check(t, logLoss < 0.1, logLoss)
+
+	// regression with sampling and missing values, which learn a default direction.
+	d, err = mat.ReadLibsvmFileToDMatrix("../test/data/iris_test.libsvm")
+	assert.NilError(t, err)
+	for i, row := range d.Features.Vectors {
+		if i%3 == 0 {
+			delete(row, i%4)
+		}
+	}
+	params = DefaultParams(ObjectiveSquaredError)
+	params.Subsample, params.ColsampleByTree, params.MaxDepth, params.Seed = 0.8, 0.5, 3, 42
+	previous := math.Inf(1)
+	for _, rounds := range []int{1, 5, 20} {
+		params.NumRounds = rounds
+		booster, ensemble = trainAndLoad(t, d, params)
+		pred, err := ensemble.PredictProba(d.Features)
+		assert.NilError(t, err)
+		for i, row := range d.Features.Vectors {
+			assert.Check(t, math.Abs((*pred.Vectors[i])[0]-booster.PredictMargin(row)) < 1e-9, "row %d", i)
+		}
+		rmse, err := metrics.RMSE(pred.Flatten(), d.Labels, nil)
+		assert.NilError(t, err)
+		assert.Check(t, rmse < previous, "%d rounds: %v >= %v", rounds, rmse, previous)
+		previous = rmse
+	}
+	assert.Check(t, previous < 0.2, previous)
+
+	params.Objective = "reg:gamma"
+	_, err = Train(d, params)
+	assert.ErrorContains(t, err, "unsupported objective")
+	params = DefaultParams(ObjectiveBinaryLogistic)
+	_, err = Train(d, params)
+	assert.ErrorContains(t, err, "must be in [0, 1]")
+	_, err = Train(mat.DMatrix{Features: d.Features}, params)
+	assert.ErrorContains(t, err, "no labels")
+}
+
+func TestSketchCuts(t *testing.T) {
+	// every distinct value but the smallest one is a cut when there are few values.
+	assert.DeepEqual(t, sketchCuts([]float64{3, 1, 2, 2, 5}, 256), []float64{2, 3, 5})
+	// quantiles otherwise.
+	assert.DeepEqual(t, sketchCuts([]float64{3, 1, 2, 2, 5}, 2), []float64{2})
+	// values equal after float32 rounding do not separate anything.
+	assert.Equal(t, len(sketchCuts([]float64{1, 1 + 1e-12}, 256)), 0)
+	assert.DeepEqual(t, sketchCuts([]float64{0.1, 0.2}, 256), []float64{float64(float32(0.2))})
+}
+
+func TestBinnedMatrix(t *testing.T) {
+	m := newBinnedMatrix(mat.SparseMatrix{Vectors: []mat.SparseVector{
+		{0: 1, 1: math.NaN()},
+		{0: 2, 2: 7},
+		{0: 3},
+	}}, 256)
+	assert.Equal(t, m.numFeat, 3)
+	assert.Check(t, reflect.DeepEqual(m.cuts, [][]float64{{2, 3}, nil, {}}), "%v", m.cuts)
+	// bin b holds the values with b cuts lower or equal to them.
+	assert.Equal(t, m.bin(0, 1), 0)
+	assert.Equal(t, m.bin(0, 2), 1)
+	assert.Equal(t, m.bin(0, 2.5), 1)
+	assert.Equal(t, m.bin(0, 3), 2)
+	for i, expected := range []int{0, 1, 2} {
+		bin, ok := m.rowBin(i, 0)
+		assert.Check(t, ok)
+		assert.Equal(t, bin, expected)
+	}
+	// NaN values are missing and features without cuts are left out.
+	_, ok := m.rowBin(0, 1)
+	assert.Check(t, !ok)
+	_, ok = m.rowBin(1, 2)
+	assert.Check(t, !ok)
+	_, ok = m.rowBin(2, 2)
+	assert.Check(t, !ok)
+
+	grads := []gradPair{{g: 1, h: 1}, {g: 2, h: 1}, {g: 4, h: 2}}
+	hist := m.histogram([]int{0, 1, 2}, grads, []bool{true, true, true})
+	assert.Check(t, reflect.DeepEqual(hist, [][]gradPair{grads, nil, nil}), "%v", hist)
+	hist = m.histogram([]int{0, 2}, grads, []bool{true, true, true})
+	assert.Check(t, reflect.DeepEqual(hist, [][]gradPair{{grads[0], {}, grads[2]}, nil, nil}), "%v", hist)
+	hist = m.histogram([]int{0, 1, 2}, grads, []bool{false, true, true})
+	assert.Check(t, reflect.DeepEqual(hist, [][]gradPair{nil, nil, nil}), "%v", hist)
+}
+
+func TestBestSplit(t *testing.T) {
+	p := DefaultParams(ObjectiveSquaredError)
+	// one row per bin and a missing row of gradient 1.
+	hist := [][]gradPair{nil, {{g: -2, h: 1}, {g: 2, h: 1}}}
+	total := gradPair{g: 1, h: 3}
+	s, ok := p.bestSplit(hist, total)
+	assert.Check(t, ok)
+	assert.Equal(t, s, split{feature: 1, cut: 0, defaultLeft: false, gain: 4.0/2 + 9.0/3 - 1.0/4,
+		left: gradPair{g: -2, h: 1}, right: gradPair{g: 3, h: 2}})
+	assert.Equal(t, p.weight(s.left), 1.0)
+
+	// missing values go left when it gains more.
+	total = gradPair{g: -3, h: 3}
+	s, ok = p.bestSplit(hist, total)
+	assert.Check(t, ok)
+	assert.Check(t, s.defaultLeft)
+	assert.Equal(t, s.left, gradPair{g: -5, h: 2})
+
+	p.Gamma = 10
+	_, ok = p.bestSplit(hist, total)
+	assert.Check(t, !ok)
+	p = DefaultParams(ObjectiveSquaredError)
+	p.MinChildWeight = 1.5
+	_, ok = p.bestSplit(hist, gradPair{g: 1, h: 3})
+	assert.Check(t, !ok)
+}
+
+func TestTrain_Continue(t *testing.T) {
+	modelPath := "../test/data/breast_cancer_xgboost_model.json"
+	ensemble, err := xgboost.LoadXGBoostFromModelJSON(modelPath, nil)
+	assert.NilError(t, err)
+	f, err := os.Open(modelPath)
+	assert.NilError(t, err)
+	defer f.Close()
+	booster, err := LoadModel(f)
+	assert.NilError(t, err)
+	assert.Equal(t, booster.NumTrees(), ensemble.NumTrees())
+	d, err := mat.ReadLibsvmFileToDMatrix("../test/data/breast_cancer_test.libsvm")
+	assert.NilError(t, err)
+	margins, err := ensemble.PredictMargin(d.Features)
+	assert.NilError(t, err)
+	for i, row := range d.Features.Vectors {
+		assert.Check(t, math.Abs((*margins.Vectors[i])[0]-booster.PredictMargin(row)) < 1e-9, "row %d", i)
+	}
+	proba, err := ensemble.PredictProba(d.Features)
+	assert.NilError(t, err)
+	logLoss, err := metrics.LogLoss(proba.Flatten(), d.Labels, nil)
+	assert.NilError(t, err)
+
+	params := DefaultParams(ObjectiveBinaryLogistic)
+	params.NumRounds = 5
+	continued, err := Continue(booster, d, params)
+	assert.NilError(t, err)
+	assert.Equal(t, booster.NumTrees(), 10)
+	assert.Equal(t, continued.NumTrees(), 15)
+	var buf bytes.Buffer
+	assert.NilError(t, continued.SaveModel(&buf))
+	refreshed, err := xgboost.LoadXGBoostFromModelJSON(writeTempFile(t, buf.String()), nil)
+	assert.NilError(t, err)
+	proba, err = refreshed.PredictProba(d.Features)
+	assert.NilError(t, err)
+	refreshedLogLoss, err := metrics.LogLoss(proba.Flatten(), d.Labels, nil)
+	assert.NilError(t, err)
+	assert.Check(t, refreshedLogLoss < logLoss, "%v >= %v", refreshedLogLoss, logLoss)
+	for i, row := range d.Features.Vectors {
+		assert.Check(t, math.Abs(1/(1+math.Exp(-continued.PredictMargin(row)))-(*proba.Vectors[i])[0]) < 1e-9,
+			"row %d", i)
+	}
+
+	// saved boosters load back unchanged.
+	reloaded, err := LoadModel(&buf)
+	assert.NilError(t, err)
+	assert.Equal(t, reloaded.NumTrees(), continued.NumTrees())
+	for i, row := range d.Features.Vectors {
+		assert.Equal(t, reloaded.PredictMargin(row), continued.PredictMargin(row), "row %d", i)
+	}
+
+	_, err = Continue(booster, d, DefaultParams(ObjectiveSquaredError))
+	assert.ErrorContains(t, err, "booster objective is binary:logistic")
+	f, err = os.Open("../test/data/iris_xgboost_model.json")
+	assert.NilError(t, err)
+	defer f.Close()
+	_, err = LoadModel(f)
+	assert.ErrorContains(t, err, "unsupported objective")
+}
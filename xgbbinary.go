@@ -0,0 +1,282 @@
+package xgboost
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/lordberre/xgboost-go/activation"
+	"github.com/lordberre/xgboost-go/inference"
+)
+
+// Sizes of the structures of the legacy binary format, they are written as raw little endian C++ structs.
+const (
+	binaryHeader           = "binf"
+	binaryLearnerParamSize = 136
+	binaryGBTreeParamSize  = 160
+	binaryTreeParamSize    = 148
+	binaryNodeSize         = 20
+	binaryNodeStatSize     = 16
+	binaryMaxStringSize    = 1 << 10
+)
+
+// binaryReader reads the legacy binary format, the first error is kept and returned by err.
+type binaryReader struct {
+	r   io.Reader
+	buf []byte
+	err error
+}
+
+func (b *binaryReader) read(n int) []byte {
+	if b.err != nil {
+		return nil
+	}
+	if cap(b.buf) < n {
+		b.buf = make([]byte, n)
+	}
+	buf := b.buf[:n]
+	if _, err := io.ReadFull(b.r, buf); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		b.err = err
+		return nil
+	}
+	return buf
+}
+
+func (b *binaryReader) int32() int32 {
+	buf := b.read(4)
+	if buf == nil {
+		return 0
+	}
+	return int32(binary.LittleEndian.Uint32(buf))
+}
+
+func (b *binaryReader) float32() float64 {
+	buf := b.read(4)
+	if buf == nil {
+		return 0
+	}
+	return float64(math.Float32frombits(binary.LittleEndian.Uint32(buf)))
+}
+
+func (b *binaryReader) string() string {
+	buf := b.read(8)
+	if buf == nil {
+		return ""
+	}
+	n := binary.LittleEndian.Uint64(buf)
+	if n > binaryMaxStringSize {
+		b.err = fmt.Errorf("string length %d is too large", n)
+		return ""
+	}
+	return string(b.read(int(n)))
+}
+
+// isBinaryModel returns true if head looks like the beginning of a legacy binary model, i.e. either the binf
+// header or a learner parameter struct followed by a printable objective name.
+func isBinaryModel(head []byte) bool {
+	if bytes.HasPrefix(head, []byte(binaryHeader)) {
+		return true
+	}
+	if len(head) < binaryLearnerParamSize+8 {
+		return false
+	}
+	n := binary.LittleEndian.Uint64(head[binaryLearnerParamSize:])
+	objective := head[binaryLearnerParamSize+8:]
+	if n == 0 || n > uint64(len(objective)) {
+		return false
+	}
+	for _, c := range objective[:n] {
+		if c < '!' || c > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+// marginToProb is the inverse of probToMargin, models saved before XGBoost 1.0 store base_score as a margin.
+func marginToProb(objective string, margin float64) float64 {
+	switch objective {
+	case "binary:logistic", "binary:logitraw", "reg:logistic":
+		return 1 / (1 + math.Exp(-margin))
+	case "count:poisson", "reg:gamma", "reg:tweedie", "survival:cox", "survival:aft":
+		return math.Exp(margin)
+	}
+	return margin
+}
+
+// readBinaryTree reads a tree into the array based tree schema of save_model.
+func readBinaryTree(b *binaryReader) (map[string]interface{}, error) {
+	param := b.read(binaryTreeParamSize)
+	if b.err != nil {
+		return nil, b.err
+	}
+	n := int(int32(binary.LittleEndian.Uint32(param[4:])))
+	if n <= 0 {
+		return nil, fmt.Errorf("invalid number of nodes %d", n)
+	}
+	// arrays are grown while reading so that a corrupted node count fails on read instead of allocating.
+	var left, right, indices, conditions, defaultLeft, hessians, gains []float64
+	for i := 0; i < n && b.err == nil; i++ {
+		node := b.read(binaryNodeSize)
+		if node == nil {
+			break
+		}
+		sindex := binary.LittleEndian.Uint32(node[12:])
+		left = append(left, float64(int32(binary.LittleEndian.Uint32(node[4:]))))
+		right = append(right, float64(int32(binary.LittleEndian.Uint32(node[8:]))))
+		indices = append(indices, float64(sindex&(1<<31-1)))
+		defaultLeft = append(defaultLeft, float64(sindex>>31))
+		conditions = append(conditions, float64(math.Float32frombits(binary.LittleEndian.Uint32(node[16:]))))
+	}
+	for i := 0; i < n && b.err == nil; i++ {
+		stat := b.read(binaryNodeStatSize)
+		if stat == nil {
+			break
+		}
+		gains = append(gains, float64(math.Float32frombits(binary.LittleEndian.Uint32(stat[0:]))))
+		hessians = append(hessians, float64(math.Float32frombits(binary.LittleEndian.Uint32(stat[4:]))))
+	}
+	if b.err != nil {
+		return nil, b.err
+	}
+	return map[string]interface{}{
+		"left_children":    left,
+		"right_children":   right,
+		"split_indices":    indices,
+		"split_conditions": conditions,
+		"default_left":     defaultLeft,
+		"sum_hessian":      hessians,
+		"loss_changes":     gains,
+	}, nil
+}
+
+// readBinaryModel reads a legacy binary model into the schema of save_model.
+func readBinaryModel(r io.Reader) (map[string]interface{}, error) {
+	br := bufio.NewReader(r)
+	if head, err := br.Peek(len(binaryHeader)); err == nil && string(head) == binaryHeader {
+		br.Discard(len(binaryHeader))
+	}
+	b := &binaryReader{r: br}
+
+	param := b.read(binaryLearnerParamSize)
+	if b.err != nil {
+		return nil, b.err
+	}
+	baseScore := float64(math.Float32frombits(binary.LittleEndian.Uint32(param[0:])))
+	numFeature := binary.LittleEndian.Uint32(param[4:])
+	numClass := int32(binary.LittleEndian.Uint32(param[8:]))
+	majorVersion := binary.LittleEndian.Uint32(param[20:])
+	objective := b.string()
+	booster := b.string()
+	if b.err != nil {
+		return nil, b.err
+	}
+	if majorVersion < 1 && (len(objective) < 6 || objective[:6] != "multi:") {
+		baseScore = marginToProb(objective, baseScore)
+	}
+	if booster != "gbtree" && booster != "dart" {
+		return nil, fmt.Errorf("%w: %s booster is not supported", ErrUnsupportedFormat, booster)
+	}
+
+	gbParam := b.read(binaryGBTreeParamSize)
+	if b.err != nil {
+		return nil, b.err
+	}
+	numTrees := int(int32(binary.LittleEndian.Uint32(gbParam[0:])))
+	if numTrees < 0 {
+		return nil, fmt.Errorf("invalid number of trees %d", numTrees)
+	}
+	var trees []interface{}
+	for i := 0; i < numTrees; i++ {
+		tree, err := readBinaryTree(b)
+		if err != nil {
+			return nil, fmt.Errorf("error while reading %d tree: %s", i, err)
+		}
+		trees = append(trees, tree)
+	}
+	var treeInfo []float64
+	for i := 0; i < numTrees && b.err == nil; i++ {
+		treeInfo = append(treeInfo, float64(b.int32()))
+	}
+	if b.err != nil {
+		return nil, b.err
+	}
+
+	model := map[string]interface{}{"tree_info": treeInfo, "trees": trees}
+	// num_parallel_tree replaced the always 1 num_roots of models saved before XGBoost 1.0.
+	if parallelTrees := int32(binary.LittleEndian.Uint32(gbParam[4:])); parallelTrees > 1 {
+		model["gbtree_model_param"] = map[string]interface{}{"num_parallel_tree": float64(parallelTrees)}
+	}
+	gradientBooster := map[string]interface{}{"name": booster, "model": model}
+	if booster == "dart" {
+		n := b.read(8)
+		if n == nil {
+			return nil, b.err
+		}
+		var weights []float64
+		for i := uint64(0); i < binary.LittleEndian.Uint64(n) && b.err == nil; i++ {
+			weights = append(weights, b.float32())
+		}
+		if b.err != nil {
+			return nil, b.err
+		}
+		gradientBooster = map[string]interface{}{
+			"name":        booster,
+			"gbtree":      map[string]interface{}{"model": model},
+			"weight_drop": weights,
+		}
+	}
+	return map[string]interface{}{
+		"learner": map[string]interface{}{
+			"objective": map[string]interface{}{"name": objective},
+			"learner_model_param": map[string]interface{}{
+				"base_score":  baseScore,
+				"num_class":   float64(numClass),
+				"num_feature": float64(numFeature),
+			},
+			"gradient_booster": gradientBooster,
+		},
+	}, nil
+}
+
+// loadXGBoostBinary loads xgboost model from legacy binary content.
+func loadXGBoostBinary(
+	r io.Reader,
+	numClasses int,
+	act activation.Activation,
+	opts ...LoadOption) (*inference.Ensemble, error) {
+	doc, err := readBinaryModel(r)
+	if err != nil {
+		if errors.Is(err, ErrUnsupportedFormat) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("%w: %s", ErrMalformedModel, err)
+	}
+	return buildModelEnsemble(doc, numClasses, act, opts...)
+}
+
+// LoadXGBoostFromBinary loads xgboost model saved in the legacy binary format, e.g. with save_model("x.model")
+// before XGBoost 2.0. Like LoadXGBoostFromModelJSON the number of classes, the objective and base_score are read
+// from the model and the activation matching the objective is used if act is nil. Categorical splits are not part
+// of this format.
+func LoadXGBoostFromBinary(
+	modelPath string,
+	act activation.Activation,
+	opts ...LoadOption) (*inference.Ensemble, error) {
+	modelFile, format, err := openModelFile(modelPath)
+	if err != nil {
+		return nil, err
+	}
+	defer modelFile.Close()
+	if format != formatBinary {
+		return nil, fmt.Errorf("%w: %s is %s, expected a binary model", ErrUnsupportedFormat, modelPath, format)
+	}
+	return loadXGBoostBinary(modelFile, 0, act, opts...)
+}
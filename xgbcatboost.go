@@ -0,0 +1,365 @@
+package xgboost
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/lordberre/xgboost-go/activation"
+	"github.com/lordberre/xgboost-go/inference"
+)
+
+// catboostObjectives maps CatBoost loss functions to the XGBoost objectives with the same prediction transform.
+var catboostObjectives = map[string]string{
+	"RMSE":         "reg:squarederror",
+	"MAE":          "reg:absoluteerror",
+	"Quantile":     "reg:quantileerror",
+	"Poisson":      "count:poisson",
+	"QueryRMSE":    "reg:squarederror",
+	"Logloss":      "binary:logistic",
+	"CrossEntropy": "binary:logistic",
+	"MultiClass":   "multi:softprob",
+	"YetiRank":     "rank:pairwise",
+	"PairLogit":    "rank:pairwise",
+}
+
+// catboostObjective returns the XGBoost objective equivalent to the loss function of a CatBoost model, params are
+// stored either as an object or as a json string depending on the CatBoost version.
+func catboostObjective(doc modelObject) (string, error) {
+	info, err := doc.object("model_info")
+	if err != nil {
+		return "", err
+	}
+	var params modelObject
+	if s, ok := info["params"].(string); ok {
+		if err := json.Unmarshal([]byte(s), &params); err != nil {
+			return "", fmt.Errorf("%w: params: %s", ErrMalformedModel, err)
+		}
+	} else if params, err = info.object("params"); err != nil {
+		return "", err
+	}
+	loss, err := params.object("loss_function")
+	if err != nil {
+		return "", err
+	}
+	name, err := loss.string("type")
+	if err != nil {
+		return "", err
+	}
+	objective, ok := catboostObjectives[name]
+	if !ok {
+		return "", fmt.Errorf("%w %s", activation.ErrUnsupportedObjective, name)
+	}
+	return objective, nil
+}
+
+// catboostFeature is a float feature of a CatBoost model.
+type catboostFeature struct {
+	index int
+	// nanTrue is true if missing values satisfy the value > border conditions of the feature.
+	nanTrue bool
+}
+
+// catboostFeatures returns the float features of a CatBoost model, categorical and text features are not
+// supported.
+func catboostFeatures(doc modelObject) ([]catboostFeature, error) {
+	info, err := doc.object("features_info")
+	if err != nil {
+		return nil, err
+	}
+	for _, key := range []string{"categorical_features", "text_features", "embedding_features"} {
+		if arr, ok := info[key].([]interface{}); ok && len(arr) > 0 {
+			return nil, fmt.Errorf("%w: %s are not supported", ErrUnsupportedFormat, key)
+		}
+	}
+	arr, ok := info["float_features"].([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%w: float_features is not an array", ErrMalformedModel)
+	}
+	features := make([]catboostFeature, len(arr))
+	for i, v := range arr {
+		f, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("%w: float feature %d is not an object", ErrMalformedModel, i)
+		}
+		// the flat index counts every feature of the input, the feature index only float ones.
+		key := "flat_feature_index"
+		if _, ok := f[key]; !ok {
+			key = "feature_index"
+		}
+		idx, err := modelObject(f).number(key)
+		if err != nil {
+			return nil, err
+		}
+		if idx < 0 || idx != math.Trunc(idx) {
+			return nil, fmt.Errorf("%w: invalid feature index %v", ErrMalformedModel, idx)
+		}
+		features[i].index = int(idx)
+		features[i].nanTrue = f["nan_value_treatment"] == "AsTrue"
+	}
+	return features, nil
+}
+
+// buildCatBoostTrees converts a symmetric tree into one tree per class. Every level of a symmetric tree tests the
+// same split and bit j of the leaf index is set when the split of level j is true, i.e. value > border. The
+// binary tree tests split j at depth j so that taking the no child sets the bit like CatBoost does.
+func buildCatBoostTrees(tree modelObject, features []catboostFeature, numClasses int) ([]*xgbTree, int, error) {
+	splits, ok := tree["splits"].([]interface{})
+	if !ok && tree["splits"] != nil {
+		return nil, 0, fmt.Errorf("%w: splits is not an array", ErrMalformedModel)
+	}
+	leafValues, err := tree.floats("leaf_values")
+	if err != nil {
+		return nil, 0, err
+	}
+	leafWeights, err := tree.floats("leaf_weights")
+	if err != nil {
+		return nil, 0, err
+	}
+	depth := len(splits)
+	if depth > 16 {
+		return nil, 0, fmt.Errorf("%w: tree depth %d is too large", ErrMalformedModel, depth)
+	}
+	numLeaves := 1 << depth
+	if len(leafValues) != numLeaves*numClasses {
+		return nil, 0, fmt.Errorf("%w: %d leaf values for depth %d and %d classes", ErrMalformedModel,
+			len(leafValues), depth, numClasses)
+	}
+	if len(leafWeights) != numLeaves {
+		leafWeights = nil
+	}
+
+	// split nodes are numbered in breadth first order, leaves follow them in leaf index order.
+	numSplits := numLeaves - 1
+	nodes := make([]*xgbNode, numSplits+numLeaves)
+	maxFeat := 0
+	for level, v := range splits {
+		split, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, 0, fmt.Errorf("%w: split %d is not an object", ErrMalformedModel, level)
+		}
+		s := modelObject(split)
+		if splitType, _ := s.string("split_type"); splitType != "FloatFeature" {
+			return nil, 0, fmt.Errorf("%w: %s splits are not supported", ErrUnsupportedFormat, splitType)
+		}
+		border, err := s.number("border")
+		if err != nil {
+			return nil, 0, err
+		}
+		f, err := s.number("float_feature_index")
+		if err != nil {
+			return nil, 0, err
+		}
+		if f < 0 || int(f) >= len(features) {
+			return nil, 0, fmt.Errorf("%w: split %d uses unknown float feature %v", ErrMalformedModel, level, f)
+		}
+		feature := features[int(f)]
+		if feature.index > maxFeat {
+			maxFeat = feature.index
+		}
+		// value > border is false for values lower than the next float32 after border.
+		threshold := float64(math.Nextafter32(float32(border), float32(math.Inf(1))))
+		for i := 1<<level - 1; i < 1<<(level+1)-1; i++ {
+			n := &xgbNode{NodeID: i, Feature: feature.index, Threshold: threshold, Yes: 2*i + 1, No: 2*i + 2}
+			n.Missing = n.Yes
+			if feature.nanTrue {
+				n.Missing = n.No
+			}
+			nodes[i] = n
+		}
+	}
+
+	trees := make([]*xgbTree, numClasses)
+	for c := range trees {
+		t := &xgbTree{nodes: make([]*xgbNode, len(nodes)), float32Comparison: true}
+		for i := 0; i < numSplits; i++ {
+			n := *nodes[i]
+			t.nodes[i] = &n
+		}
+		for leaf := 0; leaf < numLeaves; leaf++ {
+			// the breadth first numbering reverses the bits of the leaf index, bit j is the child taken at depth j.
+			pos := 0
+			for j := 0; j < depth; j++ {
+				pos = pos<<1 | (leaf>>j)&1
+			}
+			n := &xgbNode{NodeID: numSplits + pos, Flags: isLeaf, LeafValues: leafValues[leaf*numClasses+c]}
+			if leafWeights != nil {
+				n.Cover = leafWeights[leaf]
+			}
+			t.nodes[numSplits+pos] = n
+		}
+		if leafWeights != nil {
+			for i := numSplits - 1; i >= 0; i-- {
+				t.nodes[i].Cover = t.nodes[2*i+1].Cover + t.nodes[2*i+2].Cover
+			}
+			t.computeNodeMeans()
+		}
+		trees[c] = t
+	}
+	return trees, maxFeat, nil
+}
+
+// buildCatBoostEnsemble creates an ensemble from a CatBoost json model.
+func buildCatBoostEnsemble(
+	doc modelObject,
+	numClasses int,
+	act activation.Activation,
+	opts ...LoadOption) (*inference.Ensemble, error) {
+	cfg := newLoadConfig(opts)
+	if _, ok := doc["oblivious_trees"]; !ok {
+		if _, ok := doc["trees"]; ok {
+			return nil, fmt.Errorf("%w: non symmetric trees are not supported", ErrUnsupportedFormat)
+		}
+	}
+	objective, err := catboostObjective(doc)
+	if err != nil && (act == nil || !errors.Is(err, activation.ErrUnsupportedObjective)) {
+		return nil, err
+	}
+	if len(cfg.objective) != 0 && cfg.objective != objective {
+		return nil, fmt.Errorf("model objective is %s, got %s", objective, cfg.objective)
+	}
+	if act == nil {
+		if act, err = activation.FromObjective(objective); err != nil {
+			return nil, err
+		}
+	} else if objective != "" {
+		if err := activation.CheckObjective(objective, act); err != nil {
+			return nil, err
+		}
+	}
+	features, err := catboostFeatures(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	// scale_and_bias is [scale, bias] in older versions and [scale, [bias of each class]] since 0.25.
+	scale, biases := 1.0, []float64{0}
+	if sb, ok := doc["scale_and_bias"].([]interface{}); ok {
+		if len(sb) != 2 {
+			return nil, fmt.Errorf("%w: scale_and_bias must have 2 elements", ErrMalformedModel)
+		}
+		o := modelObject{"scale": sb[0], "bias": sb[1]}
+		if scale, err = o.number("scale"); err != nil {
+			return nil, err
+		}
+		if _, ok := sb[1].([]interface{}); ok {
+			biases, err = o.floats("bias")
+		} else {
+			biases[0], err = o.number("bias")
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	modelClasses := len(biases)
+	trees, ok := doc["oblivious_trees"].([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%w: oblivious_trees is not an array", ErrMalformedModel)
+	}
+	if len(trees) == 0 {
+		return nil, fmt.Errorf("%w: no trees in model", ErrMalformedModel)
+	}
+	if first, ok := trees[0].(map[string]interface{}); ok && len(biases) == 1 {
+		// old models have a single bias whatever the number of classes, which is then given by the leaves.
+		values, err := modelObject(first).floats("leaf_values")
+		if err != nil {
+			return nil, err
+		}
+		splits, _ := first["splits"].([]interface{})
+		if n := len(values) >> len(splits); n > 1 {
+			modelClasses = n
+			for len(biases) < n {
+				biases = append(biases, biases[0])
+			}
+		}
+	}
+	if numClasses > 0 && numClasses != modelClasses {
+		return nil, fmt.Errorf("model has %d classes, got %d", modelClasses, numClasses)
+	}
+	numClasses = modelClasses
+	begin, end, err := cfg.iterationRange(len(trees))
+	if err != nil {
+		return nil, err
+	}
+
+	e := &xgbEnsemble{name: "catboost", numClasses: numClasses, missing: cfg.missing, baseMargin: biases[0],
+		objective: objective}
+	e.Trees = make([]*xgbTree, 0, (end-begin)*numClasses)
+	maxFeat := 0
+	for _, f := range features {
+		maxFeat = maxInt(maxFeat, f.index)
+	}
+	for i := begin; i < end; i++ {
+		tree, ok := trees[i].(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("%w: tree %d is not an object", ErrMalformedModel, i)
+		}
+		classTrees, numFeat, err := buildCatBoostTrees(tree, features, numClasses)
+		if err != nil {
+			return nil, fmt.Errorf("error while reading %d tree: %w", i, err)
+		}
+		for c, t := range classTrees {
+			if scale != 1 {
+				for _, n := range t.nodes {
+					if n.Flags&isLeaf > 0 {
+						n.LeafValues *= scale
+					}
+				}
+				for j := range t.nodeMeans {
+					t.nodeMeans[j] *= scale
+				}
+			}
+			// the ensemble has a single base margin, the difference with the bias of other classes is added to
+			// their first tree.
+			if i == begin && biases[c] != e.baseMargin {
+				t.addToLeaves(biases[c] - e.baseMargin)
+			}
+			if cfg.validateValues {
+				if err := t.validate(); err != nil {
+					return nil, fmt.Errorf("error while reading %d tree: %w", i, err)
+				}
+			}
+			e.Trees = append(e.Trees, t)
+		}
+		maxFeat = maxInt(maxFeat, numFeat)
+	}
+	e.numFeat = maxFeat + 1
+	return e.ensemble(&cfg, act)
+}
+
+// loadCatBoost loads CatBoost model from json content.
+func loadCatBoost(
+	r io.Reader,
+	numClasses int,
+	act activation.Activation,
+	opts ...LoadOption) (*inference.Ensemble, error) {
+	var doc map[string]interface{}
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+	if err := dec.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrMalformedModel, err)
+	}
+	return buildCatBoostEnsemble(doc, numClasses, act, opts...)
+}
+
+// LoadCatBoostModel loads CatBoost model saved with save_model(format="json"). Symmetric trees of depth d are
+// evaluated as complete binary trees testing the same split on each level, so every prediction and explanation
+// method is available. Only float features are supported, missing values follow the nan_value_treatment of
+// features, i.e. the value > border condition is false unless it is AsTrue. The number of classes and the loss
+// function are read from the model and the activation matching the loss function is used if act is nil.
+func LoadCatBoostModel(
+	modelPath string,
+	act activation.Activation,
+	opts ...LoadOption) (*inference.Ensemble, error) {
+	modelFile, format, err := openModelFile(modelPath)
+	if err != nil {
+		return nil, err
+	}
+	defer modelFile.Close()
+	if format != formatModelJSON {
+		return nil, fmt.Errorf("%w: %s is %s, expected a json model", ErrUnsupportedFormat, modelPath, format)
+	}
+	return loadCatBoost(modelFile, 0, act, opts...)
+}
@@ -0,0 +1,250 @@
+package xgboost
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"go/token"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/lordberre/xgboost-go/inference"
+	"github.com/lordberre/xgboost-go/protobuf"
+)
+
+// goGenerator writes the Go source of an ensemble, every identifier of the generated file starts with name so that
+// several models can be generated in the same package.
+type goGenerator struct {
+	e    *xgbEnsemble
+	name string
+	// prefix starts the unexported identifiers.
+	prefix string
+	buf    bytes.Buffer
+	// categories contains the declarations of the category sets of categorical splits.
+	categories bytes.Buffer
+}
+
+// goFloat returns the Go expression of v, float32 values are given for float32 comparisons.
+func goFloat(v float64, float32Value bool) string {
+	bits := 64
+	if float32Value {
+		v, bits = float64(float32(v)), 32
+	}
+	var r string
+	switch {
+	case math.IsInf(v, 1):
+		r = "math.Inf(1)"
+	case math.IsInf(v, -1):
+		r = "math.Inf(-1)"
+	case math.IsNaN(v):
+		r = "math.NaN()"
+	default:
+		return strconv.FormatFloat(v, 'g', -1, bits)
+	}
+	if float32Value {
+		return "float32(" + r + ")"
+	}
+	return r
+}
+
+// node writes the statements returning the leaf value reached from node idx of tree i.
+func (g *goGenerator) node(t *xgbTree, i, idx, depth int) error {
+	if idx < 0 || idx >= len(t.nodes) || t.nodes[idx] == nil {
+		return fmt.Errorf("%w: tree %d has no node %d", ErrMalformedModel, i, idx)
+	}
+	if depth > len(t.nodes) {
+		return fmt.Errorf("%w: tree %d has a cycle", ErrMalformedModel, i)
+	}
+	n := t.nodes[idx]
+	if n.Flags&isLeaf > 0 {
+		v := n.LeafValues
+		if t.float32Values {
+			// predictions sum leaf values rounded to float32 in float64.
+			v = float64(float32(v))
+		}
+		fmt.Fprintf(&g.buf, "return %s\n", goFloat(v, false))
+		return nil
+	}
+	var cond string
+	switch {
+	case n.Flags&isCategorical > 0:
+		set := fmt.Sprintf("%sTree%dNode%dCategories", g.prefix, i, idx)
+		categories := make([]int, 0, len(n.Categories))
+		for c := range n.Categories {
+			categories = append(categories, c)
+		}
+		sort.Ints(categories)
+		fmt.Fprintf(&g.categories, "var %s = map[int]bool{", set)
+		for j, c := range categories {
+			if j > 0 {
+				g.categories.WriteString(", ")
+			}
+			fmt.Fprintf(&g.categories, "%d: true", c)
+		}
+		g.categories.WriteString("}\n")
+		cond = fmt.Sprintf("v >= 0 && v < %d && %s[int(v)]", maxCategory, set)
+	case t.float32Comparison || t.float32Values:
+		cond = "float32(v) < " + goFloat(n.Threshold, true)
+	default:
+		cond = "v < " + goFloat(n.Threshold, false)
+	}
+	zero := ""
+	if n.Flags&isZeroMissing > 0 {
+		zero = fmt.Sprintf("v >= %s && v <= %s", goFloat(-zeroThreshold, false), goFloat(zeroThreshold, false))
+	}
+	switch {
+	case n.Missing == n.Yes && zero == "":
+		cond = "!ok || " + cond
+	case n.Missing == n.Yes:
+		cond = "!ok || (" + zero + ") || " + cond
+	case n.Missing == n.No && zero == "":
+		cond = "ok && " + cond
+	case n.Missing == n.No:
+		cond = "ok && !(" + zero + ") && " + cond
+	default:
+		return fmt.Errorf("%w: tree %d node %d missing child is neither the yes nor the no child",
+			ErrUnsupportedFormat, i, idx)
+	}
+	fmt.Fprintf(&g.buf, "if v, ok := %sFeature(features, %d); %s {\n", g.prefix, n.Feature+g.e.indexBase, cond)
+	if err := g.node(t, i, n.Yes, depth+1); err != nil {
+		return err
+	}
+	g.buf.WriteString("}\n")
+	return g.node(t, i, n.No, depth+1)
+}
+
+// activation writes the statements of the prediction function, which applies the activation to the margins.
+func (g *goGenerator) activation(act protobuf.ActivateType, name string) (int, error) {
+	k := g.e.numClasses
+	fmt.Fprintf(&g.buf, "m := %sMargin(features)\n", g.name)
+	switch act {
+	case protobuf.ActivateType_RAW:
+		g.buf.WriteString("return m\n")
+	case protobuf.ActivateType_LOGISTIC:
+		if k != 1 {
+			return 0, fmt.Errorf("%w: logistic activation with %d classes cannot be exported to go",
+				ErrUnsupportedFormat, k)
+		}
+		g.buf.WriteString("m[0] = 1.0 / (1.0 + math.Exp(-m[0]))\nreturn m\n")
+	case protobuf.ActivateType_SOFTMAX:
+		g.buf.WriteString(`sum := 0.0
+for i, v := range m {
+	m[i] = math.Exp(v)
+	sum += m[i]
+}
+if sum != 0.0 {
+	inverseSum := 1.0 / sum
+	for i := range m {
+		m[i] *= inverseSum
+	}
+}
+return m
+`)
+	case protobuf.ActivateType_SOFTMAX_CLASS:
+		g.buf.WriteString(`maxVal, r := math.Inf(-1), 0
+for i, v := range m {
+	if v > maxVal {
+		maxVal, r = v, i
+	}
+}
+return [1]float64{float64(r)}
+`)
+		return 1, nil
+	case protobuf.ActivateType_EXP, protobuf.ActivateType_GAMMA, protobuf.ActivateType_TWEEDIE,
+		protobuf.ActivateType_SURVIVAL_AFT:
+		g.buf.WriteString("for i, v := range m {\nm[i] = math.Exp(v)\n}\nreturn m\n")
+	default:
+		return 0, fmt.Errorf("%w: %s activation cannot be exported to go", ErrUnsupportedFormat, name)
+	}
+	return k, nil
+}
+
+// generate writes the whole file in g.buf before formatting it.
+func (g *goGenerator) generate(packageName string, act protobuf.ActivateType, actName string) ([]byte, error) {
+	e := g.e
+	numOutputs, err := g.activation(act, actName)
+	if err != nil {
+		return nil, err
+	}
+	predict := g.buf.String()
+	g.buf.Reset()
+
+	fmt.Fprintf(&g.buf, "// Code generated by xgboost-go from a %s model. DO NOT EDIT.\n\n", e.name)
+	fmt.Fprintf(&g.buf, "package %s\n\nimport \"math\"\n\n", packageName)
+	fmt.Fprintf(&g.buf, "// %sNumFeatures is the number of features of the model.\n", g.name)
+	fmt.Fprintf(&g.buf, "const %sNumFeatures = %d\n\n", g.name, e.numFeat)
+	fmt.Fprintf(&g.buf, "// %s returns the predictions of a dense row of features like\n", g.name)
+	g.buf.WriteString("// xgboost-go PredictProbaDense, NaN values and features past the end of features are missing.\n")
+	if e.indexBase != 0 {
+		fmt.Fprintf(&g.buf, "// Feature i of the model is read at features[i+%d] like in the sparse rows of the model.\n",
+			e.indexBase)
+	}
+	fmt.Fprintf(&g.buf, "func %s(features []float64) [%d]float64 {\n%s}\n\n", g.name, numOutputs, predict)
+	fmt.Fprintf(&g.buf, "// %sMargin returns the raw score of each class for a dense row of features.\n", g.name)
+	fmt.Fprintf(&g.buf, "func %sMargin(features []float64) [%d]float64 {\n", g.name, e.numClasses)
+	fmt.Fprintf(&g.buf, "var m [%d]float64\n", e.numClasses)
+	if e.baseMargin != 0 {
+		fmt.Fprintf(&g.buf, "for i := range m {\nm[i] = %s\n}\n", goFloat(e.baseMargin, false))
+	}
+	for i := range e.Trees {
+		fmt.Fprintf(&g.buf, "m[%d] += %sTree%d(features)\n", i%e.numClasses, g.prefix, i)
+	}
+	g.buf.WriteString("return m\n}\n\n")
+
+	fmt.Fprintf(&g.buf, "// %sFeature returns feature idx of features and false if it is missing.\n", g.prefix)
+	fmt.Fprintf(&g.buf, "func %sFeature(features []float64, idx int) (float64, bool) {\n", g.prefix)
+	g.buf.WriteString("if idx >= len(features) || math.IsNaN(features[idx]) {\nreturn 0, false\n}\n")
+	if e.missing != nil {
+		fmt.Fprintf(&g.buf, "if features[idx] == %s {\nreturn 0, false\n}\n", goFloat(*e.missing, false))
+	}
+	g.buf.WriteString("return features[idx], true\n}\n")
+
+	for i, t := range e.Trees {
+		fmt.Fprintf(&g.buf, "\nfunc %sTree%d(features []float64) float64 {\n", g.prefix, i)
+		if err := g.node(t, i, 0, 0); err != nil {
+			return nil, err
+		}
+		g.buf.WriteString("}\n")
+	}
+	if g.categories.Len() > 0 {
+		g.buf.WriteString("\n")
+		g.buf.Write(g.categories.Bytes())
+	}
+	return format.Source(g.buf.Bytes())
+}
+
+// ExportGo writes ensemble as a standalone Go source file of package packageName, so that latency critical
+// users can compile the model into their binary instead of loading it. Each tree is generated as nested if/else
+// statements. The file defines name(features []float64) [N]float64 returning the predictions of a dense row
+// like PredictProbaDense, nameMargin returning the raw score of each class and the nameNumFeatures constant,
+// name must be an exported identifier. Thresholds are compared in float32 and leaf values rounded to float32 like
+// the ensemble does with WithFloat32Comparison or WithFloat32Values. With WithOneBasedFeatureIndices features are
+// indexed like the sparse rows of the ensemble, feature i of the model being read at features[i+1]. Raw, logistic,
+// softmax and exponential activations are supported, other activations return an ErrUnsupportedFormat error.
+func ExportGo(ensemble *inference.Ensemble, w io.Writer, packageName, name string) error {
+	e, ok := ensemble.EnsembleBase.(*xgbEnsemble)
+	if !ok {
+		return fmt.Errorf("%s model cannot be exported to go", ensemble.Name())
+	}
+	if !token.IsIdentifier(packageName) {
+		return fmt.Errorf("invalid package name %q", packageName)
+	}
+	if !token.IsIdentifier(name) || !token.IsExported(name) {
+		return fmt.Errorf("function name %q must be an exported identifier", name)
+	}
+	if e.numClasses <= 0 {
+		return fmt.Errorf("0 class please check your model")
+	}
+	r, size := utf8.DecodeRuneInString(name)
+	g := &goGenerator{e: e, name: name, prefix: string(unicode.ToLower(r)) + name[size:]}
+	src, err := g.generate(packageName, ensemble.Type(), ensemble.Activation.Name())
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(src)
+	return err
+}
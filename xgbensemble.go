@@ -1,14 +1,73 @@
 package xgboost
 
 import (
+	"fmt"
+
+	"github.com/lordberre/xgboost-go/activation"
+	"github.com/lordberre/xgboost-go/inference"
 	"github.com/lordberre/xgboost-go/mat"
 )
 
+// xgbEnsemble is the base model shared by all loaders. It is never modified once loaded, so predictions are safe
+// for concurrent use.
 type xgbEnsemble struct {
 	Trees      []*xgbTree
 	name       string
 	numClasses int
 	numFeat    int
+	// missing is the value treated as missing in addition to NaN and absent features, nil if there is none.
+	missing *float64
+	// baseMargin is added to the raw score of every class, it is the margin of base_score for models loaded with
+	// their parameters and 0 for json dumps which do not contain it.
+	baseMargin float64
+	// absentAsZero treats absent features of sparse rows as zeros instead of missing, like LightGBM does.
+	absentAsZero bool
+	// featureNames contains the name of each feature, nil if the model does not name them.
+	featureNames []string
+	// featureIndex maps the feature names to their index, nil if the model does not name its features.
+	featureIndex map[string]int
+	// cuts contains the sorted split thresholds of each feature of quantized ensembles, nil otherwise.
+	cuts [][]float64
+	// quantizedFloat32 is set when cuts are rounded to float32 for float32 comparisons.
+	quantizedFloat32 bool
+	// objective is the XGBoost objective of the model, empty if it is unknown.
+	objective string
+	// baseScore is the base_score stored in the model, nil if the model does not store it.
+	baseScore *float64
+	// attributes contains the attributes saved with the model, like best_iteration.
+	attributes map[string]string
+	// validateDimensions rejects rows with feature indices out of [0, numFeat) and dense rows of another width.
+	validateDimensions bool
+	// indexBase is the index of the first feature in sparse rows, 1 for models loaded with
+	// WithOneBasedFeatureIndices and 0 otherwise.
+	indexBase int
+	// parallelTrees is the num_parallel_tree of boosted random forests, the number of trees of each class in a
+	// boosting round. 0 is the same as 1.
+	parallelTrees int
+}
+
+// ensemble flattens the trees of e, which must not be modified afterwards, and returns the inference ensemble
+// using act.
+func (e *xgbEnsemble) ensemble(cfg *loadConfig, act activation.Activation) (*inference.Ensemble, error) {
+	for _, t := range e.Trees {
+		t.float32Values = cfg.float32Values
+		t.flatten()
+	}
+	if e.objective == "" {
+		e.objective = cfg.objective
+	}
+	e.validateDimensions = cfg.validateDimensions
+	e.indexBase = cfg.indexBase()
+	var err error
+	if e.featureNames, e.featureIndex, err = nameFeatures(cfg, e.featureNames, e.numFeat); err != nil {
+		return nil, err
+	}
+	if cfg.quantizeThresholds {
+		if err := e.quantize(); err != nil {
+			return nil, err
+		}
+	}
+	return &inference.Ensemble{EnsembleBase: e, Activation: act}, nil
 }
 
 // Name returns name of ensemble model.
@@ -21,16 +80,339 @@ func (e *xgbEnsemble) NumClasses() int {
 	return e.numClasses
 }
 
+// NumFeatures returns number of features expected by this ensemble model.
+func (e *xgbEnsemble) NumFeatures() int {
+	return e.numFeat
+}
+
+// Objective returns the XGBoost objective of the model, empty if the model does not store it and no objective was
+// given with WithObjective.
+func (e *xgbEnsemble) Objective() string {
+	return e.objective
+}
+
+// BaseScore returns the base_score of the model in the output space of its objective. Models which do not store it
+// return the base score equivalent to their base margin.
+func (e *xgbEnsemble) BaseScore() float64 {
+	if e.baseScore != nil {
+		return *e.baseScore
+	}
+	return marginToProb(e.objective, e.baseMargin)
+}
+
+// NumTrees returns the number of trees of the model, once the iteration range is applied.
+func (e *xgbEnsemble) NumTrees() int {
+	return len(e.Trees)
+}
+
+// Attributes returns the attributes saved with the model, nil if there is none.
+func (e *xgbEnsemble) Attributes() map[string]string {
+	return e.attributes
+}
+
 // PredictInner returns prediction of this ensemble model.
+// For multiclass models XGBoost grows one tree per class in each boosting round, so tree i contributes to class
+// i % numClasses. Element k of the returned vector is therefore the raw score of class k, matching the column order
+// of XGBoost predict(output_margin=False).
 func (e *xgbEnsemble) PredictInner(features mat.SparseVector) (mat.Vector, error) {
+	lookup, err := e.lookup(features)
+	if err != nil {
+		return mat.Vector{}, err
+	}
+	return e.predictInner(lookup, e.newPrediction())
+}
+
+// PredictInnerWithBaseMargin returns prediction of this ensemble model starting from baseMargin instead of
+// base_score, baseMargin has one value per class.
+func (e *xgbEnsemble) PredictInnerWithBaseMargin(features mat.SparseVector, baseMargin mat.Vector) (mat.Vector,
+	error) {
+	if len(baseMargin) != e.numClasses {
+		return mat.Vector{}, fmt.Errorf("%w: base margin has %d values for %d classes", mat.ErrDimensionMismatch,
+			len(baseMargin), e.numClasses)
+	}
+	lookup, err := e.lookup(features)
+	if err != nil {
+		return mat.Vector{}, err
+	}
+	pred := make(mat.Vector, e.numClasses)
+	copy(pred, baseMargin)
+	return e.predictInner(lookup, pred)
+}
+
+// PredictInnerDense returns prediction of this ensemble model for a dense row where NaN values are missing.
+func (e *xgbEnsemble) PredictInnerDense(features mat.Vector) (mat.Vector, error) {
+	if err := e.checkWidth(len(features)); err != nil {
+		return mat.Vector{}, err
+	}
+	return e.predictInner(denseLookup(features, e.missing), e.newPrediction())
+}
+
+// PredictInnerCSR returns prediction of this ensemble model for a row of a CSR matrix with sorted indices.
+func (e *xgbEnsemble) PredictInnerCSR(indices []int, values []float64) (mat.Vector, error) {
+	if err := checkCSRIndices(indices, e.indexBase, e.numFeat, e.validateDimensions); err != nil {
+		return mat.Vector{}, err
+	}
+	lookup := csrLookup(indices, values, e.missing)
+	if e.absentAsZero {
+		lookup = zeroCSRLookup(indices, values, e.missing)
+	}
+	return e.predictInner(shiftedLookup(lookup, e.indexBase), e.newPrediction())
+}
+
+// PredictInnerFloat32 returns prediction of this ensemble model for a dense row of float32 features where NaN
+// values are missing.
+func (e *xgbEnsemble) PredictInnerFloat32(features []float32) (mat.Vector, error) {
+	if err := e.checkWidth(len(features)); err != nil {
+		return mat.Vector{}, err
+	}
+	return e.predictInner(dense32Lookup(features, e.missing), e.newPrediction())
+}
+
+// PredictRowInner returns the raw prediction of a single output model for a dense row where NaN values are
+// missing, without allocating.
+func (e *xgbEnsemble) PredictRowInner(features []float64) (float64, error) {
+	if err := e.checkWidth(len(features)); err != nil {
+		return 0, err
+	}
+	return e.predictRow(denseLookup(features, e.missing))
+}
+
+// PredictSparseRowInner returns the raw prediction of a single output model for a sparse row without allocating.
+func (e *xgbEnsemble) PredictSparseRowInner(features mat.SparseVector) (float64, error) {
+	if e.indexBase != 0 {
+		lookup, err := e.lookup(features)
+		if err != nil {
+			return 0, err
+		}
+		return e.predictRow(lookup)
+	}
+	if err := e.checkIndices(features); err != nil {
+		return 0, err
+	}
+	if e.absentAsZero {
+		return e.predictRow(zeroSparseLookup(features, e.missing))
+	}
+	return e.predictRow(sparseLookup(features, e.missing))
+}
+
+// predictRow sums the leaf values of every tree of a single output model.
+func (e *xgbEnsemble) predictRow(features featureLookup) (float64, error) {
+	if e.numClasses != 1 {
+		return 0, fmt.Errorf("single row prediction requires a single output model, got %d classes", e.numClasses)
+	}
+	pred := e.baseMargin
+	for _, t := range e.Trees {
+		p, err := t.predict(features)
+		if err != nil {
+			return 0, err
+		}
+		pred += p
+	}
+	return pred, nil
+}
+
+// PredictInnerWithStats returns prediction of this ensemble model and the number of visited tree nodes.
+func (e *xgbEnsemble) PredictInnerWithStats(features mat.SparseVector) (mat.Vector, int, error) {
+	lookup, err := e.lookup(features)
+	if err != nil {
+		return mat.Vector{}, 0, err
+	}
+	pred := e.newPrediction()
+	total := 0
+	for i, t := range e.Trees {
+		idx, visited, err := t.leaf(lookup)
+		if err != nil {
+			return mat.Vector{}, 0, err
+		}
+		pred[i%e.numClasses] += t.flat.value(idx)
+		total += visited
+	}
+	return pred, total, nil
+}
+
+// PredictTreeContributionsInner returns the leaf value reached in each tree, tree i contributes to class
+// i % numClasses.
+func (e *xgbEnsemble) PredictTreeContributionsInner(features mat.SparseVector) (mat.Vector, error) {
+	lookup, err := e.lookup(features)
+	if err != nil {
+		return mat.Vector{}, err
+	}
+	r := make(mat.Vector, len(e.Trees))
+	for i, t := range e.Trees {
+		p, err := t.predict(lookup)
+		if err != nil {
+			return mat.Vector{}, fmt.Errorf("error while predicting %d tree: %w", i, err)
+		}
+		r[i] = p
+	}
+	return r, nil
+}
+
+// PredictLeafIndicesInner returns the id of the leaf reached in each tree.
+func (e *xgbEnsemble) PredictLeafIndicesInner(features mat.SparseVector) ([]int, error) {
+	lookup, err := e.lookup(features)
+	if err != nil {
+		return nil, err
+	}
+	r := make([]int, len(e.Trees))
+	for i, t := range e.Trees {
+		idx, _, err := t.leaf(lookup)
+		if err != nil {
+			return nil, fmt.Errorf("error while predicting %d tree: %w", i, err)
+		}
+		r[i] = t.nodes[idx].NodeID
+	}
+	return r, nil
+}
+
+// PredictStagedInner returns the raw predictions after each boosting round, one row per round.
+func (e *xgbEnsemble) PredictStagedInner(features mat.SparseVector) (mat.Matrix, error) {
+	lookup, err := e.lookup(features)
+	if err != nil {
+		return mat.Matrix{}, err
+	}
+	pred := e.newPrediction()
+	perRound := e.treesPerRound()
+	rounds := len(e.Trees) / perRound
+	result := mat.Matrix{Vectors: make([]*mat.Vector, rounds)}
+	for k := 0; k < rounds; k++ {
+		for i := k * perRound; i < (k+1)*perRound; i++ {
+			p, err := e.Trees[i].predict(lookup)
+			if err != nil {
+				return mat.Matrix{}, err
+			}
+			pred[i%e.numClasses] += p
+		}
+		row := make(mat.Vector, e.numClasses)
+		copy(row, pred)
+		result.Vectors[k] = &row
+	}
+	return result, nil
+}
+
+// treesPerRound returns the number of trees of a boosting round, one tree per class times the number of parallel
+// trees of random forests. Parallel trees are summed like XGBoost does, their leaves are already scaled down by
+// num_parallel_tree during training.
+func (e *xgbEnsemble) treesPerRound() int {
+	if e.parallelTrees > 1 {
+		return e.numClasses * e.parallelTrees
+	}
+	return e.numClasses
+}
+
+// lookup returns the feature lookup of a sparse row.
+func (e *xgbEnsemble) lookup(features mat.SparseVector) (featureLookup, error) {
+	if err := e.checkIndices(features); err != nil {
+		return nil, err
+	}
+	if e.absentAsZero {
+		return shiftedLookup(zeroSparseLookup(features, e.missing), e.indexBase), nil
+	}
+	return shiftedLookup(sparseLookup(features, e.missing), e.indexBase), nil
+}
+
+// checkIndices returns an error wrapping mat.ErrFeatureIndexOutOfRange if a sparse row of 1-based indices has
+// index 0 or if dimensions are validated and a feature index of a sparse row is out of the features of the model.
+func (e *xgbEnsemble) checkIndices(features mat.SparseVector) error {
+	return checkSparseIndices(features, e.indexBase, e.numFeat, e.validateDimensions)
+}
+
+// checkWidth returns an error wrapping mat.ErrDimensionMismatch if dimensions are validated and a dense row of n
+// features does not have exactly one value per feature.
+func (e *xgbEnsemble) checkWidth(n int) error {
+	if !e.validateDimensions {
+		return nil
+	}
+	return checkFeatureWidth(n, e.numFeat)
+}
+
+// checkSparseIndices returns an error wrapping mat.ErrFeatureIndexOutOfRange if a sparse row of 1-based indices,
+// base being 1, has index 0 or if validate is set and a feature index is out of [base, numFeat+base).
+func checkSparseIndices(features mat.SparseVector, base, numFeat int, validate bool) error {
+	if !validate {
+		if _, ok := features[0]; ok && base > 0 {
+			return errZeroIndex
+		}
+		return nil
+	}
+	for idx := range features {
+		if err := checkFeatureIndex(idx, base, numFeat); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkCSRIndices checks the sorted indices of a CSR row like checkSparseIndices.
+func checkCSRIndices(indices []int, base, numFeat int, validate bool) error {
+	if len(indices) == 0 {
+		return nil
+	}
+	if !validate {
+		if indices[0] == 0 && base > 0 {
+			return errZeroIndex
+		}
+		return nil
+	}
+	if err := checkFeatureIndex(indices[0], base, numFeat); err != nil {
+		return err
+	}
+	return checkFeatureIndex(indices[len(indices)-1], base, numFeat)
+}
+
+// errZeroIndex is returned for rows containing feature index 0 while indices are 1-based.
+var errZeroIndex = fmt.Errorf("%w: feature index 0 in a row of 1-based feature indices", mat.ErrFeatureIndexOutOfRange)
+
+// checkFeatureIndex returns an error wrapping mat.ErrFeatureIndexOutOfRange if idx is out of [base, numFeat+base).
+func checkFeatureIndex(idx, base, numFeat int) error {
+	if idx == 0 && base > 0 {
+		return errZeroIndex
+	}
+	if idx < base || idx >= numFeat+base {
+		return fmt.Errorf("%w: feature index %d, model has %d features", mat.ErrFeatureIndexOutOfRange, idx, numFeat)
+	}
+	return nil
+}
+
+// checkFeatureWidth returns an error wrapping mat.ErrDimensionMismatch if a dense row of n features does not have
+// numFeat features.
+func checkFeatureWidth(n, numFeat int) error {
+	if n != numFeat {
+		return fmt.Errorf("%w: row has %d features, model has %d", mat.ErrDimensionMismatch, n, numFeat)
+	}
+	return nil
+}
+
+// newPrediction returns a raw prediction initialized with the base margin.
+func (e *xgbEnsemble) newPrediction() mat.Vector {
+	pred := make(mat.Vector, e.numClasses)
+	for i := range pred {
+		pred[i] = e.baseMargin
+	}
+	return pred
+}
+
+// predictInner adds the leaf values of every tree to pred.
+func (e *xgbEnsemble) predictInner(features featureLookup, pred mat.Vector) (mat.Vector, error) {
+	if e.cuts != nil {
+		// feature values are binned once for all trees.
+		ranks := e.ranks(features)
+		for i, t := range e.Trees {
+			p, err := t.predictQuantized(ranks, features)
+			if err != nil {
+				return mat.Vector{}, err
+			}
+			pred[i%e.numClasses] += p
+		}
+		return pred, nil
+	}
 	// number of trees for 1 class.
-	pred := make([]float64, e.numClasses)
 	numTreesPerClass := len(e.Trees) / e.numClasses
 	for i := 0; i < e.numClasses; i++ {
 		for k := 0; k < numTreesPerClass; k++ {
 			p, err := e.Trees[k*e.numClasses+i].predict(features)
 			if err != nil {
-				return mat.Vector{}, nil
+				return mat.Vector{}, err
 			}
 			pred[i] += p
 		}
@@ -1,14 +1,40 @@
 package xgboost
 
 import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
 
+	"google.golang.org/protobuf/encoding/protowire"
 	"gotest.tools/assert"
 
 	"github.com/lordberre/xgboost-go/activation"
+	"github.com/lordberre/xgboost-go/calibration"
+	"github.com/lordberre/xgboost-go/inference"
 	"github.com/lordberre/xgboost-go/mat"
+	"github.com/lordberre/xgboost-go/metrics"
+	"github.com/lordberre/xgboost-go/preprocess"
+	"github.com/lordberre/xgboost-go/protobuf"
 )
 
+// raceEnabled is set when testing with the race detector.
+var raceEnabled bool
+
 func TestEnsemble_PredictBreastCancer(t *testing.T) {
 	modelPath := "test/data/breast_cancer_xgboost_dump.json"
 	ensemble, err := LoadXGBoostFromJSON(modelPath,
@@ -151,3 +177,3304 @@ func TestEnsemble_Iris(t *testing.T) {
 	err = mat.IsEqualMatrices(&predictions, &expectedProb, 0.0001)
 	assert.NilError(t, err)
 }
+
+func TestEnsemble_PartialDependence(t *testing.T) {
+	modelPath := "test/data/breast_cancer_xgboost_dump.json"
+	ensemble, err := LoadXGBoostFromJSON(modelPath,
+		"", 1, 4, &activation.Logistic{})
+	assert.NilError(t, err)
+
+	inputPath := "test/data/breast_cancer_test.libsvm"
+	input, err := mat.ReadLibsvmFileToSparseMatrix(inputPath)
+	assert.NilError(t, err)
+
+	grid := []float64{0.0, 0.1, 0.2, 0.3}
+	pdp, err := ensemble.PartialDependence(input, 27, grid)
+	assert.NilError(t, err)
+	assert.Equal(t, len(pdp), len(grid))
+
+	// background data must not be modified.
+	original, err := mat.ReadLibsvmFileToSparseMatrix(inputPath)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, input, original)
+
+	// each point is the average prediction with the feature fixed to the grid value.
+	fixed := original.Clone()
+	for _, row := range fixed.Vectors {
+		row[27] = grid[2]
+	}
+	predictions, err := ensemble.PredictProba(fixed)
+	assert.NilError(t, err)
+	sum := 0.0
+	for _, p := range predictions.Vectors {
+		sum += (*p)[0]
+	}
+	assert.Check(t, math.Abs(pdp[2]-sum/float64(len(predictions.Vectors))) < 1e-9)
+
+	// nil rows only have the varied feature.
+	withNil := mat.SparseMatrix{Vectors: []mat.SparseVector{nil, original.Vectors[0]}}
+	pdp, err = ensemble.PartialDependence(withNil, 27, grid[2:3])
+	assert.NilError(t, err)
+	predictions, err = ensemble.PredictProba(mat.SparseMatrix{Vectors: []mat.SparseVector{{27: grid[2]},
+		fixed.Vectors[0]}})
+	assert.NilError(t, err)
+	assert.Check(t, math.Abs(pdp[0]-((*predictions.Vectors[0])[0]+(*predictions.Vectors[1])[0])/2) < 1e-9)
+	assert.Check(t, withNil.Vectors[0] == nil)
+
+	_, err = ensemble.PartialDependence(mat.SparseMatrix{}, 27, grid)
+	assert.Check(t, err != nil)
+}
+
+func TestEnsemble_IrisColumnOrder(t *testing.T) {
+	modelPath := "test/data/iris_xgboost_dump.json"
+	ensemble, err := LoadXGBoostFromJSON(modelPath,
+		"", 3, 4, &activation.Softmax{})
+	assert.NilError(t, err)
+
+	inputPath := "test/data/iris_test.libsvm"
+	input, err := mat.ReadLibsvmFileToSparseMatrix(inputPath)
+	assert.NilError(t, err)
+
+	predictions, err := ensemble.PredictProba(input)
+	assert.NilError(t, err)
+
+	expectedProbPath := "test/data/iris_xgboost_true_prediction_proba.txt"
+	expectedProb, err := mat.ReadCSVFileToDenseMatrix(expectedProbPath, "\t", 0.0)
+	assert.NilError(t, err)
+
+	column := func(m mat.Matrix, k int) mat.Matrix {
+		r := mat.Matrix{Vectors: make([]*mat.Vector, len(m.Vectors))}
+		for i, v := range m.Vectors {
+			r.Vectors[i] = &mat.Vector{(*v)[k]}
+		}
+		return r
+	}
+	for k := 0; k < ensemble.NumClasses(); k++ {
+		predCol := column(predictions, k)
+		expectedCol := column(expectedProb, k)
+		err = mat.IsEqualMatrices(&predCol, &expectedCol, 0.0001)
+		assert.NilError(t, err, "class %d", k)
+	}
+}
+
+// smallModelPath is a hand written model in dump_model(with_stats=True) json format with 3 features.
+const smallModelPath = "test/data/small_xgboost_dump_stats.json"
+
+// smallModelInput contains rows with all features present and with missing features.
+var smallModelInput = mat.SparseMatrix{Vectors: []mat.SparseVector{
+	{0: 0.3, 1: 1.0, 2: 2.5},
+	{0: 0.9, 2: 0.5},
+	{1: 0.1},
+	{0: 0.5, 1: 1.5, 2: 1.0},
+}}
+
+// expectedValue returns the cover weighted expectation of tree output when only features in s are known.
+func expectedValue(t *xgbTree, idx int, x mat.SparseVector, s map[int]bool) float64 {
+	node := t.nodes[idx]
+	if node.Flags&isLeaf > 0 {
+		return node.LeafValues
+	}
+	if s[node.Feature] {
+		v, ok := x[node.Feature]
+		return expectedValue(t, t.next(idx, v, ok), x, s)
+	}
+	yes, no := t.nodes[node.Yes], t.nodes[node.No]
+	return (expectedValue(t, node.Yes, x, s)*yes.Cover + expectedValue(t, node.No, x, s)*no.Cover) / node.Cover
+}
+
+// bruteForceShapley computes exact SHAP values and SHAP interaction values by enumerating feature subsets.
+func bruteForceShapley(e *xgbEnsemble, x mat.SparseVector) (mat.Vector, [][]float64) {
+	n := e.numFeat
+	value := func(mask int) float64 {
+		s := map[int]bool{}
+		for f := 0; f < n; f++ {
+			if mask&(1<<f) != 0 {
+				s[f] = true
+			}
+		}
+		sum := 0.0
+		for _, t := range e.Trees {
+			sum += expectedValue(t, 0, x, s)
+		}
+		return sum
+	}
+	fact := func(k int) float64 {
+		r := 1.0
+		for i := 2; i <= k; i++ {
+			r *= float64(i)
+		}
+		return r
+	}
+	size := func(mask int) int {
+		c := 0
+		for ; mask > 0; mask >>= 1 {
+			c += mask & 1
+		}
+		return c
+	}
+	phi := make(mat.Vector, n)
+	inter := make([][]float64, n)
+	for i := 0; i < n; i++ {
+		inter[i] = make([]float64, n)
+		for mask := 0; mask < 1<<n; mask++ {
+			if mask&(1<<i) != 0 {
+				continue
+			}
+			k := size(mask)
+			phi[i] += fact(k) * fact(n-k-1) / fact(n) * (value(mask|1<<i) - value(mask))
+			for j := 0; j < n; j++ {
+				if j == i || mask&(1<<j) != 0 {
+					continue
+				}
+				delta := value(mask|1<<i|1<<j) - value(mask|1<<i) - value(mask|1<<j) + value(mask)
+				inter[i][j] += fact(k) * fact(n-k-2) / (2 * fact(n-1)) * delta
+			}
+		}
+	}
+	return phi, inter
+}
+
+func TestEnsemble_PredictInteractions(t *testing.T) {
+	ensemble, err := LoadXGBoostFromJSON(smallModelPath, "", 1, 0, &activation.Raw{})
+	assert.NilError(t, err)
+	base := ensemble.EnsembleBase.(*xgbEnsemble)
+
+	interactions, err := ensemble.PredictInteractions(smallModelInput)
+	assert.NilError(t, err)
+	assert.Equal(t, len(interactions), len(smallModelInput.Vectors))
+
+	margins, err := ensemble.PredictProba(smallModelInput)
+	assert.NilError(t, err)
+
+	for r, m := range interactions {
+		rows, cols, err := m.Shape()
+		assert.NilError(t, err)
+		assert.Equal(t, rows, base.numFeat+1)
+		assert.Equal(t, cols, base.numFeat+1)
+
+		phi, inter := bruteForceShapley(base, smallModelInput.Vectors[r])
+		total := 0.0
+		for i := 0; i < rows; i++ {
+			rowSum := 0.0
+			for j := 0; j < cols; j++ {
+				rowSum += (*m.Vectors[i])[j]
+				if i < base.numFeat && j < base.numFeat && i != j {
+					assert.Check(t, math.Abs((*m.Vectors[i])[j]-inter[i][j]) < 1e-9,
+						"row %d interaction (%d, %d)", r, i, j)
+					assert.Check(t, math.Abs((*m.Vectors[i])[j]-(*m.Vectors[j])[i]) < 1e-9)
+				}
+			}
+			// row sums marginalize to the main effect SHAP values.
+			if i < base.numFeat {
+				assert.Check(t, math.Abs(rowSum-phi[i]) < 1e-9, "row %d feature %d", r, i)
+			}
+			total += rowSum
+		}
+		assert.Check(t, math.Abs(total-(*margins.Vectors[r])[0]) < 1e-9)
+	}
+
+	// models without cover statistics cannot be explained.
+	ensemble, err = LoadXGBoostFromJSON("test/data/breast_cancer_xgboost_dump.json", "", 1, 4, &activation.Logistic{})
+	assert.NilError(t, err)
+	_, err = ensemble.PredictInteractions(smallModelInput)
+	assert.Check(t, err != nil)
+}
+
+func TestEnsemble_PredictProbaContext(t *testing.T) {
+	modelPath := "test/data/breast_cancer_xgboost_dump.json"
+	ensemble, err := LoadXGBoostFromJSON(modelPath,
+		"", 1, 4, &activation.Logistic{})
+	assert.NilError(t, err)
+
+	inputPath := "test/data/breast_cancer_test.libsvm"
+	input, err := mat.ReadLibsvmFileToSparseMatrix(inputPath)
+	assert.NilError(t, err)
+
+	predictions, err := ensemble.PredictProbaContext(context.Background(), input)
+	assert.NilError(t, err)
+
+	expectedPredPath := "test/data/breast_cancer_xgboost_true_prediction.txt"
+	expectedClasses, err := mat.ReadCSVFileToDenseMatrix(expectedPredPath, "\t", 0.0)
+	assert.NilError(t, err)
+
+	err = mat.IsEqualMatrices(&predictions, &expectedClasses, 0.0001)
+	assert.NilError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = ensemble.PredictProbaContext(ctx, input)
+	assert.Equal(t, err, context.Canceled)
+}
+
+func TestEnsemble_PredictRow(t *testing.T) {
+	// XGBoost models implement inference.RowPredictor, see the inference package for PredictRow itself.
+	ensemble, err := LoadXGBoostFromModelJSON("test/data/breast_cancer_xgboost_model.json", nil)
+	assert.NilError(t, err)
+	input, err := mat.ReadLibsvmFileToSparseMatrix("test/data/breast_cancer_test.libsvm")
+	assert.NilError(t, err)
+	expected, err := ensemble.PredictProba(input)
+	assert.NilError(t, err)
+
+	dense := make([]float64, ensemble.NumFeatures())
+	for i, row := range input.Vectors {
+		for j := range dense {
+			dense[j] = math.NaN()
+		}
+		for j, v := range row {
+			dense[j] = v
+		}
+		pred, err := ensemble.PredictRow(dense)
+		assert.NilError(t, err)
+		assert.Equal(t, pred, (*expected.Vectors[i])[0])
+		pred, err = ensemble.PredictSparseRow(row)
+		assert.NilError(t, err)
+		assert.Equal(t, pred, (*expected.Vectors[i])[0])
+	}
+
+	if !raceEnabled {
+		allocs := testing.AllocsPerRun(100, func() {
+			_, _ = ensemble.PredictRow(dense)
+			_, _ = ensemble.PredictSparseRow(input.Vectors[0])
+		})
+		assert.Equal(t, allocs, 0.0)
+	}
+}
+
+func TestWithFloat32Values(t *testing.T) {
+	for _, c := range []struct{ model, input string }{
+		{"test/data/breast_cancer_xgboost_model.json", "test/data/breast_cancer_test.libsvm"},
+		{"test/data/iris_xgboost_model.json", "test/data/iris_test.libsvm"},
+	} {
+		ensemble, err := LoadXGBoostModel(c.model, "", 0, 0, nil, WithFloat32Comparison())
+		assert.NilError(t, err)
+		ensemble32, err := LoadXGBoostModel(c.model, "", 0, 0, nil, WithFloat32Values())
+		assert.NilError(t, err)
+		for _, tree := range ensemble32.EnsembleBase.(*xgbEnsemble).Trees {
+			assert.Check(t, tree.flat.thresholds == nil && tree.flat.values == nil)
+		}
+		input, err := mat.ReadLibsvmFileToSparseMatrix(c.input)
+		assert.NilError(t, err)
+		expected, err := ensemble.PredictProba(input)
+		assert.NilError(t, err)
+		predictions, err := ensemble32.PredictProba(input)
+		assert.NilError(t, err)
+		assert.NilError(t, mat.IsEqualMatrices(&predictions, &expected, 1e-6), c.model)
+
+		dense := make([][]float32, len(input.Vectors))
+		for i, row := range input.Vectors {
+			dense[i] = make([]float32, ensemble.NumFeatures())
+			for j := range dense[i] {
+				if v, ok := row[j]; ok {
+					dense[i][j] = float32(v)
+				} else {
+					dense[i][j] = float32(math.NaN())
+				}
+			}
+		}
+		for _, e := range []*inference.Ensemble{ensemble, ensemble32} {
+			predictions, err = e.PredictProbaFloat32(dense)
+			assert.NilError(t, err)
+			assert.NilError(t, mat.IsEqualMatrices(&predictions, &expected, 1e-6), c.model)
+		}
+	}
+}
+
+func TestWithQuantizedThresholds(t *testing.T) {
+	breastCancer, err := mat.ReadLibsvmFileToSparseMatrix("test/data/breast_cancer_test.libsvm")
+	assert.NilError(t, err)
+	// NaN values present in sparse rows go to yes children like with float64 thresholds.
+	breastCancer.Vectors = append(breastCancer.Vectors, mat.SparseVector{0: math.NaN(), 20: math.NaN()})
+	iris, err := mat.ReadLibsvmFileToSparseMatrix("test/data/iris_test.libsvm")
+	assert.NilError(t, err)
+	lightgbmInput := mat.SparseMatrix{Vectors: []mat.SparseVector{
+		{0: 1.5, 1: 1, 2: 1}, {0: 1.6}, {0: math.NaN()}, {}, {1: 0.2}, {1: 0, 2: 3}, {1: 1, 2: 3.7}, {1: 1, 2: -1},
+		{1: 1, 2: math.NaN()},
+	}}
+	lightgbmPath := writeTempFile(t, lightgbmSmallModel)
+	for _, c := range []struct {
+		load  func(opts ...LoadOption) (*inference.Ensemble, error)
+		input mat.SparseMatrix
+		opts  []LoadOption
+	}{
+		{func(opts ...LoadOption) (*inference.Ensemble, error) {
+			return LoadXGBoostModel("test/data/breast_cancer_xgboost_model.json", "", 0, 0, nil, opts...)
+		}, breastCancer, nil},
+		{func(opts ...LoadOption) (*inference.Ensemble, error) {
+			return LoadXGBoostModel("test/data/iris_xgboost_model.json", "", 0, 0, nil, opts...)
+		}, iris, nil},
+		{func(opts ...LoadOption) (*inference.Ensemble, error) {
+			return LoadXGBoostModel("test/data/iris_xgboost_model.json", "", 0, 0, nil, opts...)
+		}, iris, []LoadOption{WithFloat32Comparison()}},
+		{func(opts ...LoadOption) (*inference.Ensemble, error) {
+			return LoadXGBoostModel("test/data/breast_cancer_xgboost_model.json", "", 0, 0, nil, opts...)
+		}, breastCancer, []LoadOption{WithFloat32Values()}},
+		{func(opts ...LoadOption) (*inference.Ensemble, error) {
+			return LoadLightGBMModel(lightgbmPath, nil, opts...)
+		}, lightgbmInput, nil},
+	} {
+		ensemble, err := c.load(c.opts...)
+		assert.NilError(t, err)
+		quantized, err := c.load(append(c.opts, WithQuantizedThresholds())...)
+		assert.NilError(t, err)
+		assert.Check(t, quantized.EnsembleBase.(*xgbEnsemble).cuts != nil)
+		for _, tree := range quantized.EnsembleBase.(*xgbEnsemble).Trees {
+			assert.Check(t, tree.flat.thresholds == nil && tree.flat.thresholds32 == nil)
+		}
+
+		expected, err := ensemble.PredictProba(c.input)
+		assert.NilError(t, err)
+		predictions, err := quantized.PredictProba(c.input)
+		assert.NilError(t, err)
+		assert.DeepEqual(t, predictions, expected)
+		expectedLeaves, err := ensemble.PredictLeafIndices(c.input)
+		assert.NilError(t, err)
+		leaves, err := quantized.PredictLeafIndices(c.input)
+		assert.NilError(t, err)
+		assert.DeepEqual(t, leaves, expectedLeaves)
+	}
+
+	tree := &xgbTree{nodes: []*xgbNode{
+		{NodeID: 0, Threshold: math.NaN(), Yes: 1, No: 2, Missing: 1},
+		{NodeID: 1, Flags: isLeaf, LeafValues: 1},
+		{NodeID: 2, Flags: isLeaf, LeafValues: 2},
+	}}
+	tree.flatten()
+	e := &xgbEnsemble{Trees: []*xgbTree{tree}, numClasses: 1, numFeat: 1}
+	err = e.quantize()
+	assert.Check(t, errors.Is(err, ErrMalformedModel))
+	assert.ErrorContains(t, err, "cannot be quantized")
+}
+
+func TestEnsemble_FeatureImportance(t *testing.T) {
+	// splits of the small model: f0 has gains 12.5 and 2.5 with covers 100 and 30, f1 has gains 4.25, 6.75 and
+	// 1.5 with covers 60, 100 and 45, f2 has gains 8 and 3 with covers 100 and 55.
+	expected := map[string]map[int]float64{
+		inference.ImportanceWeight:     {0: 2, 1: 3, 2: 2},
+		inference.ImportanceGain:       {0: 7.5, 1: 12.5 / 3, 2: 5.5},
+		inference.ImportanceCover:      {0: 65, 1: 205.0 / 3, 2: 77.5},
+		inference.ImportanceTotalGain:  {0: 15, 1: 12.5, 2: 11},
+		inference.ImportanceTotalCover: {0: 130, 1: 205, 2: 155},
+	}
+	dump, err := LoadXGBoostFromJSON("test/data/small_xgboost_dump_stats.json", "", 1, 0, &activation.Raw{})
+	assert.NilError(t, err)
+	model, err := LoadXGBoostFromModelJSON("test/data/small_xgboost_model_stats.json", nil)
+	assert.NilError(t, err)
+	for _, ensemble := range []*inference.Ensemble{dump, model} {
+		for kind, values := range expected {
+			importance, err := ensemble.FeatureImportance(kind)
+			assert.NilError(t, err)
+			assert.Check(t, importance.ByName == nil)
+			assert.Equal(t, len(importance.ByIndex), len(values))
+			for feat, v := range values {
+				assert.Check(t, math.Abs(importance.ByIndex[feat]-v) < 1e-6, "%s feature %d: %v != %v", kind, feat,
+					importance.ByIndex[feat], v)
+			}
+		}
+	}
+	_, err = dump.FeatureImportance("frequency")
+	assert.ErrorContains(t, err, "unknown importance type")
+
+	// names come from the feature map, dumps without statistics only have split counts.
+	ensemble, err := LoadXGBoostFromJSON("test/data/breast_cancer_xgboost_dump_fmap.json",
+		"test/data/breast_cancer_fmap.txt", 1, 4, &activation.Logistic{})
+	assert.NilError(t, err)
+	importance, err := ensemble.FeatureImportance(inference.ImportanceWeight)
+	assert.NilError(t, err)
+	assert.Equal(t, len(importance.ByName), len(importance.ByIndex))
+	assert.Equal(t, importance.ByName["mean_radius"], importance.ByIndex[0])
+	_, err = ensemble.FeatureImportance(inference.ImportanceGain)
+	assert.ErrorContains(t, err, "no gain statistics")
+	_, err = ensemble.FeatureImportance(inference.ImportanceCover)
+	assert.ErrorContains(t, err, "no cover statistics")
+
+	lightgbm, err := LoadLightGBMModel(writeTempFile(t, lightgbmSmallModel), nil)
+	assert.NilError(t, err)
+	importance, err = lightgbm.FeatureImportance(inference.ImportanceTotalGain)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, importance.ByName, map[string]float64{"a": 1, "b": 1, "c": 1})
+}
+
+func TestEnsemble_Trees(t *testing.T) {
+	ensemble, err := LoadXGBoostFromJSON("test/data/small_xgboost_dump_stats.json", "", 1, 0, &activation.Raw{})
+	assert.NilError(t, err)
+	trees, err := ensemble.Trees()
+	assert.NilError(t, err)
+	assert.Equal(t, len(trees), 3)
+	assert.DeepEqual(t, *trees[0].Nodes[0], inference.Node{ID: 0, Feature: 0, Threshold: 0.5, Yes: 1, No: 2,
+		Missing: 1, Gain: 12.5, Cover: 100})
+	assert.DeepEqual(t, *trees[0].Nodes[2], inference.Node{ID: 2, Leaf: true, Value: 0.3, Cover: 40})
+	// trees are copies.
+	trees[0].Nodes[2].Value = 1
+	trees, err = ensemble.Trees()
+	assert.NilError(t, err)
+	assert.Equal(t, trees[0].Nodes[2].Value, 0.3)
+
+	var buf bytes.Buffer
+	assert.NilError(t, ensemble.DumpText(&buf, true))
+	assert.Equal(t, buf.String(), `booster[0]:
+0:[f0<0.5] yes=1,no=2,missing=1,gain=12.5,cover=100
+	1:[f1<1.5] yes=3,no=4,missing=4,gain=4.25,cover=60
+		3:leaf=-0.2,cover=35
+		4:leaf=0.1,cover=25
+	2:leaf=0.3,cover=40
+booster[1]:
+0:[f2<2] yes=1,no=2,missing=2,gain=8,cover=100
+	1:leaf=-0.15,cover=70
+	2:[f0<0.8] yes=3,no=4,missing=3,gain=2.5,cover=30
+		3:leaf=0.05,cover=12
+		4:leaf=0.4,cover=18
+booster[2]:
+0:[f1<0.5] yes=1,no=2,missing=1,gain=6.75,cover=100
+	1:[f1<0.2] yes=3,no=4,missing=3,gain=1.5,cover=45
+		3:leaf=0.2,cover=20
+		4:leaf=-0.1,cover=25
+	2:[f2<1] yes=5,no=6,missing=6,gain=3,cover=55
+		5:leaf=0.25,cover=30
+		6:leaf=-0.3,cover=25
+`)
+
+	// categorical splits list their categories and features are named by the model.
+	lightgbm, err := LoadLightGBMModel(writeTempFile(t, lightgbmSmallModel), nil)
+	assert.NilError(t, err)
+	trees, err = lightgbm.Trees()
+	assert.NilError(t, err)
+	assert.Check(t, trees[0].Nodes[1].ZeroAsMissing)
+	buf.Reset()
+	assert.NilError(t, lightgbm.DumpText(&buf, false))
+	assert.Check(t, strings.Contains(buf.String(), ":[c:{1,3}] yes="), buf.String())
+}
+
+func TestEnsemble_ToDOT(t *testing.T) {
+	ensemble, err := LoadXGBoostFromJSON("test/data/small_xgboost_dump_stats.json", "", 1, 0, &activation.Raw{})
+	assert.NilError(t, err)
+	dot, err := ensemble.ToDOT(1)
+	assert.NilError(t, err)
+	assert.Equal(t, dot, `digraph {
+    graph [ rankdir=TB ]
+    0 [ label="f2<2" ]
+    0 -> 1 [label="yes" color="#0000FF"]
+    0 -> 2 [label="no, missing" color="#FF0000"]
+    1 [ label="leaf=-0.15" ]
+    2 [ label="f0<0.8" ]
+    2 -> 3 [label="yes, missing" color="#0000FF"]
+    2 -> 4 [label="no" color="#FF0000"]
+    3 [ label="leaf=0.05" ]
+    4 [ label="leaf=0.4" ]
+}
+`)
+	_, err = ensemble.ToDOT(3)
+	assert.ErrorContains(t, err, "out of range")
+
+	ensemble, err = LoadXGBoostFromJSON("test/data/breast_cancer_xgboost_dump_fmap.json",
+		"test/data/breast_cancer_fmap.txt", 1, 4, &activation.Logistic{})
+	assert.NilError(t, err)
+	dot, err = ensemble.ToDOT(0)
+	assert.NilError(t, err)
+	trees, err := ensemble.Trees()
+	assert.NilError(t, err)
+	assert.Check(t, strings.Contains(dot, fmt.Sprintf(`0 [ label="%s<`,
+		ensemble.EnsembleBase.(*xgbEnsemble).featureNames[trees[0].Nodes[0].Feature])), dot)
+}
+
+func TestEnsemble_Metadata(t *testing.T) {
+	ensemble, err := LoadXGBoostFromModelJSON("test/data/small_xgboost_model_stats.json", nil)
+	assert.NilError(t, err)
+	assert.Equal(t, ensemble.NumFeatures(), 3)
+	assert.Equal(t, ensemble.NumClasses(), 1)
+	assert.Equal(t, ensemble.Objective(), "reg:squarederror")
+	assert.Equal(t, ensemble.BaseScore(), 0.5)
+	assert.Equal(t, ensemble.NumTrees(), 3)
+	assert.DeepEqual(t, ensemble.Attributes(), map[string]string{"best_iteration": "1", "best_score": "0.25"})
+	bestIteration, ok := ensemble.Attribute("best_iteration")
+	assert.Check(t, ok)
+	assert.Equal(t, bestIteration, "1")
+	_, ok = ensemble.Attribute("best_ntree_limit")
+	assert.Check(t, !ok)
+	// the returned attributes are a copy.
+	ensemble.Attributes()["best_iteration"] = "2"
+	bestIteration, _ = ensemble.Attribute("best_iteration")
+	assert.Equal(t, bestIteration, "1")
+
+	ensemble, err = LoadXGBoostFromModelJSON("test/data/iris_xgboost_model.json", nil, WithIterationRange(0, 4))
+	assert.NilError(t, err)
+	assert.Equal(t, ensemble.Objective(), "multi:softprob")
+	assert.Equal(t, ensemble.NumTrees(), 12)
+	assert.Check(t, ensemble.Attributes() == nil)
+
+	// dumps do not store the base score, it is the one of their base margin.
+	ensemble, err = LoadXGBoostFromJSON("test/data/breast_cancer_xgboost_dump.json", "", 1, 4, nil,
+		WithObjective("binary:logistic"))
+	assert.NilError(t, err)
+	assert.Equal(t, ensemble.Objective(), "binary:logistic")
+	assert.Equal(t, ensemble.BaseScore(), 0.5)
+	ensemble, err = LoadXGBoostFromJSON("test/data/breast_cancer_xgboost_dump.json", "", 1, 4, &activation.Logistic{})
+	assert.NilError(t, err)
+	assert.Equal(t, ensemble.Objective(), "")
+	assert.Equal(t, ensemble.BaseScore(), 0.0)
+
+	ensemble, err = LoadLightGBMModel("test/data/breast_cancer_lightgbm_model.txt", nil)
+	assert.NilError(t, err)
+	assert.Equal(t, ensemble.Objective(), "binary:logistic")
+}
+
+func TestWithOneBasedFeatureIndices(t *testing.T) {
+	modelPath := "test/data/breast_cancer_xgboost_model.json"
+	ensemble, err := LoadXGBoostFromModelJSON(modelPath, nil)
+	assert.NilError(t, err)
+	names := make([]string, ensemble.NumFeatures())
+	for i := range names {
+		names[i] = fmt.Sprintf("f%d", i)
+	}
+	oneBased, err := LoadXGBoostFromModelJSON(modelPath, nil, WithOneBasedFeatureIndices(), WithFeatureNames(names))
+	assert.NilError(t, err)
+	input, err := mat.ReadLibsvmFileToSparseMatrix("test/data/breast_cancer_test.libsvm")
+	assert.NilError(t, err)
+	shifted := mat.SparseMatrix{Vectors: make([]mat.SparseVector, len(input.Vectors))}
+	for i, row := range input.Vectors {
+		shifted.Vectors[i] = mat.SparseVector{}
+		for idx, v := range row {
+			shifted.Vectors[i][idx+1] = v
+		}
+	}
+	expected, err := ensemble.PredictProba(input)
+	assert.NilError(t, err)
+	predictions, err := oneBased.PredictProba(shifted)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, predictions, expected)
+	predictions, err = oneBased.PredictProbaCSR(shifted.ToCSR())
+	assert.NilError(t, err)
+	assert.DeepEqual(t, predictions, expected)
+	p, err := oneBased.PredictSparseRow(shifted.Vectors[0])
+	assert.NilError(t, err)
+	assert.Equal(t, p, (*expected.Vectors[0])[0])
+	leaves, err := oneBased.PredictLeafIndices(shifted)
+	assert.NilError(t, err)
+	expectedLeaves, err := ensemble.PredictLeafIndices(input)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, leaves, expectedLeaves)
+	idx, ok := oneBased.FeatureIndex("f3")
+	assert.Check(t, ok)
+	assert.Equal(t, idx, 4)
+
+	// 0-based rows are rejected instead of being shifted.
+	_, err = oneBased.PredictProba(input)
+	assert.Check(t, errors.Is(err, mat.ErrFeatureIndexOutOfRange))
+	_, err = oneBased.PredictProbaCSR(input.ToCSR())
+	assert.Check(t, errors.Is(err, mat.ErrFeatureIndexOutOfRange))
+	_, err = oneBased.PredictSparseRow(mat.SparseVector{0: 1})
+	assert.Check(t, errors.Is(err, mat.ErrFeatureIndexOutOfRange))
+
+	validated, err := LoadXGBoostFromModelJSON(modelPath, nil, WithOneBasedFeatureIndices(),
+		WithDimensionValidation())
+	assert.NilError(t, err)
+	_, err = validated.PredictProba(mat.SparseMatrix{Vectors: []mat.SparseVector{{validated.NumFeatures(): 1}}})
+	assert.NilError(t, err)
+	_, err = validated.PredictProba(mat.SparseMatrix{Vectors: []mat.SparseVector{{validated.NumFeatures() + 1: 1}}})
+	assert.Check(t, errors.Is(err, mat.ErrFeatureIndexOutOfRange))
+
+	linearPath := writeTempFile(t, `{"learner": {
+  "gradient_booster": {"name": "gblinear", "model": {"weights": [0.5, -1, 2, 0.25]}},
+  "learner_model_param": {"base_score": "5E-1", "num_class": "0", "num_feature": "3"},
+  "objective": {"name": "binary:logistic"}
+}}`)
+	linear, err := LoadXGBoostFromModelJSON(linearPath, &activation.Logistic{}, WithOneBasedFeatureIndices())
+	assert.NilError(t, err)
+	margins, err := linear.PredictMargin(mat.SparseMatrix{Vectors: []mat.SparseVector{{1: 1, 2: 2}, {3: 1.5}}})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, margins, mat.Matrix{Vectors: []*mat.Vector{{-1.25}, {3.25}}})
+	_, err = linear.PredictMargin(mat.SparseMatrix{Vectors: []mat.SparseVector{{0: 1}}})
+	assert.Check(t, errors.Is(err, mat.ErrFeatureIndexOutOfRange))
+}
+
+func TestWithDimensionValidation(t *testing.T) {
+	modelPath := "test/data/breast_cancer_xgboost_model.json"
+	ensemble, err := LoadXGBoostFromModelJSON(modelPath, nil)
+	assert.NilError(t, err)
+	validated, err := LoadXGBoostFromModelJSON(modelPath, nil, WithDimensionValidation())
+	assert.NilError(t, err)
+	input, err := mat.ReadLibsvmFileToSparseMatrix("test/data/breast_cancer_test.libsvm")
+	assert.NilError(t, err)
+	expected, err := ensemble.PredictProba(input)
+	assert.NilError(t, err)
+	proba, err := validated.PredictProba(input)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, proba, expected)
+
+	numFeat := validated.NumFeatures()
+	for _, idx := range []int{numFeat, -1} {
+		row := mat.SparseVector{0: 1, idx: 1}
+		_, err = ensemble.PredictProba(mat.SparseMatrix{Vectors: []mat.SparseVector{row}})
+		assert.NilError(t, err)
+		_, err = validated.PredictProba(mat.SparseMatrix{Vectors: []mat.SparseVector{row}})
+		assert.Check(t, errors.Is(err, mat.ErrFeatureIndexOutOfRange), err)
+		_, err = validated.PredictSparseRow(row)
+		assert.Check(t, errors.Is(err, mat.ErrFeatureIndexOutOfRange), err)
+		_, err = validated.PredictContributions(mat.SparseMatrix{Vectors: []mat.SparseVector{row}})
+		assert.Check(t, errors.Is(err, mat.ErrFeatureIndexOutOfRange), err)
+	}
+
+	_, err = validated.PredictRow(make([]float64, numFeat))
+	assert.NilError(t, err)
+	for _, width := range []int{numFeat - 1, numFeat + 1} {
+		_, err = ensemble.PredictRow(make([]float64, width))
+		assert.NilError(t, err)
+		_, err = validated.PredictRow(make([]float64, width))
+		assert.Check(t, errors.Is(err, mat.ErrDimensionMismatch), err)
+		row := make(mat.Vector, width)
+		_, err = validated.PredictProbaDense(mat.Matrix{Vectors: []*mat.Vector{&row}})
+		assert.Check(t, errors.Is(err, mat.ErrDimensionMismatch), err)
+		_, err = validated.PredictProbaFloat32([][]float32{make([]float32, width)})
+		assert.Check(t, errors.Is(err, mat.ErrDimensionMismatch), err)
+	}
+}
+
+func TestEnsemble_PredictByName(t *testing.T) {
+	input, err := mat.ReadLibsvmFileToSparseMatrix("test/data/breast_cancer_test.libsvm")
+	assert.NilError(t, err)
+	featMap, _, err := loadFeatureMap("test/data/breast_cancer_fmap.txt")
+	assert.NilError(t, err)
+	names := featureMapNames(featMap)
+	ensemble, err := LoadXGBoostFromModelJSON("test/data/breast_cancer_xgboost_model.json", nil,
+		WithFeatureMap("test/data/breast_cancer_fmap.txt"))
+	assert.NilError(t, err)
+	expected, err := ensemble.PredictProba(input)
+	assert.NilError(t, err)
+	for i, row := range input.Vectors[:10] {
+		named := map[string]float64{}
+		for idx, v := range row {
+			named[names[idx]] = v
+		}
+		pred, err := ensemble.PredictByName(named)
+		assert.NilError(t, err)
+		assert.DeepEqual(t, pred, *expected.Vectors[i])
+	}
+	idx, ok := ensemble.FeatureIndex("mean_texture")
+	assert.Check(t, ok)
+	assert.Equal(t, idx, 1)
+	_, err = ensemble.PredictByName(map[string]float64{"mean_radius": 1, "radius": 2})
+	assert.Check(t, errors.Is(err, inference.ErrUnknownFeature), err)
+
+	// names given with WithFeatureNames override the ones of the model.
+	custom := make([]string, ensemble.NumFeatures())
+	for i := range custom {
+		custom[i] = fmt.Sprintf("x%d", i)
+	}
+	ensemble, err = LoadXGBoostFromModelJSON("test/data/breast_cancer_xgboost_model.json", nil,
+		WithFeatureMap("test/data/breast_cancer_fmap.txt"), WithFeatureNames(custom))
+	assert.NilError(t, err)
+	pred, err := ensemble.PredictByName(map[string]float64{"x0": input.Vectors[0][0]})
+	assert.NilError(t, err)
+	assert.Equal(t, len(pred), 1)
+	_, ok = ensemble.FeatureIndex("mean_radius")
+	assert.Check(t, !ok)
+
+	_, err = LoadXGBoostFromModelJSON("test/data/breast_cancer_xgboost_model.json", nil,
+		WithFeatureNames(custom[:3]))
+	assert.ErrorContains(t, err, "3 feature names")
+	_, err = LoadXGBoostFromModelJSON("test/data/breast_cancer_xgboost_model.json", nil,
+		WithFeatureNames(append([]string{"x1"}, custom[1:]...)))
+	assert.ErrorContains(t, err, "duplicate feature name x1")
+	ensemble, err = LoadXGBoostFromModelJSON("test/data/breast_cancer_xgboost_model.json", nil)
+	assert.NilError(t, err)
+	_, err = ensemble.PredictByName(map[string]float64{"f0": 1})
+	assert.ErrorContains(t, err, "does not name its features")
+}
+
+func TestEnsemble_ConcurrentPredictions(t *testing.T) {
+	ensemble, err := LoadXGBoostFromModelJSON("test/data/iris_xgboost_model.json", nil)
+	assert.NilError(t, err)
+	input, err := mat.ReadLibsvmFileToSparseMatrix("test/data/iris_test.libsvm")
+	assert.NilError(t, err)
+	proba, err := ensemble.PredictProba(input)
+	assert.NilError(t, err)
+	margin, err := ensemble.PredictMargin(input)
+	assert.NilError(t, err)
+	staged, err := ensemble.PredictStaged(input)
+	assert.NilError(t, err)
+	leaves, err := ensemble.PredictLeafIndices(input)
+	assert.NilError(t, err)
+	cached, err := inference.NewCachedPredictor(ensemble, 10)
+	assert.NilError(t, err)
+
+	// run with -race to check that predictions only read the shared ensemble.
+	checks := []func() error{
+		func() error {
+			pred, err := ensemble.PredictProba(input)
+			if err == nil && !reflect.DeepEqual(pred, proba) {
+				err = fmt.Errorf("PredictProba mismatch")
+			}
+			return err
+		},
+		func() error {
+			pred, err := ensemble.PredictMargin(input)
+			if err == nil && !reflect.DeepEqual(pred, margin) {
+				err = fmt.Errorf("PredictMargin mismatch")
+			}
+			return err
+		},
+		func() error {
+			pred, err := ensemble.PredictStaged(input)
+			if err == nil && !reflect.DeepEqual(pred, staged) {
+				err = fmt.Errorf("PredictStaged mismatch")
+			}
+			return err
+		},
+		func() error {
+			pred, err := ensemble.PredictLeafIndices(input)
+			if err == nil && !reflect.DeepEqual(pred, leaves) {
+				err = fmt.Errorf("PredictLeafIndices mismatch")
+			}
+			return err
+		},
+		func() error {
+			pred, err := ensemble.PredictBatch(context.Background(), input, 2)
+			if err == nil && !reflect.DeepEqual(pred, proba) {
+				err = fmt.Errorf("PredictBatch mismatch")
+			}
+			return err
+		},
+		func() error {
+			pred, err := cached.PredictProba(input)
+			if err == nil && !reflect.DeepEqual(pred, proba) {
+				err = fmt.Errorf("CachedPredictor.PredictProba mismatch")
+			}
+			return err
+		},
+	}
+	const goroutines = 16
+	errs := make(chan error, goroutines)
+	var wg sync.WaitGroup
+	wg.Add(goroutines)
+	for g := 0; g < goroutines; g++ {
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 2*len(checks); i++ {
+				if err := checks[(g+i)%len(checks)](); err != nil {
+					errs <- err
+					return
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		assert.NilError(t, err)
+	}
+}
+
+func TestEnsemble_Clone(t *testing.T) {
+	ensemble, err := LoadXGBoostFromModelJSON("test/data/iris_xgboost_model.json", nil)
+	assert.NilError(t, err)
+	input, err := mat.ReadLibsvmFileToSparseMatrix("test/data/iris_test.libsvm")
+	assert.NilError(t, err)
+	expected, err := ensemble.PredictProba(input)
+	assert.NilError(t, err)
+
+	clone := ensemble.Clone()
+	clone.Activation = &activation.Raw{}
+	raw, err := clone.PredictProba(input)
+	assert.NilError(t, err)
+	margin, err := ensemble.PredictMargin(input)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, raw, margin)
+	pred, err := ensemble.PredictProba(input)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, pred, expected)
+
+	cached, err := inference.NewCachedPredictor(ensemble, 10)
+	assert.NilError(t, err)
+	_, err = cached.PredictProba(input)
+	assert.NilError(t, err)
+	assert.Equal(t, cached.Len(), 10)
+	cachedClone := cached.Clone()
+	assert.Equal(t, cachedClone.Len(), 0)
+	pred, err = cachedClone.PredictProba(input)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, pred, expected)
+}
+
+// benchmarkInput returns rows where roughly 80% are repeats of a small set of distinct rows.
+func benchmarkInput(b *testing.B) mat.SparseMatrix {
+	input, err := mat.ReadLibsvmFileToSparseMatrix("test/data/breast_cancer_test.libsvm")
+	assert.NilError(b, err)
+	rows := mat.SparseMatrix{Vectors: make([]mat.SparseVector, 0, 1000)}
+	for i := 0; i < 1000; i++ {
+		if i%5 == 0 {
+			rows.Vectors = append(rows.Vectors, input.Vectors[i%len(input.Vectors)])
+		} else {
+			rows.Vectors = append(rows.Vectors, input.Vectors[i%10])
+		}
+	}
+	return rows
+}
+
+// benchmarkEnsemble returns the breast cancer model with its trees repeated to the size of a typical
+// production model. Repeated trees are copies so that, like in a real model, they do not share memory.
+func benchmarkEnsemble(b *testing.B) *inference.Ensemble {
+	ensemble, err := LoadXGBoostFromJSON("test/data/breast_cancer_xgboost_dump.json",
+		"", 1, 4, &activation.Logistic{})
+	assert.NilError(b, err)
+	base := ensemble.EnsembleBase.(*xgbEnsemble)
+	trees := base.Trees
+	for i := 1; i < 50; i++ {
+		for _, t := range trees {
+			tree := &xgbTree{nodes: make([]*xgbNode, len(t.nodes)), float32Comparison: t.float32Comparison}
+			for j, n := range t.nodes {
+				node := *n
+				tree.nodes[j] = &node
+			}
+			tree.flatten()
+			base.Trees = append(base.Trees, tree)
+		}
+	}
+	return ensemble
+}
+
+func BenchmarkEnsemble_PredictProba(b *testing.B) {
+	ensemble := benchmarkEnsemble(b)
+	input := benchmarkInput(b)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, err := ensemble.PredictProba(input)
+		assert.NilError(b, err)
+	}
+}
+
+func BenchmarkEnsemble_PredictProbaDense(b *testing.B) {
+	ensemble := benchmarkEnsemble(b)
+	input := benchmarkInput(b)
+	dense := mat.Matrix{Vectors: make([]*mat.Vector, len(input.Vectors))}
+	for i, row := range input.Vectors {
+		vec := make(mat.Vector, ensemble.NumFeatures())
+		for j := range vec {
+			vec[j] = math.NaN()
+		}
+		for j, v := range row {
+			vec[j] = v
+		}
+		dense.Vectors[i] = &vec
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, err := ensemble.PredictProbaDense(dense)
+		assert.NilError(b, err)
+	}
+}
+
+func BenchmarkEnsemble_PredictProbaCSR(b *testing.B) {
+	ensemble := benchmarkEnsemble(b)
+	input := benchmarkInput(b).ToCSR()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, err := ensemble.PredictProbaCSR(input)
+		assert.NilError(b, err)
+	}
+}
+
+func BenchmarkEnsemble_PredictProbaQuantized(b *testing.B) {
+	ensemble := benchmarkEnsemble(b)
+	assert.NilError(b, ensemble.EnsembleBase.(*xgbEnsemble).quantize())
+	input := benchmarkInput(b)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, err := ensemble.PredictProba(input)
+		assert.NilError(b, err)
+	}
+}
+
+func BenchmarkCachedPredictor_PredictProba(b *testing.B) {
+	ensemble := benchmarkEnsemble(b)
+	cached, err := inference.NewCachedPredictor(ensemble, 128)
+	assert.NilError(b, err)
+	input := benchmarkInput(b)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, err := cached.PredictProba(input)
+		assert.NilError(b, err)
+	}
+}
+
+// writeTempFile writes content into a temporary file and returns its path.
+func writeTempFile(t *testing.T, content string) string {
+	f, err := ioutil.TempFile("", "xgboost_test")
+	assert.NilError(t, err)
+	defer f.Close()
+	_, err = f.WriteString(content)
+	assert.NilError(t, err)
+	t.Cleanup(func() { os.Remove(f.Name()) })
+	return f.Name()
+}
+
+func TestEnsemble_Float32Comparison(t *testing.T) {
+	modelPath := writeTempFile(t, `[
+  { "nodeid": 0, "depth": 0, "split": "f0", "split_condition": 0.1, "yes": 1, "no": 2, "missing": 1, "children": [
+    { "nodeid": 1, "leaf": -1 },
+    { "nodeid": 2, "leaf": 1 }
+  ]}
+]`)
+	// value is below the threshold in float64 but rounds to the same float32 threshold.
+	input := mat.SparseMatrix{Vectors: []mat.SparseVector{{0: 0.0999999999}}}
+	assert.Equal(t, float32(0.0999999999), float32(0.1))
+
+	ensemble, err := LoadXGBoostFromJSON(modelPath, "", 1, 0, &activation.Raw{})
+	assert.NilError(t, err)
+	predictions, err := ensemble.PredictProba(input)
+	assert.NilError(t, err)
+	assert.Equal(t, (*predictions.Vectors[0])[0], -1.0)
+
+	ensemble, err = LoadXGBoostFromJSON(modelPath, "", 1, 0, &activation.Raw{}, WithFloat32Comparison())
+	assert.NilError(t, err)
+	predictions, err = ensemble.PredictProba(input)
+	assert.NilError(t, err)
+	assert.Equal(t, (*predictions.Vectors[0])[0], 1.0)
+}
+
+func TestEnsemble_PredictClass(t *testing.T) {
+	ensemble, err := LoadXGBoostFromJSON("test/data/iris_xgboost_dump.json",
+		"", 3, 4, &activation.Softmax{})
+	assert.NilError(t, err)
+	input, err := mat.ReadLibsvmFileToSparseMatrix("test/data/iris_test.libsvm")
+	assert.NilError(t, err)
+	expectedClasses, err := mat.ReadCSVFileToDenseMatrix("test/data/iris_xgboost_true_prediction.txt", "\t", 0.0)
+	assert.NilError(t, err)
+
+	classes, err := ensemble.PredictClass(input)
+	assert.NilError(t, err)
+	assert.Equal(t, len(classes), len(expectedClasses.Vectors))
+	for i, c := range classes {
+		assert.Equal(t, float64(c), (*expectedClasses.Vectors[i])[0])
+	}
+
+	ensemble, err = LoadXGBoostFromJSON("test/data/breast_cancer_xgboost_dump.json",
+		"", 1, 4, &activation.Logistic{})
+	assert.NilError(t, err)
+	input, err = mat.ReadLibsvmFileToSparseMatrix("test/data/breast_cancer_test.libsvm")
+	assert.NilError(t, err)
+	probs, err := ensemble.PredictProba(input)
+	assert.NilError(t, err)
+
+	for _, threshold := range []float64{0.5, 0.9} {
+		classes, err = ensemble.PredictClassWithThreshold(input, threshold)
+		assert.NilError(t, err)
+		for i, c := range classes {
+			assert.Equal(t, c == 1, (*probs.Vectors[i])[0] >= threshold)
+		}
+	}
+}
+
+func TestModelEnsemble(t *testing.T) {
+	first, err := LoadXGBoostFromJSON("test/data/breast_cancer_xgboost_dump.json",
+		"", 1, 4, &activation.Logistic{})
+	assert.NilError(t, err)
+	second, err := LoadXGBoostFromJSON("test/data/breast_cancer_xgboost_dump_fmap.json",
+		"test/data/breast_cancer_fmap.txt", 1, 4, &activation.Logistic{})
+	assert.NilError(t, err)
+	input, err := mat.ReadLibsvmFileToSparseMatrix("test/data/breast_cancer_test.libsvm")
+	assert.NilError(t, err)
+	expected, err := first.PredictProba(input)
+	assert.NilError(t, err)
+
+	models, err := inference.NewModelEnsemble([]*inference.Ensemble{first, second}, nil)
+	assert.NilError(t, err)
+	predictions, err := models.PredictProba(input)
+	assert.NilError(t, err)
+	assert.NilError(t, mat.IsEqualMatrices(&predictions, &expected, 1e-9))
+
+	// members must share the same activation.
+	raw, err := LoadXGBoostFromJSON("test/data/breast_cancer_xgboost_dump_regression.json",
+		"", 1, 4, &activation.Raw{})
+	assert.NilError(t, err)
+	_, err = inference.NewModelEnsemble([]*inference.Ensemble{first, raw}, nil)
+	assert.Check(t, err != nil)
+
+	// weighted average of a model with itself gives the model predictions.
+	models, err = inference.NewModelEnsemble([]*inference.Ensemble{first, first}, []float64{1, 3})
+	assert.NilError(t, err)
+	predictions, err = models.PredictProba(input)
+	assert.NilError(t, err)
+	assert.NilError(t, mat.IsEqualMatrices(&predictions, &expected, 1e-9))
+
+	iris, err := LoadXGBoostFromJSON("test/data/iris_xgboost_dump.json", "", 3, 4, &activation.Softmax{})
+	assert.NilError(t, err)
+	_, err = inference.NewModelEnsemble([]*inference.Ensemble{first, iris}, nil)
+	assert.Check(t, err != nil)
+	_, err = inference.NewModelEnsemble([]*inference.Ensemble{first, second}, []float64{1})
+	assert.Check(t, err != nil)
+}
+
+func TestLoadXGBoostFromJSON_Objective(t *testing.T) {
+	modelPath := "test/data/breast_cancer_xgboost_dump.json"
+	_, err := LoadXGBoostFromJSON(modelPath, "", 1, 4, &activation.Logistic{}, WithObjective("binary:logistic"))
+	assert.NilError(t, err)
+
+	_, err = LoadXGBoostFromJSON(modelPath, "", 1, 4, &activation.Raw{}, WithObjective("binary:logistic"))
+	assert.ErrorContains(t, err, "objective binary:logistic expects LOGISTIC activation, got RAW")
+
+	_, err = LoadXGBoostFromJSON(modelPath, "", 1, 4, &activation.Raw{}, WithObjective("unknown:objective"))
+	assert.ErrorContains(t, err, "unsupported objective")
+}
+
+func TestEnsemble_IrisSoftmaxObjectives(t *testing.T) {
+	modelPath := "test/data/iris_xgboost_dump.json"
+	input, err := mat.ReadLibsvmFileToSparseMatrix("test/data/iris_test.libsvm")
+	assert.NilError(t, err)
+
+	// multi:softmax returns the predicted class.
+	ensemble, err := LoadXGBoostFromJSON(modelPath, "", 3, 4, nil, WithObjective("multi:softmax"))
+	assert.NilError(t, err)
+	assert.Equal(t, ensemble.Activation.Name(), "SOFTMAX_CLASS")
+	expectedClasses, err := mat.ReadCSVFileToDenseMatrix("test/data/iris_xgboost_true_prediction.txt", "\t", 0.0)
+	assert.NilError(t, err)
+	predictions, err := ensemble.PredictProba(input)
+	assert.NilError(t, err)
+	assert.NilError(t, mat.IsEqualMatrices(&predictions, &expectedClasses, 0))
+	predictions, err = ensemble.Predict(input)
+	assert.NilError(t, err)
+	assert.NilError(t, mat.IsEqualMatrices(&predictions, &expectedClasses, 0))
+	classes, err := ensemble.PredictClass(input)
+	assert.NilError(t, err)
+	for i, c := range classes {
+		assert.Equal(t, float64(c), (*expectedClasses.Vectors[i])[0])
+	}
+
+	// multi:softprob returns probabilities.
+	ensemble, err = LoadXGBoostFromJSON(modelPath, "", 3, 4, nil, WithObjective("multi:softprob"))
+	assert.NilError(t, err)
+	assert.Equal(t, ensemble.Activation.Name(), "SOFTMAX")
+	expectedProb, err := mat.ReadCSVFileToDenseMatrix("test/data/iris_xgboost_true_prediction_proba.txt", "\t", 0.0)
+	assert.NilError(t, err)
+	predictions, err = ensemble.PredictProba(input)
+	assert.NilError(t, err)
+	assert.NilError(t, mat.IsEqualMatrices(&predictions, &expectedProb, 0.0001))
+
+	_, err = LoadXGBoostFromJSON(modelPath, "", 3, 4, &activation.Softmax{}, WithObjective("multi:softmax"))
+	assert.Check(t, err != nil)
+	_, err = LoadXGBoostFromJSON(modelPath, "", 3, 4, nil)
+	assert.Check(t, err != nil)
+}
+
+func TestEnsemble_PredictProbaDense(t *testing.T) {
+	ensemble, err := LoadXGBoostFromJSON("test/data/breast_cancer_xgboost_dump.json",
+		"", 1, 4, &activation.Logistic{})
+	assert.NilError(t, err)
+	input, err := mat.ReadLibsvmFileToSparseMatrix("test/data/breast_cancer_test.libsvm")
+	assert.NilError(t, err)
+
+	// absent sparse features become NaN in the dense matrix.
+	dense := mat.Matrix{Vectors: make([]*mat.Vector, len(input.Vectors))}
+	for i, row := range input.Vectors {
+		v := make(mat.Vector, 30)
+		for j := range v {
+			if val, ok := row[j]; ok {
+				v[j] = val
+			} else {
+				v[j] = math.NaN()
+			}
+		}
+		dense.Vectors[i] = &v
+	}
+	expected, err := ensemble.PredictProba(input)
+	assert.NilError(t, err)
+	predictions, err := ensemble.PredictProbaDense(dense)
+	assert.NilError(t, err)
+	assert.NilError(t, mat.IsEqualMatrices(&predictions, &expected, 0))
+
+	// zero is a real value for dense input but missing once converted to sparse.
+	modelPath := writeTempFile(t, `[
+  { "nodeid": 0, "depth": 0, "split": "f0", "split_condition": -1, "yes": 1, "no": 2, "missing": 1, "children": [
+    { "nodeid": 1, "leaf": -1 },
+    { "nodeid": 2, "leaf": 1 }
+  ]}
+]`)
+	ensemble, err = LoadXGBoostFromJSON(modelPath, "", 1, 0, &activation.Raw{})
+	assert.NilError(t, err)
+	data := [][]float64{{0}}
+	sparse, err := mat.GetSparseMatrixFromSlice(data)
+	assert.NilError(t, err)
+	predictions, err = ensemble.PredictProba(sparse)
+	assert.NilError(t, err)
+	assert.Equal(t, (*predictions.Vectors[0])[0], -1.0)
+	predictions, err = ensemble.PredictProbaDense(mat.Matrix{Vectors: []*mat.Vector{{0}}})
+	assert.NilError(t, err)
+	assert.Equal(t, (*predictions.Vectors[0])[0], 1.0)
+}
+
+func TestEnsemble_PredictProbaWithStats(t *testing.T) {
+	ensemble, err := LoadXGBoostFromJSON("test/data/iris_xgboost_dump.json",
+		"", 3, 4, &activation.Softmax{})
+	assert.NilError(t, err)
+	input, err := mat.ReadLibsvmFileToSparseMatrix("test/data/iris_test.libsvm")
+	assert.NilError(t, err)
+
+	expected, err := ensemble.PredictProba(input)
+	assert.NilError(t, err)
+	predictions, stats, err := ensemble.PredictProbaWithStats(input)
+	assert.NilError(t, err)
+	assert.NilError(t, mat.IsEqualMatrices(&predictions, &expected, 0))
+	assert.Equal(t, stats.Rows, len(input.Vectors))
+
+	// every tree visits at least its root and at most max depth + 1 nodes.
+	numTrees := len(ensemble.EnsembleBase.(*xgbEnsemble).Trees)
+	assert.Check(t, stats.NodesVisited >= numTrees*len(input.Vectors))
+	assert.Check(t, stats.NodesVisited <= 5*numTrees*len(input.Vectors))
+
+	// small model visits are easy to count by hand.
+	ensemble, err = LoadXGBoostFromJSON(smallModelPath, "", 1, 0, &activation.Raw{})
+	assert.NilError(t, err)
+	_, stats, err = ensemble.PredictProbaWithStats(mat.SparseMatrix{Vectors: []mat.SparseVector{{0: 1, 2: 0}}})
+	assert.NilError(t, err)
+	assert.Equal(t, stats.NodesVisited, 2+2+3)
+}
+
+func TestEnsemble_SplitBoundary(t *testing.T) {
+	modelPath := writeTempFile(t, `[
+  { "nodeid": 0, "depth": 0, "split": "f0", "split_condition": 0.5, "yes": 1, "no": 2, "missing": 2, "children": [
+    { "nodeid": 1, "leaf": -1 },
+    { "nodeid": 2, "leaf": 1 }
+  ]}
+]`)
+	input := mat.SparseMatrix{Vectors: []mat.SparseVector{
+		{0: float64(math.Nextafter32(0.5, 0))},
+		{0: 0.5},
+		{0: math.Nextafter(0.5, 1)},
+		{},
+	}}
+	expected := mat.Matrix{Vectors: []*mat.Vector{{-1}, {1}, {1}, {1}}}
+	for _, opts := range [][]LoadOption{nil, {WithFloat32Comparison()}} {
+		ensemble, err := LoadXGBoostFromJSON(modelPath, "", 1, 0, &activation.Raw{}, opts...)
+		assert.NilError(t, err)
+		predictions, err := ensemble.PredictProba(input)
+		assert.NilError(t, err)
+		assert.NilError(t, mat.IsEqualMatrices(&predictions, &expected, 0))
+	}
+}
+
+func TestEnsemble_CategoricalSplit(t *testing.T) {
+	// color is categorical and size is numerical.
+	modelPath := writeTempFile(t, `[
+  { "nodeid": 0, "depth": 0, "split": "color", "split_condition": [1, 3], "yes": 1, "no": 2, "missing": 2, "children": [
+    { "nodeid": 1, "leaf": 1 },
+    { "nodeid": 2, "depth": 1, "split": "size", "split_condition": 3, "yes": 3, "no": 4, "missing": 3, "children": [
+      { "nodeid": 3, "leaf": -1 },
+      { "nodeid": 4, "leaf": -2 }
+    ]}
+  ]}
+]`)
+	fmapPath := writeTempFile(t, "0\tcolor\tc\n1\tsize\tq\n")
+	ensemble, err := LoadXGBoostFromJSON(modelPath, fmapPath, 1, 0, &activation.Raw{})
+	assert.NilError(t, err)
+
+	input := mat.SparseMatrix{Vectors: []mat.SparseVector{
+		{0: 1, 1: 5},
+		{0: 3, 1: 5},
+		// numerically 2 is between the categories but it is not one of them.
+		{0: 2, 1: 5},
+		{0: 2, 1: 1},
+		// fractional values are truncated like in XGBoost.
+		{0: 1.5, 1: 1},
+		{0: 3.9, 1: 5},
+		{0: 0.5, 1: 1},
+		// negative and too large values are not valid categories.
+		{0: -1, 1: 5},
+		{0: 1<<24 + 1, 1: 5},
+		{1: 5},
+	}}
+	expected := mat.Matrix{Vectors: []*mat.Vector{{1}, {1}, {-2}, {-1}, {1}, {1}, {-1}, {-2}, {-2}, {-2}}}
+	predictions, err := ensemble.PredictProba(input)
+	assert.NilError(t, err)
+	assert.NilError(t, mat.IsEqualMatrices(&predictions, &expected, 0))
+
+	// feature map types must agree with the splits.
+	fmapPath = writeTempFile(t, "0\tcolor\tq\n1\tsize\tq\n")
+	_, err = LoadXGBoostFromJSON(modelPath, fmapPath, 1, 0, &activation.Raw{})
+	assert.ErrorContains(t, err, "feature color has type q")
+	fmapPath = writeTempFile(t, "0\tcolor\tc\n1\tsize\tc\n")
+	_, err = LoadXGBoostFromJSON(modelPath, fmapPath, 1, 0, &activation.Raw{})
+	assert.ErrorContains(t, err, "feature size has type c")
+}
+
+func TestLoadXGBoostFromJSON_Errors(t *testing.T) {
+	_, err := LoadXGBoostFromJSON("test/data/does_not_exist.json", "", 1, 4, &activation.Logistic{})
+	assert.Check(t, errors.Is(err, os.ErrNotExist))
+
+	_, err = LoadXGBoostFromJSON(writeTempFile(t, "[{\"nodeid\": "), "", 1, 4, &activation.Logistic{})
+	assert.Check(t, errors.Is(err, ErrMalformedModel))
+
+	_, err = LoadXGBoostFromJSON("test/data/iris_xgboost_dump.json", "", 4, 4, &activation.Softmax{})
+	assert.Check(t, errors.Is(err, ErrMalformedModel))
+
+	_, err = LoadXGBoostFromJSON("test/data/breast_cancer_xgboost_dump.json", "", 1, 4, nil,
+		WithObjective("binary:hinge"))
+	assert.Check(t, errors.Is(err, ErrUnsupportedObjective))
+}
+
+// gzipFile compresses file at path into a temporary file and returns its path.
+func gzipFile(t *testing.T, path string) string {
+	content, err := ioutil.ReadFile(path)
+	assert.NilError(t, err)
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	_, err = w.Write(content)
+	assert.NilError(t, err)
+	assert.NilError(t, w.Close())
+	return writeTempFile(t, buf.String())
+}
+
+func TestLoadXGBoostModel(t *testing.T) {
+	modelPath := "test/data/breast_cancer_xgboost_dump.json"
+	input, err := mat.ReadLibsvmFileToSparseMatrix("test/data/breast_cancer_test.libsvm")
+	assert.NilError(t, err)
+	expected, err := mat.ReadCSVFileToDenseMatrix("test/data/breast_cancer_xgboost_true_prediction.txt", "\t", 0.0)
+	assert.NilError(t, err)
+
+	for _, path := range []string{modelPath, gzipFile(t, modelPath)} {
+		ensemble, err := LoadXGBoostModel(path, "", 1, 4, &activation.Logistic{})
+		assert.NilError(t, err)
+		predictions, err := ensemble.PredictProba(input)
+		assert.NilError(t, err)
+		assert.NilError(t, mat.IsEqualMatrices(&predictions, &expected, 0.0001))
+	}
+
+	// gzip dumps can also be read by the json dump loader.
+	_, err = LoadXGBoostFromJSON(gzipFile(t, modelPath), "", 1, 4, &activation.Logistic{})
+	assert.NilError(t, err)
+
+	_, err = LoadXGBoostModel("test/data/breast_cancer_test.libsvm", "", 1, 4, &activation.Logistic{})
+	assert.Check(t, errors.Is(err, ErrUnsupportedFormat))
+	_, err = LoadXGBoostFromJSON("test/data/breast_cancer_test.libsvm", "", 1, 4, &activation.Logistic{})
+	assert.Check(t, errors.Is(err, ErrUnsupportedFormat))
+}
+
+func TestDetectFormat(t *testing.T) {
+	cases := map[string]modelFormat{
+		"  [ {\"nodeid\": 0}]":                      formatDumpJSON,
+		"{\"learner\": {}}":                         formatModelJSON,
+		"{ \n \"version\": [1]}":                    formatModelJSON,
+		"{L\x00\x00\x00\x00\x00\x00\x00\x07learner": formatUBJSON,
+		"{i\x07learner{":                            formatUBJSON,
+		"binf\x00\x00\x00?":                         formatBinary,
+		"tree\nversion=v4\n":                        formatLightGBM,
+		"1 0:1.5":                                   formatUnknown,
+		"":                                          formatUnknown,
+	}
+	for content, expected := range cases {
+		format, err := detectFormat(bufio.NewReader(strings.NewReader(content)))
+		assert.NilError(t, err)
+		assert.Equal(t, format, expected, content)
+	}
+}
+
+func TestEnsemble_PredictProbaFromLibsvm(t *testing.T) {
+	ensemble, err := LoadXGBoostFromJSON("test/data/breast_cancer_xgboost_dump.json",
+		"", 1, 4, &activation.Logistic{})
+	assert.NilError(t, err)
+
+	predictions, err := ensemble.PredictProbaFromLibsvm("test/data/breast_cancer_test.libsvm")
+	assert.NilError(t, err)
+	expected, err := mat.ReadCSVFileToDenseMatrix("test/data/breast_cancer_xgboost_true_prediction.txt", "\t", 0.0)
+	assert.NilError(t, err)
+	assert.NilError(t, mat.IsEqualMatrices(&predictions, &expected, 0.0001))
+
+	_, err = ensemble.PredictProbaFromLibsvm(writeTempFile(t, "1 0:1\n1 0:x\n"))
+	assert.Check(t, errors.Is(err, mat.ErrMalformedRow))
+	assert.ErrorContains(t, err, "at row 1")
+}
+
+func TestEnsemble_PredictLogOdds(t *testing.T) {
+	ensemble, err := LoadXGBoostFromJSON("test/data/breast_cancer_xgboost_dump.json",
+		"", 1, 4, &activation.Logistic{})
+	assert.NilError(t, err)
+	input, err := mat.ReadLibsvmFileToSparseMatrix("test/data/breast_cancer_test.libsvm")
+	assert.NilError(t, err)
+
+	logOdds, err := ensemble.PredictLogOdds(input)
+	assert.NilError(t, err)
+	proba, err := ensemble.PredictProba(input)
+	assert.NilError(t, err)
+	assert.Equal(t, len(logOdds), len(proba.Vectors))
+	for i, p := range proba.Vectors {
+		logit := math.Log((*p)[0] / (1 - (*p)[0]))
+		assert.Check(t, math.Abs(logit-logOdds[i]) < 1e-6, "row %d: %f != %f", i, logit, logOdds[i])
+	}
+
+	raw, err := LoadXGBoostFromJSON("test/data/breast_cancer_xgboost_dump.json",
+		"", 1, 4, &activation.Raw{})
+	assert.NilError(t, err)
+	_, err = raw.PredictLogOdds(input)
+	assert.ErrorContains(t, err, "requires logistic activation")
+}
+
+func TestEnsemble_MissingValue(t *testing.T) {
+	modelPath := writeTempFile(t, `[
+  { "nodeid": 0, "depth": 0, "split": "f0", "split_condition": 0.5, "yes": 1, "no": 2, "missing": 2, "children": [
+    { "nodeid": 1, "leaf": -1 },
+    { "nodeid": 2, "leaf": 1 }
+  ]}
+]`)
+	dense := mat.Matrix{Vectors: []*mat.Vector{{0}, {1}, {math.NaN()}}}
+	sparse := mat.SparseMatrix{Vectors: []mat.SparseVector{{0: 0}, {0: 1}, {}}}
+
+	ensemble, err := LoadXGBoostFromJSON(modelPath, "", 1, 0, &activation.Raw{})
+	assert.NilError(t, err)
+	predictions, err := ensemble.PredictProbaDense(dense)
+	assert.NilError(t, err)
+	expected := mat.Matrix{Vectors: []*mat.Vector{{-1}, {1}, {1}}}
+	assert.NilError(t, mat.IsEqualMatrices(&predictions, &expected, 0))
+
+	// with missing=0, zeros follow the default branch like in xgboost.DMatrix(missing=0).
+	ensemble, err = LoadXGBoostFromJSON(modelPath, "", 1, 0, &activation.Raw{}, WithMissingValue(0))
+	assert.NilError(t, err)
+	expected = mat.Matrix{Vectors: []*mat.Vector{{1}, {1}, {1}}}
+	predictions, err = ensemble.PredictProbaDense(dense)
+	assert.NilError(t, err)
+	assert.NilError(t, mat.IsEqualMatrices(&predictions, &expected, 0))
+	predictions, err = ensemble.PredictProba(sparse)
+	assert.NilError(t, err)
+	assert.NilError(t, mat.IsEqualMatrices(&predictions, &expected, 0))
+
+	// the marker can also be handled when building the input instead of when loading the model.
+	ensemble, err = LoadXGBoostFromJSON(modelPath, "", 1, 0, &activation.Raw{})
+	assert.NilError(t, err)
+	data := [][]float64{{0}, {1}, {-999}}
+	expected = mat.Matrix{Vectors: []*mat.Vector{{-1}, {1}, {1}}}
+	dense, err = mat.GetDenseMatrixFromSlice(data, -999)
+	assert.NilError(t, err)
+	predictions, err = ensemble.PredictProbaDense(dense)
+	assert.NilError(t, err)
+	assert.NilError(t, mat.IsEqualMatrices(&predictions, &expected, 0))
+	sparse, err = mat.GetSparseMatrixFromSliceWithMissing(data, -999)
+	assert.NilError(t, err)
+	predictions, err = ensemble.PredictProba(sparse)
+	assert.NilError(t, err)
+	assert.NilError(t, mat.IsEqualMatrices(&predictions, &expected, 0))
+}
+
+func TestEnsemble_PredictTreeContributions(t *testing.T) {
+	ensemble, err := LoadXGBoostFromJSON("test/data/iris_xgboost_dump.json",
+		"", 3, 3, &activation.Raw{})
+	assert.NilError(t, err)
+	input, err := mat.ReadLibsvmFileToSparseMatrix("test/data/iris_test.libsvm")
+	assert.NilError(t, err)
+
+	for _, row := range input.Vectors {
+		contributions, err := ensemble.PredictTreeContributions(row)
+		assert.NilError(t, err)
+		margin, err := ensemble.PredictInner(row)
+		assert.NilError(t, err)
+		sums := make(mat.Vector, 3)
+		for i, c := range contributions {
+			sums[i%3] += c
+		}
+		assert.NilError(t, mat.IsEqualVectors(&sums, &margin, 1e-9))
+	}
+}
+
+func TestScoreFile(t *testing.T) {
+	opts := ScoreOptions{NumClasses: 1, MaxDepth: 4, Activation: &activation.Logistic{}}
+	expected, err := mat.ReadCSVFileToDenseMatrix("test/data/breast_cancer_xgboost_true_prediction.txt", "\t", 0.0)
+	assert.NilError(t, err)
+
+	output := writeTempFile(t, "")
+	assert.NilError(t, ScoreFile("test/data/breast_cancer_xgboost_dump.json", "test/data/breast_cancer_test.libsvm",
+		output, opts))
+	predictions, err := mat.ReadCSVFileToDenseMatrix(output, "\t", 0.0)
+	assert.NilError(t, err)
+	assert.NilError(t, mat.IsEqualMatrices(&predictions, &expected, 0.0001))
+
+	// same rows as dense csv with missing features left empty.
+	input, err := mat.ReadLibsvmFileToSparseMatrix("test/data/breast_cancer_test.libsvm")
+	assert.NilError(t, err)
+	var csv strings.Builder
+	for _, row := range input.Vectors {
+		for j := 0; j < 30; j++ {
+			if j > 0 {
+				csv.WriteString(";")
+			}
+			if v, ok := row[j]; ok {
+				csv.WriteString(strconv.FormatFloat(v, 'g', -1, 64))
+			}
+		}
+		csv.WriteString("\n")
+	}
+	opts.InputFormat = InputCSV
+	opts.Delimiter = ";"
+	opts.OutputFormat = OutputJSONL
+	assert.NilError(t, ScoreFile("test/data/breast_cancer_xgboost_dump.json", writeTempFile(t, csv.String()),
+		output, opts))
+	b, err := ioutil.ReadFile(output)
+	assert.NilError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(b)), "\n")
+	assert.Equal(t, len(lines), len(expected.Vectors))
+	var first []float64
+	assert.NilError(t, json.Unmarshal([]byte(lines[0]), &first))
+	assert.Check(t, math.Abs(first[0]-(*expected.Vectors[0])[0]) < 0.0001)
+
+	// tree limit keeps the first boosting round only.
+	opts = ScoreOptions{NumClasses: 1, MaxDepth: 4, Activation: &activation.Raw{}, TreeLimit: 1}
+	assert.NilError(t, ScoreFile("test/data/breast_cancer_xgboost_dump.json", "test/data/breast_cancer_test.libsvm",
+		output, opts))
+	predictions, err = mat.ReadCSVFileToDenseMatrix(output, "\t", 0.0)
+	assert.NilError(t, err)
+	ensemble, err := LoadXGBoostFromJSON("test/data/breast_cancer_xgboost_dump.json", "", 1, 4, &activation.Raw{})
+	assert.NilError(t, err)
+	trees, err := ensemble.PredictTreeContributions(input.Vectors[0])
+	assert.NilError(t, err)
+	assert.Equal(t, (*predictions.Vectors[0])[0], trees[0])
+
+	opts.InputFormat = "parquet"
+	assert.ErrorContains(t, ScoreFile("test/data/breast_cancer_xgboost_dump.json",
+		"test/data/breast_cancer_test.libsvm", output, opts), "unknown input format")
+}
+
+func TestPipeline(t *testing.T) {
+	const modelPath = "test/data/breast_cancer_xgboost_model.json"
+	ensemble, err := LoadXGBoostModel(modelPath, "", 0, 0, nil)
+	assert.NilError(t, err)
+	sparse, err := mat.ReadLibsvmFileToSparseMatrix("test/data/breast_cancer_test.libsvm")
+	assert.NilError(t, err)
+	dense := mat.Matrix{Vectors: make([]*mat.Vector, len(sparse.Vectors))}
+	for i, row := range sparse.Vectors {
+		vec := make(mat.Vector, ensemble.NumFeatures())
+		for j := range vec {
+			vec[j] = math.NaN()
+		}
+		for j, v := range row {
+			vec[j] = v
+		}
+		dense.Vectors[i] = &vec
+	}
+
+	pipeline, err := NewPipeline(modelPath, 0, nil)
+	assert.NilError(t, err)
+	preds, err := pipeline.Predict(dense)
+	assert.NilError(t, err)
+	expected, err := ensemble.PredictProbaDense(dense)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, preds, expected)
+
+	// scaled rows are predicted then calibrated.
+	scaler := &preprocess.MinMaxScaler{}
+	assert.NilError(t, scaler.Fit(dense))
+	pipeline.Preprocessor = scaler
+	platt := &calibration.PlattScaling{A: -2, B: 0.5}
+	pipeline.Calibrator = platt
+	preds, err = pipeline.Predict(dense)
+	assert.NilError(t, err)
+	scaled, err := scaler.Transform(dense)
+	assert.NilError(t, err)
+	scores, err := ensemble.PredictProbaDense(scaled)
+	assert.NilError(t, err)
+	for i, v := range scores.Vectors {
+		calibrated, err := platt.Transform(*v)
+		assert.NilError(t, err)
+		assert.DeepEqual(t, *preds.Vectors[i], calibrated)
+	}
+
+	var buf bytes.Buffer
+	assert.NilError(t, WritePipelineJSON(pipeline, &buf))
+	read, err := ReadPipelineJSON(&buf)
+	assert.NilError(t, err)
+	readPreds, err := read.Predict(dense)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, readPreds, preds)
+
+	// hashed rows, saved with an activation resolved by name.
+	hashing := &preprocess.HashingVectorizer{NumFeatures: ensemble.NumFeatures(), AlternateSign: true}
+	pipeline, err = NewPipeline(modelPath, 0, &activation.Logistic{})
+	assert.NilError(t, err)
+	pipeline.Vectorizer = hashing
+	rows := []map[string]float64{{"mean radius": 14.2, "worst area": 880}, {"mean texture": 21}}
+	preds, err = pipeline.PredictFeatures(rows)
+	assert.NilError(t, err)
+	for i, row := range rows {
+		hashed, err := hashing.Transform(row)
+		assert.NilError(t, err)
+		pred, err := ensemble.PredictProba(mat.SparseMatrix{Vectors: []mat.SparseVector{hashed}})
+		assert.NilError(t, err)
+		assert.DeepEqual(t, preds.Vectors[i], pred.Vectors[0])
+	}
+	assert.NilError(t, WritePipelineJSON(pipeline, &buf))
+	read, err = ReadPipelineJSON(&buf)
+	assert.NilError(t, err)
+	readPreds, err = read.PredictFeatures(rows)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, readPreds, preds)
+	_, err = read.PredictTokens([][]string{{"a", "b"}})
+	assert.NilError(t, err)
+
+	_, err = (&Pipeline{Model: ensemble}).PredictTokens([][]string{{"a"}})
+	assert.ErrorContains(t, err, "no vectorizer")
+	iris, err := NewPipeline("test/data/iris_xgboost_model.json", 0, nil)
+	assert.NilError(t, err)
+	iris.Calibrator = platt
+	_, err = iris.Predict(mat.Matrix{Vectors: []*mat.Vector{{5.1, 3.5, 1.4, 0.2}}})
+	assert.Check(t, errors.Is(err, mat.ErrDimensionMismatch))
+	_, err = ReadPipelineJSON(strings.NewReader(`{"num_classes": 1}`))
+	assert.Check(t, errors.Is(err, ErrMalformedModel))
+}
+
+func TestPipeline_LoadOptions(t *testing.T) {
+	const modelPath = "test/data/breast_cancer_xgboost_model.json"
+	opts := []LoadOption{WithTreeLimit(3), WithMissingValue(0), WithOneBasedFeatureIndices(),
+		WithFeatureMap("test/data/breast_cancer_fmap.txt")}
+	ensemble, err := LoadXGBoostModel(modelPath, "", 0, 0, nil, opts...)
+	assert.NilError(t, err)
+	rows, err := mat.ReadLibsvmFileToSparseMatrix("test/data/breast_cancer_test.libsvm")
+	assert.NilError(t, err)
+	input := mat.SparseMatrix{Vectors: make([]mat.SparseVector, len(rows.Vectors))}
+	for i, row := range rows.Vectors {
+		input.Vectors[i] = make(mat.SparseVector, len(row))
+		for j, v := range row {
+			input.Vectors[i][j+1] = v
+		}
+	}
+	expected, err := ensemble.PredictProba(input)
+	assert.NilError(t, err)
+
+	pipeline, err := NewPipeline(modelPath, 0, nil, opts...)
+	assert.NilError(t, err)
+	var buf bytes.Buffer
+	assert.NilError(t, WritePipelineJSON(pipeline, &buf))
+	// the feature map is saved as feature names, the pipeline no longer needs it.
+	assert.Check(t, !strings.Contains(buf.String(), "fmap"))
+	read, err := ReadPipelineJSON(&buf)
+	assert.NilError(t, err)
+	readOptions, err := newOptionsJSON(read.cfg)
+	assert.NilError(t, err)
+	options, err := newOptionsJSON(pipeline.cfg)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, readOptions, options)
+	assert.Check(t, len(options.FeatureNames) >= ensemble.NumFeatures())
+	assert.Equal(t, read.Model.NumTrees(), 3)
+	idx, ok := read.Model.FeatureIndex("mean_texture")
+	assert.Check(t, ok)
+	assert.Equal(t, idx, 2)
+	preds, err := read.Model.PredictProba(input)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, preds, expected)
+
+	// options given to ReadPipelineJSON override the saved ones.
+	assert.NilError(t, WritePipelineJSON(pipeline, &buf))
+	read, err = ReadPipelineJSON(&buf, WithTreeLimit(0))
+	assert.NilError(t, err)
+	full, err := LoadXGBoostModel(modelPath, "", 0, 0, nil)
+	assert.NilError(t, err)
+	assert.Equal(t, read.Model.NumTrees(), full.NumTrees())
+
+	pipeline, err = NewPipeline(modelPath, 0, nil, WithMissingValue(math.Inf(-1)))
+	assert.NilError(t, err)
+	assert.ErrorContains(t, WritePipelineJSON(pipeline, &buf), "cannot be saved")
+}
+
+func TestValueValidation(t *testing.T) {
+	_, err := LoadXGBoostFromJSON("test/data/breast_cancer_xgboost_dump.json", "", 1, 4, &activation.Logistic{},
+		WithValueValidation())
+	assert.NilError(t, err)
+
+	tree := &xgbTree{nodes: []*xgbNode{
+		{NodeID: 0, Threshold: 0.5, Yes: 1, No: 2, Missing: 1},
+		{NodeID: 1, Flags: isLeaf, LeafValues: 1},
+		{NodeID: 2, Flags: isLeaf, LeafValues: math.NaN()},
+	}}
+	err = tree.validate()
+	assert.Check(t, errors.Is(err, ErrMalformedModel))
+	assert.ErrorContains(t, err, "node 2 has NaN leaf value")
+
+	tree.nodes[2].LeafValues = 0
+	tree.nodes[0].Threshold = math.Inf(1)
+	assert.ErrorContains(t, tree.validate(), "node 0 has +Inf split threshold")
+}
+
+func TestLoadXGBoostFromModelJSON(t *testing.T) {
+	input, err := mat.ReadLibsvmFileToSparseMatrix("test/data/breast_cancer_test.libsvm")
+	assert.NilError(t, err)
+	expected, err := mat.ReadCSVFileToDenseMatrix("test/data/breast_cancer_xgboost_true_prediction.txt", "\t", 0.0)
+	assert.NilError(t, err)
+	modelPath := "test/data/breast_cancer_xgboost_model.json"
+	for _, path := range []string{modelPath, gzipFile(t, modelPath)} {
+		ensemble, err := LoadXGBoostFromModelJSON(path, nil)
+		assert.NilError(t, err)
+		assert.Equal(t, ensemble.Type(), protobuf.ActivateType_LOGISTIC)
+		assert.Equal(t, ensemble.NumFeatures(), 30)
+		predictions, err := ensemble.PredictProba(input)
+		assert.NilError(t, err)
+		assert.NilError(t, mat.IsEqualMatrices(&predictions, &expected, 0.0001))
+	}
+	ensemble, err := LoadXGBoostModel(modelPath, "", 0, 0, nil)
+	assert.NilError(t, err)
+	predictions, err := ensemble.PredictProba(input)
+	assert.NilError(t, err)
+	assert.NilError(t, mat.IsEqualMatrices(&predictions, &expected, 0.0001))
+	_, err = LoadXGBoostModel(modelPath, "", 3, 0, nil)
+	assert.ErrorContains(t, err, "model has 1 classes, got 3")
+	_, err = LoadXGBoostFromModelJSON(modelPath, &activation.Raw{})
+	assert.ErrorContains(t, err, "expects LOGISTIC activation")
+	_, err = LoadXGBoostFromModelJSON("test/data/breast_cancer_xgboost_dump.json", nil)
+	assert.Check(t, errors.Is(err, ErrUnsupportedFormat))
+	_, err = LoadXGBoostFromJSON(modelPath, "", 1, 4, &activation.Logistic{})
+	assert.Check(t, errors.Is(err, ErrUnsupportedFormat))
+
+	// multiclass with the number of classes read from the model.
+	input, err = mat.ReadLibsvmFileToSparseMatrix("test/data/iris_test.libsvm")
+	assert.NilError(t, err)
+	expected, err = mat.ReadCSVFileToDenseMatrix("test/data/iris_xgboost_true_prediction_proba.txt", "\t", 0.0)
+	assert.NilError(t, err)
+	ensemble, err = LoadXGBoostFromModelJSON("test/data/iris_xgboost_model.json", nil)
+	assert.NilError(t, err)
+	assert.Equal(t, ensemble.NumClasses(), 3)
+	predictions, err = ensemble.PredictProba(input)
+	assert.NilError(t, err)
+	assert.NilError(t, mat.IsEqualMatrices(&predictions, &expected, 0.0001))
+	classes, err := ensemble.PredictClass(input)
+	assert.NilError(t, err)
+
+	// multi:softmax models predict classes and still give the probabilities of each class.
+	content, err := ioutil.ReadFile("test/data/iris_xgboost_model.json")
+	assert.NilError(t, err)
+	softmaxPath := writeTempFile(t, strings.Replace(string(content), "multi:softprob", "multi:softmax", 1))
+	ensemble, err = LoadXGBoostModel(softmaxPath, "", 0, 0, nil)
+	assert.NilError(t, err)
+	assert.Equal(t, ensemble.NumClasses(), 3)
+	assert.Equal(t, ensemble.Type(), protobuf.ActivateType_SOFTMAX_CLASS)
+	predictions, err = ensemble.PredictClassProba(input)
+	assert.NilError(t, err)
+	assert.NilError(t, mat.IsEqualMatrices(&predictions, &expected, 0.0001))
+	softmaxClasses, err := ensemble.PredictClass(input)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, softmaxClasses, classes)
+	predictions, err = ensemble.PredictProba(input)
+	assert.NilError(t, err)
+	for i, p := range predictions.Vectors {
+		assert.DeepEqual(t, *p, mat.Vector{float64(classes[i])})
+	}
+	ensemble.Activation = &activation.Raw{}
+	_, err = ensemble.PredictClassProba(input)
+	assert.ErrorContains(t, err, "require a logistic or softmax activation")
+
+	// base_score is part of raw predictions and of the SHAP bias, cover comes from sum_hessian.
+	dump, err := LoadXGBoostFromJSON("test/data/small_xgboost_dump_stats.json", "", 1, 0, &activation.Raw{})
+	assert.NilError(t, err)
+	ensemble, err = LoadXGBoostFromModelJSON("test/data/small_xgboost_model_stats.json", nil)
+	assert.NilError(t, err)
+	for _, row := range smallModelInput.Vectors {
+		want, err := dump.PredictInner(row)
+		assert.NilError(t, err)
+		got, err := ensemble.PredictInner(row)
+		assert.NilError(t, err)
+		assert.Check(t, math.Abs(got[0]-want[0]-0.5) < 1e-9)
+
+		phi, err := ensemble.EnsembleBase.(*xgbEnsemble).contributions(row, 0, 0)
+		assert.NilError(t, err)
+		sum := 0.0
+		for _, v := range phi {
+			sum += v
+		}
+		assert.Check(t, math.Abs(sum-got[0]) < 1e-9)
+	}
+}
+
+func TestLoadXGBoostFromModelJSON_Poisson(t *testing.T) {
+	input, err := mat.ReadLibsvmFileToSparseMatrix("test/data/breast_cancer_test.libsvm")
+	assert.NilError(t, err)
+	content, err := ioutil.ReadFile("test/data/breast_cancer_xgboost_model.json")
+	assert.NilError(t, err)
+	modelPath := writeTempFile(t, strings.Replace(string(content), "binary:logistic", "count:poisson", 1))
+	ensemble, err := LoadXGBoostFromModelJSON(modelPath, nil)
+	assert.NilError(t, err)
+	assert.Equal(t, ensemble.Type(), protobuf.ActivateType_EXP)
+	_, err = LoadXGBoostFromModelJSON(modelPath, &activation.Raw{})
+	assert.ErrorContains(t, err, "expects EXP activation")
+
+	// raw predictions are log rates including log(base_score), predictions are expected counts.
+	margins, err := ensemble.PredictMargin(input)
+	assert.NilError(t, err)
+	predictions, err := ensemble.Predict(input)
+	assert.NilError(t, err)
+	for i, m := range margins.Vectors {
+		assert.Check(t, math.Abs((*predictions.Vectors[i])[0]-math.Exp((*m)[0])) < 1e-12, i)
+	}
+	logistic, err := LoadXGBoostFromModelJSON("test/data/breast_cancer_xgboost_model.json", nil)
+	assert.NilError(t, err)
+	logit, err := logistic.PredictMargin(input)
+	assert.NilError(t, err)
+	for i, m := range margins.Vectors {
+		// base_score 0.5 is a log odds of 0 and a log rate of log(0.5).
+		assert.Check(t, math.Abs((*m)[0]-(*logit.Vectors[i])[0]-math.Log(0.5)) < 1e-6, i)
+	}
+
+	dump, err := LoadXGBoostFromJSON("test/data/breast_cancer_xgboost_dump.json", "", 1, 0, nil,
+		WithObjective("count:poisson"))
+	assert.NilError(t, err)
+	assert.Equal(t, dump.Type(), protobuf.ActivateType_EXP)
+}
+
+func TestLoadXGBoostFromModelJSON_LogLink(t *testing.T) {
+	input, err := mat.ReadLibsvmFileToSparseMatrix("test/data/breast_cancer_test.libsvm")
+	assert.NilError(t, err)
+	content, err := ioutil.ReadFile("test/data/breast_cancer_xgboost_model.json")
+	assert.NilError(t, err)
+	poisson, err := LoadXGBoostFromModelJSON(
+		writeTempFile(t, strings.Replace(string(content), "binary:logistic", "count:poisson", 1)), nil)
+	assert.NilError(t, err)
+	expected, err := poisson.PredictProba(input)
+	assert.NilError(t, err)
+
+	// gamma, tweedie and survival objectives use the same log link and base_score conversion as poisson.
+	for _, c := range []struct {
+		objective string
+		want      protobuf.ActivateType
+		// param is the tweedie variance power or the aft sigma.
+		param float64
+	}{
+		{`"reg:gamma"`, protobuf.ActivateType_GAMMA, 0},
+		{`"reg:tweedie"`, protobuf.ActivateType_TWEEDIE, 1.5},
+		{`"reg:tweedie","tweedie_regression_param":{"tweedie_variance_power":"1.2"}`, protobuf.ActivateType_TWEEDIE,
+			1.2},
+		{`"survival:cox"`, protobuf.ActivateType_EXP, 0},
+		{`"survival:aft"`, protobuf.ActivateType_SURVIVAL_AFT, 1},
+		{`"survival:aft","aft_loss_param":{"aft_loss_distribution":"logistic","aft_loss_distribution_scale":"1.3"}`,
+			protobuf.ActivateType_SURVIVAL_AFT, 1.3},
+	} {
+		modelPath := writeTempFile(t, strings.Replace(string(content), `"binary:logistic"`, c.objective, 1))
+		ensemble, err := LoadXGBoostFromModelJSON(modelPath, nil)
+		assert.NilError(t, err)
+		assert.Equal(t, ensemble.Type(), c.want)
+		switch a := ensemble.Activation.(type) {
+		case *activation.Tweedie:
+			assert.Equal(t, a.VariancePower, c.param)
+		case *activation.SurvivalAFT:
+			assert.Equal(t, a.Sigma, c.param)
+			if c.param != 1 {
+				assert.Equal(t, a.Distribution, "logistic")
+			} else {
+				assert.Equal(t, a.Distribution, "normal")
+			}
+		}
+		predictions, err := ensemble.PredictProba(input)
+		assert.NilError(t, err)
+		assert.NilError(t, mat.IsEqualMatrices(&predictions, &expected, 1e-12), c.objective)
+		_, err = LoadXGBoostFromModelJSON(modelPath, &activation.Raw{})
+		assert.ErrorContains(t, err, "activation, got RAW")
+	}
+
+	lightgbm, err := LoadLightGBMModel(writeTempFile(t, strings.Replace(lightgbmSmallModel, "objective=regression",
+		"objective=tweedie tweedie_variance_power:1.7", 1)), nil)
+	assert.NilError(t, err)
+	assert.Equal(t, lightgbm.Activation.(*activation.Tweedie).VariancePower, 1.7)
+}
+
+func TestLoadXGBoostFromModelJSON_Categorical(t *testing.T) {
+	modelPath := writeTempFile(t, `{"learner": {
+  "gradient_booster": {"name": "gbtree", "model": {"tree_info": [0], "trees": [{
+    "left_children": [1, 3, -1, -1, -1], "right_children": [2, 4, -1, -1, -1],
+    "split_indices": [0, 1, 0, 0, 0], "split_conditions": [0, 3, 1, -1, -2],
+    "default_left": [1, 1, 0, 0, 0], "split_type": [1, 0, 0, 0, 0],
+    "categories": [1, 3], "categories_nodes": [0], "categories_segments": [0], "categories_sizes": [2]
+  }]}},
+  "learner_model_param": {"base_score": "0", "num_class": "0", "num_feature": "2"},
+  "objective": {"name": "reg:squarederror"}
+}}`)
+	ensemble, err := LoadXGBoostFromModelJSON(modelPath, nil)
+	assert.NilError(t, err)
+	input := mat.SparseMatrix{Vectors: []mat.SparseVector{
+		{0: 1, 1: 5},
+		{0: 3, 1: 5},
+		{0: 2, 1: 5},
+		{0: 2, 1: 1},
+		// fractional values are truncated like in XGBoost.
+		{0: 1.5, 1: 1},
+		{0: 3.9, 1: 5},
+		{0: 0.5, 1: 1},
+		// negative and too large values are not valid categories.
+		{0: -1, 1: 5},
+		{0: 1<<24 + 1, 1: 5},
+		{1: 5},
+	}}
+	expected := mat.Matrix{Vectors: []*mat.Vector{{1}, {1}, {-2}, {-1}, {1}, {1}, {-1}, {-2}, {-2}, {-2}}}
+	predictions, err := ensemble.PredictProba(input)
+	assert.NilError(t, err)
+	assert.NilError(t, mat.IsEqualMatrices(&predictions, &expected, 0))
+
+	modelPath = writeTempFile(t, `{"learner": {
+  "gradient_booster": {"name": "gbunknown", "model": {}},
+  "learner_model_param": {"base_score": "0", "num_class": "0", "num_feature": "2"},
+  "objective": {"name": "reg:squarederror"}
+}}`)
+	_, err = LoadXGBoostFromModelJSON(modelPath, nil)
+	assert.Check(t, errors.Is(err, ErrUnsupportedFormat))
+}
+
+func TestLoadXGBoostFromModelJSON_Dart(t *testing.T) {
+	dart := func(weights string) string {
+		return `{"learner": {
+  "gradient_booster": {"name": "dart", "weight_drop": ` + weights + `, "gbtree": {"model": {"tree_info": [0, 0],
+    "trees": [{
+      "left_children": [1, -1, -1], "right_children": [2, -1, -1],
+      "split_indices": [0, 0, 0], "split_conditions": [0.5, 1, -1], "default_left": [1, 0, 0],
+      "sum_hessian": [4, 2, 2]
+    }, {
+      "left_children": [1, -1, -1], "right_children": [2, -1, -1],
+      "split_indices": [1, 0, 0], "split_conditions": [2, 0.25, 0.75], "default_left": [0, 0, 0],
+      "sum_hessian": [4, 1, 3]
+    }]}}},
+  "learner_model_param": {"base_score": "5E-1", "num_class": "0", "num_feature": "2"},
+  "objective": {"name": "reg:squarederror"}
+}}`
+	}
+	ensemble, err := LoadXGBoostFromModelJSON(writeTempFile(t, dart("[0.5, 2]")), nil)
+	assert.NilError(t, err)
+	// the output is the weighted sum of the trees, weights are applied without dropping trees at inference.
+	input := mat.SparseMatrix{Vectors: []mat.SparseVector{{0: 0, 1: 1}, {0: 1, 1: 3}, {}}}
+	expected := mat.Matrix{Vectors: []*mat.Vector{
+		{0.5 + 0.5*1 + 2*0.25}, {0.5 + 0.5*-1 + 2*0.75}, {0.5 + 0.5*1 + 2*0.75},
+	}}
+	predictions, err := ensemble.PredictRegression(input, 0)
+	assert.NilError(t, err)
+	assert.NilError(t, mat.IsEqualMatrices(&predictions, &expected, 1e-12))
+
+	staged, err := ensemble.PredictStaged(mat.SparseMatrix{Vectors: input.Vectors[:1]})
+	assert.NilError(t, err)
+	assert.Equal(t, len(staged), 2)
+	assert.NilError(t, mat.IsEqualVectors(staged[0].Vectors[0], &mat.Vector{1}, 1e-12))
+	assert.NilError(t, mat.IsEqualVectors(staged[1].Vectors[0], expected.Vectors[0], 1e-12))
+
+	contributions, err := ensemble.PredictContributions(mat.SparseMatrix{Vectors: input.Vectors[:1]})
+	assert.NilError(t, err)
+	sum := 0.0
+	for _, v := range *contributions.Vectors[0] {
+		sum += v
+	}
+	assert.Assert(t, math.Abs(sum-(*expected.Vectors[0])[0]) < 1e-12)
+
+	_, err = LoadXGBoostFromModelJSON(writeTempFile(t, dart("[0.5]")), nil)
+	assert.Check(t, errors.Is(err, ErrMalformedModel))
+}
+
+func TestLoadXGBoostFromModelJSON_RandomForest(t *testing.T) {
+	stump := func(feature int, threshold, yes, no float64) string {
+		return fmt.Sprintf(`{"left_children": [1, -1, -1], "right_children": [2, -1, -1],
+      "split_indices": [%d, 0, 0], "split_conditions": [%v, %v, %v], "default_left": [1, 0, 0]}`,
+			feature, threshold, yes, no)
+	}
+	forest := func(parallelTrees int, trees ...string) string {
+		treeInfo := strings.TrimSuffix(strings.Repeat("0, ", len(trees)), ", ")
+		return fmt.Sprintf(`{"learner": {
+  "gradient_booster": {"name": "gbtree", "model": {
+    "gbtree_model_param": {"num_parallel_tree": "%d", "num_trees": "%d"},
+    "tree_info": [%s], "trees": [%s]}},
+  "learner_model_param": {"base_score": "5E-1", "num_class": "0", "num_feature": "2"},
+  "objective": {"name": "reg:squarederror"}
+}}`, parallelTrees, len(trees), treeInfo, strings.Join(trees, ", "))
+	}
+	// 2 rounds of 2 parallel trees whose leaves are already scaled by the learning rate and num_parallel_tree.
+	trees := []string{stump(0, 1, 0.1, 0.2), stump(1, 1, 0.3, 0.4), stump(0, 2, 0.05, 0.15), stump(1, 2, 0.25, 0.35)}
+	modelPath := writeTempFile(t, forest(2, trees...))
+	input := mat.SparseMatrix{Vectors: []mat.SparseVector{{0: 0, 1: 0}, {0: 3, 1: 3}}}
+
+	ensemble, err := LoadXGBoostFromModelJSON(modelPath, nil)
+	assert.NilError(t, err)
+	assert.Equal(t, ensemble.NumTrees(), 4)
+	// parallel trees are summed like XGBoost does.
+	expected := mat.Matrix{Vectors: []*mat.Vector{{0.5 + 0.1 + 0.3 + 0.05 + 0.25}, {0.5 + 0.2 + 0.4 + 0.15 + 0.35}}}
+	predictions, err := ensemble.PredictRegression(input, 0)
+	assert.NilError(t, err)
+	assert.NilError(t, mat.IsEqualMatrices(&predictions, &expected, 1e-12))
+
+	// a boosting round has num_parallel_tree trees.
+	staged, err := ensemble.PredictStaged(input)
+	assert.NilError(t, err)
+	assert.Equal(t, len(staged), 2)
+	firstRound := mat.Matrix{Vectors: []*mat.Vector{{0.5 + 0.1 + 0.3}, {0.5 + 0.2 + 0.4}}}
+	assert.NilError(t, mat.IsEqualMatrices(&staged[0], &firstRound, 1e-12))
+	assert.NilError(t, mat.IsEqualMatrices(&staged[1], &expected, 1e-12))
+
+	ensemble, err = LoadXGBoostFromModelJSON(modelPath, nil, WithTreeLimit(1))
+	assert.NilError(t, err)
+	assert.Equal(t, ensemble.NumTrees(), 2)
+	predictions, err = ensemble.PredictRegression(input, 0)
+	assert.NilError(t, err)
+	assert.NilError(t, mat.IsEqualMatrices(&predictions, &firstRound, 1e-12))
+
+	ensemble, err = LoadXGBoostFromModelJSON(modelPath, nil, WithIterationRange(1, 2))
+	assert.NilError(t, err)
+	assert.Equal(t, ensemble.NumTrees(), 2)
+
+	_, err = LoadXGBoostFromModelJSON(writeTempFile(t, forest(2, trees[:3]...)), nil)
+	assert.Check(t, errors.Is(err, ErrMalformedModel))
+}
+
+func TestLoadXGBoostFromModelJSON_Linear(t *testing.T) {
+	modelPath := writeTempFile(t, `{"learner": {
+  "attributes": {"best_iteration": "9"},
+  "feature_names": ["a", "b", "c"],
+  "gradient_booster": {"name": "gblinear", "model": {"boosted_rounds": 10, "weights": [0.5, -1, 2, 0.25]}},
+  "learner_model_param": {"base_score": "5E-1", "num_class": "0", "num_feature": "3"},
+  "objective": {"name": "binary:logistic"}
+}}`)
+	ensemble, err := LoadXGBoostFromModelJSON(modelPath, &activation.Logistic{})
+	assert.NilError(t, err)
+	assert.Equal(t, ensemble.NumClasses(), 1)
+	assert.Equal(t, ensemble.NumFeatures(), 3)
+	assert.Equal(t, ensemble.Objective(), "binary:logistic")
+	assert.Equal(t, ensemble.BaseScore(), 0.5)
+	assert.Equal(t, ensemble.NumTrees(), 0)
+	assert.DeepEqual(t, ensemble.Attributes(), map[string]string{"best_iteration": "9"})
+
+	input := mat.SparseMatrix{Vectors: []mat.SparseVector{{0: 1, 1: 2}, {2: 1.5}, {}, {0: math.NaN(), 2: 1}}}
+	margins := mat.Vector{-1.25, 3.25, 0.25, 2.25}
+	expected := mat.Matrix{Vectors: make([]*mat.Vector, len(margins))}
+	for i, m := range margins {
+		expected.Vectors[i] = &mat.Vector{1 / (1 + math.Exp(-m))}
+	}
+	predictions, err := ensemble.PredictProba(input)
+	assert.NilError(t, err)
+	assert.NilError(t, mat.IsEqualMatrices(&predictions, &expected, 1e-12))
+
+	dense, err := ensemble.PredictProbaDense(mat.Matrix{Vectors: []*mat.Vector{{1, 2, math.NaN()}}})
+	assert.NilError(t, err)
+	assert.NilError(t, mat.IsEqualVectors(dense.Vectors[0], expected.Vectors[0], 1e-12))
+
+	contributions, err := ensemble.PredictContributions(mat.SparseMatrix{Vectors: []mat.SparseVector{{0: 1, 1: 2}}})
+	assert.NilError(t, err)
+	assert.NilError(t, mat.IsEqualVectors(contributions.Vectors[0], &mat.Vector{0.5, -2, 0, 0.25}, 1e-12))
+
+	byName, err := ensemble.PredictByName(map[string]float64{"c": 1.5})
+	assert.NilError(t, err)
+	assert.NilError(t, mat.IsEqualVectors(&byName, expected.Vectors[1], 1e-12))
+
+	// weights are grouped by feature, each feature having one coefficient per class, and end with biases.
+	modelPath = writeTempFile(t, `{"learner": {
+  "gradient_booster": {"name": "gblinear", "model": {"weights": [1, -1, 0.5, 2, 0.1, -0.1]}},
+  "learner_model_param": {"base_score": "0", "num_class": "2", "num_feature": "2"},
+  "objective": {"name": "multi:softprob"}
+}}`)
+	ensemble, err = LoadXGBoostFromModelJSON(modelPath, &activation.Softmax{})
+	assert.NilError(t, err)
+	assert.Equal(t, ensemble.NumClasses(), 2)
+	predictions, err = ensemble.PredictMargin(mat.SparseMatrix{Vectors: []mat.SparseVector{{0: 2, 1: 4}}})
+	assert.NilError(t, err)
+	assert.NilError(t, mat.IsEqualVectors(predictions.Vectors[0], &mat.Vector{4.1, 5.9}, 1e-12))
+
+	modelPath = writeTempFile(t, `{"learner": {
+  "gradient_booster": {"name": "gblinear", "model": {"weights": [1, 2]}},
+  "learner_model_param": {"base_score": "0", "num_class": "0", "num_feature": "2"},
+  "objective": {"name": "reg:squarederror"}
+}}`)
+	_, err = LoadXGBoostFromModelJSON(modelPath, &activation.Raw{})
+	assert.Check(t, errors.Is(err, ErrMalformedModel))
+}
+
+func TestLoadXGBoostFromUBJ(t *testing.T) {
+	input, err := mat.ReadLibsvmFileToSparseMatrix("test/data/breast_cancer_test.libsvm")
+	assert.NilError(t, err)
+	expected, err := mat.ReadCSVFileToDenseMatrix("test/data/breast_cancer_xgboost_true_prediction.txt", "\t", 0.0)
+	assert.NilError(t, err)
+	f, err := os.Open("test/data/breast_cancer_xgboost_model.ubj")
+	assert.NilError(t, err)
+	defer f.Close()
+	ensemble, err := LoadXGBoostFromUBJ(f, nil)
+	assert.NilError(t, err)
+	predictions, err := ensemble.PredictProba(input)
+	assert.NilError(t, err)
+	assert.NilError(t, mat.IsEqualMatrices(&predictions, &expected, 0.0001))
+
+	input, err = mat.ReadLibsvmFileToSparseMatrix("test/data/iris_test.libsvm")
+	assert.NilError(t, err)
+	expected, err = mat.ReadCSVFileToDenseMatrix("test/data/iris_xgboost_true_prediction_proba.txt", "\t", 0.0)
+	assert.NilError(t, err)
+	modelPath := "test/data/iris_xgboost_model.ubj"
+	for _, path := range []string{modelPath, gzipFile(t, modelPath)} {
+		ensemble, err = LoadXGBoostModel(path, "", 0, 0, nil)
+		assert.NilError(t, err)
+		assert.Equal(t, ensemble.NumClasses(), 3)
+		predictions, err = ensemble.PredictProba(input)
+		assert.NilError(t, err)
+		assert.NilError(t, mat.IsEqualMatrices(&predictions, &expected, 0.0001))
+	}
+
+	// unsized containers, no-op markers and high precision numbers.
+	ubj := "{i\x07learner{i\x09objective{i\x04nameSi\x10reg:squarederror}" +
+		"i\x13learner_model_param{i\x0abase_scoreHi\x03" + "0.5" + "i\x09num_classSi\x010i\x0bnum_featureU\x01}" +
+		"i\x10gradient_booster{i\x04nameSi\x06gbtreei\x05model{i\x09tree_info[U\x00]" +
+		"i\x05trees[{i\x0dleft_children[i\xffN]i\x0eright_children[$i#i\x01\xff" +
+		"i\x0dsplit_indices[$U#U\x01\x00i\x10split_conditions[$D#i\x01?\xf0\x00\x00\x00\x00\x00\x00" +
+		"i\x0cdefault_left[F]}]}}}}"
+	ensemble, err = LoadXGBoostFromUBJ(strings.NewReader(ubj), nil)
+	assert.NilError(t, err)
+	pred, err := ensemble.PredictInner(mat.SparseVector{})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, pred, mat.Vector{1.5})
+
+	_, err = LoadXGBoostFromUBJ(strings.NewReader(ubj[:len(ubj)-10]), nil)
+	assert.Check(t, errors.Is(err, ErrMalformedModel))
+	_, err = LoadXGBoostFromUBJ(strings.NewReader("{[$d#L\x7f\xff\xff\xff\xff\xff\xff\xff"), nil)
+	assert.Check(t, errors.Is(err, ErrMalformedModel))
+	// corrupted lengths and nesting fail without allocating the length or exhausting the stack.
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	_, err = LoadXGBoostFromUBJ(strings.NewReader("{i\x07learnerSl\x7f\xff\xff\xffabc"), nil)
+	runtime.ReadMemStats(&after)
+	assert.Check(t, errors.Is(err, ErrMalformedModel))
+	assert.Check(t, after.TotalAlloc-before.TotalAlloc < 1<<20, after.TotalAlloc-before.TotalAlloc)
+	_, err = LoadXGBoostFromUBJ(strings.NewReader("{i\x01a"+strings.Repeat("[", 1<<20)), nil)
+	assert.Check(t, errors.Is(err, ErrMalformedModel))
+	assert.ErrorContains(t, err, "nested too deeply")
+}
+
+func TestLoadXGBoostFromBinary(t *testing.T) {
+	input, err := mat.ReadLibsvmFileToSparseMatrix("test/data/breast_cancer_test.libsvm")
+	assert.NilError(t, err)
+	expected, err := mat.ReadCSVFileToDenseMatrix("test/data/breast_cancer_xgboost_true_prediction.txt", "\t", 0.0)
+	assert.NilError(t, err)
+	modelPath := "test/data/breast_cancer_xgboost.model"
+	ensemble, err := LoadXGBoostFromBinary(modelPath, nil)
+	assert.NilError(t, err)
+	assert.Equal(t, ensemble.Type(), protobuf.ActivateType_LOGISTIC)
+	predictions, err := ensemble.PredictProba(input)
+	assert.NilError(t, err)
+	assert.NilError(t, mat.IsEqualMatrices(&predictions, &expected, 0.0001))
+
+	// before XGBoost 1.0 base_score is stored as a margin and the file may start with a binf header.
+	content, err := ioutil.ReadFile(modelPath)
+	assert.NilError(t, err)
+	legacy := append([]byte("binf"), content...)
+	copy(legacy[4:], []byte{0, 0, 0, 0})
+	copy(legacy[4+20:], []byte{0, 0, 0, 0})
+	legacyPath := writeTempFile(t, string(legacy))
+	for _, path := range []string{legacyPath, gzipFile(t, legacyPath)} {
+		ensemble, err = LoadXGBoostModel(path, "", 0, 0, nil)
+		assert.NilError(t, err)
+		predictions, err = ensemble.PredictProba(input)
+		assert.NilError(t, err)
+		assert.NilError(t, mat.IsEqualMatrices(&predictions, &expected, 0.0001))
+	}
+
+	input, err = mat.ReadLibsvmFileToSparseMatrix("test/data/iris_test.libsvm")
+	assert.NilError(t, err)
+	expected, err = mat.ReadCSVFileToDenseMatrix("test/data/iris_xgboost_true_prediction_proba.txt", "\t", 0.0)
+	assert.NilError(t, err)
+	ensemble, err = LoadXGBoostModel("test/data/iris_xgboost.model", "", 3, 0, &activation.Softmax{})
+	assert.NilError(t, err)
+	predictions, err = ensemble.PredictProba(input)
+	assert.NilError(t, err)
+	assert.NilError(t, mat.IsEqualMatrices(&predictions, &expected, 0.0001))
+
+	_, err = LoadXGBoostFromBinary(writeTempFile(t, string(content[:len(content)/2])), nil)
+	assert.Check(t, errors.Is(err, ErrMalformedModel))
+	_, err = LoadXGBoostFromBinary("test/data/breast_cancer_xgboost_model.json", nil)
+	assert.Check(t, errors.Is(err, ErrUnsupportedFormat))
+}
+
+// writeMulticlassSmallModel writes the small model as a softprob model with numClass classes, tree_info gives the
+// class of each tree and the trees of the small model are repeated to match its length.
+func writeMulticlassSmallModel(t *testing.T, numClass int, treeInfo []int) string {
+	b, err := ioutil.ReadFile("test/data/small_xgboost_model_stats.json")
+	assert.NilError(t, err)
+	var doc map[string]interface{}
+	assert.NilError(t, json.Unmarshal(b, &doc))
+	learner := doc["learner"].(map[string]interface{})
+	learner["objective"] = map[string]interface{}{"name": "multi:softprob"}
+	learner["learner_model_param"].(map[string]interface{})["num_class"] = strconv.Itoa(numClass)
+	model := learner["gradient_booster"].(map[string]interface{})["model"].(map[string]interface{})
+	trees := model["trees"].([]interface{})
+	for len(trees) < len(treeInfo) {
+		trees = append(trees, trees...)
+	}
+	model["trees"] = trees[:len(treeInfo)]
+	model["tree_info"] = treeInfo
+	b, err = json.Marshal(doc)
+	assert.NilError(t, err)
+	return writeTempFile(t, string(b))
+}
+
+func TestEnsemble_PredictContributions(t *testing.T) {
+	ensemble, err := LoadXGBoostFromJSON(smallModelPath, "", 1, 0, &activation.Raw{})
+	assert.NilError(t, err)
+	base := ensemble.EnsembleBase.(*xgbEnsemble)
+	contributions, err := ensemble.PredictContributions(smallModelInput)
+	assert.NilError(t, err)
+	margins, err := ensemble.PredictProba(smallModelInput)
+	assert.NilError(t, err)
+	for r, row := range contributions.Vectors {
+		assert.Equal(t, len(*row), base.numFeat+1)
+		phi, _ := bruteForceShapley(base, smallModelInput.Vectors[r])
+		sum := 0.0
+		for i, v := range *row {
+			if i < base.numFeat {
+				assert.Check(t, math.Abs(v-phi[i]) < 1e-9, "row %d feature %d", r, i)
+			}
+			sum += v
+		}
+		assert.Check(t, math.Abs(sum-(*margins.Vectors[r])[0]) < 1e-9)
+	}
+
+	// a 2 classes model where both classes have the trees of the small model.
+	multiclass, err := LoadXGBoostFromModelJSON(writeMulticlassSmallModel(t, 2, []int{0, 0, 0, 1, 1, 1}), nil)
+	assert.NilError(t, err)
+	single, err := LoadXGBoostFromModelJSON("test/data/small_xgboost_model_stats.json", nil)
+	assert.NilError(t, err)
+
+	expected, err := single.PredictContributions(smallModelInput)
+	assert.NilError(t, err)
+	contributions, err = multiclass.PredictContributions(smallModelInput)
+	assert.NilError(t, err)
+	for r, row := range contributions.Vectors {
+		want := append(append(mat.Vector{}, *expected.Vectors[r]...), *expected.Vectors[r]...)
+		assert.NilError(t, mat.IsEqualVectors(row, &want, 1e-9))
+	}
+
+	ensemble, err = LoadXGBoostFromJSON("test/data/breast_cancer_xgboost_dump.json", "", 1, 4, &activation.Logistic{})
+	assert.NilError(t, err)
+	_, err = ensemble.PredictContributions(smallModelInput)
+	assert.ErrorContains(t, err, "no cover statistics")
+}
+
+func TestEnsemble_PredictInteractionsMulticlass(t *testing.T) {
+	// each class has a single tree of the small model.
+	multiclass, err := LoadXGBoostFromModelJSON(writeMulticlassSmallModel(t, 3, []int{0, 1, 2}), nil)
+	assert.NilError(t, err)
+
+	interactions, err := multiclass.PredictInteractions(smallModelInput)
+	assert.NilError(t, err)
+	contributions, err := multiclass.PredictContributions(smallModelInput)
+	assert.NilError(t, err)
+	n := multiclass.NumFeatures() + 1
+	for r, m := range interactions {
+		rows, cols, err := m.Shape()
+		assert.NilError(t, err)
+		assert.Equal(t, rows, 3*n)
+		assert.Equal(t, cols, n)
+		// rows of class k sum to its SHAP values.
+		for i := 0; i < rows; i++ {
+			rowSum := 0.0
+			for _, v := range *m.Vectors[i] {
+				rowSum += v
+			}
+			assert.Check(t, math.Abs(rowSum-(*contributions.Vectors[r])[i]) < 1e-9, "row %d class %d", r, i/n)
+		}
+	}
+}
+
+func TestEnsemble_PredictLeafIndices(t *testing.T) {
+	ensemble, err := LoadXGBoostFromJSON(smallModelPath, "", 1, 0, &activation.Raw{})
+	assert.NilError(t, err)
+	base := ensemble.EnsembleBase.(*xgbEnsemble)
+	leaves, err := ensemble.PredictLeafIndices(smallModelInput)
+	assert.NilError(t, err)
+	margins, err := ensemble.PredictProba(smallModelInput)
+	assert.NilError(t, err)
+	for r, row := range leaves {
+		assert.Equal(t, len(row), len(base.Trees))
+		sum := 0.0
+		for i, id := range row {
+			node := base.Trees[i].nodes[id]
+			assert.Check(t, node.Flags&isLeaf > 0)
+			sum += node.LeafValues
+		}
+		assert.Equal(t, sum, (*margins.Vectors[r])[0])
+	}
+
+	// leaf ids are the same whatever the model format.
+	input, err := mat.ReadLibsvmFileToSparseMatrix("test/data/iris_test.libsvm")
+	assert.NilError(t, err)
+	dump, err := LoadXGBoostFromJSON("test/data/iris_xgboost_dump.json", "", 3, 3, &activation.Softmax{})
+	assert.NilError(t, err)
+	model, err := LoadXGBoostFromModelJSON("test/data/iris_xgboost_model.json", nil)
+	assert.NilError(t, err)
+	expected, err := dump.PredictLeafIndices(input)
+	assert.NilError(t, err)
+	leaves, err = model.PredictLeafIndices(input)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, leaves, expected)
+}
+
+func TestEnsemble_PredictApproxContributions(t *testing.T) {
+	ensemble, err := LoadXGBoostFromJSON(smallModelPath, "", 1, 0, &activation.Raw{})
+	assert.NilError(t, err)
+	base := ensemble.EnsembleBase.(*xgbEnsemble)
+	contributions, err := ensemble.PredictApproxContributions(smallModelInput)
+	assert.NilError(t, err)
+	margins, err := ensemble.PredictProba(smallModelInput)
+	assert.NilError(t, err)
+	for r, row := range contributions.Vectors {
+		assert.Equal(t, len(*row), base.numFeat+1)
+		// only features split on along the decision paths get a contribution.
+		used := map[int]bool{}
+		for _, tree := range base.Trees {
+			lookup := sparseLookup(smallModelInput.Vectors[r], nil)
+			for idx := 0; tree.nodes[idx].Flags&isLeaf == 0; {
+				node := tree.nodes[idx]
+				used[node.Feature] = true
+				v, ok := lookup(node.Feature)
+				idx = tree.next(idx, v, ok)
+			}
+		}
+		sum := 0.0
+		for i, v := range *row {
+			if i < base.numFeat && !used[i] {
+				assert.Equal(t, v, 0.0, "row %d feature %d", r, i)
+			}
+			sum += v
+		}
+		assert.Check(t, math.Abs(sum-(*margins.Vectors[r])[0]) < 1e-9)
+	}
+
+	multiclass, err := LoadXGBoostFromModelJSON(writeMulticlassSmallModel(t, 3, []int{0, 1, 2}), nil)
+	assert.NilError(t, err)
+	contributions, err = multiclass.PredictApproxContributions(smallModelInput)
+	assert.NilError(t, err)
+	numFeat := multiclass.NumFeatures()
+	for r, row := range contributions.Vectors {
+		assert.Equal(t, len(*row), 3*(numFeat+1))
+		margin, err := multiclass.PredictInner(smallModelInput.Vectors[r])
+		assert.NilError(t, err)
+		for c := 0; c < 3; c++ {
+			sum := 0.0
+			for _, v := range (*row)[c*(numFeat+1) : (c+1)*(numFeat+1)] {
+				sum += v
+			}
+			assert.Check(t, math.Abs(sum-margin[c]) < 1e-5, "row %d class %d", r, c)
+		}
+	}
+
+	input, err := mat.ReadLibsvmFileToSparseMatrix("test/data/iris_test.libsvm")
+	assert.NilError(t, err)
+	noStats, err := LoadXGBoostFromJSON("test/data/iris_xgboost_dump.json", "", 3, 3, &activation.Softmax{})
+	assert.NilError(t, err)
+	_, err = noStats.PredictApproxContributions(input)
+	assert.ErrorContains(t, err, "with_stats")
+}
+
+func TestEnsemble_PredictStaged(t *testing.T) {
+	const irisDump = "test/data/iris_xgboost_dump.json"
+	input, err := mat.ReadLibsvmFileToSparseMatrix("test/data/iris_test.libsvm")
+	assert.NilError(t, err)
+	ensemble, err := LoadXGBoostFromJSON(irisDump, "", 3, 3, &activation.Raw{})
+	assert.NilError(t, err)
+	rounds := len(ensemble.EnsembleBase.(*xgbEnsemble).Trees) / 3
+	staged, err := ensemble.PredictStaged(input)
+	assert.NilError(t, err)
+	assert.Equal(t, len(staged), rounds)
+
+	for _, k := range []int{0, 1, rounds - 1} {
+		limited, err := LoadXGBoostFromJSON(irisDump, "", 3, 3, &activation.Raw{}, WithIterationRange(0, k+1))
+		assert.NilError(t, err)
+		expected, err := limited.PredictProba(input)
+		assert.NilError(t, err)
+		assert.NilError(t, mat.IsEqualMatrices(&staged[k], &expected, 1e-9), "round %d", k)
+	}
+
+	// rounds [1, 3) predict the difference between the staged predictions after rounds 2 and 0.
+	ranged, err := LoadXGBoostFromJSON(irisDump, "", 3, 3, &activation.Raw{}, WithIterationRange(1, 3))
+	assert.NilError(t, err)
+	assert.Equal(t, len(ranged.EnsembleBase.(*xgbEnsemble).Trees), 6)
+	predictions, err := ranged.PredictProba(input)
+	assert.NilError(t, err)
+	for i, row := range predictions.Vectors {
+		for c, v := range *row {
+			assert.Check(t, math.Abs(v-((*staged[2].Vectors[i])[c]-(*staged[0].Vectors[i])[c])) < 1e-9)
+		}
+	}
+	model, err := LoadXGBoostFromModelJSON("test/data/iris_xgboost_model.json", &activation.Softmax{},
+		WithIterationRange(1, 3))
+	assert.NilError(t, err)
+	assert.Equal(t, len(model.EnsembleBase.(*xgbEnsemble).Trees), 6)
+
+	// the activation is applied to each round.
+	softmax, err := LoadXGBoostFromJSON(irisDump, "", 3, 3, &activation.Softmax{})
+	assert.NilError(t, err)
+	stagedProba, err := softmax.PredictStaged(input)
+	assert.NilError(t, err)
+	proba, err := softmax.PredictProba(input)
+	assert.NilError(t, err)
+	assert.NilError(t, mat.IsEqualMatrices(&stagedProba[rounds-1], &proba, 1e-9))
+
+	for _, r := range [][2]int{{-1, 2}, {2, 2}, {3, 1}, {rounds, 0}} {
+		_, err = LoadXGBoostFromJSON(irisDump, "", 3, 3, &activation.Raw{}, WithIterationRange(r[0], r[1]))
+		assert.ErrorContains(t, err, "invalid iteration range")
+	}
+}
+
+func TestEnsemble_PredictMargin(t *testing.T) {
+	input, err := mat.ReadLibsvmFileToSparseMatrix("test/data/iris_test.libsvm")
+	assert.NilError(t, err)
+	softmax, err := LoadXGBoostFromModelJSON("test/data/iris_xgboost_model.json", nil)
+	assert.NilError(t, err)
+	margins, err := softmax.PredictMargin(input)
+	assert.NilError(t, err)
+	proba, err := softmax.PredictProba(input)
+	assert.NilError(t, err)
+	for i, row := range margins.Vectors {
+		assert.Equal(t, len(*row), 3)
+		transformed, err := softmax.Transform(*row)
+		assert.NilError(t, err)
+		for c, v := range transformed {
+			assert.Check(t, math.Abs(v-(*proba.Vectors[i])[c]) < 1e-9)
+		}
+	}
+
+	// margins include base_score and are written by ScoreFile with OutputMargin.
+	logistic, err := LoadXGBoostFromModelJSON("test/data/breast_cancer_xgboost_model.json", nil)
+	assert.NilError(t, err)
+	input, err = mat.ReadLibsvmFileToSparseMatrix("test/data/breast_cancer_test.libsvm")
+	assert.NilError(t, err)
+	margins, err = logistic.PredictMargin(input)
+	assert.NilError(t, err)
+	logOdds, err := logistic.PredictLogOdds(input)
+	assert.NilError(t, err)
+	for i, row := range margins.Vectors {
+		assert.Equal(t, (*row)[0], logOdds[i])
+	}
+	output := writeTempFile(t, "")
+	assert.NilError(t, ScoreFile("test/data/breast_cancer_xgboost_model.json", "test/data/breast_cancer_test.libsvm",
+		output, ScoreOptions{OutputMargin: true}))
+	written, err := mat.ReadCSVFileToDenseMatrix(output, "\t", 0.0)
+	assert.NilError(t, err)
+	assert.NilError(t, mat.IsEqualMatrices(&written, &margins, 1e-9))
+}
+
+func TestEnsemble_PredictProbaWithBaseMargin(t *testing.T) {
+	ensemble, err := LoadXGBoostFromModelJSON("test/data/breast_cancer_xgboost_model.json", nil)
+	assert.NilError(t, err)
+	input, err := mat.ReadLibsvmFileToSparseMatrix("test/data/breast_cancer_test.libsvm")
+	assert.NilError(t, err)
+	margins, err := ensemble.PredictMargin(input)
+	assert.NilError(t, err)
+	base := ensemble.EnsembleBase.(*xgbEnsemble).baseMargin
+
+	offsets := mat.Matrix{Vectors: make([]*mat.Vector, len(input.Vectors))}
+	for i := range offsets.Vectors {
+		offsets.Vectors[i] = &mat.Vector{float64(i%5) - 2}
+	}
+	predictions, err := ensemble.PredictProbaWithBaseMargin(input, offsets)
+	assert.NilError(t, err)
+	for i, row := range predictions.Vectors {
+		// the offset replaces base_score.
+		margin := (*margins.Vectors[i])[0] - base + (*offsets.Vectors[i])[0]
+		assert.Check(t, math.Abs((*row)[0]-1/(1+math.Exp(-margin))) < 1e-9, "row %d", i)
+	}
+
+	_, err = ensemble.PredictProbaWithBaseMargin(input, mat.Matrix{Vectors: offsets.Vectors[1:]})
+	assert.Check(t, errors.Is(err, mat.ErrDimensionMismatch))
+	offsets.Vectors[3] = &mat.Vector{0, 0}
+	_, err = ensemble.PredictProbaWithBaseMargin(input, offsets)
+	assert.Check(t, errors.Is(err, mat.ErrDimensionMismatch))
+}
+
+func TestEnsemble_PredictProbaFromArrow(t *testing.T) {
+	ensemble, err := LoadXGBoostFromModelJSON("test/data/breast_cancer_xgboost_model.json", &activation.Logistic{})
+	assert.NilError(t, err)
+	columns := []string{"f0", "f1", "f2", "f3"}
+	for _, path := range []string{"test/data/small.arrow", "test/data/small.arrows"} {
+		m, _, err := mat.ReadArrowToMatrix(path, columns)
+		assert.NilError(t, err)
+		expected, err := ensemble.PredictProbaDense(m)
+		assert.NilError(t, err)
+		f, err := os.Open(path)
+		assert.NilError(t, err)
+		predictions, err := ensemble.PredictProbaFromArrow(f, columns)
+		f.Close()
+		assert.NilError(t, err)
+		assert.NilError(t, mat.IsEqualMatrices(&predictions, &expected, 0))
+	}
+}
+
+func TestEnsemble_PredictProbaCSR(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		load func() (*inference.Ensemble, error)
+		data string
+	}{
+		{"binary", func() (*inference.Ensemble, error) {
+			return LoadXGBoostFromModelJSON("test/data/breast_cancer_xgboost_model.json", nil)
+		}, "test/data/breast_cancer_test.libsvm"},
+		{"multiclass", func() (*inference.Ensemble, error) {
+			return LoadXGBoostFromModelJSON("test/data/iris_xgboost_model.json", &activation.Softmax{})
+		}, "test/data/iris_test.libsvm"},
+		{"lightgbm", func() (*inference.Ensemble, error) {
+			return LoadLightGBMModel("test/data/breast_cancer_lightgbm_model.txt", nil)
+		}, "test/data/breast_cancer_test.libsvm"},
+	} {
+		ensemble, err := tc.load()
+		assert.NilError(t, err, tc.name)
+		features, err := mat.ReadLibsvmFileToSparseMatrix(tc.data)
+		assert.NilError(t, err)
+		expected, err := ensemble.PredictProba(features)
+		assert.NilError(t, err)
+		predictions, err := ensemble.PredictProbaCSR(features.ToCSR())
+		assert.NilError(t, err, tc.name)
+		assert.DeepEqual(t, predictions, expected)
+
+		// base models without CSR support predict the converted rows.
+		generic := &inference.Ensemble{EnsembleBase: struct{ inference.EnsembleBase }{ensemble.EnsembleBase},
+			Activation: ensemble.Activation}
+		predictions, err = generic.PredictProbaCSR(features.ToCSR())
+		assert.NilError(t, err, tc.name)
+		assert.DeepEqual(t, predictions, expected)
+	}
+
+	ensemble, err := LoadXGBoostFromModelJSON("test/data/breast_cancer_xgboost_model.json", nil,
+		WithDimensionValidation())
+	assert.NilError(t, err)
+	_, err = ensemble.PredictProbaCSR(mat.CSRMatrix{Indptr: []int{0, 1}, Indices: []int{1000}, Values: []float64{1}})
+	assert.Check(t, errors.Is(err, mat.ErrFeatureIndexOutOfRange))
+	_, err = ensemble.PredictProbaCSR(mat.CSRMatrix{Indptr: []int{0, 2}, Indices: []int{3, 1}, Values: []float64{1, 2}})
+	assert.Check(t, errors.Is(err, mat.ErrMalformedRow))
+}
+
+func TestEnsemble_PredictDMatrix(t *testing.T) {
+	ensemble, err := LoadXGBoostFromModelJSON("test/data/breast_cancer_xgboost_model.json", nil)
+	assert.NilError(t, err)
+	d, err := mat.ReadLibsvmFileToDMatrix("test/data/breast_cancer_test.libsvm")
+	assert.NilError(t, err)
+	expected, err := ensemble.PredictProba(d.Features)
+	assert.NilError(t, err)
+	predictions, err := ensemble.PredictDMatrix(d)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, predictions, expected)
+
+	d.BaseMargins = mat.Matrix{Vectors: make([]*mat.Vector, d.Rows())}
+	for i := range d.BaseMargins.Vectors {
+		d.BaseMargins.Vectors[i] = &mat.Vector{float64(i%5) - 2}
+	}
+	expected, err = ensemble.PredictProbaWithBaseMargin(d.Features, d.BaseMargins)
+	assert.NilError(t, err)
+	predictions, err = ensemble.PredictDMatrix(d)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, predictions, expected)
+
+	d.Labels = d.Labels[1:]
+	_, err = ensemble.PredictDMatrix(d)
+	assert.Check(t, errors.Is(err, mat.ErrDimensionMismatch))
+}
+
+func TestEnsemble_MarginAUC(t *testing.T) {
+	// the AUC of margins is the one of probabilities.
+	ensemble, err := LoadXGBoostFromModelJSON("test/data/breast_cancer_xgboost_model.json", nil)
+	assert.NilError(t, err)
+	d, err := mat.ReadLibsvmFileToDMatrix("test/data/breast_cancer_test.libsvm")
+	assert.NilError(t, err)
+	proba, err := ensemble.PredictProba(d.Features)
+	assert.NilError(t, err)
+	margins, err := ensemble.PredictMargin(d.Features)
+	assert.NilError(t, err)
+	auc, err := metrics.AUC(proba.Flatten(), d.Labels, nil)
+	assert.NilError(t, err)
+	assert.Check(t, auc > 0.95, auc)
+	marginAUC, err := metrics.AUC(margins.Flatten(), d.Labels, nil)
+	assert.NilError(t, err)
+	assert.Check(t, math.Abs(marginAUC-auc) < 1e-12, "%v != %v", marginAUC, auc)
+}
+
+// lightgbmSmallModel is a hand written LightGBM model with one split per missing type and a single leaf tree.
+const lightgbmSmallModel = `tree
+version=v4
+num_class=1
+num_tree_per_iteration=1
+label_index=0
+max_feature_idx=2
+objective=regression
+feature_names=a b c
+tree_sizes=0 0
+
+Tree=0
+num_leaves=4
+num_cat=1
+split_feature=0 1 2
+split_gain=1 1 1
+threshold=1.5 0.5 0
+decision_type=10 4 1
+left_child=1 -2 -3
+right_child=-1 2 -4
+leaf_value=100 1 2 3
+leaf_count=10 10 10 10
+internal_count=40 30 20
+cat_boundaries=0 1
+cat_threshold=10
+is_linear=0
+shrinkage=1
+
+Tree=1
+num_leaves=1
+num_cat=0
+split_feature=
+split_gain=
+threshold=
+decision_type=
+left_child=
+right_child=
+leaf_value=0.5
+is_linear=0
+shrinkage=1
+
+end of trees
+
+parameters:
+end of parameters
+`
+
+func TestLoadLightGBMModel(t *testing.T) {
+	ensemble, err := LoadLightGBMModel(writeTempFile(t, lightgbmSmallModel), nil)
+	assert.NilError(t, err)
+	assert.Equal(t, ensemble.Name(), "lightgbm")
+	assert.Equal(t, ensemble.NumFeatures(), 3)
+	input := mat.SparseMatrix{Vectors: []mat.SparseVector{
+		// values equal to the threshold go left.
+		{0: 1.5, 1: 1, 2: 1},
+		{0: 1.6},
+		// NaN follows the default left direction of the first split, absent features are zeros and zeros go
+		// to the default right direction of the second split.
+		{0: math.NaN()},
+		{},
+		{1: 0.2},
+		{1: 0, 2: 3},
+		// categories are truncated, negative ones and NaN (category 0 without NaN missing type) go right.
+		{1: 1, 2: 3.7},
+		{1: 1, 2: -1},
+		{1: 1, 2: math.NaN()},
+	}}
+	expected := mat.Matrix{Vectors: []*mat.Vector{{2.5}, {100.5}, {3.5}, {3.5}, {1.5}, {2.5}, {2.5}, {3.5}, {3.5}}}
+	predictions, err := ensemble.PredictProba(input)
+	assert.NilError(t, err)
+	assert.NilError(t, mat.IsEqualMatrices(&predictions, &expected, 0))
+
+	dense := mat.Matrix{Vectors: []*mat.Vector{{1.5, 1, 1}, {math.NaN(), math.NaN(), math.NaN()}, {0, 0.2, 0}}}
+	expected = mat.Matrix{Vectors: []*mat.Vector{{2.5}, {3.5}, {1.5}}}
+	predictions, err = ensemble.PredictProbaDense(dense)
+	assert.NilError(t, err)
+	assert.NilError(t, mat.IsEqualMatrices(&predictions, &expected, 0))
+	leaves, err := ensemble.PredictLeafIndices(mat.SparseMatrix{Vectors: []mat.SparseVector{{0: 2}}})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, leaves, [][]int{{3, 0}})
+
+	ensemble, err = LoadXGBoostModel(writeTempFile(t, lightgbmSmallModel), "", 0, 0, nil, WithMissingValue(-999))
+	assert.NilError(t, err)
+	predictions, err = ensemble.PredictProba(mat.SparseMatrix{Vectors: []mat.SparseVector{{0: -999, 1: 0.2}}})
+	assert.NilError(t, err)
+	assert.Equal(t, (*predictions.Vectors[0])[0], 1.5)
+
+	// cover statistics come from data counts.
+	contributions, err := ensemble.PredictContributions(input)
+	assert.NilError(t, err)
+	for r, row := range contributions.Vectors {
+		sum := 0.0
+		for _, v := range *row {
+			sum += v
+		}
+		margin, err := ensemble.PredictInner(input.Vectors[r])
+		assert.NilError(t, err)
+		assert.Check(t, math.Abs(sum-margin[0]) < 1e-9, "row %d", r)
+	}
+}
+
+func TestLoadLightGBMModel_XGBoostEquivalent(t *testing.T) {
+	// the LightGBM models are converted from the XGBoost models, missing features are NaN so that both
+	// libraries send them to the default direction.
+	for _, c := range []struct{ lightgbm, xgboost, input string }{
+		{"test/data/breast_cancer_lightgbm_model.txt", "test/data/breast_cancer_xgboost_model.json",
+			"test/data/breast_cancer_test.libsvm"},
+		{"test/data/iris_lightgbm_model.txt", "test/data/iris_xgboost_model.json", "test/data/iris_test.libsvm"},
+	} {
+		input, err := mat.ReadLibsvmFileToSparseMatrix(c.input)
+		assert.NilError(t, err)
+		xgbModel, err := LoadXGBoostFromModelJSON(c.xgboost, nil)
+		assert.NilError(t, err)
+		lgbModel, err := LoadXGBoostModel(c.lightgbm, "", 0, 0, nil)
+		assert.NilError(t, err)
+		assert.Equal(t, lgbModel.NumClasses(), xgbModel.NumClasses())
+		assert.Equal(t, lgbModel.NumFeatures(), xgbModel.NumFeatures())
+		dense := mat.Matrix{Vectors: make([]*mat.Vector, len(input.Vectors))}
+		for i, row := range input.Vectors {
+			vec := make(mat.Vector, xgbModel.NumFeatures())
+			for j := range vec {
+				if v, ok := row[j]; ok {
+					vec[j] = v
+				} else {
+					vec[j] = math.NaN()
+				}
+			}
+			dense.Vectors[i] = &vec
+		}
+		expected, err := xgbModel.PredictProbaDense(dense)
+		assert.NilError(t, err)
+		predictions, err := lgbModel.PredictProbaDense(dense)
+		assert.NilError(t, err)
+		assert.NilError(t, mat.IsEqualMatrices(&predictions, &expected, 1e-6), c.lightgbm)
+	}
+}
+
+func TestLoadLightGBMModel_Errors(t *testing.T) {
+	_, err := LoadLightGBMModel("test/data/iris_xgboost_model.json", nil)
+	assert.Check(t, errors.Is(err, ErrUnsupportedFormat))
+
+	replace := func(old, new string) string {
+		return writeTempFile(t, strings.Replace(lightgbmSmallModel, old, new, 1))
+	}
+	_, err = LoadLightGBMModel(replace("objective=regression", "objective=cross_entropy_lambda"), nil)
+	assert.Check(t, errors.Is(err, activation.ErrUnsupportedObjective))
+	_, err = LoadLightGBMModel(replace("objective=regression", "objective=cross_entropy_lambda"), &activation.Raw{})
+	assert.NilError(t, err)
+	_, err = LoadLightGBMModel(replace("objective=regression", "objective=binary sigmoid:2"), nil)
+	assert.Check(t, errors.Is(err, ErrUnsupportedFormat))
+	_, err = LoadLightGBMModel(replace("objective=regression", "objective=regression sqrt"), nil)
+	assert.Check(t, errors.Is(err, ErrUnsupportedFormat))
+	_, err = LoadLightGBMModel(replace("objective=regression", "objective=regression sqrt"), &activation.Raw{})
+	assert.Check(t, errors.Is(err, ErrUnsupportedFormat))
+	_, err = LoadLightGBMModel(replace("objective=regression", "objective=regression\naverage_output"), nil)
+	assert.Check(t, errors.Is(err, ErrUnsupportedFormat))
+	_, err = LoadLightGBMModel(replace("is_linear=0", "is_linear=1"), nil)
+	assert.Check(t, errors.Is(err, ErrUnsupportedFormat))
+	_, err = LoadLightGBMModel(replace("objective=regression", "objective=binary sigmoid:1"), &activation.Raw{})
+	assert.ErrorContains(t, err, "expects LOGISTIC activation")
+
+	for old, new := range map[string]string{
+		"left_child=1 -2 -3":   "left_child=0 -2 -3",
+		"right_child=-1 2 -4":  "right_child=-1 2 -5",
+		"leaf_value=100 1 2 3": "leaf_value=100 1 2",
+		"cat_boundaries=0 1":   "cat_boundaries=0 2",
+		"decision_type=10 4 1": "decision_type=10 4",
+		"end of trees":         "",
+	} {
+		_, err = LoadLightGBMModel(replace(old, new), nil)
+		assert.Check(t, errors.Is(err, ErrMalformedModel), old)
+	}
+}
+
+// onnxNode is the decoded TreeEnsemble operator of an exported ONNX model.
+type onnxNode struct {
+	opType  string
+	ints    map[string][]int64
+	floats  map[string][]float32
+	strings map[string][]string
+}
+
+// onnxFields returns the length delimited and varint fields of a protobuf message by field number.
+func onnxFields(t *testing.T, b []byte) (map[protowire.Number][][]byte, map[protowire.Number][]uint64,
+	map[protowire.Number][]uint32) {
+	bytesFields := map[protowire.Number][][]byte{}
+	varints := map[protowire.Number][]uint64{}
+	fixed := map[protowire.Number][]uint32{}
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		assert.Assert(t, n > 0)
+		b = b[n:]
+		switch typ {
+		case protowire.BytesType:
+			v, n := protowire.ConsumeBytes(b)
+			assert.Assert(t, n > 0)
+			bytesFields[num] = append(bytesFields[num], v)
+			b = b[n:]
+		case protowire.VarintType:
+			v, n := protowire.ConsumeVarint(b)
+			assert.Assert(t, n > 0)
+			varints[num] = append(varints[num], v)
+			b = b[n:]
+		case protowire.Fixed32Type:
+			v, n := protowire.ConsumeFixed32(b)
+			assert.Assert(t, n > 0)
+			fixed[num] = append(fixed[num], v)
+			b = b[n:]
+		default:
+			t.Fatalf("unexpected wire type %d", typ)
+		}
+	}
+	return bytesFields, varints, fixed
+}
+
+func decodeONNX(t *testing.T, b []byte) onnxNode {
+	model, _, _ := onnxFields(t, b)
+	assert.Equal(t, len(model[7]), 1)
+	graph, _, _ := onnxFields(t, model[7][0])
+	assert.Equal(t, len(graph[1]), 1)
+	node, _, _ := onnxFields(t, graph[1][0])
+	r := onnxNode{opType: string(node[4][0]), ints: map[string][]int64{}, floats: map[string][]float32{},
+		strings: map[string][]string{}}
+	assert.Equal(t, string(node[7][0]), "ai.onnx.ml")
+	for _, a := range node[5] {
+		bytesFields, varints, fixed := onnxFields(t, a)
+		name := string(bytesFields[1][0])
+		for _, v := range varints[8] {
+			r.ints[name] = append(r.ints[name], int64(v))
+		}
+		if v, ok := varints[3]; ok {
+			r.ints[name] = []int64{int64(v[0])}
+		}
+		for _, v := range fixed[7] {
+			r.floats[name] = append(r.floats[name], math.Float32frombits(v))
+		}
+		for _, s := range append(bytesFields[9], bytesFields[4]...) {
+			r.strings[name] = append(r.strings[name], string(s))
+		}
+	}
+	return r
+}
+
+// predict evaluates the operator like ONNX runtimes do and returns its scores after post transform.
+func (n onnxNode) predict(row []float32) []float64 {
+	prefix := "class"
+	if n.opType == "TreeEnsembleRegressor" {
+		prefix = "target"
+	}
+	type key struct{ tree, node int64 }
+	index := map[key]int{}
+	for i := range n.ints["nodes_nodeids"] {
+		index[key{n.ints["nodes_treeids"][i], n.ints["nodes_nodeids"][i]}] = i
+	}
+	weights := map[key][]int{}
+	for i := range n.ints[prefix+"_nodeids"] {
+		k := key{n.ints[prefix+"_treeids"][i], n.ints[prefix+"_nodeids"][i]}
+		weights[k] = append(weights[k], i)
+	}
+	scores := make([]float64, len(n.floats["base_values"]))
+	for i, v := range n.floats["base_values"] {
+		scores[i] = float64(v)
+	}
+	for tree := int64(0); ; tree++ {
+		i, ok := index[key{tree, 0}]
+		if !ok {
+			break
+		}
+		for n.strings["nodes_modes"][i] != "LEAF" {
+			v := row[n.ints["nodes_featureids"][i]]
+			next := n.ints["nodes_falsenodeids"][i]
+			if math.IsNaN(float64(v)) {
+				if n.ints["nodes_missing_value_tracks_true"][i] == 1 {
+					next = n.ints["nodes_truenodeids"][i]
+				}
+			} else if v < n.floats["nodes_values"][i] {
+				next = n.ints["nodes_truenodeids"][i]
+			}
+			i = index[key{tree, next}]
+		}
+		for _, w := range weights[key{tree, n.ints["nodes_nodeids"][i]}] {
+			scores[n.ints[prefix+"_ids"][w]] += float64(n.floats[prefix+"_weights"][w])
+		}
+	}
+	switch n.strings["post_transform"][0] {
+	case "LOGISTIC":
+		return []float64{1 / (1 + math.Exp(-scores[0]))}
+	case "SOFTMAX":
+		r, err := (&activation.Softmax{}).Transform(scores)
+		if err != nil {
+			panic(err)
+		}
+		return r
+	}
+	return scores
+}
+
+func TestExportONNX(t *testing.T) {
+	for _, c := range []struct {
+		model, input, opType string
+		raw                  bool
+	}{
+		{"test/data/breast_cancer_xgboost_model.json", "test/data/breast_cancer_test.libsvm",
+			"TreeEnsembleClassifier", false},
+		{"test/data/iris_xgboost_model.json", "test/data/iris_test.libsvm", "TreeEnsembleClassifier", false},
+		{"test/data/iris_xgboost_model.json", "test/data/iris_test.libsvm", "TreeEnsembleRegressor", true},
+	} {
+		ensemble, err := LoadXGBoostModel(c.model, "", 0, 0, nil, WithFloat32Comparison())
+		assert.NilError(t, err)
+		if c.raw {
+			ensemble.Activation = &activation.Raw{}
+		}
+		var buf bytes.Buffer
+		assert.NilError(t, ExportONNX(ensemble, &buf))
+		node := decodeONNX(t, buf.Bytes())
+		assert.Equal(t, node.opType, c.opType)
+
+		input, err := mat.ReadLibsvmFileToSparseMatrix(c.input)
+		assert.NilError(t, err)
+		var expected mat.Matrix
+		if c.raw {
+			expected, err = ensemble.PredictMargin(input)
+		} else {
+			expected, err = ensemble.PredictProba(input)
+		}
+		assert.NilError(t, err)
+		for i, row := range input.Vectors {
+			dense := make([]float32, ensemble.NumFeatures())
+			for j := range dense {
+				if v, ok := row[j]; ok {
+					dense[j] = float32(v)
+				} else {
+					dense[j] = float32(math.NaN())
+				}
+			}
+			predictions := node.predict(dense)
+			assert.Equal(t, len(predictions), len(*expected.Vectors[i]))
+			for k, v := range predictions {
+				assert.Check(t, math.Abs(v-(*expected.Vectors[i])[k]) < 1e-5, "%s row %d", c.model, i)
+			}
+		}
+	}
+
+	ensemble, err := LoadLightGBMModel(writeTempFile(t, lightgbmSmallModel), nil)
+	assert.NilError(t, err)
+	assert.Check(t, errors.Is(ExportONNX(ensemble, ioutil.Discard), ErrUnsupportedFormat))
+}
+
+func TestExportGo(t *testing.T) {
+	goTool, err := exec.LookPath("go")
+	if err != nil || testing.Short() {
+		t.Skip("go tool is needed to compile generated code")
+	}
+	breastCancer, err := mat.ReadLibsvmFileToSparseMatrix("test/data/breast_cancer_test.libsvm")
+	assert.NilError(t, err)
+	iris, err := mat.ReadLibsvmFileToSparseMatrix("test/data/iris_test.libsvm")
+	assert.NilError(t, err)
+	lightgbmInput := mat.SparseMatrix{Vectors: []mat.SparseVector{
+		{0: 1.5, 1: 1, 2: 1}, {0: 1.6}, {0: math.NaN()}, {}, {1: 0.2}, {1: 0, 2: 3}, {1: 1, 2: 3.7}, {1: 1, 2: -1},
+		{1: 1, 2: math.NaN()},
+	}}
+	lightgbm, err := LoadLightGBMModel(writeTempFile(t, lightgbmSmallModel), nil)
+	assert.NilError(t, err)
+	cases := []struct {
+		name     string
+		model    string
+		ensemble *inference.Ensemble
+		input    mat.SparseMatrix
+		raw      bool
+	}{
+		{name: "BreastCancer", model: "test/data/breast_cancer_xgboost_model.json", input: breastCancer},
+		{name: "Iris", model: "test/data/iris_xgboost_model.json", input: iris},
+		{name: "IrisRaw", model: "test/data/iris_xgboost_model.json", input: iris, raw: true},
+		{name: "IrisFloat32", model: "test/data/iris_xgboost_model.json", input: iris},
+		{name: "IrisFloat32Values", model: "test/data/iris_xgboost_model.json", input: iris},
+		{name: "IrisOneBased", model: "test/data/iris_xgboost_model.json", input: iris},
+		{name: "LightGBM", ensemble: lightgbm, input: lightgbmInput},
+	}
+
+	dir, err := ioutil.TempDir("", "xgboost_codegen")
+	assert.NilError(t, err)
+	defer os.RemoveAll(dir)
+	var main bytes.Buffer
+	main.WriteString("package main\n\nimport (\n\"fmt\"\n\"math\"\n)\n\nvar _ = math.NaN\n\nfunc main() {\n")
+	var expected []float64
+	for i, c := range cases {
+		if c.ensemble == nil {
+			var opts []LoadOption
+			switch c.name {
+			case "IrisFloat32":
+				opts = append(opts, WithFloat32Comparison())
+			case "IrisFloat32Values":
+				opts = append(opts, WithFloat32Values())
+			case "IrisOneBased":
+				opts = append(opts, WithOneBasedFeatureIndices())
+			}
+			c.ensemble, err = LoadXGBoostModel(c.model, "", 0, 0, nil, opts...)
+			assert.NilError(t, err)
+			if c.raw {
+				c.ensemble.Activation = &activation.Raw{}
+			}
+		}
+		f, err := os.Create(filepath.Join(dir, fmt.Sprintf("model%d.go", i)))
+		assert.NilError(t, err)
+		assert.NilError(t, ExportGo(c.ensemble, f, "main", c.name))
+		assert.NilError(t, f.Close())
+
+		// one-based models read feature i at index i+1 of the generated code and of the sparse rows.
+		base := 0
+		if c.name == "IrisOneBased" {
+			base = 1
+		}
+		dense := mat.Matrix{Vectors: make([]*mat.Vector, len(c.input.Vectors))}
+		shifted := mat.SparseMatrix{Vectors: make([]mat.SparseVector, len(c.input.Vectors))}
+		for r, row := range c.input.Vectors {
+			vec := make(mat.Vector, c.ensemble.NumFeatures())
+			shifted.Vectors[r] = mat.SparseVector{}
+			for idx, v := range row {
+				shifted.Vectors[r][idx+base] = v
+			}
+			fmt.Fprintf(&main, "fmt.Println(%s([]float64{", c.name)
+			if base > 0 {
+				main.WriteString("math.NaN(), ")
+			}
+			for j := range vec {
+				v, ok := row[j]
+				if !ok && c.ensemble.Name() != "lightgbm" {
+					v = math.NaN()
+				}
+				vec[j] = v
+				if math.IsNaN(v) {
+					main.WriteString("math.NaN(), ")
+				} else {
+					fmt.Fprintf(&main, "%v, ", v)
+				}
+			}
+			main.WriteString("}))\n")
+			dense.Vectors[r] = &vec
+		}
+		predictions, err := c.ensemble.PredictProbaDense(dense)
+		assert.NilError(t, err)
+		if base > 0 {
+			predictions, err = c.ensemble.PredictProba(shifted)
+			assert.NilError(t, err)
+		}
+		for _, row := range predictions.Vectors {
+			expected = append(expected, *row...)
+		}
+	}
+	main.WriteString("}\n")
+	assert.NilError(t, ioutil.WriteFile(filepath.Join(dir, "main.go"), main.Bytes(), 0644))
+	assert.NilError(t, ioutil.WriteFile(filepath.Join(dir, "go.mod"), []byte("module codegen\n\ngo 1.14\n"), 0644))
+
+	cmd := exec.Command(goTool, "run", ".")
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	assert.NilError(t, err, string(out))
+	fields := strings.Fields(strings.NewReplacer("[", " ", "]", " ").Replace(string(out)))
+	assert.Equal(t, len(fields), len(expected))
+	for i, field := range fields {
+		v, err := strconv.ParseFloat(field, 64)
+		assert.NilError(t, err)
+		assert.Check(t, math.Abs(v-expected[i]) < 1e-12, "value %d: %v != %v", i, v, expected[i])
+	}
+
+	var buf bytes.Buffer
+	ensemble, err := LoadXGBoostModel("test/data/iris_xgboost_model.json", "", 0, 0, nil)
+	assert.NilError(t, err)
+	assert.ErrorContains(t, ExportGo(ensemble, &buf, "main", "predict"), "must be an exported identifier")
+	assert.ErrorContains(t, ExportGo(ensemble, &buf, "a-b", "Predict"), "invalid package name")
+	ensemble.Activation = &activation.Logistic{}
+	assert.Check(t, errors.Is(ExportGo(ensemble, &buf, "main", "Predict"), ErrUnsupportedFormat))
+}
+
+const pmmlSmallModel = `<?xml version="1.0" encoding="UTF-8"?>
+<PMML xmlns="http://www.dmg.org/PMML-4_4" version="4.4">
+ <DataDictionary>
+  <DataField name="y" optype="continuous" dataType="double"/>
+  <DataField name="a" optype="continuous" dataType="double"/>
+  <DataField name="b" optype="continuous" dataType="double"/>
+  <DataField name="c" optype="categorical" dataType="integer"/>
+ </DataDictionary>
+ <TreeModel functionName="regression" missingValueStrategy="defaultChild">
+  <MiningSchema>
+   <MiningField name="y" usageType="target"/>
+   <MiningField name="a"/>
+   <MiningField name="b"/>
+   <MiningField name="c"/>
+  </MiningSchema>
+  <Targets><Target field="y" rescaleConstant="0.5"/></Targets>
+  <Node id="0" defaultChild="1"><True/>
+   <Node id="1"><SimplePredicate field="a" operator="lessOrEqual" value="1.5"/>
+    <Node id="3" score="10"><CompoundPredicate booleanOperator="surrogate">
+     <SimplePredicate field="b" operator="greaterOrEqual" value="2"/><True/></CompoundPredicate></Node>
+    <Node id="4" score="20"><SimplePredicate field="b" operator="lessThan" value="2"/></Node>
+   </Node>
+   <Node id="2" defaultChild="5"><True/>
+    <Node id="5" score="30"><SimpleSetPredicate field="c" booleanOperator="isIn">
+     <Array type="int" n="2">1 3</Array></SimpleSetPredicate></Node>
+    <Node id="6" score="40"><True/></Node>
+   </Node>
+  </Node>
+ </TreeModel>
+</PMML>
+`
+
+func TestLoadPMML(t *testing.T) {
+	ensemble, err := LoadPMML(writeTempFile(t, pmmlSmallModel), nil)
+	assert.NilError(t, err)
+	assert.Equal(t, ensemble.Name(), "pmml")
+	assert.Equal(t, ensemble.NumFeatures(), 3)
+	assert.Equal(t, ensemble.NumClasses(), 1)
+	input := mat.SparseMatrix{Vectors: []mat.SparseVector{
+		{0: 1.5, 1: 2},
+		{0: 1.5, 1: 1},
+		// missing values follow the surrogate predicate and the default child of nodes.
+		{0: 1},
+		{},
+		{0: 2, 2: 3},
+		{0: 2, 2: 2},
+		{0: 2},
+		// categories are truncated like XGBoost categorical splits.
+		{0: 2, 2: 3.5},
+	}}
+	expected := mat.Matrix{Vectors: []*mat.Vector{{10.5}, {20.5}, {10.5}, {10.5}, {30.5}, {40.5}, {30.5}, {30.5}}}
+	predictions, err := ensemble.PredictProba(input)
+	assert.NilError(t, err)
+	assert.NilError(t, mat.IsEqualMatrices(&predictions, &expected, 0))
+}
+
+func TestLoadPMML_XGBoostEquivalent(t *testing.T) {
+	// the PMML models are converted by hand from the XGBoost models, test/scripts/xgboost_pmml.py writes the
+	// ones converted by JPMML-XGBoost.
+	for _, c := range []struct{ pmml, xgboost, input string }{
+		{"test/data/breast_cancer_xgboost.pmml", "test/data/breast_cancer_xgboost_model.json",
+			"test/data/breast_cancer_test.libsvm"},
+		{"test/data/iris_xgboost.pmml", "test/data/iris_xgboost_model.json", "test/data/iris_test.libsvm"},
+	} {
+		input, err := mat.ReadLibsvmFileToSparseMatrix(c.input)
+		assert.NilError(t, err)
+		for _, opts := range [][]LoadOption{nil, {WithIterationRange(1, 3)}} {
+			xgbModel, err := LoadXGBoostFromModelJSON(c.xgboost, nil, opts...)
+			assert.NilError(t, err)
+			pmmlModel, err := LoadPMML(c.pmml, nil, opts...)
+			assert.NilError(t, err)
+			assert.Equal(t, pmmlModel.NumClasses(), xgbModel.NumClasses())
+			assert.Equal(t, pmmlModel.Type(), xgbModel.Type())
+			expected, err := xgbModel.PredictProba(input)
+			assert.NilError(t, err)
+			predictions, err := pmmlModel.PredictProba(input)
+			assert.NilError(t, err)
+			assert.NilError(t, mat.IsEqualMatrices(&predictions, &expected, 1e-6), c.pmml)
+		}
+	}
+}
+
+func TestLoadPMML_Errors(t *testing.T) {
+	_, err := LoadPMML("test/data/iris_xgboost_model.json", nil)
+	assert.Check(t, errors.Is(err, ErrMalformedModel))
+
+	replace := func(old, new string) string {
+		return writeTempFile(t, strings.Replace(pmmlSmallModel, old, new, 1))
+	}
+	for old, new := range map[string]string{
+		`operator="lessOrEqual"`:                 `operator="equal"`,
+		`<Node id="6" score="40"><True/></Node>`: `<Node id="6" score="40"><True/></Node><Node score="1"><True/></Node>`,
+		`rescaleConstant="0.5"`:                  `rescaleConstant="0.5" rescaleFactor="2"`,
+		`functionName="regression"`:              `functionName="clustering"`,
+		`booleanOperator="surrogate"`:            `booleanOperator="and"`,
+	} {
+		_, err = LoadPMML(replace(old, new), nil)
+		assert.Check(t, errors.Is(err, ErrUnsupportedFormat), old)
+	}
+	for old, new := range map[string]string{
+		`field="a"`:      `field="d"`,
+		`score="10"`:     `score="x"`,
+		`<MiningSchema>`: `<Schema>`,
+		`</PMML>`:        ``,
+	} {
+		_, err = LoadPMML(replace(old, new), nil)
+		assert.Check(t, errors.Is(err, ErrMalformedModel), old)
+	}
+}
+
+func TestLoadTreelite(t *testing.T) {
+	ensemble, err := LoadTreelite("test/data/small_treelite.bin", nil)
+	assert.NilError(t, err)
+	assert.Equal(t, ensemble.Name(), "treelite")
+	assert.Equal(t, ensemble.NumFeatures(), 3)
+	assert.Equal(t, ensemble.NumClasses(), 2)
+	input := mat.SparseMatrix{Vectors: []mat.SparseVector{
+		// values equal to a less or equal threshold go left, missing values follow the default direction.
+		{0: 1},
+		// categories of the split go right, values greater than the threshold go left.
+		{0: 2, 1: 3, 2: 2},
+		{0: 2, 1: 2, 2: 2.5},
+		{0: 2},
+	}}
+	expected := mat.Matrix{Vectors: []*mat.Vector{{10.5, 2.5}, {30.5, 3.5}, {20.5, 2.5}, {20.5, 2.5}}}
+	predictions, err := ensemble.PredictProba(input)
+	assert.NilError(t, err)
+	assert.NilError(t, mat.IsEqualMatrices(&predictions, &expected, 0))
+}
+
+func TestLoadTreelite_XGBoostEquivalent(t *testing.T) {
+	// the checkpoints are converted by hand from the XGBoost models, test/scripts/treelite_checkpoints.py writes
+	// the ones serialized by treelite.
+	for _, c := range []struct{ treelite, xgboost, input string }{
+		{"test/data/breast_cancer_treelite.bin", "test/data/breast_cancer_xgboost_model.json",
+			"test/data/breast_cancer_test.libsvm"},
+		{"test/data/iris_treelite.bin", "test/data/iris_xgboost_model.json", "test/data/iris_test.libsvm"},
+	} {
+		input, err := mat.ReadLibsvmFileToSparseMatrix(c.input)
+		assert.NilError(t, err)
+		for _, opts := range [][]LoadOption{nil, {WithIterationRange(1, 3)}} {
+			xgbModel, err := LoadXGBoostFromModelJSON(c.xgboost, nil, opts...)
+			assert.NilError(t, err)
+			treeliteModel, err := LoadTreelite(c.treelite, nil, opts...)
+			assert.NilError(t, err)
+			assert.Equal(t, treeliteModel.NumClasses(), xgbModel.NumClasses())
+			assert.Equal(t, treeliteModel.NumFeatures(), xgbModel.NumFeatures())
+			assert.Equal(t, treeliteModel.Type(), xgbModel.Type())
+			expected, err := xgbModel.PredictProba(input)
+			assert.NilError(t, err)
+			predictions, err := treeliteModel.PredictProba(input)
+			assert.NilError(t, err)
+			assert.NilError(t, mat.IsEqualMatrices(&predictions, &expected, 1e-6), c.treelite)
+		}
+	}
+}
+
+func TestLoadTreelite_Errors(t *testing.T) {
+	model, err := ioutil.ReadFile("test/data/small_treelite.bin")
+	assert.NilError(t, err)
+	write := func(offset int, b ...byte) string {
+		corrupted := append([]byte{}, model...)
+		copy(corrupted[offset:], b)
+		return writeTempFile(t, string(corrupted))
+	}
+	// version 3 checkpoints have a different layout.
+	_, err = LoadTreelite(write(0, 3), nil)
+	assert.Check(t, errors.Is(err, ErrUnsupportedFormat))
+	// uint32 leaf outputs.
+	_, err = LoadTreelite(write(13, 1), nil)
+	assert.Check(t, errors.Is(err, ErrUnsupportedFormat))
+	// averaged trees of random forests.
+	_, err = LoadTreelite(write(27, 1), nil)
+	assert.Check(t, errors.Is(err, ErrUnsupportedFormat))
+	_, err = LoadTreelite(writeTempFile(t, string(model[:len(model)-10])), nil)
+	assert.Check(t, errors.Is(err, ErrMalformedModel))
+	_, err = LoadTreelite("test/data/iris_xgboost_model.json", nil)
+	assert.Check(t, errors.Is(err, ErrUnsupportedFormat))
+}
+
+const catboostSmallModel = `{
+  "model_info": {"params": {"loss_function": {"type": "RMSE"}}},
+  "features_info": {
+    "float_features": [
+      {"borders": [1.5], "feature_index": 0, "flat_feature_index": 0, "nan_value_treatment": "AsIs"},
+      {"borders": [0.5], "feature_index": 1, "flat_feature_index": 2, "nan_value_treatment": "AsTrue"}
+    ]
+  },
+  "oblivious_trees": [
+    {
+      "leaf_values": [1, 2, 3, 4],
+      "leaf_weights": [10, 20, 30, 40],
+      "splits": [
+        {"border": 1.5, "float_feature_index": 0, "split_index": 0, "split_type": "FloatFeature"},
+        {"border": 0.5, "float_feature_index": 1, "split_index": 1, "split_type": "FloatFeature"}
+      ]
+    },
+    {"leaf_values": [10], "leaf_weights": [100], "splits": []}
+  ],
+  "scale_and_bias": [2, [0.5]]
+}`
+
+func TestLoadCatBoostModel(t *testing.T) {
+	ensemble, err := LoadCatBoostModel(writeTempFile(t, catboostSmallModel), nil)
+	assert.NilError(t, err)
+	assert.Equal(t, ensemble.Name(), "catboost")
+	assert.Equal(t, ensemble.NumFeatures(), 3)
+	assert.Equal(t, ensemble.NumClasses(), 1)
+	input := mat.SparseMatrix{Vectors: []mat.SparseVector{
+		// the first split sets bit 0 of the leaf index, values equal to the border are not greater than it.
+		{0: 1, 2: 0},
+		{0: 2, 2: 0},
+		{0: 1.5, 2: 1},
+		{0: 2, 2: 1},
+		// missing values do not satisfy the first split and satisfy the AsTrue one.
+		{},
+	}}
+	expected := mat.Matrix{Vectors: []*mat.Vector{{22.5}, {24.5}, {26.5}, {28.5}, {26.5}}}
+	predictions, err := ensemble.PredictProba(input)
+	assert.NilError(t, err)
+	assert.NilError(t, mat.IsEqualMatrices(&predictions, &expected, 0))
+
+	// contributions of the scaled leaves sum to the prediction.
+	contributions, err := ensemble.PredictContributions(input)
+	assert.NilError(t, err)
+	for i, row := range contributions.Vectors {
+		sum := 0.0
+		for _, v := range *row {
+			sum += v
+		}
+		assert.Check(t, math.Abs(sum-(*expected.Vectors[i])[0]) < 1e-9, i)
+	}
+
+	multiclass := strings.NewReplacer(
+		`"RMSE"`, `"MultiClass"`,
+		`[1, 2, 3, 4]`, `[1, -1, 2, -2, 3, -3, 4, -4]`,
+		`[10]`, `[10, 20]`,
+		`[2, [0.5]]`, `[1, [0, 1]]`).Replace(catboostSmallModel)
+	ensemble, err = LoadCatBoostModel(writeTempFile(t, multiclass), nil)
+	assert.NilError(t, err)
+	assert.Equal(t, ensemble.NumClasses(), 2)
+	assert.Equal(t, ensemble.Type(), protobuf.ActivateType_SOFTMAX)
+	margins, err := ensemble.PredictMargin(mat.SparseMatrix{Vectors: []mat.SparseVector{{0: 2, 2: 0}}})
+	assert.NilError(t, err)
+	expected = mat.Matrix{Vectors: []*mat.Vector{{12, 19}}}
+	assert.NilError(t, mat.IsEqualMatrices(&margins, &expected, 0))
+}
+
+func TestLoadCatBoostModel_Errors(t *testing.T) {
+	_, err := LoadCatBoostModel("test/data/iris_lightgbm_model.txt", nil)
+	assert.Check(t, errors.Is(err, ErrUnsupportedFormat))
+
+	replace := func(old, new string) string {
+		return writeTempFile(t, strings.Replace(catboostSmallModel, old, new, 1))
+	}
+	_, err = LoadCatBoostModel(replace(`"RMSE"`, `"Lq"`), nil)
+	assert.Check(t, errors.Is(err, activation.ErrUnsupportedObjective))
+	_, err = LoadCatBoostModel(replace(`"RMSE"`, `"Lq"`), &activation.Raw{})
+	assert.NilError(t, err)
+	_, err = LoadCatBoostModel(replace(`"RMSE"`, `"Logloss"`), &activation.Raw{})
+	assert.ErrorContains(t, err, "expects LOGISTIC activation")
+
+	for old, new := range map[string]string{
+		`"float_features"`:             `"categorical_features": [{"feature_index": 1}], "float_features"`,
+		`"split_type": "FloatFeature"`: `"split_type": "OnlineCtr"`,
+		`"oblivious_trees"`:            `"trees"`,
+	} {
+		_, err = LoadCatBoostModel(replace(old, new), nil)
+		assert.Check(t, errors.Is(err, ErrUnsupportedFormat), old)
+	}
+	for old, new := range map[string]string{
+		`[1, 2, 3, 4]`:                 `[1, 2, 3]`,
+		`"float_feature_index": 1`:     `"float_feature_index": 2`,
+		`"scale_and_bias": [2, [0.5]]`: `"scale_and_bias": [2]`,
+		`"model_info"`:                 `"info"`,
+	} {
+		_, err = LoadCatBoostModel(replace(old, new), nil)
+		assert.Check(t, errors.Is(err, ErrMalformedModel), old)
+	}
+}
+
+// halfActivation is a custom activation halving raw predictions.
+type halfActivation struct{}
+
+func (a *halfActivation) Transform(rawPredictions mat.Vector) (mat.Vector, error) {
+	for i := range rawPredictions {
+		rawPredictions[i] /= 2
+	}
+	return rawPredictions, nil
+}
+
+func (a *halfActivation) Type() protobuf.ActivateType {
+	return protobuf.ActivateType_UNKNOWN
+}
+
+func (a *halfActivation) Name() string {
+	return "half"
+}
+
+func TestActivationRegistry(t *testing.T) {
+	a, err := activation.ByName("LOGISTIC")
+	assert.NilError(t, err)
+	assert.Equal(t, a.Type(), protobuf.ActivateType_LOGISTIC)
+	_, err = activation.ByName("half")
+	assert.Check(t, errors.Is(err, activation.ErrUnknownActivation))
+
+	// models with a registered objective use the registered activation.
+	activation.Register("reg:half", &halfActivation{})
+	assert.Check(t, func() (panicked bool) {
+		defer func() { panicked = recover() != nil }()
+		activation.Register("reg:half", &halfActivation{})
+		return false
+	}(), "duplicate registration must panic")
+	assert.Check(t, func() (panicked bool) {
+		defer func() { panicked = recover() != nil }()
+		activation.Register("other", nil)
+		return false
+	}())
+	a, err = activation.ByName("reg:half")
+	assert.NilError(t, err)
+	assert.Equal(t, a.Name(), "half")
+
+	content, err := ioutil.ReadFile("test/data/breast_cancer_xgboost_model.json")
+	assert.NilError(t, err)
+	modelPath := writeTempFile(t, strings.Replace(string(content), "binary:logistic", "reg:half", 1))
+	ensemble, err := LoadXGBoostFromModelJSON(modelPath, nil)
+	assert.NilError(t, err)
+	assert.Equal(t, ensemble.Activation.Name(), "half")
+	input := mat.SparseMatrix{Vectors: []mat.SparseVector{{0: 1, 1: 2}}}
+	margins, err := ensemble.PredictMargin(input)
+	assert.NilError(t, err)
+	predictions, err := ensemble.PredictProba(input)
+	assert.NilError(t, err)
+	assert.Equal(t, (*predictions.Vectors[0])[0], (*margins.Vectors[0])[0]/2)
+	_, err = LoadXGBoostFromModelJSON(modelPath, &activation.Raw{})
+	assert.ErrorContains(t, err, "expects UNKNOWN activation")
+}
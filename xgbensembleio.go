@@ -18,52 +18,76 @@ import (
 type xgboostJSON struct {
 	NodeID                int            `json:"nodeid,omitempty"`
 	SplitFeatureID        string         `json:"split,omitempty"`
-	SplitFeatureThreshold float64        `json:"split_condition,omitempty"`
+	SplitFeatureThreshold splitCondition `json:"split_condition,omitempty"`
 	YesID                 int            `json:"yes,omitempty"`
 	NoID                  int            `json:"no,omitempty"`
 	MissingID             int            `json:"missing,omitempty"`
 	LeafValue             float64        `json:"leaf,omitempty"`
+	Cover                 float64        `json:"cover,omitempty"`
+	Gain                  float64        `json:"gain,omitempty"`
 	Children              []*xgboostJSON `json:"children,omitempty"`
 }
 
-func loadFeatureMap(filePath string) (map[string]int, error) {
+// splitCondition is either a numerical threshold or a list of categories for categorical splits.
+type splitCondition struct {
+	Threshold  float64
+	Categories []int
+}
+
+// UnmarshalJSON parses split condition from a number or an array of categories.
+func (c *splitCondition) UnmarshalJSON(data []byte) error {
+	trimmed := strings.TrimSpace(string(data))
+	if strings.HasPrefix(trimmed, "[") {
+		c.Categories = make([]int, 0)
+		return json.Unmarshal(data, &c.Categories)
+	}
+	return json.Unmarshal(data, &c.Threshold)
+}
+
+// categoricalFeatureType is the feature map type of categorical features.
+const categoricalFeatureType = "c"
+
+// loadFeatureMap returns the feature name to index mapping and the type of each feature index.
+func loadFeatureMap(filePath string) (map[string]int, map[int]string, error) {
 	featureFile, err := os.Open(filePath)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	defer featureFile.Close()
 
 	read := bufio.NewReader(featureFile)
 	featureMap := make(map[string]int, 0)
+	featureTypes := make(map[int]string, 0)
 	for {
 		// feature map format: feature_index feature_name feature_type
 		line, err := read.ReadString('\n')
-		if err != nil {
-			if err == io.EOF {
-				break
-			}
-			return nil, err
+		if err != nil && err != io.EOF {
+			return nil, nil, err
 		}
-		tk := strings.Split(line, " ")
+		if err == io.EOF && len(strings.TrimSpace(line)) == 0 {
+			break
+		}
+		tk := strings.Fields(line)
 		if len(tk) != 3 {
-			return nil, fmt.Errorf("wrong feature map format")
+			return nil, nil, fmt.Errorf("%w: wrong feature map format", ErrMalformedModel)
 		}
 		featIdx, err := strconv.Atoi(tk[0])
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 		if _, ok := featureMap[tk[1]]; ok {
-			return nil, fmt.Errorf("duplicate feature name")
+			return nil, nil, fmt.Errorf("%w: duplicate feature name %s", ErrMalformedModel, tk[1])
 		}
 		featureMap[tk[1]] = featIdx
+		featureTypes[featIdx] = tk[2]
 	}
-	return featureMap, nil
+	return featureMap, featureTypes, nil
 }
 
 func convertFeatToIdx(featureMap map[string]int, feature string) (int, error) {
 	if featureMap != nil {
 		if _, ok := featureMap[feature]; !ok {
-			return 0, fmt.Errorf("cannot find feature %s in feature map", feature)
+			return 0, fmt.Errorf("%w: cannot find feature %s in feature map", ErrMalformedModel, feature)
 		}
 		return featureMap[feature], nil
 
@@ -78,7 +102,21 @@ func convertFeatToIdx(featureMap map[string]int, feature string) (int, error) {
 	return idx, nil
 }
 
-func buildTree(xgbTreeJSON *xgboostJSON, maxDepth int, featureMap map[string]int) (*xgbTree, int, error) {
+// featureMapNames returns the name of each feature index of a feature map.
+func featureMapNames(featMap map[string]int) []string {
+	maxIdx := -1
+	for _, idx := range featMap {
+		maxIdx = maxInt(maxIdx, idx)
+	}
+	names := make([]string, maxIdx+1)
+	for name, idx := range featMap {
+		names[idx] = name
+	}
+	return names
+}
+
+func buildTree(xgbTreeJSON *xgboostJSON, maxDepth int, featureMap map[string]int,
+	featureTypes map[int]string) (*xgbTree, int, error) {
 	stack := make([]*xgboostJSON, 0)
 	maxFeatIdx := 0
 	t := &xgbTree{}
@@ -99,6 +137,7 @@ func buildTree(xgbTreeJSON *xgboostJSON, maxDepth int, featureMap map[string]int
 				NodeID:     stackData.NodeID,
 				Flags:      isLeaf,
 				LeafValues: stackData.LeafValue,
+				Cover:      stackData.Cover,
 			}
 		} else {
 			featIdx, err := convertFeatToIdx(featureMap, stackData.SplitFeatureID)
@@ -110,11 +149,26 @@ func buildTree(xgbTreeJSON *xgboostJSON, maxDepth int, featureMap map[string]int
 			}
 			node = &xgbNode{
 				NodeID:    stackData.NodeID,
-				Threshold: stackData.SplitFeatureThreshold,
+				Threshold: stackData.SplitFeatureThreshold.Threshold,
 				No:        stackData.NoID,
 				Yes:       stackData.YesID,
 				Missing:   stackData.MissingID,
 				Feature:   featIdx,
+				Cover:     stackData.Cover,
+				Gain:      stackData.Gain,
+			}
+			categorical := stackData.SplitFeatureThreshold.Categories != nil
+			if featureTypes != nil && categorical != (featureTypes[featIdx] == categoricalFeatureType) {
+				return nil, 0, fmt.Errorf("%w: feature %s has type %s in feature map which does not match its split",
+					ErrMalformedModel,
+					stackData.SplitFeatureID, featureTypes[featIdx])
+			}
+			if categorical {
+				node.Flags |= isCategorical
+				node.Categories = make(map[int]struct{}, len(stackData.SplitFeatureThreshold.Categories))
+				for _, c := range stackData.SplitFeatureThreshold.Categories {
+					node.Categories[c] = struct{}{}
+				}
 			}
 			// find real length of the tree.
 			if maxDepth != 0 {
@@ -129,8 +183,8 @@ func buildTree(xgbTreeJSON *xgboostJSON, maxDepth int, featureMap map[string]int
 		}
 		if maxNumNodes > 0 {
 			if node.NodeID >= maxNumNodes {
-				return nil, 0, fmt.Errorf("wrong tree max depth %d, please check your model again for the"+
-					" correct parameter", maxDepth)
+				return nil, 0, fmt.Errorf("%w: wrong tree max depth %d, please check your model again for the"+
+					" correct parameter", ErrMalformedModel, maxDepth)
 			}
 			t.nodes[node.NodeID] = node
 		} else {
@@ -145,33 +199,68 @@ func buildTree(xgbTreeJSON *xgboostJSON, maxDepth int, featureMap map[string]int
 	} else {
 		t.nodes = t.nodes[:maxIdx+1]
 	}
+	t.computeNodeMeans()
 
 	return t, maxFeatIdx, nil
 }
 
-// LoadXGBoostFromJSON loads xgboost model from json file.
+// LoadXGBoostFromJSON loads xgboost model from json file generated by dump_model, the file can be gzip compressed.
+// Split thresholds and leaf values are kept as float64 and compared against float64 feature values unless
+// WithFloat32Comparison is given.
 func LoadXGBoostFromJSON(
 	modelPath,
 	featuresMapPath string,
 	numClasses int,
 	maxDepth int,
-	activation activation.Activation) (*inference.Ensemble, error) {
-	modelFile, err := os.Open(modelPath)
+	act activation.Activation,
+	opts ...LoadOption) (*inference.Ensemble, error) {
+	modelFile, format, err := openModelFile(modelPath)
 	if err != nil {
 		return nil, err
 	}
 	defer modelFile.Close()
+	if format != formatDumpJSON {
+		return nil, fmt.Errorf("%w: %s is %s, expected a json dump", ErrUnsupportedFormat, modelPath, format)
+	}
+	return loadXGBoostDump(modelFile, featuresMapPath, numClasses, maxDepth, act, opts...)
+}
+
+// loadXGBoostDump loads xgboost model from json dump content.
+func loadXGBoostDump(
+	r io.Reader,
+	featuresMapPath string,
+	numClasses int,
+	maxDepth int,
+	act activation.Activation,
+	opts ...LoadOption) (*inference.Ensemble, error) {
+	cfg := newLoadConfig(opts)
+	var err error
+	if len(cfg.objective) != 0 {
+		if act == nil {
+			act, err = activation.FromObjective(cfg.objective)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if err := activation.CheckObjective(cfg.objective, act); err != nil {
+			return nil, err
+		}
+	}
+	if act == nil {
+		return nil, fmt.Errorf("activation cannot be nil")
+	}
 
 	var xgbEnsembleJSON []*xgboostJSON
 
-	dec := json.NewDecoder(modelFile)
+	dec := json.NewDecoder(r)
 	err = dec.Decode(&xgbEnsembleJSON)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("%w: %s", ErrMalformedModel, err)
 	}
 	var featMap map[string]int
+	var featTypes map[int]string
 	if len(featuresMapPath) != 0 {
-		featMap, err = loadFeatureMap(featuresMapPath)
+		featMap, featTypes, err = loadFeatureMap(featuresMapPath)
 		if err != nil {
 			return nil, err
 		}
@@ -186,21 +275,35 @@ func LoadXGBoostFromJSON(
 		return nil, fmt.Errorf("num class cannot be 0 or smaller: %d", numClasses)
 	}
 	if nTrees == 0 {
-		return nil, fmt.Errorf("no trees in file")
+		return nil, fmt.Errorf("%w: no trees in file", ErrMalformedModel)
 	} else if nTrees%numClasses != 0 {
-		return nil, fmt.Errorf("wrong number of trees %d for number of class %d", nTrees, numClasses)
+		return nil, fmt.Errorf("%w: wrong number of trees %d for number of class %d", ErrMalformedModel,
+			nTrees, numClasses)
+	}
+	begin, end, err := cfg.iterationRange(nTrees / numClasses)
+	if err != nil {
+		return nil, err
 	}
 
-	e := &xgbEnsemble{name: "xgboost", numClasses: numClasses}
-	e.Trees = make([]*xgbTree, 0, nTrees)
+	e := &xgbEnsemble{name: "xgboost", numClasses: numClasses, missing: cfg.missing}
+	if featMap != nil {
+		e.featureNames = featureMapNames(featMap)
+	}
+	e.Trees = make([]*xgbTree, 0, (end-begin)*numClasses)
 	// TODO: Need to check if max feature index will be the last feature column.
 	// if it is not the case we should find another way to find the number of features.
 	maxFeat := 0
-	for i := 0; i < nTrees; i++ {
-		tree, numFeat, err := buildTree(xgbEnsembleJSON[i], maxDepth, featMap)
+	for i := begin * numClasses; i < end*numClasses; i++ {
+		tree, numFeat, err := buildTree(xgbEnsembleJSON[i], maxDepth, featMap, featTypes)
 		if err != nil {
-			return nil, fmt.Errorf("error while reading %d tree: %s", i, err.Error())
+			return nil, fmt.Errorf("error while reading %d tree: %w", i, err)
+		}
+		if cfg.validateValues {
+			if err := tree.validate(); err != nil {
+				return nil, fmt.Errorf("error while reading %d tree: %w", i, err)
+			}
 		}
+		tree.float32Comparison = cfg.float32Comparison
 		e.Trees = append(e.Trees, tree)
 		if numFeat > maxFeat {
 			maxFeat = numFeat
@@ -208,5 +311,5 @@ func LoadXGBoostFromJSON(
 	}
 	e.numFeat = maxFeat + 1
 
-	return &inference.Ensemble{EnsembleBase: e, Activation: activation}, nil
+	return e.ensemble(&cfg, act)
 }
@@ -0,0 +1,17 @@
+package xgboost
+
+import (
+	"errors"
+
+	"github.com/lordberre/xgboost-go/activation"
+)
+
+// Errors returned by the model loaders, wrapped with details so callers can check them using errors.Is.
+var (
+	// ErrMalformedModel is returned when a model or feature map file does not have the expected format.
+	ErrMalformedModel = errors.New("malformed model")
+	// ErrUnsupportedFormat is returned when a model file format is not supported.
+	ErrUnsupportedFormat = errors.New("unsupported model format")
+	// ErrUnsupportedObjective is returned when the model objective is not supported.
+	ErrUnsupportedObjective = activation.ErrUnsupportedObjective
+)
@@ -0,0 +1,49 @@
+package xgboost
+
+import "fmt"
+
+// nameFeatures returns the feature names of a model of numFeat features named names and the index of each name.
+// Names given with WithFeatureMap or WithFeatureNames override the names stored in the model.
+func nameFeatures(cfg *loadConfig, names []string, numFeat int) ([]string, map[string]int, error) {
+	var override []string
+	if cfg.featureMapPath != "" {
+		featMap, _, err := loadFeatureMap(cfg.featureMapPath)
+		if err != nil {
+			return nil, nil, err
+		}
+		override = featureMapNames(featMap)
+	}
+	if cfg.featureNames != nil {
+		override = append([]string(nil), cfg.featureNames...)
+	}
+	if override != nil {
+		if len(override) < numFeat {
+			return nil, nil, fmt.Errorf("%d feature names for a model of %d features", len(override), numFeat)
+		}
+		names = override
+	}
+	if names == nil {
+		return nil, nil, nil
+	}
+	index := make(map[string]int, len(names))
+	for idx, name := range names {
+		if name == "" {
+			continue
+		}
+		if _, ok := index[name]; ok {
+			return nil, nil, fmt.Errorf("duplicate feature name %s", name)
+		}
+		index[name] = idx
+	}
+	return names, index, nil
+}
+
+// FeatureIndex returns the index of the feature called name in sparse rows and false if the model has no such
+// feature.
+func (e *xgbEnsemble) FeatureIndex(name string) (int, bool) {
+	idx, ok := e.featureIndex[name]
+	if !ok {
+		return 0, false
+	}
+	return idx + e.indexBase, true
+}
@@ -0,0 +1,193 @@
+package xgboost
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/lordberre/xgboost-go/activation"
+	"github.com/lordberre/xgboost-go/inference"
+)
+
+// modelFormat is the serialization format of a model file.
+type modelFormat int
+
+const (
+	formatUnknown modelFormat = iota
+	// formatDumpJSON is the json array written by dump_model.
+	formatDumpJSON
+	// formatModelJSON is the json object written by save_model.
+	formatModelJSON
+	// formatUBJSON is the universal binary json written by save_model.
+	formatUBJSON
+	// formatBinary is the legacy binary format written by save_model before XGBoost 2.0.
+	formatBinary
+	// formatLightGBM is the text format written by LightGBM save_model.
+	formatLightGBM
+)
+
+func (f modelFormat) String() string {
+	switch f {
+	case formatDumpJSON:
+		return "json dump"
+	case formatModelJSON:
+		return "json model"
+	case formatUBJSON:
+		return "ubjson model"
+	case formatBinary:
+		return "binary model"
+	case formatLightGBM:
+		return "lightgbm model"
+	}
+	return "unknown format"
+}
+
+// gzipMagic are the first bytes of gzip compressed files.
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// modelFile is an opened model file, possibly decompressed on the fly.
+type modelFile struct {
+	*bufio.Reader
+	closers []io.Closer
+}
+
+// Close closes the decompressor and the underlying file.
+func (f *modelFile) Close() error {
+	var err error
+	for i := len(f.closers) - 1; i >= 0; i-- {
+		if e := f.closers[i].Close(); e != nil && err == nil {
+			err = e
+		}
+	}
+	return err
+}
+
+// isSpace returns true for json whitespaces.
+func isSpace(b byte) bool {
+	return b == ' ' || b == '\t' || b == '\n' || b == '\r'
+}
+
+// detectFormat guesses the model format from the first bytes of r without consuming them.
+func detectFormat(r *bufio.Reader) (modelFormat, error) {
+	head, err := r.Peek(binaryLearnerParamSize + 8 + 64)
+	if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+		return formatUnknown, err
+	}
+	if isBinaryModel(head) {
+		return formatBinary, nil
+	}
+	if bytes.HasPrefix(head, []byte(lightgbmHeader+"\n")) || bytes.HasPrefix(head, []byte(lightgbmHeader+"\r\n")) {
+		return formatLightGBM, nil
+	}
+	i := 0
+	for i < len(head) && isSpace(head[i]) {
+		i++
+	}
+	if i == len(head) {
+		return formatUnknown, nil
+	}
+	switch head[i] {
+	case '[':
+		return formatDumpJSON, nil
+	case '{':
+		// json objects continue with a quoted key, ubjson objects with a key length type marker
+		// or an optimized container marker.
+		i++
+		if i < len(head) && (head[i] == '$' || head[i] == '#' || head[i] == 'i' || head[i] == 'U' ||
+			head[i] == 'I' || head[i] == 'l' || head[i] == 'L') {
+			return formatUBJSON, nil
+		}
+		for i < len(head) && isSpace(head[i]) {
+			i++
+		}
+		if i == len(head) || head[i] == '"' || head[i] == '}' {
+			return formatModelJSON, nil
+		}
+	}
+	return formatUnknown, nil
+}
+
+// openModelFile opens a model file, transparently decompressing gzip content, and detects its format.
+func openModelFile(path string) (*modelFile, modelFormat, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, formatUnknown, err
+	}
+	f, format, err := newModelFile(file, path)
+	if err != nil {
+		file.Close()
+		return nil, formatUnknown, err
+	}
+	f.closers = append([]io.Closer{file}, f.closers...)
+	return f, format, nil
+}
+
+// newModelFile wraps the content of the model named name read from r, transparently decompressing gzip content,
+// and detects its format. Closing it does not close r.
+func newModelFile(r io.Reader, name string) (*modelFile, modelFormat, error) {
+	f := &modelFile{Reader: bufio.NewReader(r)}
+	magic, err := f.Peek(len(gzipMagic))
+	if err == nil && magic[0] == gzipMagic[0] && magic[1] == gzipMagic[1] {
+		gz, err := gzip.NewReader(f.Reader)
+		if err != nil {
+			return nil, formatUnknown, fmt.Errorf("%w: cannot decompress %s: %s", ErrMalformedModel, name, err)
+		}
+		f.closers = append(f.closers, gz)
+		f.Reader = bufio.NewReader(gz)
+	}
+	format, err := detectFormat(f.Reader)
+	if err != nil {
+		f.Close()
+		return nil, formatUnknown, err
+	}
+	return f, format, nil
+}
+
+// LoadXGBoostModel loads a model file whatever the format it was saved with, gzip compressed files are
+// decompressed transparently and the content format is detected from its first bytes. Parameters are the
+// same as LoadXGBoostFromJSON. For models saved with Booster.save_model in JSON, UBJSON or binary format,
+// featuresMapPath and maxDepth are not used, numClasses is checked against the model unless it is 0 and act can
+// be nil, see LoadXGBoostFromModelJSON. LightGBM text models are loaded the same way, see LoadLightGBMModel.
+// Other formats return an ErrUnsupportedFormat error.
+func LoadXGBoostModel(
+	modelPath,
+	featuresMapPath string,
+	numClasses int,
+	maxDepth int,
+	act activation.Activation,
+	opts ...LoadOption) (*inference.Ensemble, error) {
+	f, format, err := openModelFile(modelPath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return loadModelFile(f, format, modelPath, featuresMapPath, numClasses, maxDepth, act, opts...)
+}
+
+// loadModelFile loads the model named name from f of the given format, see LoadXGBoostModel.
+func loadModelFile(
+	f *modelFile,
+	format modelFormat,
+	name,
+	featuresMapPath string,
+	numClasses int,
+	maxDepth int,
+	act activation.Activation,
+	opts ...LoadOption) (*inference.Ensemble, error) {
+	switch format {
+	case formatDumpJSON:
+		return loadXGBoostDump(f, featuresMapPath, numClasses, maxDepth, act, opts...)
+	case formatModelJSON:
+		return loadXGBoostModelJSON(f, numClasses, act, opts...)
+	case formatUBJSON:
+		return loadXGBoostModelUBJ(f, numClasses, act, opts...)
+	case formatBinary:
+		return loadXGBoostBinary(f, numClasses, act, opts...)
+	case formatLightGBM:
+		return loadLightGBM(f, numClasses, act, opts...)
+	}
+	return nil, fmt.Errorf("%w: %s is neither json, ubjson, binary nor lightgbm model", ErrUnsupportedFormat, name)
+}
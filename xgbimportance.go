@@ -0,0 +1,66 @@
+package xgboost
+
+import (
+	"fmt"
+
+	"github.com/lordberre/xgboost-go/inference"
+)
+
+// FeatureNames returns the name of each feature, nil if the model does not name them.
+func (e *xgbEnsemble) FeatureNames() []string {
+	return e.featureNames
+}
+
+// FeatureImportanceInner returns the importance of every feature used by a split like XGBoost get_score, kind is
+// one of the inference.Importance types.
+func (e *xgbEnsemble) FeatureImportanceInner(kind string) (map[int]float64, error) {
+	var needsGain, needsCover bool
+	switch kind {
+	case inference.ImportanceWeight:
+	case inference.ImportanceGain, inference.ImportanceTotalGain:
+		needsGain = true
+	case inference.ImportanceCover, inference.ImportanceTotalCover:
+		needsCover = true
+	default:
+		return nil, fmt.Errorf("unknown importance type %q", kind)
+	}
+
+	splits := make(map[int]int)
+	totals := make(map[int]float64)
+	hasGain := false
+	for i, t := range e.Trees {
+		for j, n := range t.nodes {
+			if n == nil || n.Flags&isLeaf > 0 {
+				continue
+			}
+			if needsCover && n.Cover <= 0 {
+				return nil, fmt.Errorf("tree %d node %d has no cover statistics, please dump the model with "+
+					"with_stats=True", i, j)
+			}
+			splits[n.Feature]++
+			switch {
+			case needsGain:
+				totals[n.Feature] += n.Gain
+				hasGain = hasGain || n.Gain != 0
+			case needsCover:
+				totals[n.Feature] += n.Cover
+			}
+		}
+	}
+	if needsGain && !hasGain && len(splits) > 0 {
+		return nil, fmt.Errorf("model has no gain statistics, please dump the model with with_stats=True")
+	}
+
+	r := make(map[int]float64, len(splits))
+	for feat, count := range splits {
+		switch kind {
+		case inference.ImportanceWeight:
+			r[feat] = float64(count)
+		case inference.ImportanceGain, inference.ImportanceCover:
+			r[feat] = totals[feat] / float64(count)
+		default:
+			r[feat] = totals[feat]
+		}
+	}
+	return r, nil
+}
@@ -0,0 +1,38 @@
+package xgboost
+
+import (
+	"sort"
+
+	"github.com/lordberre/xgboost-go/inference"
+)
+
+// TreesInner returns a copy of the trees of the ensemble.
+func (e *xgbEnsemble) TreesInner() []inference.Tree {
+	trees := make([]inference.Tree, len(e.Trees))
+	for i, t := range e.Trees {
+		trees[i] = inference.Tree{Class: i % e.numClasses, Nodes: make([]*inference.Node, len(t.nodes))}
+		for j, n := range t.nodes {
+			if n == nil {
+				continue
+			}
+			node := &inference.Node{ID: j, Leaf: n.Flags&isLeaf > 0, Cover: n.Cover, Gain: n.Gain}
+			if node.Leaf {
+				node.Value = n.LeafValues
+			} else {
+				node.Feature, node.Yes, node.No, node.Missing = n.Feature, n.Yes, n.No, n.Missing
+				node.ZeroAsMissing = n.Flags&isZeroMissing > 0
+				if n.Flags&isCategorical > 0 {
+					node.Categories = make([]int, 0, len(n.Categories))
+					for c := range n.Categories {
+						node.Categories = append(node.Categories, c)
+					}
+					sort.Ints(node.Categories)
+				} else {
+					node.Threshold = n.Threshold
+				}
+			}
+			trees[i].Nodes[j] = node
+		}
+	}
+	return trees
+}
@@ -0,0 +1,424 @@
+package xgboost
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/lordberre/xgboost-go/activation"
+	"github.com/lordberre/xgboost-go/inference"
+)
+
+// Bits of LightGBM decision_type values.
+const (
+	lightgbmCategoricalMask = 1
+	lightgbmDefaultLeftMask = 2
+	lightgbmMissingZero     = 1
+	lightgbmMissingNaN      = 2
+)
+
+// lightgbmHeader is the first line of LightGBM model files.
+const lightgbmHeader = "tree"
+
+// lightgbmObjectives maps LightGBM objectives to the XGBoost objective with the same output transformation.
+var lightgbmObjectives = map[string]string{
+	"binary":        "binary:logistic",
+	"cross_entropy": "reg:logistic",
+	"multiclass":    "multi:softprob",
+	"regression":    "reg:squarederror",
+	"regression_l1": "reg:absoluteerror",
+	"huber":         "reg:pseudohubererror",
+	"fair":          "reg:squarederror",
+	"quantile":      "reg:quantileerror",
+	"poisson":       "count:poisson",
+	"gamma":         "reg:gamma",
+	"tweedie":       "reg:tweedie",
+	"mape":          "reg:absoluteerror",
+	"lambdarank":    "rank:ndcg",
+	"rank_xendcg":   "rank:ndcg",
+}
+
+// lightgbmSection contains the key=value lines of the header or of a tree of a LightGBM model file.
+type lightgbmSection map[string]string
+
+func (s lightgbmSection) int(key string) (int, error) {
+	v, ok := s[key]
+	if !ok {
+		return 0, fmt.Errorf("%w: missing %s", ErrMalformedModel, key)
+	}
+	r, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %s is not an integer: %s", ErrMalformedModel, key, v)
+	}
+	return r, nil
+}
+
+// floats returns the space separated values of key, nil if key is absent.
+func (s lightgbmSection) floats(key string) ([]float64, error) {
+	v, ok := s[key]
+	if !ok {
+		return nil, nil
+	}
+	fields := strings.Fields(v)
+	r := make([]float64, len(fields))
+	for i, f := range fields {
+		var err error
+		if r[i], err = strconv.ParseFloat(f, 64); err != nil {
+			return nil, fmt.Errorf("%w: %s has invalid value %s", ErrMalformedModel, key, f)
+		}
+	}
+	return r, nil
+}
+
+// ints returns the space separated values of key, nil if key is absent.
+func (s lightgbmSection) ints(key string) ([]int, error) {
+	v, ok := s[key]
+	if !ok {
+		return nil, nil
+	}
+	fields := strings.Fields(v)
+	r := make([]int, len(fields))
+	for i, f := range fields {
+		var err error
+		if r[i], err = strconv.Atoi(f); err != nil {
+			return nil, fmt.Errorf("%w: %s has invalid value %s", ErrMalformedModel, key, f)
+		}
+	}
+	return r, nil
+}
+
+// readLightGBMModel reads the header and the trees of a LightGBM model file, the sections following the trees
+// such as feature importances and parameters are not read.
+func readLightGBMModel(r io.Reader) (lightgbmSection, []lightgbmSection, error) {
+	br := bufio.NewReader(r)
+	header := lightgbmSection{}
+	var trees []lightgbmSection
+	section := header
+	for first := true; ; first = false {
+		line, err := br.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return nil, nil, err
+		}
+		if len(line) == 0 && err == io.EOF {
+			return nil, nil, fmt.Errorf("%w: missing end of trees", ErrMalformedModel)
+		}
+		line = strings.TrimSpace(line)
+		if first && line != lightgbmHeader {
+			return nil, nil, fmt.Errorf("%w: not a lightgbm model", ErrMalformedModel)
+		}
+		if line == "end of trees" {
+			return header, trees, nil
+		}
+		if strings.HasPrefix(line, "Tree=") {
+			section = lightgbmSection{}
+			trees = append(trees, section)
+			continue
+		}
+		if idx := strings.IndexByte(line, '='); idx >= 0 {
+			section[line[:idx]] = line[idx+1:]
+		} else if line != "" {
+			section[line] = ""
+		}
+	}
+}
+
+// lightgbmCategories returns the categories of the bitset at catIdx.
+func lightgbmCategories(boundaries []int, bitsets []int, catIdx int) (map[int]struct{}, error) {
+	if catIdx < 0 || catIdx+1 >= len(boundaries) || boundaries[catIdx] < 0 ||
+		boundaries[catIdx] > boundaries[catIdx+1] || boundaries[catIdx+1] > len(bitsets) {
+		return nil, fmt.Errorf("%w: invalid categorical split %d", ErrMalformedModel, catIdx)
+	}
+	r := make(map[int]struct{})
+	for i, bits := range bitsets[boundaries[catIdx]:boundaries[catIdx+1]] {
+		for b := 0; b < 32; b++ {
+			if uint32(bits)&(1<<uint(b)) != 0 {
+				r[i*32+b] = struct{}{}
+			}
+		}
+	}
+	return r, nil
+}
+
+// buildLightGBMTree creates a tree from a LightGBM tree section together with its max feature index. Split nodes
+// keep their LightGBM index and leaf i follows them at index numLeaves - 1 + i. The <= comparison of
+// LightGBM is turned into the < comparison of XGBoost with the next float64 after the threshold. Leaf values
+// already include the shrinkage of their tree.
+func buildLightGBMTree(s lightgbmSection) (*xgbTree, int, error) {
+	if isLinear, ok := s["is_linear"]; ok && isLinear != "0" {
+		return nil, 0, fmt.Errorf("%w: linear trees are not supported", ErrUnsupportedFormat)
+	}
+	numLeaves, err := s.int("num_leaves")
+	if err != nil {
+		return nil, 0, err
+	}
+	leafValues, err := s.floats("leaf_value")
+	if err != nil {
+		return nil, 0, err
+	}
+	if numLeaves < 1 || len(leafValues) != numLeaves {
+		return nil, 0, fmt.Errorf("%w: %d leaf values for %d leaves", ErrMalformedModel, len(leafValues),
+			numLeaves)
+	}
+	arrays := make(map[string][]int)
+	for _, key := range []string{"split_feature", "decision_type", "left_child", "right_child", "cat_boundaries",
+		"cat_threshold", "internal_count", "leaf_count"} {
+		if arrays[key], err = s.ints(key); err != nil {
+			return nil, 0, err
+		}
+	}
+	thresholds, err := s.floats("threshold")
+	if err != nil {
+		return nil, 0, err
+	}
+	gains, err := s.floats("split_gain")
+	if err != nil {
+		return nil, 0, err
+	}
+	numSplits := numLeaves - 1
+	for _, key := range []string{"split_feature", "decision_type", "left_child", "right_child"} {
+		if len(arrays[key]) != numSplits {
+			return nil, 0, fmt.Errorf("%w: %s has %d values for %d splits", ErrMalformedModel, key,
+				len(arrays[key]), numSplits)
+		}
+	}
+	if len(thresholds) != numSplits {
+		return nil, 0, fmt.Errorf("%w: threshold has %d values for %d splits", ErrMalformedModel, len(thresholds),
+			numSplits)
+	}
+	internalCounts, leafCounts := arrays["internal_count"], arrays["leaf_count"]
+	hasCounts := len(internalCounts) == numSplits && len(leafCounts) == numLeaves
+
+	maxFeatIdx := 0
+	t := &xgbTree{nodes: make([]*xgbNode, numSplits+numLeaves)}
+	for i, v := range leafValues {
+		node := &xgbNode{NodeID: numSplits + i, Flags: isLeaf, LeafValues: v}
+		if hasCounts {
+			node.Cover = float64(leafCounts[i])
+		}
+		t.nodes[numSplits+i] = node
+	}
+	child := func(i, c int) (int, error) {
+		if c < 0 {
+			if ^c >= numLeaves {
+				return 0, fmt.Errorf("%w: node %d has invalid leaf child %d", ErrMalformedModel, i, ^c)
+			}
+			return numSplits + ^c, nil
+		}
+		// splits are numbered in creation order which guarantees traversal ends.
+		if c <= i || c >= numSplits {
+			return 0, fmt.Errorf("%w: node %d has invalid child %d", ErrMalformedModel, i, c)
+		}
+		return c, nil
+	}
+	for i := 0; i < numSplits; i++ {
+		node := &xgbNode{NodeID: i, Feature: arrays["split_feature"][i]}
+		if node.Feature < 0 {
+			return nil, 0, fmt.Errorf("%w: node %d has invalid split index %d", ErrMalformedModel, i, node.Feature)
+		}
+		if hasCounts {
+			node.Cover = float64(internalCounts[i])
+		}
+		if len(gains) == numSplits {
+			node.Gain = gains[i]
+		}
+		if node.Yes, err = child(i, arrays["left_child"][i]); err != nil {
+			return nil, 0, err
+		}
+		if node.No, err = child(i, arrays["right_child"][i]); err != nil {
+			return nil, 0, err
+		}
+		decisionType := arrays["decision_type"][i]
+		missingType := (decisionType >> 2) & 3
+		if decisionType&lightgbmCategoricalMask > 0 {
+			node.Flags = isCategorical
+			if node.Categories, err = lightgbmCategories(arrays["cat_boundaries"], arrays["cat_threshold"],
+				int(thresholds[i])); err != nil {
+				return nil, 0, err
+			}
+			// NaN goes right for NaN missing type, otherwise it is category 0.
+			node.Missing = node.No
+			if _, in := node.Categories[0]; in && missingType != lightgbmMissingNaN {
+				node.Missing = node.Yes
+			}
+		} else {
+			node.Threshold = math.Nextafter(thresholds[i], math.Inf(1))
+			switch missingType {
+			case lightgbmMissingZero, lightgbmMissingNaN:
+				if missingType == lightgbmMissingZero {
+					node.Flags = isZeroMissing
+				}
+				node.Missing = node.No
+				if decisionType&lightgbmDefaultLeftMask > 0 {
+					node.Missing = node.Yes
+				}
+			default:
+				// without missing type NaN is compared as zero.
+				node.Missing = node.No
+				if 0 < node.Threshold {
+					node.Missing = node.Yes
+				}
+			}
+		}
+		if node.Feature > maxFeatIdx {
+			maxFeatIdx = node.Feature
+		}
+		t.nodes[i] = node
+	}
+	t.computeNodeMeans()
+	return t, maxFeatIdx, nil
+}
+
+// lightgbmObjective returns the XGBoost objective equivalent to the LightGBM objective line of the model header.
+func lightgbmObjective(line string) (string, error) {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return "", fmt.Errorf("%w: missing objective", ErrMalformedModel)
+	}
+	// options changing the output transformation, like sigmoid:2 or the sqrt of regression, are not supported.
+	for _, f := range fields[1:] {
+		if f != "sigmoid:1" && !strings.HasPrefix(f, "num_class:") && !strings.HasPrefix(f, "tweedie_variance_power:") {
+			return "", fmt.Errorf("%w: objective option %s is not supported", ErrUnsupportedFormat, f)
+		}
+	}
+	objective, ok := lightgbmObjectives[fields[0]]
+	if !ok {
+		return "", fmt.Errorf("%w %s", activation.ErrUnsupportedObjective, fields[0])
+	}
+	return objective, nil
+}
+
+// lightgbmVariancePower returns the tweedie_variance_power of a tweedie objective line.
+func lightgbmVariancePower(line string) (float64, error) {
+	for _, f := range strings.Fields(line) {
+		if strings.HasPrefix(f, "tweedie_variance_power:") {
+			v, err := strconv.ParseFloat(strings.TrimPrefix(f, "tweedie_variance_power:"), 64)
+			if err != nil {
+				return 0, fmt.Errorf("%w: invalid %s", ErrMalformedModel, f)
+			}
+			return v, nil
+		}
+	}
+	return activation.DefaultTweedieVariancePower, nil
+}
+
+// buildLightGBMEnsemble creates an ensemble from a LightGBM model file content.
+func buildLightGBMEnsemble(
+	header lightgbmSection,
+	trees []lightgbmSection,
+	numClasses int,
+	act activation.Activation,
+	opts ...LoadOption) (*inference.Ensemble, error) {
+	cfg := newLoadConfig(opts)
+	if _, ok := header["average_output"]; ok {
+		return nil, fmt.Errorf("%w: random forest models are not supported", ErrUnsupportedFormat)
+	}
+	objective, err := lightgbmObjective(header["objective"])
+	if err != nil && (act == nil || !errors.Is(err, activation.ErrUnsupportedObjective)) {
+		return nil, err
+	}
+	if len(cfg.objective) != 0 && cfg.objective != objective {
+		return nil, fmt.Errorf("model objective is %s, got %s", header["objective"], cfg.objective)
+	}
+	if act == nil {
+		if act, err = activation.FromObjective(objective); err != nil {
+			return nil, err
+		}
+		if tweedie, ok := act.(*activation.Tweedie); ok {
+			if tweedie.VariancePower, err = lightgbmVariancePower(header["objective"]); err != nil {
+				return nil, err
+			}
+		}
+	} else if objective != "" {
+		if err := activation.CheckObjective(objective, act); err != nil {
+			return nil, err
+		}
+	}
+
+	modelClasses, err := header.int("num_tree_per_iteration")
+	if err != nil {
+		return nil, err
+	}
+	if modelClasses < 1 {
+		return nil, fmt.Errorf("%w: invalid number of trees per iteration %d", ErrMalformedModel, modelClasses)
+	}
+	if numClasses > 0 && numClasses != modelClasses {
+		return nil, fmt.Errorf("model has %d classes, got %d", modelClasses, numClasses)
+	}
+	numClasses = modelClasses
+	maxFeat, err := header.int("max_feature_idx")
+	if err != nil {
+		return nil, err
+	}
+	if len(trees) == 0 {
+		return nil, fmt.Errorf("%w: no trees in model", ErrMalformedModel)
+	} else if len(trees)%numClasses != 0 {
+		return nil, fmt.Errorf("%w: wrong number of trees %d for number of class %d", ErrMalformedModel,
+			len(trees), numClasses)
+	}
+	begin, end, err := cfg.iterationRange(len(trees) / numClasses)
+	if err != nil {
+		return nil, err
+	}
+
+	e := &xgbEnsemble{name: "lightgbm", numClasses: numClasses, missing: cfg.missing, absentAsZero: true,
+		objective: objective}
+	if names := strings.Fields(header["feature_names"]); len(names) != 0 {
+		e.featureNames = names
+	}
+	e.Trees = make([]*xgbTree, 0, (end-begin)*numClasses)
+	for i := begin * numClasses; i < end*numClasses; i++ {
+		tree, numFeat, err := buildLightGBMTree(trees[i])
+		if err != nil {
+			return nil, fmt.Errorf("error while reading %d tree: %w", i, err)
+		}
+		if cfg.validateValues {
+			if err := tree.validate(); err != nil {
+				return nil, fmt.Errorf("error while reading %d tree: %w", i, err)
+			}
+		}
+		e.Trees = append(e.Trees, tree)
+		if numFeat > maxFeat {
+			maxFeat = numFeat
+		}
+	}
+	e.numFeat = maxFeat + 1
+	return e.ensemble(&cfg, act)
+}
+
+// loadLightGBM loads LightGBM model from model.txt content.
+func loadLightGBM(
+	r io.Reader,
+	numClasses int,
+	act activation.Activation,
+	opts ...LoadOption) (*inference.Ensemble, error) {
+	header, trees, err := readLightGBMModel(r)
+	if err != nil {
+		return nil, err
+	}
+	return buildLightGBMEnsemble(header, trees, numClasses, act, opts...)
+}
+
+// LoadLightGBMModel loads LightGBM model saved with Booster.save_model in text format. Trees are mapped onto the
+// same structures as XGBoost models so every prediction and explanation method is available. Like LightGBM,
+// absent features of sparse rows are zeros rather than missing values, only NaN values and the value given to
+// WithMissingValue are missing. The number of classes and the objective are read from the model and the
+// activation matching the objective is used if act is nil, act is required for objectives without a supported
+// activation such as cross_entropy_lambda. WithFloat32Comparison has no effect as LightGBM compares values in float64.
+func LoadLightGBMModel(
+	modelPath string,
+	act activation.Activation,
+	opts ...LoadOption) (*inference.Ensemble, error) {
+	modelFile, format, err := openModelFile(modelPath)
+	if err != nil {
+		return nil, err
+	}
+	defer modelFile.Close()
+	if format != formatLightGBM {
+		return nil, fmt.Errorf("%w: %s is %s, expected a lightgbm model", ErrUnsupportedFormat, modelPath, format)
+	}
+	return loadLightGBM(modelFile, 0, act, opts...)
+}
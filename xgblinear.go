@@ -0,0 +1,238 @@
+package xgboost
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/lordberre/xgboost-go/activation"
+	"github.com/lordberre/xgboost-go/inference"
+	"github.com/lordberre/xgboost-go/mat"
+)
+
+// xgbLinear is the base model of XGBoost models trained with booster=gblinear, the raw score of class c is the
+// base margin plus bias[c] plus the sum of the present features weighted by their coefficient. Like xgbEnsemble
+// it is never modified once loaded.
+type xgbLinear struct {
+	numClasses int
+	numFeat    int
+	// weights[f*numClasses+c] is the coefficient of feature f for class c.
+	weights []float64
+	bias    []float64
+	// missing is the value treated as missing in addition to NaN and absent features, nil if there is none.
+	missing            *float64
+	baseMargin         float64
+	objective          string
+	baseScore          float64
+	attributes         map[string]string
+	featureNames       []string
+	featureIndex       map[string]int
+	validateDimensions bool
+	// indexBase is the index of the first feature in sparse rows like for xgbEnsemble.
+	indexBase int
+}
+
+// buildLinearModel returns the ensemble of the linear model of a gblinear booster, whose weights hold the
+// numFeat coefficients of each class followed by the bias of each class like XGBoost.
+func buildLinearModel(booster modelObject, l *xgbLinear, cfg *loadConfig, act activation.Activation) (
+	*inference.Ensemble, error) {
+	if cfg.iterationBegin != 0 || cfg.iterationEnd > 0 {
+		return nil, fmt.Errorf("%w: gblinear models cannot be sliced by iteration", ErrUnsupportedFormat)
+	}
+	model, err := booster.object("model")
+	if err != nil {
+		return nil, err
+	}
+	weights, err := model.floats("weights")
+	if err != nil {
+		return nil, err
+	}
+	if len(weights) != (l.numFeat+1)*l.numClasses {
+		return nil, fmt.Errorf("%w: %d linear weights for %d features and %d classes", ErrMalformedModel,
+			len(weights), l.numFeat, l.numClasses)
+	}
+	if cfg.validateValues {
+		for i, w := range weights {
+			if math.IsNaN(w) || math.IsInf(w, 0) {
+				return nil, fmt.Errorf("%w: linear weight %d is %v", ErrMalformedModel, i, w)
+			}
+		}
+	}
+	split := l.numFeat * l.numClasses
+	l.weights, l.bias = weights[:split:split], weights[split:]
+	l.validateDimensions = cfg.validateDimensions
+	l.indexBase = cfg.indexBase()
+	if l.featureNames, l.featureIndex, err = nameFeatures(cfg, l.featureNames, l.numFeat); err != nil {
+		return nil, err
+	}
+	return &inference.Ensemble{EnsembleBase: l, Activation: act}, nil
+}
+
+// Name returns name of ensemble model.
+func (l *xgbLinear) Name() string {
+	return "xgboost"
+}
+
+// NumClasses returns number of classes of this linear model.
+func (l *xgbLinear) NumClasses() int {
+	return l.numClasses
+}
+
+// NumFeatures returns number of features expected by this linear model.
+func (l *xgbLinear) NumFeatures() int {
+	return l.numFeat
+}
+
+// isMissing returns whether feature value v is missing.
+func (l *xgbLinear) isMissing(v float64) bool {
+	return math.IsNaN(v) || (l.missing != nil && v == *l.missing)
+}
+
+// add adds the contribution of value v of feature idx to the raw score of each class, features the model does
+// not have are ignored like XGBoost does.
+func (l *xgbLinear) add(pred mat.Vector, idx int, v float64) {
+	if idx < 0 || idx >= l.numFeat || l.isMissing(v) {
+		return
+	}
+	for c := range pred {
+		pred[c] += v * l.weights[idx*l.numClasses+c]
+	}
+}
+
+// newPrediction returns a raw prediction initialized with the base margin and the bias of each class.
+func (l *xgbLinear) newPrediction() mat.Vector {
+	pred := make(mat.Vector, l.numClasses)
+	for c := range pred {
+		pred[c] = l.baseMargin
+	}
+	l.addBias(pred)
+	return pred
+}
+
+// addBias adds the bias of each class to a raw prediction.
+func (l *xgbLinear) addBias(pred mat.Vector) {
+	for c := range pred {
+		pred[c] += l.bias[c]
+	}
+}
+
+// addSparse adds the contribution of the features of a sparse row to a raw prediction.
+func (l *xgbLinear) addSparse(pred mat.Vector, features mat.SparseVector) error {
+	if err := checkSparseIndices(features, l.indexBase, l.numFeat, l.validateDimensions); err != nil {
+		return err
+	}
+	for idx, v := range features {
+		l.add(pred, idx-l.indexBase, v)
+	}
+	return nil
+}
+
+// PredictInner returns the raw score of each class for a sparse row.
+func (l *xgbLinear) PredictInner(features mat.SparseVector) (mat.Vector, error) {
+	pred := l.newPrediction()
+	if err := l.addSparse(pred, features); err != nil {
+		return mat.Vector{}, err
+	}
+	return pred, nil
+}
+
+// PredictInnerDense returns the raw score of each class for a dense row where NaN values are missing.
+func (l *xgbLinear) PredictInnerDense(features mat.Vector) (mat.Vector, error) {
+	if l.validateDimensions {
+		if err := checkFeatureWidth(len(features), l.numFeat); err != nil {
+			return mat.Vector{}, err
+		}
+	}
+	pred := l.newPrediction()
+	for idx, v := range features {
+		l.add(pred, idx, v)
+	}
+	return pred, nil
+}
+
+// PredictInnerCSR returns the raw score of each class for a row of a CSR matrix.
+func (l *xgbLinear) PredictInnerCSR(indices []int, values []float64) (mat.Vector, error) {
+	if err := checkCSRIndices(indices, l.indexBase, l.numFeat, l.validateDimensions); err != nil {
+		return mat.Vector{}, err
+	}
+	pred := l.newPrediction()
+	for j, idx := range indices {
+		l.add(pred, idx-l.indexBase, values[j])
+	}
+	return pred, nil
+}
+
+// PredictInnerWithBaseMargin returns the raw score of each class for a sparse row starting from the given base
+// margin instead of the base score of the model.
+func (l *xgbLinear) PredictInnerWithBaseMargin(features mat.SparseVector, baseMargin mat.Vector) (mat.Vector,
+	error) {
+	if len(baseMargin) != l.numClasses {
+		return mat.Vector{}, fmt.Errorf("%w: base margin has %d values for %d classes", mat.ErrDimensionMismatch,
+			len(baseMargin), l.numClasses)
+	}
+	pred := make(mat.Vector, l.numClasses)
+	copy(pred, baseMargin)
+	l.addBias(pred)
+	if err := l.addSparse(pred, features); err != nil {
+		return mat.Vector{}, err
+	}
+	return pred, nil
+}
+
+// PredictContributionsInner returns the contribution of each feature to the raw score of every class with the
+// layout of xgbEnsemble, numFeat + 1 values per class with the bias last. The contribution of a present feature
+// is its value times its coefficient, like XGBoost pred_contribs for gblinear.
+func (l *xgbLinear) PredictContributionsInner(features mat.SparseVector) (mat.Vector, error) {
+	if err := checkSparseIndices(features, l.indexBase, l.numFeat, l.validateDimensions); err != nil {
+		return mat.Vector{}, err
+	}
+	stride := l.numFeat + 1
+	r := make(mat.Vector, l.numClasses*stride)
+	for c := 0; c < l.numClasses; c++ {
+		r[c*stride+l.numFeat] = l.baseMargin + l.bias[c]
+	}
+	for idx, v := range features {
+		idx -= l.indexBase
+		if idx < 0 || idx >= l.numFeat || l.isMissing(v) {
+			continue
+		}
+		for c := 0; c < l.numClasses; c++ {
+			r[c*stride+idx] = v * l.weights[idx*l.numClasses+c]
+		}
+	}
+	return r, nil
+}
+
+// Objective returns the XGBoost objective of the model.
+func (l *xgbLinear) Objective() string {
+	return l.objective
+}
+
+// BaseScore returns the base_score of the model in the output space of its objective.
+func (l *xgbLinear) BaseScore() float64 {
+	return l.baseScore
+}
+
+// NumTrees returns 0 as linear models have no trees.
+func (l *xgbLinear) NumTrees() int {
+	return 0
+}
+
+// Attributes returns the attributes saved with the model, nil if there is none.
+func (l *xgbLinear) Attributes() map[string]string {
+	return l.attributes
+}
+
+// FeatureNames returns the name of each feature, nil if the model does not name them.
+func (l *xgbLinear) FeatureNames() []string {
+	return l.featureNames
+}
+
+// FeatureIndex returns the index of the feature called name in sparse rows and false if the model has no such
+// feature.
+func (l *xgbLinear) FeatureIndex(name string) (int, bool) {
+	idx, ok := l.featureIndex[name]
+	if !ok {
+		return 0, false
+	}
+	return idx + l.indexBase, true
+}
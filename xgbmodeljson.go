@@ -0,0 +1,602 @@
+package xgboost
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+
+	"github.com/lordberre/xgboost-go/activation"
+	"github.com/lordberre/xgboost-go/inference"
+)
+
+// modelObject is a decoded object of the XGBoost model schema written by Booster.save_model, either from JSON or
+// UBJSON.
+type modelObject map[string]interface{}
+
+// object returns the object stored under key.
+func (o modelObject) object(key string) (modelObject, error) {
+	v, ok := o[key]
+	if !ok {
+		return nil, fmt.Errorf("%w: missing %s", ErrMalformedModel, key)
+	}
+	r, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%w: %s is not an object", ErrMalformedModel, key)
+	}
+	return r, nil
+}
+
+// string returns the string stored under key.
+func (o modelObject) string(key string) (string, error) {
+	v, ok := o[key]
+	if !ok {
+		return "", fmt.Errorf("%w: missing %s", ErrMalformedModel, key)
+	}
+	r, ok := v.(string)
+	if !ok {
+		return "", fmt.Errorf("%w: %s is not a string", ErrMalformedModel, key)
+	}
+	return r, nil
+}
+
+// number returns the number stored under key, XGBoost stores parameters as strings so they are parsed as well.
+func (o modelObject) number(key string) (float64, error) {
+	v, ok := o[key]
+	if !ok {
+		return 0, fmt.Errorf("%w: missing %s", ErrMalformedModel, key)
+	}
+	r, err := modelNumber(v)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %s: %s", ErrMalformedModel, key, err)
+	}
+	return r, nil
+}
+
+// floats returns the array of numbers stored under key, absent keys are empty arrays.
+func (o modelObject) floats(key string) ([]float64, error) {
+	v, ok := o[key]
+	if !ok {
+		return nil, nil
+	}
+	switch arr := v.(type) {
+	case []float64:
+		return arr, nil
+	case []interface{}:
+		r := make([]float64, len(arr))
+		for i, e := range arr {
+			f, err := modelNumber(e)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %s[%d]: %s", ErrMalformedModel, key, i, err)
+			}
+			r[i] = f
+		}
+		return r, nil
+	}
+	return nil, fmt.Errorf("%w: %s is not an array", ErrMalformedModel, key)
+}
+
+// strings returns the array of strings stored under key, absent keys are empty arrays.
+func (o modelObject) strings(key string) ([]string, error) {
+	v, ok := o[key]
+	if !ok {
+		return nil, nil
+	}
+	arr, ok := v.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%w: %s is not an array", ErrMalformedModel, key)
+	}
+	r := make([]string, len(arr))
+	for i, e := range arr {
+		if r[i], ok = e.(string); !ok {
+			return nil, fmt.Errorf("%w: %s[%d] is not a string", ErrMalformedModel, key, i)
+		}
+	}
+	return r, nil
+}
+
+// stringMap returns the object of strings stored under key, absent keys are nil maps.
+func (o modelObject) stringMap(key string) (map[string]string, error) {
+	v, ok := o[key]
+	if !ok {
+		return nil, nil
+	}
+	obj, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%w: %s is not an object", ErrMalformedModel, key)
+	}
+	r := make(map[string]string, len(obj))
+	for k, e := range obj {
+		if r[k], ok = e.(string); !ok {
+			return nil, fmt.Errorf("%w: %s.%s is not a string", ErrMalformedModel, key, k)
+		}
+	}
+	return r, nil
+}
+
+// ints returns the array of integers stored under key, absent keys are empty arrays.
+func (o modelObject) ints(key string) ([]int, error) {
+	floats, err := o.floats(key)
+	if err != nil {
+		return nil, err
+	}
+	r := make([]int, len(floats))
+	for i, f := range floats {
+		if f != math.Trunc(f) || math.Abs(f) > math.MaxInt32 {
+			return nil, fmt.Errorf("%w: %s[%d] is not an integer: %v", ErrMalformedModel, key, i, f)
+		}
+		r[i] = int(f)
+	}
+	return r, nil
+}
+
+// modelNumber converts a decoded value to float64.
+func modelNumber(v interface{}) (float64, error) {
+	switch n := v.(type) {
+	case float64:
+		return n, nil
+	case int64:
+		return float64(n), nil
+	case bool:
+		if n {
+			return 1, nil
+		}
+		return 0, nil
+	case json.Number:
+		return strconv.ParseFloat(string(n), 64)
+	case string:
+		return strconv.ParseFloat(n, 64)
+	}
+	return 0, fmt.Errorf("unexpected value %v", v)
+}
+
+// probToMargin converts base_score to the margin added to raw predictions like the ProbToMargin method of
+// XGBoost objectives.
+func probToMargin(objective string, baseScore float64) float64 {
+	switch objective {
+	case "binary:logistic", "binary:logitraw", "reg:logistic":
+		return -math.Log(1/baseScore - 1)
+	case "count:poisson", "reg:gamma", "reg:tweedie", "survival:cox", "survival:aft":
+		return math.Log(baseScore)
+	}
+	return baseScore
+}
+
+// tweedieVariancePower returns the tweedie_variance_power of a reg:tweedie objective.
+func tweedieVariancePower(objective modelObject) (float64, error) {
+	param, err := objective.object("tweedie_regression_param")
+	if err != nil {
+		return activation.DefaultTweedieVariancePower, nil
+	}
+	if _, ok := param["tweedie_variance_power"]; !ok {
+		return activation.DefaultTweedieVariancePower, nil
+	}
+	return param.number("tweedie_variance_power")
+}
+
+// readAFTParam reads the aft_loss_param of a survival:aft objective into a.
+func readAFTParam(objective modelObject, a *activation.SurvivalAFT) error {
+	param, err := objective.object("aft_loss_param")
+	if err != nil {
+		return nil
+	}
+	if _, ok := param["aft_loss_distribution"]; ok {
+		if a.Distribution, err = param.string("aft_loss_distribution"); err != nil {
+			return err
+		}
+	}
+	if _, ok := param["aft_loss_distribution_scale"]; ok {
+		if a.Sigma, err = param.number("aft_loss_distribution_scale"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// numParallelTree returns the num_parallel_tree of boosted random forests stored in gbtree_model_param, 1 if the
+// model does not store it.
+func numParallelTree(model modelObject) (int, error) {
+	param, err := model.object("gbtree_model_param")
+	if err != nil {
+		return 1, nil
+	}
+	if _, ok := param["num_parallel_tree"]; !ok {
+		return 1, nil
+	}
+	n, err := param.number("num_parallel_tree")
+	if err != nil {
+		return 0, err
+	}
+	if n < 1 || n != math.Trunc(n) {
+		return 0, fmt.Errorf("%w: invalid num_parallel_tree %v", ErrMalformedModel, n)
+	}
+	return int(n), nil
+}
+
+// buildModelTree creates a tree from the array based tree schema of XGBoost models.
+func buildModelTree(tree modelObject, dartWeight float64) (*xgbTree, int, error) {
+	if param, err := tree.object("tree_param"); err == nil {
+		if size, err := param.number("size_leaf_vector"); err == nil && size > 1 {
+			return nil, 0, fmt.Errorf("%w: multi target trees are not supported", ErrUnsupportedFormat)
+		}
+	}
+	left, err := tree.ints("left_children")
+	if err != nil {
+		return nil, 0, err
+	}
+	right, err := tree.ints("right_children")
+	if err != nil {
+		return nil, 0, err
+	}
+	indices, err := tree.ints("split_indices")
+	if err != nil {
+		return nil, 0, err
+	}
+	conditions, err := tree.floats("split_conditions")
+	if err != nil {
+		return nil, 0, err
+	}
+	defaultLeft, err := tree.ints("default_left")
+	if err != nil {
+		return nil, 0, err
+	}
+	hessians, err := tree.floats("sum_hessian")
+	if err != nil {
+		return nil, 0, err
+	}
+	gains, err := tree.floats("loss_changes")
+	if err != nil {
+		return nil, 0, err
+	}
+	splitTypes, err := tree.ints("split_type")
+	if err != nil {
+		return nil, 0, err
+	}
+	n := len(left)
+	if n == 0 {
+		return nil, 0, fmt.Errorf("%w: tree without nodes", ErrMalformedModel)
+	}
+	if len(right) != n || len(indices) != n || len(conditions) != n || len(defaultLeft) != n {
+		return nil, 0, fmt.Errorf("%w: tree arrays have different lengths", ErrMalformedModel)
+	}
+	if (len(hessians) != 0 && len(hessians) != n) || (len(splitTypes) != 0 && len(splitTypes) != n) ||
+		(len(gains) != 0 && len(gains) != n) {
+		return nil, 0, fmt.Errorf("%w: tree arrays have different lengths", ErrMalformedModel)
+	}
+
+	categories, err := modelCategories(tree)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	maxFeatIdx := 0
+	t := &xgbTree{nodes: make([]*xgbNode, n)}
+	for i := 0; i < n; i++ {
+		node := &xgbNode{NodeID: i}
+		if len(hessians) != 0 {
+			node.Cover = hessians[i]
+		}
+		if left[i] == -1 {
+			node.Flags = isLeaf
+			node.LeafValues = conditions[i] * dartWeight
+			t.nodes[i] = node
+			continue
+		}
+		// children are always created after their parent which guarantees traversal ends.
+		if left[i] <= i || right[i] <= i || left[i] >= n || right[i] >= n {
+			return nil, 0, fmt.Errorf("%w: node %d has invalid children %d and %d", ErrMalformedModel, i, left[i],
+				right[i])
+		}
+		if indices[i] < 0 {
+			return nil, 0, fmt.Errorf("%w: node %d has invalid split index %d", ErrMalformedModel, i, indices[i])
+		}
+		node.Feature = indices[i]
+		node.Threshold = conditions[i]
+		if len(gains) != 0 {
+			node.Gain = gains[i]
+		}
+		node.Yes, node.No = left[i], right[i]
+		if len(splitTypes) != 0 && splitTypes[i] == 1 {
+			// XGBoost sends the split categories to the right child.
+			node.Flags = isCategorical
+			node.Categories = categories[i]
+			node.Yes, node.No = right[i], left[i]
+		}
+		if defaultLeft[i] != 0 {
+			node.Missing = left[i]
+		} else {
+			node.Missing = right[i]
+		}
+		if node.Feature > maxFeatIdx {
+			maxFeatIdx = node.Feature
+		}
+		t.nodes[i] = node
+	}
+	t.computeNodeMeans()
+	return t, maxFeatIdx, nil
+}
+
+// modelCategories returns the categories of each categorical split node.
+func modelCategories(tree modelObject) (map[int]map[int]struct{}, error) {
+	nodes, err := tree.ints("categories_nodes")
+	if err != nil {
+		return nil, err
+	}
+	segments, err := tree.ints("categories_segments")
+	if err != nil {
+		return nil, err
+	}
+	sizes, err := tree.ints("categories_sizes")
+	if err != nil {
+		return nil, err
+	}
+	values, err := tree.ints("categories")
+	if err != nil {
+		return nil, err
+	}
+	if len(segments) != len(nodes) || len(sizes) != len(nodes) {
+		return nil, fmt.Errorf("%w: categories arrays have different lengths", ErrMalformedModel)
+	}
+	r := make(map[int]map[int]struct{}, len(nodes))
+	for i, nid := range nodes {
+		if segments[i] < 0 || sizes[i] < 0 || segments[i]+sizes[i] > len(values) {
+			return nil, fmt.Errorf("%w: categories of node %d are out of range", ErrMalformedModel, nid)
+		}
+		set := make(map[int]struct{}, sizes[i])
+		for _, c := range values[segments[i] : segments[i]+sizes[i]] {
+			set[c] = struct{}{}
+		}
+		r[nid] = set
+	}
+	return r, nil
+}
+
+// buildModelEnsemble creates an ensemble from a decoded XGBoost model. numClasses is checked against the model
+// if > 0 and the activation matching the model objective is used if act is nil.
+func buildModelEnsemble(
+	doc modelObject,
+	numClasses int,
+	act activation.Activation,
+	opts ...LoadOption) (*inference.Ensemble, error) {
+	cfg := newLoadConfig(opts)
+	learner, err := doc.object("learner")
+	if err != nil {
+		return nil, err
+	}
+	objectiveObj, err := learner.object("objective")
+	if err != nil {
+		return nil, err
+	}
+	objective, err := objectiveObj.string("name")
+	if err != nil {
+		return nil, err
+	}
+	if len(cfg.objective) != 0 && cfg.objective != objective {
+		return nil, fmt.Errorf("model objective is %s, got %s", objective, cfg.objective)
+	}
+	if act == nil {
+		act, err = activation.FromObjective(objective)
+		if err != nil {
+			return nil, err
+		}
+		switch a := act.(type) {
+		case *activation.Tweedie:
+			if a.VariancePower, err = tweedieVariancePower(objectiveObj); err != nil {
+				return nil, err
+			}
+		case *activation.SurvivalAFT:
+			if err := readAFTParam(objectiveObj, a); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if err := activation.CheckObjective(objective, act); err != nil {
+		return nil, err
+	}
+
+	param, err := learner.object("learner_model_param")
+	if err != nil {
+		return nil, err
+	}
+	baseScore, err := param.number("base_score")
+	if err != nil {
+		return nil, err
+	}
+	modelClasses, err := param.number("num_class")
+	if err != nil {
+		return nil, err
+	}
+	if modelClasses < 1 {
+		modelClasses = 1
+	}
+	if numTarget, err := param.number("num_target"); err == nil && numTarget > 1 {
+		return nil, fmt.Errorf("%w: multi target models are not supported", ErrUnsupportedFormat)
+	}
+	if numClasses > 0 && numClasses != int(modelClasses) {
+		return nil, fmt.Errorf("model has %d classes, got %d", int(modelClasses), numClasses)
+	}
+	numClasses = int(modelClasses)
+	numFeature, err := param.number("num_feature")
+	if err != nil {
+		return nil, err
+	}
+
+	featureNames, err := learner.strings("feature_names")
+	if err != nil {
+		return nil, err
+	}
+	attributes, err := learner.stringMap("attributes")
+	if err != nil {
+		return nil, err
+	}
+	if len(attributes) == 0 {
+		attributes = nil
+	}
+	if len(featureNames) == 0 {
+		featureNames = nil
+	}
+
+	booster, err := learner.object("gradient_booster")
+	if err != nil {
+		return nil, err
+	}
+	boosterName, err := booster.string("name")
+	if err != nil {
+		return nil, err
+	}
+	var weights []float64
+	switch boosterName {
+	case "gbtree":
+	case "gblinear":
+		l := &xgbLinear{
+			numClasses:   numClasses,
+			numFeat:      int(numFeature),
+			missing:      cfg.missing,
+			baseMargin:   probToMargin(objective, baseScore),
+			objective:    objective,
+			baseScore:    baseScore,
+			attributes:   attributes,
+			featureNames: featureNames,
+		}
+		return buildLinearModel(booster, l, &cfg, act)
+	case "dart":
+		if weights, err = booster.floats("weight_drop"); err != nil {
+			return nil, err
+		}
+		if booster, err = booster.object("gbtree"); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("%w: %s booster is not supported", ErrUnsupportedFormat, boosterName)
+	}
+	model, err := booster.object("model")
+	if err != nil {
+		return nil, err
+	}
+	treeInfo, err := model.ints("tree_info")
+	if err != nil {
+		return nil, err
+	}
+	treesValue, ok := model["trees"].([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%w: trees is not an array", ErrMalformedModel)
+	}
+	nTrees := len(treesValue)
+	if nTrees == 0 {
+		return nil, fmt.Errorf("%w: no trees in model", ErrMalformedModel)
+	}
+	if len(treeInfo) != nTrees {
+		return nil, fmt.Errorf("%w: %d trees but %d tree info", ErrMalformedModel, nTrees, len(treeInfo))
+	}
+	if weights != nil && len(weights) != nTrees {
+		return nil, fmt.Errorf("%w: %d trees but %d dart weights", ErrMalformedModel, nTrees, len(weights))
+	}
+
+	// trees are reordered so that tree i belongs to class i % numClasses like in json dumps.
+	perClass := make([][]*xgbTree, numClasses)
+	maxFeat := int(numFeature) - 1
+	for i, v := range treesValue {
+		treeObj, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("%w: tree %d is not an object", ErrMalformedModel, i)
+		}
+		if treeInfo[i] < 0 || treeInfo[i] >= numClasses {
+			return nil, fmt.Errorf("%w: tree %d belongs to class %d of %d", ErrMalformedModel, i, treeInfo[i],
+				numClasses)
+		}
+		dartWeight := 1.0
+		if weights != nil {
+			dartWeight = weights[i]
+		}
+		tree, numFeat, err := buildModelTree(treeObj, dartWeight)
+		if err != nil {
+			return nil, fmt.Errorf("error while reading %d tree: %w", i, err)
+		}
+		if cfg.validateValues {
+			if err := tree.validate(); err != nil {
+				return nil, fmt.Errorf("error while reading %d tree: %w", i, err)
+			}
+		}
+		tree.float32Comparison = cfg.float32Comparison
+		perClass[treeInfo[i]] = append(perClass[treeInfo[i]], tree)
+		if numFeat > maxFeat {
+			maxFeat = numFeat
+		}
+	}
+	perClassTrees := len(perClass[0])
+	for c, trees := range perClass {
+		if len(trees) != perClassTrees {
+			return nil, fmt.Errorf("%w: class %d has %d trees, class 0 has %d", ErrMalformedModel, c, len(trees),
+				perClassTrees)
+		}
+	}
+	parallelTrees, err := numParallelTree(model)
+	if err != nil {
+		return nil, err
+	}
+	if perClassTrees%parallelTrees != 0 {
+		return nil, fmt.Errorf("%w: %d trees per class is not a multiple of num_parallel_tree %d", ErrMalformedModel,
+			perClassTrees, parallelTrees)
+	}
+	begin, end, err := cfg.iterationRange(perClassTrees / parallelTrees)
+	if err != nil {
+		return nil, err
+	}
+
+	e := &xgbEnsemble{
+		name:          "xgboost",
+		numClasses:    numClasses,
+		numFeat:       maxFeat + 1,
+		missing:       cfg.missing,
+		baseMargin:    probToMargin(objective, baseScore),
+		objective:     objective,
+		baseScore:     &baseScore,
+		attributes:    attributes,
+		featureNames:  featureNames,
+		parallelTrees: parallelTrees,
+	}
+	// the parallel trees of a round follow each other class after class like the rounds of boosted trees.
+	begin, end = begin*parallelTrees, end*parallelTrees
+	e.Trees = make([]*xgbTree, 0, (end-begin)*numClasses)
+	for k := begin; k < end; k++ {
+		for c := 0; c < numClasses; c++ {
+			e.Trees = append(e.Trees, perClass[c][k])
+		}
+	}
+	return e.ensemble(&cfg, act)
+}
+
+// loadXGBoostModelJSON loads xgboost model from save_model JSON content.
+func loadXGBoostModelJSON(
+	r io.Reader,
+	numClasses int,
+	act activation.Activation,
+	opts ...LoadOption) (*inference.Ensemble, error) {
+	var doc map[string]interface{}
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+	if err := dec.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrMalformedModel, err)
+	}
+	return buildModelEnsemble(doc, numClasses, act, opts...)
+}
+
+// LoadXGBoostFromModelJSON loads xgboost model saved with Booster.save_model in JSON format, gzip compressed files
+// are supported as well. Unlike json dumps, the number of classes, the objective and base_score are read from the
+// model, base_score is included in the raw predictions. If act is nil the activation matching the model objective
+// is used.
+func LoadXGBoostFromModelJSON(
+	modelPath string,
+	act activation.Activation,
+	opts ...LoadOption) (*inference.Ensemble, error) {
+	modelFile, format, err := openModelFile(modelPath)
+	if err != nil {
+		return nil, err
+	}
+	defer modelFile.Close()
+	if format != formatModelJSON {
+		return nil, fmt.Errorf("%w: %s is %s, expected a json model", ErrUnsupportedFormat, modelPath, format)
+	}
+	return loadXGBoostModelJSON(modelFile, 0, act, opts...)
+}
@@ -0,0 +1,259 @@
+package xgboost
+
+import (
+	"fmt"
+	"io"
+	"math"
+
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"github.com/lordberre/xgboost-go/inference"
+	"github.com/lordberre/xgboost-go/protobuf"
+)
+
+// Versions written in exported ONNX models, TreeEnsemble operators are defined by the ai.onnx.ml domain.
+const (
+	onnxIRVersion    = 7
+	onnxOpsetVersion = 13
+	onnxMLDomain     = "ai.onnx.ml"
+	onnxMLVersion    = 1
+	onnxProducerName = "xgboost-go"
+)
+
+// Values of the TensorProto.DataType and AttributeProto.AttributeType enums of onnx.proto.
+const (
+	onnxTypeFloat   = 1
+	onnxTypeInt64   = 7
+	onnxAttrInt     = 2
+	onnxAttrString  = 3
+	onnxAttrFloats  = 6
+	onnxAttrInts    = 7
+	onnxAttrStrings = 8
+)
+
+// Names of the graph input and of its batch dimension.
+const (
+	onnxInputName     = "input"
+	onnxBatchDimParam = "N"
+)
+
+// onnxMessage is a protobuf message being encoded, fields of onnx.proto are written in field number order.
+type onnxMessage []byte
+
+func (m onnxMessage) int(num protowire.Number, v int64) onnxMessage {
+	m = protowire.AppendTag(m, num, protowire.VarintType)
+	return protowire.AppendVarint(m, uint64(v))
+}
+
+func (m onnxMessage) string(num protowire.Number, s string) onnxMessage {
+	m = protowire.AppendTag(m, num, protowire.BytesType)
+	return protowire.AppendString(m, s)
+}
+
+func (m onnxMessage) message(num protowire.Number, sub onnxMessage) onnxMessage {
+	m = protowire.AppendTag(m, num, protowire.BytesType)
+	return protowire.AppendBytes(m, sub)
+}
+
+// onnxAttribute holds the values of a TreeEnsemble attribute.
+type onnxAttribute struct {
+	name    string
+	ints    []int64
+	floats  []float32
+	strings []string
+	str     string
+	i       *int64
+}
+
+// encode returns the AttributeProto of a.
+func (a onnxAttribute) encode() onnxMessage {
+	m := onnxMessage{}.string(1, a.name)
+	var attrType int64
+	switch {
+	case a.i != nil:
+		m = m.int(3, *a.i)
+		attrType = onnxAttrInt
+	case a.str != "":
+		m = m.string(4, a.str)
+		attrType = onnxAttrString
+	case a.floats != nil:
+		for _, f := range a.floats {
+			m = protowire.AppendTag(m, 7, protowire.Fixed32Type)
+			m = protowire.AppendFixed32(m, math.Float32bits(f))
+		}
+		attrType = onnxAttrFloats
+	case a.strings != nil:
+		for _, s := range a.strings {
+			m = m.string(9, s)
+		}
+		attrType = onnxAttrStrings
+	default:
+		for _, v := range a.ints {
+			m = m.int(8, v)
+		}
+		attrType = onnxAttrInts
+	}
+	return m.int(20, attrType)
+}
+
+// onnxTensorInfo returns the ValueInfoProto of a tensor whose first dimension is the batch size.
+func onnxTensorInfo(name string, elemType int64, dims ...int64) onnxMessage {
+	shape := onnxMessage{}.message(1, onnxMessage{}.string(2, onnxBatchDimParam))
+	for _, d := range dims {
+		shape = shape.message(1, onnxMessage{}.int(1, d))
+	}
+	tensorType := onnxMessage{}.int(1, elemType).message(2, shape)
+	return onnxMessage{}.string(1, name).message(2, onnxMessage{}.message(1, tensorType))
+}
+
+// onnxTreeAttributes returns the node and leaf attributes of the TreeEnsemble operator, the prefix of leaf
+// attributes is target for regressors and class for classifiers.
+func onnxTreeAttributes(e *xgbEnsemble, leafPrefix string) ([]onnxAttribute, error) {
+	var treeIDs, nodeIDs, featureIDs, trueIDs, falseIDs, missingTrue []int64
+	var values []float32
+	var modes []string
+	var leafTreeIDs, leafNodeIDs, leafClassIDs []int64
+	var leafWeights []float32
+	for i, t := range e.Trees {
+		for j, n := range t.nodes {
+			if n == nil {
+				continue
+			}
+			treeIDs = append(treeIDs, int64(i))
+			nodeIDs = append(nodeIDs, int64(j))
+			if n.Flags&isLeaf > 0 {
+				modes = append(modes, "LEAF")
+				featureIDs = append(featureIDs, 0)
+				values = append(values, 0)
+				trueIDs = append(trueIDs, 0)
+				falseIDs = append(falseIDs, 0)
+				missingTrue = append(missingTrue, 0)
+				leafTreeIDs = append(leafTreeIDs, int64(i))
+				leafNodeIDs = append(leafNodeIDs, int64(j))
+				leafClassIDs = append(leafClassIDs, int64(i%e.numClasses))
+				leafWeights = append(leafWeights, float32(n.LeafValues))
+				continue
+			}
+			if n.Flags&isCategorical > 0 {
+				return nil, fmt.Errorf("%w: tree %d node %d is a categorical split which onnx does not support",
+					ErrUnsupportedFormat, i, j)
+			}
+			if n.Flags&isZeroMissing > 0 {
+				return nil, fmt.Errorf("%w: tree %d node %d treats zeros as missing which onnx does not support",
+					ErrUnsupportedFormat, i, j)
+			}
+			modes = append(modes, "BRANCH_LT")
+			featureIDs = append(featureIDs, int64(n.Feature))
+			values = append(values, float32(n.Threshold))
+			trueIDs = append(trueIDs, int64(n.Yes))
+			falseIDs = append(falseIDs, int64(n.No))
+			if n.Missing == n.Yes {
+				missingTrue = append(missingTrue, 1)
+			} else {
+				missingTrue = append(missingTrue, 0)
+			}
+		}
+	}
+	return []onnxAttribute{
+		{name: "nodes_treeids", ints: treeIDs},
+		{name: "nodes_nodeids", ints: nodeIDs},
+		{name: "nodes_featureids", ints: featureIDs},
+		{name: "nodes_values", floats: values},
+		{name: "nodes_modes", strings: modes},
+		{name: "nodes_truenodeids", ints: trueIDs},
+		{name: "nodes_falsenodeids", ints: falseIDs},
+		{name: "nodes_missing_value_tracks_true", ints: missingTrue},
+		{name: leafPrefix + "_treeids", ints: leafTreeIDs},
+		{name: leafPrefix + "_nodeids", ints: leafNodeIDs},
+		{name: leafPrefix + "_ids", ints: leafClassIDs},
+		{name: leafPrefix + "_weights", floats: leafWeights},
+	}, nil
+}
+
+// ExportONNX writes ensemble as an ONNX model with a single TreeEnsembleRegressor or TreeEnsembleClassifier
+// operator of the ai.onnx.ml domain, so that it can be served by ONNX runtimes. The model takes a float input
+// tensor of shape [N, NumFeatures] where NaN values are missing. Raw activation models are exported as regressors
+// with one output per class in the "variable" output, logistic and softmax models as classifiers with "label"
+// and "probabilities" outputs. ONNX compares feature values and thresholds in float32, like XGBoost and the
+// WithFloat32Comparison option. Categorical splits and LightGBM zero as missing splits cannot be
+// represented and return an ErrUnsupportedFormat error.
+func ExportONNX(ensemble *inference.Ensemble, w io.Writer) error {
+	e, ok := ensemble.EnsembleBase.(*xgbEnsemble)
+	if !ok {
+		return fmt.Errorf("%s model cannot be exported to onnx", ensemble.Name())
+	}
+	baseValues := make([]float32, e.numClasses)
+	for i := range baseValues {
+		baseValues[i] = float32(e.baseMargin)
+	}
+
+	var opType, leafPrefix, postTransform string
+	var outputNames []string
+	var outputs []onnxMessage
+	switch ensemble.Type() {
+	case protobuf.ActivateType_RAW:
+		opType, leafPrefix, postTransform = "TreeEnsembleRegressor", "target", "NONE"
+		outputNames = []string{"variable"}
+		outputs = []onnxMessage{onnxTensorInfo("variable", onnxTypeFloat, int64(e.numClasses))}
+	case protobuf.ActivateType_LOGISTIC, protobuf.ActivateType_SOFTMAX, protobuf.ActivateType_SOFTMAX_CLASS:
+		opType, leafPrefix, postTransform = "TreeEnsembleClassifier", "class", "SOFTMAX"
+		if ensemble.Type() == protobuf.ActivateType_LOGISTIC {
+			postTransform = "LOGISTIC"
+		}
+		outputNames = []string{"label", "probabilities"}
+		outputs = []onnxMessage{
+			onnxTensorInfo("label", onnxTypeInt64),
+			onnxTensorInfo("probabilities", onnxTypeFloat, int64(maxInt(e.numClasses, 2))),
+		}
+	default:
+		return fmt.Errorf("%w: %s activation cannot be exported to onnx", ErrUnsupportedFormat,
+			ensemble.Activation.Name())
+	}
+	if ensemble.Type() == protobuf.ActivateType_LOGISTIC && e.numClasses != 1 {
+		return fmt.Errorf("%w: logistic activation with %d classes cannot be exported to onnx",
+			ErrUnsupportedFormat, e.numClasses)
+	}
+
+	attributes, err := onnxTreeAttributes(e, leafPrefix)
+	if err != nil {
+		return err
+	}
+	attributes = append(attributes,
+		onnxAttribute{name: "base_values", floats: baseValues},
+		onnxAttribute{name: "post_transform", str: postTransform})
+	if leafPrefix == "target" {
+		n := int64(e.numClasses)
+		attributes = append(attributes,
+			onnxAttribute{name: "n_targets", i: &n},
+			onnxAttribute{name: "aggregate_function", str: "SUM"})
+	} else {
+		// binary models have a single score turned into the probabilities of classes 0 and 1.
+		labels := make([]int64, maxInt(e.numClasses, 2))
+		for i := range labels {
+			labels[i] = int64(i)
+		}
+		attributes = append(attributes, onnxAttribute{name: "classlabels_int64s", ints: labels})
+	}
+
+	node := onnxMessage{}.string(1, onnxInputName)
+	for _, name := range outputNames {
+		node = node.string(2, name)
+	}
+	node = node.string(3, opType).string(4, opType)
+	for _, a := range attributes {
+		node = node.message(5, a.encode())
+	}
+	node = node.string(7, onnxMLDomain)
+
+	graph := onnxMessage{}.message(1, node).string(2, e.name)
+	graph = graph.message(11, onnxTensorInfo(onnxInputName, onnxTypeFloat, int64(e.numFeat)))
+	for _, o := range outputs {
+		graph = graph.message(12, o)
+	}
+
+	model := onnxMessage{}.int(1, onnxIRVersion).string(2, onnxProducerName).message(7, graph)
+	model = model.message(8, onnxMessage{}.string(1, "").int(2, onnxOpsetVersion))
+	model = model.message(8, onnxMessage{}.string(1, onnxMLDomain).int(2, onnxMLVersion))
+	_, err = w.Write(model)
+	return err
+}
@@ -0,0 +1,173 @@
+package xgboost
+
+import (
+	"fmt"
+	"math"
+)
+
+// LoadOption configures how a model is loaded.
+type LoadOption func(*loadConfig)
+
+type loadConfig struct {
+	float32Comparison  bool
+	objective          string
+	missing            *float64
+	iterationBegin     int
+	iterationEnd       int
+	validateValues     bool
+	float32Values      bool
+	validateDimensions bool
+	oneBasedIndices    bool
+	featureNames       []string
+	featureMapPath     string
+	// quantizeThresholds replaces split thresholds by indices into per feature cut arrays once trees are loaded.
+	quantizeThresholds bool
+}
+
+func newLoadConfig(opts []LoadOption) loadConfig {
+	cfg := loadConfig{}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return cfg
+}
+
+// indexBase returns the index of the first feature in sparse rows.
+func (c *loadConfig) indexBase() int {
+	if c.oneBasedIndices {
+		return 1
+	}
+	return 0
+}
+
+// WithFloat32Comparison makes tree traversal compare feature values against split thresholds in float32.
+// XGBoost stores thresholds and feature values as float32, so this option bit-matches its routing for values
+// lying right on a split boundary. By default comparisons are done in float64.
+func WithFloat32Comparison() LoadOption {
+	return func(c *loadConfig) {
+		c.float32Comparison = true
+	}
+}
+
+// WithFloat32Values stores split thresholds and leaf values used by predictions as float32, halving the memory
+// traversed by predictions of large ensembles. Like with WithFloat32Comparison feature values are compared in
+// float32, leaf values are rounded to float32 but summed in float64, so predictions differ from the float64 ones
+// by about the float32 precision of leaf values. Explanations and exports keep using float64 values.
+func WithFloat32Values() LoadOption {
+	return func(c *loadConfig) {
+		c.float32Values = true
+	}
+}
+
+// WithQuantizedThresholds replaces the split thresholds of the loaded trees by uint16 indices into per feature
+// sorted arrays of the distinct thresholds of the model, like the cuts of XGBoost histogram method. Rows are then
+// binned once for every tree and plain numerical splits only compare integers, which reduces the memory of huge
+// ensembles. Predictions are unchanged. Loading fails if a feature has more than 65536 distinct thresholds or a
+// threshold is NaN.
+func WithQuantizedThresholds() LoadOption {
+	return func(c *loadConfig) {
+		c.quantizeThresholds = true
+	}
+}
+
+// WithObjective sets the XGBoost objective the model was trained with, e.g. binary:logistic. The loader then
+// checks that the given activation matches the objective and fails otherwise. If no activation is given the
+// one matching the objective is used, e.g. multi:softmax predicts class indices while multi:softprob predicts
+// probabilities.
+func WithObjective(objective string) LoadOption {
+	return func(c *loadConfig) {
+		c.objective = objective
+	}
+}
+
+// WithMissingValue makes features equal to missing follow the default branch of splits, like
+// xgboost.DMatrix(missing=...) does. NaN and absent features are always treated as missing.
+func WithMissingValue(missing float64) LoadOption {
+	return func(c *loadConfig) {
+		if math.IsNaN(missing) {
+			c.missing = nil
+			return
+		}
+		c.missing = &missing
+	}
+}
+
+// WithTreeLimit only loads the first n boosting rounds of the model, like the ntree_limit parameter of XGBoost
+// predict. For multiclass models a boosting round has one tree per class. n <= 0 loads every tree.
+func WithTreeLimit(n int) LoadOption {
+	return WithIterationRange(0, n)
+}
+
+// WithIterationRange only loads the boosting rounds [begin, end) of the model, like the iteration_range parameter
+// of XGBoost predict. For multiclass models a boosting round has one tree per class, and num_parallel_tree trees
+// per class for boosted random forests. end <= 0 or an end past the last round loads up to the last round,
+// base_score is always kept.
+func WithIterationRange(begin, end int) LoadOption {
+	return func(c *loadConfig) {
+		c.iterationBegin = begin
+		c.iterationEnd = end
+	}
+}
+
+// iterationRange returns the boosting rounds to load out of rounds.
+func (c *loadConfig) iterationRange(rounds int) (int, int, error) {
+	begin, end := c.iterationBegin, c.iterationEnd
+	if end <= 0 || end > rounds {
+		end = rounds
+	}
+	if begin < 0 || begin >= end {
+		return 0, 0, fmt.Errorf("invalid iteration range [%d, %d) for %d boosting rounds", c.iterationBegin,
+			c.iterationEnd, rounds)
+	}
+	return begin, end, nil
+}
+
+// WithValueValidation makes the loader return an error identifying the tree and node of any NaN or infinite leaf
+// value or split threshold. Without validation such values propagate through prediction: a NaN leaf makes the raw
+// score of its class NaN, an infinite leaf makes it infinite (or NaN if both signs are summed), and a NaN threshold
+// sends every non missing value to the yes child since comparisons with NaN are false.
+func WithValueValidation() LoadOption {
+	return func(c *loadConfig) {
+		c.validateValues = true
+	}
+}
+
+// WithDimensionValidation makes predictions return an error wrapping mat.ErrFeatureIndexOutOfRange for sparse rows
+// with a feature index out of [0, NumFeatures), or [1, NumFeatures] with WithOneBasedFeatureIndices, and an error
+// wrapping mat.ErrDimensionMismatch for dense rows which do not have exactly NumFeatures values. Without
+// validation absent and extra features are silently ignored or treated as missing, which hides inputs built for
+// another model. The number of features of json dumps is only
+// the highest feature index used by a split plus one, so this option is meant for models storing num_feature.
+func WithDimensionValidation() LoadOption {
+	return func(c *loadConfig) {
+		c.validateDimensions = true
+	}
+}
+
+// WithOneBasedFeatureIndices makes predictions read sparse and CSR rows whose feature indices start from 1, like
+// libsvm files read without mat.WithOneBasedIndex: feature i of the model is read at index i+1 of the rows and
+// FeatureIndex returns indices in the same base. Rows containing index 0 return an error wrapping
+// mat.ErrFeatureIndexOutOfRange so that 0-based rows are not silently shifted. Dense rows are not affected, their
+// values are positional, and contributions still have one value per feature of the model starting from feature 0.
+func WithOneBasedFeatureIndices() LoadOption {
+	return func(c *loadConfig) {
+		c.oneBasedIndices = true
+	}
+}
+
+// WithFeatureNames names the features of the model, names[i] being the name of feature i, like the feature_names of
+// the training DMatrix. The names override the ones stored in the model and must cover every feature of the model.
+func WithFeatureNames(names []string) LoadOption {
+	return func(c *loadConfig) {
+		c.featureNames = names
+	}
+}
+
+// WithFeatureMap names the features of the model from the feature map file at path, the fmap given to XGBoost
+// dump_model whose lines are "index name type". The names override the ones stored in the model, WithFeatureNames
+// takes precedence over this option.
+func WithFeatureMap(path string) LoadOption {
+	return func(c *loadConfig) {
+		c.featureMapPath = path
+	}
+}
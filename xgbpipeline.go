@@ -0,0 +1,300 @@
+package xgboost
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"reflect"
+
+	"github.com/lordberre/xgboost-go/activation"
+	"github.com/lordberre/xgboost-go/calibration"
+	"github.com/lordberre/xgboost-go/inference"
+	"github.com/lordberre/xgboost-go/mat"
+	"github.com/lordberre/xgboost-go/preprocess"
+)
+
+// Pipeline wires the stages of serving a model into one call: raw inputs are vectorized or preprocessed,
+// predicted by the model with its activation and optionally calibrated. A pipeline is saved as a single JSON
+// document holding the fitted stages and the content of the model file, so that serving code loads it in one
+// call instead of assembling the stages by hand. Stages are optional and must not be modified while predictions
+// are running.
+type Pipeline struct {
+	// Vectorizer hashes the named features and tokens of PredictFeatures, PredictCategorical and PredictTokens.
+	Vectorizer *preprocess.HashingVectorizer
+	// Preprocessor transforms the dense rows of Predict before prediction.
+	Preprocessor preprocess.Transformer
+	// Calibrator maps the predictions of single output models to calibrated probabilities.
+	Calibrator calibration.Calibrator
+	Model      *inference.Ensemble
+
+	// model is the content of the model file, numClasses, act and cfg the parameters it was loaded with.
+	model      []byte
+	numClasses int
+	act        activation.Activation
+	cfg        loadConfig
+}
+
+// pipelineJSON is the document written by WritePipelineJSON.
+type pipelineJSON struct {
+	Model        []byte                        `json:"model"`
+	NumClasses   int                           `json:"num_classes"`
+	Activation   string                        `json:"activation,omitempty"`
+	Options      optionsJSON                   `json:"options"`
+	Vectorizer   *preprocess.HashingVectorizer `json:"vectorizer,omitempty"`
+	Preprocessor json.RawMessage               `json:"preprocessor,omitempty"`
+	Calibrator   json.RawMessage               `json:"calibrator,omitempty"`
+}
+
+// optionsJSON contains the load options of a pipeline model.
+type optionsJSON struct {
+	Float32Comparison  bool     `json:"float32_comparison,omitempty"`
+	Float32Values      bool     `json:"float32_values,omitempty"`
+	QuantizeThresholds bool     `json:"quantize_thresholds,omitempty"`
+	Objective          string   `json:"objective,omitempty"`
+	Missing            *float64 `json:"missing,omitempty"`
+	IterationBegin     int      `json:"iteration_begin,omitempty"`
+	IterationEnd       int      `json:"iteration_end,omitempty"`
+	ValidateValues     bool     `json:"validate_values,omitempty"`
+	ValidateDimensions bool     `json:"validate_dimensions,omitempty"`
+	OneBasedIndices    bool     `json:"one_based_indices,omitempty"`
+	FeatureNames       []string `json:"feature_names,omitempty"`
+}
+
+// newOptionsJSON returns the options of cfg, whose feature map must already be resolved to feature names.
+func newOptionsJSON(cfg loadConfig) (optionsJSON, error) {
+	if cfg.missing != nil && math.IsInf(*cfg.missing, 0) {
+		return optionsJSON{}, fmt.Errorf("missing value %v cannot be saved as JSON", *cfg.missing)
+	}
+	return optionsJSON{
+		Float32Comparison:  cfg.float32Comparison,
+		Float32Values:      cfg.float32Values,
+		QuantizeThresholds: cfg.quantizeThresholds,
+		Objective:          cfg.objective,
+		Missing:            cfg.missing,
+		IterationBegin:     cfg.iterationBegin,
+		IterationEnd:       cfg.iterationEnd,
+		ValidateValues:     cfg.validateValues,
+		ValidateDimensions: cfg.validateDimensions,
+		OneBasedIndices:    cfg.oneBasedIndices,
+		FeatureNames:       cfg.featureNames,
+	}, nil
+}
+
+// option returns the load option restoring o.
+func (o optionsJSON) option() LoadOption {
+	return func(c *loadConfig) {
+		*c = loadConfig{
+			float32Comparison:  o.Float32Comparison,
+			float32Values:      o.Float32Values,
+			quantizeThresholds: o.QuantizeThresholds,
+			objective:          o.Objective,
+			missing:            o.Missing,
+			iterationBegin:     o.IterationBegin,
+			iterationEnd:       o.IterationEnd,
+			validateValues:     o.ValidateValues,
+			validateDimensions: o.ValidateDimensions,
+			oneBasedIndices:    o.OneBasedIndices,
+			featureNames:       o.FeatureNames,
+		}
+	}
+}
+
+// NewPipeline returns a pipeline predicting with the model at modelPath, loaded like LoadXGBoostModel, without
+// any other stage. JSON dumps are loaded without feature map and tree depth check, prefer models saved with
+// save_model. If act is nil the activation matching the model objective is used and this is saved with the
+// pipeline, otherwise act is saved by name and must be registered, see activation.Register. Load options are saved
+// with the pipeline, the names of a feature map given with WithFeatureMap being saved as feature names.
+func NewPipeline(modelPath string, numClasses int, act activation.Activation, opts ...LoadOption) (*Pipeline,
+	error) {
+	content, err := ioutil.ReadFile(modelPath)
+	if err != nil {
+		return nil, err
+	}
+	return newPipeline(content, modelPath, numClasses, act, opts...)
+}
+
+// newPipeline returns a pipeline predicting with the model of content named name.
+func newPipeline(
+	content []byte,
+	name string,
+	numClasses int,
+	act activation.Activation,
+	opts ...LoadOption) (*Pipeline, error) {
+	f, format, err := newModelFile(bytes.NewReader(content), name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	cfg := newLoadConfig(opts)
+	if cfg.featureMapPath != "" {
+		if cfg.featureNames == nil {
+			featMap, _, err := loadFeatureMap(cfg.featureMapPath)
+			if err != nil {
+				return nil, err
+			}
+			cfg.featureNames = featureMapNames(featMap)
+		}
+		cfg.featureMapPath = ""
+	}
+	model, err := loadModelFile(f, format, name, "", numClasses, 0, act, func(c *loadConfig) { *c = cfg })
+	if err != nil {
+		return nil, err
+	}
+	return &Pipeline{Model: model, model: content, numClasses: numClasses, act: act, cfg: cfg}, nil
+}
+
+// WritePipelineJSON writes the model and the fitted stages of p to w as JSON.
+func WritePipelineJSON(p *Pipeline, w io.Writer) error {
+	if p.model == nil {
+		return fmt.Errorf("pipeline has no model, create it with NewPipeline")
+	}
+	options, err := newOptionsJSON(p.cfg)
+	if err != nil {
+		return err
+	}
+	pj := pipelineJSON{Model: p.model, NumClasses: p.numClasses, Options: options, Vectorizer: p.Vectorizer}
+	if p.act != nil {
+		a, err := activation.ByName(p.act.Name())
+		if err != nil {
+			return err
+		}
+		if !reflect.DeepEqual(a, p.act) {
+			return fmt.Errorf("activation %s differs from the registered one", p.act.Name())
+		}
+		pj.Activation = p.act.Name()
+	}
+	var buf bytes.Buffer
+	if p.Preprocessor != nil {
+		if err := preprocess.WriteTransformerJSON(p.Preprocessor, &buf); err != nil {
+			return err
+		}
+		pj.Preprocessor = append(json.RawMessage(nil), buf.Bytes()...)
+		buf.Reset()
+	}
+	if p.Calibrator != nil {
+		if err := calibration.WriteCalibratorJSON(p.Calibrator, &buf); err != nil {
+			return err
+		}
+		pj.Calibrator = append(json.RawMessage(nil), buf.Bytes()...)
+	}
+	return json.NewEncoder(w).Encode(pj)
+}
+
+// ReadPipelineJSON reads a pipeline written by WritePipelineJSON. Its model is loaded with the saved load options
+// followed by opts, which override them.
+func ReadPipelineJSON(r io.Reader, opts ...LoadOption) (*Pipeline, error) {
+	var pj pipelineJSON
+	if err := json.NewDecoder(r).Decode(&pj); err != nil {
+		return nil, fmt.Errorf("cannot decode pipeline: %s", err)
+	}
+	if len(pj.Model) == 0 {
+		return nil, fmt.Errorf("%w: pipeline has no model", ErrMalformedModel)
+	}
+	var act activation.Activation
+	if pj.Activation != "" {
+		var err error
+		if act, err = activation.ByName(pj.Activation); err != nil {
+			return nil, err
+		}
+	}
+	opts = append([]LoadOption{pj.Options.option()}, opts...)
+	p, err := newPipeline(pj.Model, "pipeline model", pj.NumClasses, act, opts...)
+	if err != nil {
+		return nil, err
+	}
+	p.Vectorizer = pj.Vectorizer
+	if len(pj.Preprocessor) != 0 {
+		if p.Preprocessor, err = preprocess.ReadTransformerJSON(bytes.NewReader(pj.Preprocessor)); err != nil {
+			return nil, err
+		}
+	}
+	if len(pj.Calibrator) != 0 {
+		if p.Calibrator, err = calibration.ReadCalibratorJSON(bytes.NewReader(pj.Calibrator)); err != nil {
+			return nil, err
+		}
+	}
+	return p, nil
+}
+
+// Predict returns the predictions of dense rows, transformed by Preprocessor if set. Only NaN values are
+// missing, see inference.Ensemble.PredictProbaDense.
+func (p *Pipeline) Predict(features mat.Matrix) (mat.Matrix, error) {
+	if p.Preprocessor != nil {
+		var err error
+		if features, err = p.Preprocessor.Transform(features); err != nil {
+			return mat.Matrix{}, err
+		}
+	}
+	preds, err := p.Model.PredictProbaDense(features)
+	if err != nil {
+		return mat.Matrix{}, err
+	}
+	return p.calibrate(preds)
+}
+
+// PredictFeatures returns the predictions of rows of named features hashed by Vectorizer.
+func (p *Pipeline) PredictFeatures(rows []map[string]float64) (mat.Matrix, error) {
+	return p.predictHashed(len(rows), func(i int) (mat.SparseVector, error) {
+		return p.Vectorizer.Transform(rows[i])
+	})
+}
+
+// PredictCategorical returns the predictions of rows of categorical features hashed by Vectorizer.
+func (p *Pipeline) PredictCategorical(rows []map[string]string) (mat.Matrix, error) {
+	return p.predictHashed(len(rows), func(i int) (mat.SparseVector, error) {
+		return p.Vectorizer.TransformCategorical(rows[i])
+	})
+}
+
+// PredictTokens returns the predictions of rows of tokens hashed by Vectorizer.
+func (p *Pipeline) PredictTokens(rows [][]string) (mat.Matrix, error) {
+	return p.predictHashed(len(rows), func(i int) (mat.SparseVector, error) {
+		return p.Vectorizer.TransformTokens(rows[i])
+	})
+}
+
+// predictHashed returns the predictions of n rows, row returning the hashed row i.
+func (p *Pipeline) predictHashed(n int, row func(i int) (mat.SparseVector, error)) (mat.Matrix, error) {
+	if p.Vectorizer == nil {
+		return mat.Matrix{}, fmt.Errorf("pipeline has no vectorizer")
+	}
+	features := mat.SparseMatrix{Vectors: make([]mat.SparseVector, n)}
+	for i := range features.Vectors {
+		var err error
+		if features.Vectors[i], err = row(i); err != nil {
+			return mat.Matrix{}, err
+		}
+	}
+	preds, err := p.Model.PredictProba(features)
+	if err != nil {
+		return mat.Matrix{}, err
+	}
+	return p.calibrate(preds)
+}
+
+// calibrate returns preds mapped by Calibrator if set.
+func (p *Pipeline) calibrate(preds mat.Matrix) (mat.Matrix, error) {
+	if p.Calibrator == nil {
+		return preds, nil
+	}
+	scores := make(mat.Vector, len(preds.Vectors))
+	for i, v := range preds.Vectors {
+		if len(*v) != 1 {
+			return mat.Matrix{}, fmt.Errorf("%w: calibrator needs a single prediction per row, got %d",
+				mat.ErrDimensionMismatch, len(*v))
+		}
+		scores[i] = (*v)[0]
+	}
+	calibrated, err := p.Calibrator.Transform(scores)
+	if err != nil {
+		return mat.Matrix{}, err
+	}
+	result := mat.Matrix{Vectors: make([]*mat.Vector, len(calibrated))}
+	for i := range calibrated {
+		result.Vectors[i] = &mat.Vector{calibrated[i]}
+	}
+	return result, nil
+}
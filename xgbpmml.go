@@ -0,0 +1,496 @@
+package xgboost
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/lordberre/xgboost-go/activation"
+	"github.com/lordberre/xgboost-go/inference"
+)
+
+// pmmlElement is a generic PMML element, documents are decoded without a schema since models only use a small
+// subset of it.
+type pmmlElement struct {
+	XMLName  xml.Name
+	Attrs    []xml.Attr    `xml:",any,attr"`
+	Children []pmmlElement `xml:",any"`
+	Text     string        `xml:",chardata"`
+}
+
+func (e *pmmlElement) attr(name string) string {
+	for _, a := range e.Attrs {
+		if a.Name.Local == name {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+func (e *pmmlElement) number(name string) (float64, error) {
+	v, err := strconv.ParseFloat(e.attr(name), 64)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %s of %s is not a number: %q", ErrMalformedModel, name, e.XMLName.Local,
+			e.attr(name))
+	}
+	return v, nil
+}
+
+func (e *pmmlElement) children(name string) []*pmmlElement {
+	var r []*pmmlElement
+	for i := range e.Children {
+		if e.Children[i].XMLName.Local == name {
+			r = append(r, &e.Children[i])
+		}
+	}
+	return r
+}
+
+func (e *pmmlElement) child(name string) *pmmlElement {
+	if c := e.children(name); len(c) > 0 {
+		return c[0]
+	}
+	return nil
+}
+
+// model returns the model element of e, i.e. of the document or of a segment.
+func (e *pmmlElement) model() *pmmlElement {
+	for i := range e.Children {
+		switch e.Children[i].XMLName.Local {
+		case "TreeModel", "MiningModel", "RegressionModel":
+			return &e.Children[i]
+		}
+	}
+	return nil
+}
+
+// pmmlPredicate is a predicate of a tree node, feature < threshold for numerical splits or feature in categories
+// for categorical splits. inverted is true if the predicate is the complement, missing is true if missing values
+// satisfy the predicate.
+type pmmlPredicate struct {
+	always     bool
+	feature    int
+	threshold  float64
+	categories map[int]struct{}
+	inverted   bool
+	missing    bool
+}
+
+// pmmlTreeBuilder converts PMML nodes into the nodes of a tree.
+type pmmlTreeBuilder struct {
+	features map[string]int
+	// defaultChild is true for trees sending missing values to the defaultChild of nodes.
+	defaultChild bool
+	nodes        []*xgbNode
+}
+
+// predicate parses the predicate of a node.
+func (b *pmmlTreeBuilder) predicate(e *pmmlElement) (pmmlPredicate, error) {
+	switch e.XMLName.Local {
+	case "True":
+		return pmmlPredicate{always: true}, nil
+	case "SimplePredicate":
+		feature, ok := b.features[e.attr("field")]
+		if !ok {
+			return pmmlPredicate{}, fmt.Errorf("%w: unknown field %q", ErrMalformedModel, e.attr("field"))
+		}
+		p := pmmlPredicate{feature: feature}
+		op := e.attr("operator")
+		if op == "isMissing" {
+			return p, fmt.Errorf("%w: isMissing predicates are only supported in compound predicates",
+				ErrUnsupportedFormat)
+		}
+		v, err := e.number("value")
+		if err != nil {
+			return p, err
+		}
+		switch op {
+		case "lessThan":
+			p.threshold = v
+		case "lessOrEqual":
+			p.threshold = math.Nextafter(v, math.Inf(1))
+		case "greaterOrEqual":
+			p.threshold, p.inverted = v, true
+		case "greaterThan":
+			p.threshold, p.inverted = math.Nextafter(v, math.Inf(1)), true
+		default:
+			return p, fmt.Errorf("%w: %s operator is not supported", ErrUnsupportedFormat, op)
+		}
+		return p, nil
+	case "SimpleSetPredicate":
+		feature, ok := b.features[e.attr("field")]
+		if !ok {
+			return pmmlPredicate{}, fmt.Errorf("%w: unknown field %q", ErrMalformedModel, e.attr("field"))
+		}
+		p := pmmlPredicate{feature: feature, categories: make(map[int]struct{}),
+			inverted: e.attr("booleanOperator") == "isNotIn"}
+		array := e.child("Array")
+		if array == nil {
+			return p, fmt.Errorf("%w: set predicate without array", ErrMalformedModel)
+		}
+		for _, v := range strings.Fields(array.Text) {
+			c, err := strconv.Atoi(strings.Trim(v, `"`))
+			if err != nil {
+				return p, fmt.Errorf("%w: category %s is not an integer", ErrUnsupportedFormat, v)
+			}
+			p.categories[c] = struct{}{}
+		}
+		return p, nil
+	case "CompoundPredicate":
+		// missing values are handled with either isMissing or ... or surrogate predicates.
+		var operands []*pmmlElement
+		for i := range e.Children {
+			operands = append(operands, &e.Children[i])
+		}
+		op := e.attr("booleanOperator")
+		if len(operands) == 2 && op == "or" && operands[0].XMLName.Local == "SimplePredicate" &&
+			operands[0].attr("operator") == "isMissing" {
+			p, err := b.predicate(operands[1])
+			p.missing = true
+			return p, err
+		}
+		if len(operands) == 2 && op == "surrogate" &&
+			(operands[1].XMLName.Local == "True" || operands[1].XMLName.Local == "False") {
+			p, err := b.predicate(operands[0])
+			p.missing = operands[1].XMLName.Local == "True"
+			return p, err
+		}
+		return pmmlPredicate{}, fmt.Errorf("%w: %s compound predicate is not supported", ErrUnsupportedFormat, op)
+	}
+	return pmmlPredicate{}, fmt.Errorf("%w: %s predicate is not supported", ErrUnsupportedFormat, e.XMLName.Local)
+}
+
+// nodePredicate returns the predicate of node, the first child element which is not an extension or a score.
+func (b *pmmlTreeBuilder) nodePredicate(node *pmmlElement) (pmmlPredicate, error) {
+	for i := range node.Children {
+		switch node.Children[i].XMLName.Local {
+		case "Extension", "Node", "ScoreDistribution", "Partition":
+			continue
+		}
+		return b.predicate(&node.Children[i])
+	}
+	return pmmlPredicate{}, fmt.Errorf("%w: node without predicate", ErrMalformedModel)
+}
+
+// build appends node and its descendants in pre-order and returns the index of node.
+func (b *pmmlTreeBuilder) build(node *pmmlElement) (int, error) {
+	idx := len(b.nodes)
+	n := &xgbNode{NodeID: idx}
+	b.nodes = append(b.nodes, n)
+	if c := node.attr("recordCount"); c != "" {
+		if v, err := strconv.ParseFloat(c, 64); err == nil {
+			n.Cover = v
+		}
+	}
+	children := node.children("Node")
+	if len(children) == 0 {
+		v, err := node.number("score")
+		if err != nil {
+			return 0, err
+		}
+		n.Flags = isLeaf
+		n.LeafValues = v
+		return idx, nil
+	}
+	if len(children) != 2 {
+		return 0, fmt.Errorf("%w: node with %d children, only binary splits are supported", ErrUnsupportedFormat,
+			len(children))
+	}
+	first, err := b.nodePredicate(children[0])
+	if err != nil {
+		return 0, err
+	}
+	// the second child is either always true or the complement of the first one.
+	if _, err := b.nodePredicate(children[1]); err != nil {
+		return 0, err
+	}
+	if first.always {
+		return 0, fmt.Errorf("%w: first child of a split cannot be always true", ErrUnsupportedFormat)
+	}
+	left, err := b.build(children[0])
+	if err != nil {
+		return 0, err
+	}
+	right, err := b.build(children[1])
+	if err != nil {
+		return 0, err
+	}
+
+	n.Feature = first.feature
+	n.Yes, n.No = left, right
+	if first.inverted {
+		n.Yes, n.No = right, left
+	}
+	if first.categories != nil {
+		n.Flags = isCategorical
+		n.Categories = first.categories
+	} else {
+		n.Threshold = first.threshold
+	}
+	// missing values go to the first child satisfied by them, otherwise the second child is taken because
+	// unknown predicates are false.
+	switch {
+	case first.missing:
+		n.Missing = left
+	case b.defaultChild && node.attr("defaultChild") != "":
+		n.Missing = right
+		if node.attr("defaultChild") == children[0].attr("id") {
+			n.Missing = left
+		}
+	default:
+		n.Missing = right
+	}
+	return idx, nil
+}
+
+// pmmlTrees returns the trees of a tree model or of a sum of tree models, together with the constant added to
+// their sum.
+func pmmlTrees(model *pmmlElement, features map[string]int) ([]*xgbTree, float64, error) {
+	base := 0.0
+	if target := model.child("Targets"); target != nil {
+		for _, t := range target.children("Target") {
+			if f := t.attr("rescaleFactor"); f != "" && f != "1" {
+				return nil, 0, fmt.Errorf("%w: rescaleFactor %s is not supported", ErrUnsupportedFormat, f)
+			}
+			if t.attr("rescaleConstant") != "" {
+				v, err := t.number("rescaleConstant")
+				if err != nil {
+					return nil, 0, err
+				}
+				base += v
+			}
+		}
+	}
+	switch model.XMLName.Local {
+	case "TreeModel":
+		root := model.child("Node")
+		if root == nil {
+			return nil, 0, fmt.Errorf("%w: tree model without node", ErrMalformedModel)
+		}
+		b := &pmmlTreeBuilder{features: features, defaultChild: model.attr("missingValueStrategy") == "defaultChild"}
+		if _, err := b.build(root); err != nil {
+			return nil, 0, err
+		}
+		t := &xgbTree{nodes: b.nodes, float32Comparison: model.attr("x-mathContext") == "float"}
+		return []*xgbTree{t}, base, nil
+	case "MiningModel":
+		segmentation := model.child("Segmentation")
+		if segmentation == nil {
+			return nil, 0, fmt.Errorf("%w: mining model without segmentation", ErrMalformedModel)
+		}
+		if m := segmentation.attr("multipleModelMethod"); m != "sum" {
+			return nil, 0, fmt.Errorf("%w: %s segmentation of regression models is not supported",
+				ErrUnsupportedFormat, m)
+		}
+		var trees []*xgbTree
+		for i, segment := range segmentation.children("Segment") {
+			if segment.child("True") == nil {
+				return nil, 0, fmt.Errorf("%w: segment %d predicate is not always true", ErrUnsupportedFormat, i)
+			}
+			sub := segment.model()
+			if sub == nil {
+				return nil, 0, fmt.Errorf("%w: segment %d without model", ErrMalformedModel, i)
+			}
+			t, b, err := pmmlTrees(sub, features)
+			if err != nil {
+				return nil, 0, err
+			}
+			trees = append(trees, t...)
+			base += b
+		}
+		return trees, base, nil
+	}
+	return nil, 0, fmt.Errorf("%w: %s is not supported", ErrUnsupportedFormat, model.XMLName.Local)
+}
+
+// pmmlClassifier returns the trees of each class of a model chain classifier whose last segment turns the sums of
+// the previous segments into probabilities with a logit or softmax regression model.
+func pmmlClassifier(model *pmmlElement, features map[string]int) ([][]*xgbTree, []float64, activation.Activation,
+	error) {
+	segmentation := model.child("Segmentation")
+	if segmentation == nil || segmentation.attr("multipleModelMethod") != "modelChain" {
+		return nil, nil, nil, fmt.Errorf("%w: classification models must be a model chain", ErrUnsupportedFormat)
+	}
+	segments := segmentation.children("Segment")
+	if len(segments) < 2 {
+		return nil, nil, nil, fmt.Errorf("%w: model chain with %d segments", ErrMalformedModel, len(segments))
+	}
+	regression := segments[len(segments)-1].model()
+	if regression == nil || regression.XMLName.Local != "RegressionModel" {
+		return nil, nil, nil, fmt.Errorf("%w: model chain must end with a regression model", ErrUnsupportedFormat)
+	}
+	numClasses := len(segments) - 1
+	var act activation.Activation
+	switch method := regression.attr("normalizationMethod"); {
+	case method == "logit" && numClasses == 1:
+		act = &activation.Logistic{}
+	case method == "softmax" && numClasses > 1:
+		act = &activation.Softmax{}
+	default:
+		return nil, nil, nil, fmt.Errorf("%w: %s normalization with %d classes is not supported",
+			ErrUnsupportedFormat, method, numClasses)
+	}
+
+	// regression tables add intercept + sum of the output of a segment.
+	outputs := make(map[string]int)
+	perClass := make([][]*xgbTree, numClasses)
+	bases := make([]float64, numClasses)
+	for c, segment := range segments[:numClasses] {
+		sub := segment.model()
+		if sub == nil || segment.child("True") == nil {
+			return nil, nil, nil, fmt.Errorf("%w: segment %d must be an always true model", ErrUnsupportedFormat, c)
+		}
+		if output := sub.child("Output"); output != nil {
+			for _, f := range output.children("OutputField") {
+				outputs[f.attr("name")] = c
+			}
+		}
+		var err error
+		if perClass[c], bases[c], err = pmmlTrees(sub, features); err != nil {
+			return nil, nil, nil, err
+		}
+	}
+	seen := make(map[int]bool)
+	for _, table := range regression.children("RegressionTable") {
+		predictors := table.children("NumericPredictor")
+		if len(predictors) == 0 {
+			continue
+		}
+		c, ok := outputs[predictors[0].attr("name")]
+		if len(predictors) != 1 || !ok || seen[c] {
+			return nil, nil, nil, fmt.Errorf("%w: regression table must use the output of one segment",
+				ErrUnsupportedFormat)
+		}
+		if coef := predictors[0].attr("coefficient"); coef != "" && coef != "1" {
+			return nil, nil, nil, fmt.Errorf("%w: coefficient %s is not supported", ErrUnsupportedFormat, coef)
+		}
+		if table.attr("intercept") != "" {
+			intercept, err := table.number("intercept")
+			if err != nil {
+				return nil, nil, nil, err
+			}
+			bases[c] += intercept
+		}
+		seen[c] = true
+	}
+	if len(seen) != numClasses {
+		return nil, nil, nil, fmt.Errorf("%w: %d regression tables for %d classes", ErrMalformedModel, len(seen),
+			numClasses)
+	}
+	return perClass, bases, act, nil
+}
+
+// loadPMML loads a gradient boosted trees model from PMML content.
+func loadPMML(r io.Reader, numClasses int, act activation.Activation, opts ...LoadOption) (*inference.Ensemble,
+	error) {
+	cfg := newLoadConfig(opts)
+	var doc pmmlElement
+	if err := xml.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrMalformedModel, err)
+	}
+	if doc.XMLName.Local != "PMML" {
+		return nil, fmt.Errorf("%w: %s is not a pmml document", ErrMalformedModel, doc.XMLName.Local)
+	}
+	model := doc.model()
+	if model == nil {
+		return nil, fmt.Errorf("%w: pmml document without model", ErrMalformedModel)
+	}
+	schema := model.child("MiningSchema")
+	if schema == nil {
+		return nil, fmt.Errorf("%w: model without mining schema", ErrMalformedModel)
+	}
+	features := make(map[string]int)
+	for _, f := range schema.children("MiningField") {
+		switch f.attr("usageType") {
+		case "", "active":
+			features[f.attr("name")] = len(features)
+		}
+	}
+
+	var perClass [][]*xgbTree
+	var bases []float64
+	var modelAct activation.Activation
+	switch model.attr("functionName") {
+	case "regression":
+		trees, base, err := pmmlTrees(model, features)
+		if err != nil {
+			return nil, err
+		}
+		perClass, bases, modelAct = [][]*xgbTree{trees}, []float64{base}, &activation.Raw{}
+	case "classification":
+		var err error
+		if perClass, bases, modelAct, err = pmmlClassifier(model, features); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("%w: %s models are not supported", ErrUnsupportedFormat, model.attr("functionName"))
+	}
+	if numClasses > 0 && numClasses != len(perClass) {
+		return nil, fmt.Errorf("model has %d classes, got %d", len(perClass), numClasses)
+	}
+	numClasses = len(perClass)
+	if act == nil {
+		act = modelAct
+	}
+
+	// classes with fewer trees are padded with empty trees to keep tree i in class i % numClasses.
+	rounds := 0
+	for _, trees := range perClass {
+		rounds = maxInt(rounds, len(trees))
+	}
+	if rounds == 0 {
+		return nil, fmt.Errorf("%w: no trees in model", ErrMalformedModel)
+	}
+	begin, end, err := cfg.iterationRange(rounds)
+	if err != nil {
+		return nil, err
+	}
+	e := &xgbEnsemble{name: "pmml", numClasses: numClasses, numFeat: len(features), missing: cfg.missing,
+		baseMargin: bases[0], featureNames: make([]string, len(features))}
+	for name, idx := range features {
+		e.featureNames[idx] = name
+	}
+	for k := begin; k < end; k++ {
+		for c, trees := range perClass {
+			t := &xgbTree{nodes: []*xgbNode{{Flags: isLeaf}}}
+			if k < len(trees) {
+				t = trees[k]
+			}
+			// the ensemble has a single base margin, the difference with the base of other classes is added to
+			// the leaves of their first tree.
+			t.computeNodeMeans()
+			if k == begin && bases[c] != e.baseMargin {
+				t.addToLeaves(bases[c] - e.baseMargin)
+			}
+			if cfg.float32Comparison {
+				t.float32Comparison = true
+			}
+			if cfg.validateValues {
+				if err := t.validate(); err != nil {
+					return nil, fmt.Errorf("error while reading %d tree of class %d: %w", k, c, err)
+				}
+			}
+			e.Trees = append(e.Trees, t)
+		}
+	}
+	return e.ensemble(&cfg, act)
+}
+
+// LoadPMML loads a gradient boosted trees model from a PMML document, e.g. exported by jpmml-xgboost or
+// sklearn2pmml. Regression models are a TreeModel or a MiningModel summing TreeModel segments, classification
+// models are a model chain of one such sum per class followed by a logit or softmax RegressionModel. Feature
+// indices follow the order of the active fields of the top level MiningSchema. Only binary splits on
+// lessThan, lessOrEqual, greaterOrEqual, greaterThan and isIn predicates are supported, missing values follow
+// isMissing alternatives, surrogate predicates or the defaultChild of nodes and go to the last child otherwise.
+// The activation of the model is used if act is nil.
+func LoadPMML(modelPath string, act activation.Activation, opts ...LoadOption) (*inference.Ensemble, error) {
+	modelFile, _, err := openModelFile(modelPath)
+	if err != nil {
+		return nil, err
+	}
+	defer modelFile.Close()
+	return loadPMML(modelFile, 0, act, opts...)
+}
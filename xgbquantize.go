@@ -0,0 +1,127 @@
+package xgboost
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// maxQuantizedCuts is the number of distinct thresholds of a feature that uint16 bins can index.
+const maxQuantizedCuts = 1 << 16
+
+// quantize replaces the split thresholds of the flat trees by uint16 indices into per feature sorted arrays of
+// the distinct thresholds of the ensemble, like the cuts of XGBoost histogram method. Thresholds are rounded to
+// float32 first for trees comparing in float32, which every tree must then do.
+func (e *xgbEnsemble) quantize() error {
+	if len(e.Trees) == 0 {
+		return nil
+	}
+	float32Comparison := e.Trees[0].float32Comparison || e.Trees[0].float32Values
+	thresholds := map[int]map[float64]struct{}{}
+	numFeat := 0
+	for i, t := range e.Trees {
+		if (t.float32Comparison || t.float32Values) != float32Comparison {
+			return fmt.Errorf("quantization requires every tree to compare in the same precision")
+		}
+		for j, n := range t.nodes {
+			if n == nil || n.Flags&(isLeaf|isCategorical) > 0 {
+				continue
+			}
+			th := n.Threshold
+			if float32Comparison {
+				th = float64(float32(th))
+			}
+			if math.IsNaN(th) {
+				return fmt.Errorf("%w: tree %d node %d has NaN split threshold which cannot be quantized",
+					ErrMalformedModel, i, j)
+			}
+			if thresholds[n.Feature] == nil {
+				thresholds[n.Feature] = map[float64]struct{}{}
+			}
+			thresholds[n.Feature][th] = struct{}{}
+			numFeat = maxInt(numFeat, n.Feature+1)
+		}
+	}
+
+	cuts := make([][]float64, numFeat)
+	for feat, values := range thresholds {
+		if len(values) > maxQuantizedCuts {
+			return fmt.Errorf("feature %d has %d distinct split thresholds, at most %d can be quantized", feat,
+				len(values), maxQuantizedCuts)
+		}
+		for th := range values {
+			cuts[feat] = append(cuts[feat], th)
+		}
+		sort.Float64s(cuts[feat])
+	}
+	for _, t := range e.Trees {
+		f := &t.flat
+		f.bins = make([]uint16, len(t.nodes))
+		for j, n := range t.nodes {
+			if n == nil || n.Flags&(isLeaf|isCategorical) > 0 {
+				continue
+			}
+			th := n.Threshold
+			if float32Comparison {
+				th = float64(float32(th))
+			}
+			f.bins[j] = uint16(sort.SearchFloat64s(cuts[n.Feature], th))
+		}
+		f.cuts, f.thresholds, f.thresholds32 = cuts, nil, nil
+	}
+	e.cuts, e.quantizedFloat32 = cuts, float32Comparison
+	return nil
+}
+
+// ranks returns the number of cuts lower or equal to each feature value, -1 for missing features, so that a
+// value goes to the no child of a quantized split when its rank is greater than the bin of the split.
+func (e *xgbEnsemble) ranks(features featureLookup) []int32 {
+	ranks := make([]int32, len(e.cuts))
+	for feat, cuts := range e.cuts {
+		if len(cuts) == 0 {
+			continue
+		}
+		v, ok := features(feat)
+		switch {
+		case !ok:
+			ranks[feat] = -1
+		case math.IsNaN(v):
+			// comparisons with NaN are false so NaN values present in sparse rows go to yes children.
+		default:
+			if e.quantizedFloat32 {
+				v = float64(float32(v))
+			}
+			ranks[feat] = int32(sort.Search(len(cuts), func(i int) bool { return cuts[i] > v }))
+		}
+	}
+	return ranks
+}
+
+// predictQuantized returns the leaf value reached by features in a quantized tree given their ranks. Plain
+// numerical splits only compare integers, other splits use the feature values.
+func (t *xgbTree) predictQuantized(ranks []int32, features featureLookup) (float64, error) {
+	f := &t.flat
+	idx := 0
+	for {
+		flags := f.flags[idx]
+		if flags&(isLeaf|isNilNode) > 0 {
+			if flags&isNilNode > 0 {
+				return 0, fmt.Errorf("%w: nil node", ErrMalformedModel)
+			}
+			return f.value(idx), nil
+		}
+		if flags == 0 {
+			switch r := ranks[f.features[idx]]; {
+			case r < 0:
+				idx = int(f.missing[idx])
+			case r > int32(f.bins[idx]):
+				idx = int(f.no[idx])
+			default:
+				idx = int(f.yes[idx])
+			}
+			continue
+		}
+		v, ok := features(int(f.features[idx]))
+		idx = t.next(idx, v, ok)
+	}
+}
@@ -0,0 +1,113 @@
+package xgboost
+
+import (
+	"fmt"
+	"math"
+	"os"
+
+	"github.com/lordberre/xgboost-go/activation"
+	"github.com/lordberre/xgboost-go/mat"
+)
+
+// InputFormat is the format of files scored by ScoreFile.
+type InputFormat string
+
+// OutputFormat is the format of predictions written by ScoreFile.
+type OutputFormat string
+
+// ScoreFile input and output formats.
+const (
+	// InputLibsvm reads input with mat.ReadLibsvmFileToSparseMatrix, label column is ignored.
+	InputLibsvm InputFormat = "libsvm"
+	// InputCSV reads dense input with mat.ReadCSVFileToDenseMatrix, empty values are missing.
+	InputCSV InputFormat = "csv"
+
+	// OutputText writes one row of predictions per line separated by tabs.
+	OutputText OutputFormat = "text"
+	// OutputJSONL writes one JSON array of predictions per line.
+	OutputJSONL OutputFormat = "jsonl"
+)
+
+// ScoreOptions contains the model and file settings used by ScoreFile.
+type ScoreOptions struct {
+	// FeatureMapPath is the optional feature map of the model.
+	FeatureMapPath string
+	NumClasses     int
+	MaxDepth       int
+	// Activation of the model, if nil the activation matching Objective is used.
+	Activation activation.Activation
+	Objective  string
+	// InputFormat defaults to InputLibsvm.
+	InputFormat InputFormat
+	// Delimiter of csv input, defaults to ",".
+	Delimiter string
+	// OutputFormat defaults to OutputText.
+	OutputFormat OutputFormat
+	// TreeLimit only uses the first boosting rounds of the model if > 0, see WithTreeLimit.
+	TreeLimit int
+	// OutputMargin writes raw margins instead of applying the activation, like output_margin=True.
+	OutputMargin bool
+}
+
+// ScoreFile loads the model at modelPath, predicts probabilities of every row of inputPath and writes them to
+// outputPath. It is a one call batch path composing LoadXGBoostModel, the mat readers and writers and
+// PredictProba, which all remain usable on their own.
+func ScoreFile(modelPath, inputPath, outputPath string, opts ScoreOptions) error {
+	loadOpts := []LoadOption{WithTreeLimit(opts.TreeLimit)}
+	if opts.Objective != "" {
+		loadOpts = append(loadOpts, WithObjective(opts.Objective))
+	}
+	ensemble, err := LoadXGBoostModel(modelPath, opts.FeatureMapPath, opts.NumClasses, opts.MaxDepth,
+		opts.Activation, loadOpts...)
+	if err != nil {
+		return fmt.Errorf("cannot load model %s: %w", modelPath, err)
+	}
+	if opts.OutputMargin {
+		ensemble.Activation = &activation.Raw{}
+	}
+
+	var predictions mat.Matrix
+	switch opts.InputFormat {
+	case InputLibsvm, "":
+		predictions, err = ensemble.PredictProbaFromLibsvm(inputPath)
+	case InputCSV:
+		delimiter := opts.Delimiter
+		if delimiter == "" {
+			delimiter = ","
+		}
+		var input mat.Matrix
+		input, err = mat.ReadCSVFileToDenseMatrix(inputPath, delimiter, math.NaN())
+		if err != nil {
+			return fmt.Errorf("cannot read %s: %w", inputPath, err)
+		}
+		predictions, err = ensemble.PredictProbaDense(input)
+	default:
+		return fmt.Errorf("unknown input format %q", opts.InputFormat)
+	}
+	if err != nil {
+		return err
+	}
+
+	var write func(m *mat.Matrix, f *os.File) error
+	switch opts.OutputFormat {
+	case OutputText, "":
+		write = func(m *mat.Matrix, f *os.File) error {
+			return mat.WriteMatrixToCSV(m, f, "\t")
+		}
+	case OutputJSONL:
+		write = func(m *mat.Matrix, f *os.File) error {
+			return mat.WriteMatrixToJSONL(m, f)
+		}
+	default:
+		return fmt.Errorf("unknown output format %q", opts.OutputFormat)
+	}
+	f, err := os.Create(outputPath)
+	if err != nil {
+		return err
+	}
+	if err := write(&predictions, f); err != nil {
+		f.Close()
+		return fmt.Errorf("cannot write predictions to %s: %w", outputPath, err)
+	}
+	return f.Close()
+}
@@ -0,0 +1,303 @@
+package xgboost
+
+import (
+	"fmt"
+
+	"github.com/lordberre/xgboost-go/mat"
+)
+
+// pathElement is an element of the unique feature path used by TreeSHAP.
+type pathElement struct {
+	featureIndex int
+	zeroFraction float64
+	oneFraction  float64
+	pweight      float64
+}
+
+// extendPath extends the unique path with a new feature split.
+func extendPath(path []pathElement, uniqueDepth int, zeroFraction, oneFraction float64, featureIndex int) {
+	path[uniqueDepth] = pathElement{
+		featureIndex: featureIndex,
+		zeroFraction: zeroFraction,
+		oneFraction:  oneFraction,
+	}
+	if uniqueDepth == 0 {
+		path[uniqueDepth].pweight = 1.0
+	}
+	for i := uniqueDepth - 1; i >= 0; i-- {
+		path[i+1].pweight += oneFraction * path[i].pweight * float64(i+1) / float64(uniqueDepth+1)
+		path[i].pweight = zeroFraction * path[i].pweight * float64(uniqueDepth-i) / float64(uniqueDepth+1)
+	}
+}
+
+// unwindPath undoes a previous extendPath call for the element at pathIndex.
+func unwindPath(path []pathElement, uniqueDepth, pathIndex int) {
+	oneFraction := path[pathIndex].oneFraction
+	zeroFraction := path[pathIndex].zeroFraction
+	nextOnePortion := path[uniqueDepth].pweight
+
+	for i := uniqueDepth - 1; i >= 0; i-- {
+		if oneFraction != 0 {
+			tmp := path[i].pweight
+			path[i].pweight = nextOnePortion * float64(uniqueDepth+1) / (float64(i+1) * oneFraction)
+			nextOnePortion = tmp - path[i].pweight*zeroFraction*float64(uniqueDepth-i)/float64(uniqueDepth+1)
+		} else {
+			path[i].pweight = path[i].pweight * float64(uniqueDepth+1) / (zeroFraction * float64(uniqueDepth-i))
+		}
+	}
+	for i := pathIndex; i < uniqueDepth; i++ {
+		path[i].featureIndex = path[i+1].featureIndex
+		path[i].zeroFraction = path[i+1].zeroFraction
+		path[i].oneFraction = path[i+1].oneFraction
+	}
+}
+
+// unwoundPathSum returns the total permutation weight if the element at pathIndex was unwound.
+func unwoundPathSum(path []pathElement, uniqueDepth, pathIndex int) float64 {
+	oneFraction := path[pathIndex].oneFraction
+	zeroFraction := path[pathIndex].zeroFraction
+	nextOnePortion := path[uniqueDepth].pweight
+	total := 0.0
+
+	for i := uniqueDepth - 1; i >= 0; i-- {
+		if oneFraction != 0 {
+			tmp := nextOnePortion * float64(uniqueDepth+1) / (float64(i+1) * oneFraction)
+			total += tmp
+			nextOnePortion = path[i].pweight - tmp*zeroFraction*float64(uniqueDepth-i)/float64(uniqueDepth+1)
+		} else if zeroFraction != 0 {
+			total += path[i].pweight / zeroFraction / (float64(uniqueDepth-i) / float64(uniqueDepth+1))
+		}
+	}
+	return total
+}
+
+// treeShap recursively computes SHAP values of the tree, this is a port of the TreeSHAP algorithm used by
+// XGBoost. condition is 0 for plain SHAP values, 1 when conditionFeature is fixed to be present and -1 when it
+// is fixed to be missing, the latter two are used to derive interaction values.
+func (t *xgbTree) treeShap(features featureLookup, phi []float64, nodeIdx, uniqueDepth int,
+	parentPath []pathElement, parentZeroFraction, parentOneFraction float64, parentFeatureIndex int,
+	condition, conditionFeature int, conditionFraction float64) {
+	// stop if we have no weight coming down to us.
+	if conditionFraction == 0 {
+		return
+	}
+	node := t.nodes[nodeIdx]
+
+	// extend the unique path.
+	path := make([]pathElement, uniqueDepth+1)
+	copy(path, parentPath)
+	if condition == 0 || conditionFeature != parentFeatureIndex {
+		extendPath(path, uniqueDepth, parentZeroFraction, parentOneFraction, parentFeatureIndex)
+	}
+
+	if node.Flags&isLeaf > 0 {
+		for i := 1; i <= uniqueDepth; i++ {
+			w := unwoundPathSum(path, uniqueDepth, i)
+			el := path[i]
+			phi[el.featureIndex] += w * (el.oneFraction - el.zeroFraction) * node.LeafValues * conditionFraction
+		}
+		return
+	}
+
+	v, ok := features(node.Feature)
+	hotIdx := t.next(nodeIdx, v, ok)
+	coldIdx := node.Yes
+	if hotIdx == node.Yes {
+		coldIdx = node.No
+	}
+	hotZeroFraction := t.nodes[hotIdx].Cover / node.Cover
+	coldZeroFraction := t.nodes[coldIdx].Cover / node.Cover
+	incomingZeroFraction := 1.0
+	incomingOneFraction := 1.0
+
+	// see if we have already split on this feature, if so we undo that split so we can redo it for this node.
+	pathIndex := 0
+	for ; pathIndex <= uniqueDepth; pathIndex++ {
+		if path[pathIndex].featureIndex == node.Feature {
+			break
+		}
+	}
+	if pathIndex != uniqueDepth+1 {
+		incomingZeroFraction = path[pathIndex].zeroFraction
+		incomingOneFraction = path[pathIndex].oneFraction
+		unwindPath(path, uniqueDepth, pathIndex)
+		uniqueDepth--
+	}
+
+	// divide up the condition fraction among the recursive calls.
+	hotConditionFraction := conditionFraction
+	coldConditionFraction := conditionFraction
+	if condition > 0 && node.Feature == conditionFeature {
+		coldConditionFraction = 0
+		uniqueDepth--
+	} else if condition < 0 && node.Feature == conditionFeature {
+		hotConditionFraction *= hotZeroFraction
+		coldConditionFraction *= coldZeroFraction
+		uniqueDepth--
+	}
+
+	t.treeShap(features, phi, hotIdx, uniqueDepth+1, path, hotZeroFraction*incomingZeroFraction,
+		incomingOneFraction, node.Feature, condition, conditionFeature, hotConditionFraction)
+	t.treeShap(features, phi, coldIdx, uniqueDepth+1, path, coldZeroFraction*incomingZeroFraction,
+		0, node.Feature, condition, conditionFeature, coldConditionFraction)
+}
+
+// contributions adds SHAP values of the tree to phi, the last element of phi is the bias term.
+func (t *xgbTree) contributions(features featureLookup, phi []float64, condition, conditionFeature int) error {
+	if t.nodeMeans == nil {
+		return fmt.Errorf("tree has no cover statistics, please dump the model with with_stats=True")
+	}
+	if condition == 0 {
+		phi[len(phi)-1] += t.nodeMeans[0]
+	}
+	if t.nodes[0].Flags&isLeaf > 0 {
+		return nil
+	}
+	t.treeShap(features, phi, 0, 0, nil, 1, 1, -1, condition, conditionFeature, 1)
+	return nil
+}
+
+// classContributions returns SHAP values of the trees of class with numFeat + 1 elements, the last one being
+// the bias.
+func (e *xgbEnsemble) classContributions(
+	features featureLookup, class, condition, conditionFeature int) (mat.Vector, error) {
+	phi := make(mat.Vector, e.numFeat+1)
+	if condition == 0 {
+		phi[e.numFeat] = e.baseMargin
+	}
+	for i := class; i < len(e.Trees); i += e.numClasses {
+		if err := e.Trees[i].contributions(features, phi, condition, conditionFeature); err != nil {
+			return mat.Vector{}, fmt.Errorf("error while calculating contributions of %d tree: %s", i, err)
+		}
+	}
+	return phi, nil
+}
+
+// contributions returns SHAP values of a single output ensemble with numFeat + 1 elements, the last one being
+// the bias.
+func (e *xgbEnsemble) contributions(
+	features mat.SparseVector, condition, conditionFeature int) (mat.Vector, error) {
+	if e.numClasses != 1 {
+		return mat.Vector{}, fmt.Errorf("feature contributions only support single output models, got %d classes",
+			e.numClasses)
+	}
+	lookup, err := e.lookup(features)
+	if err != nil {
+		return mat.Vector{}, err
+	}
+	return e.classContributions(lookup, 0, condition, conditionFeature)
+}
+
+// PredictContributionsInner returns SHAP values of every class, numFeat + 1 values per class with the bias last.
+// Values of class k are at [k*(numFeat+1), (k+1)*(numFeat+1)).
+func (e *xgbEnsemble) PredictContributionsInner(features mat.SparseVector) (mat.Vector, error) {
+	lookup, err := e.lookup(features)
+	if err != nil {
+		return mat.Vector{}, err
+	}
+	r := make(mat.Vector, 0, e.numClasses*(e.numFeat+1))
+	for c := 0; c < e.numClasses; c++ {
+		phi, err := e.classContributions(lookup, c, 0, 0)
+		if err != nil {
+			return mat.Vector{}, err
+		}
+		r = append(r, phi...)
+	}
+	return r, nil
+}
+
+// classInteractions appends rows of the SHAP interaction values of the trees of class to result.
+func (e *xgbEnsemble) classInteractions(features featureLookup, class int, result *mat.Matrix) error {
+	diag, err := e.classContributions(features, class, 0, 0)
+	if err != nil {
+		return err
+	}
+	n := len(diag)
+	rows := make([]*mat.Vector, n)
+	for i := 0; i < n; i++ {
+		row := make(mat.Vector, n)
+		row[i] = diag[i]
+		rows[i] = &row
+	}
+	for i := 0; i < e.numFeat; i++ {
+		on, err := e.classContributions(features, class, 1, i)
+		if err != nil {
+			return err
+		}
+		off, err := e.classContributions(features, class, -1, i)
+		if err != nil {
+			return err
+		}
+		row := *rows[i]
+		for j := 0; j < n; j++ {
+			if j == i {
+				continue
+			}
+			row[j] = (on[j] - off[j]) / 2.0
+			row[i] -= row[j]
+		}
+	}
+	result.Vectors = append(result.Vectors, rows...)
+	return nil
+}
+
+// PredictInteractionsInner returns SHAP interaction values as a (numFeat+1)x(numFeat+1) matrix per class, with
+// the last row and column for the bias term, the matrices of the classes follow each other. Each row sums to the
+// SHAP value of the corresponding feature.
+func (e *xgbEnsemble) PredictInteractionsInner(features mat.SparseVector) (mat.Matrix, error) {
+	lookup, err := e.lookup(features)
+	if err != nil {
+		return mat.Matrix{}, err
+	}
+	result := mat.Matrix{Vectors: make([]*mat.Vector, 0, e.numClasses*(e.numFeat+1))}
+	for c := 0; c < e.numClasses; c++ {
+		if err := e.classInteractions(lookup, c, &result); err != nil {
+			return mat.Matrix{}, err
+		}
+	}
+	return result, nil
+}
+
+// approxContributions adds Saabas contributions of the tree to phi: each split on the decision path attributes
+// the change of the expected value of the tree to its feature. The last element of phi is the bias term.
+func (t *xgbTree) approxContributions(features featureLookup, phi []float64) error {
+	if t.nodeMeans == nil {
+		return fmt.Errorf("tree has no cover statistics, please dump the model with with_stats=True")
+	}
+	phi[len(phi)-1] += t.nodeMeans[0]
+	idx := 0
+	for {
+		node := t.nodes[idx]
+		if node == nil {
+			return fmt.Errorf("%w: nil node", ErrMalformedModel)
+		}
+		if node.Flags&isLeaf > 0 {
+			return nil
+		}
+		v, ok := features(node.Feature)
+		next := t.next(idx, v, ok)
+		phi[node.Feature] += t.nodeMeans[next] - t.nodeMeans[idx]
+		idx = next
+	}
+}
+
+// PredictApproxContributionsInner returns Saabas contributions of every class with the same layout as
+// PredictContributionsInner.
+func (e *xgbEnsemble) PredictApproxContributionsInner(features mat.SparseVector) (mat.Vector, error) {
+	lookup, err := e.lookup(features)
+	if err != nil {
+		return mat.Vector{}, err
+	}
+	stride := e.numFeat + 1
+	r := make(mat.Vector, e.numClasses*stride)
+	for c := 0; c < e.numClasses; c++ {
+		r[c*stride+e.numFeat] = e.baseMargin
+	}
+	for i, t := range e.Trees {
+		c := i % e.numClasses
+		if err := t.approxContributions(lookup, r[c*stride:(c+1)*stride]); err != nil {
+			return mat.Vector{}, fmt.Errorf("error while calculating contributions of %d tree: %s", i, err)
+		}
+	}
+	return r, nil
+}
@@ -2,15 +2,25 @@ package xgboost
 
 import (
 	"fmt"
+	"math"
+	"sort"
 
 	"github.com/lordberre/xgboost-go/mat"
 )
 
 // xgbtree constant values.
 const (
-	isLeaf = 1
+	isLeaf        = 1
+	isCategorical = 2
+	// isZeroMissing nodes send zero values to the missing child like LightGBM zero_as_missing splits.
+	isZeroMissing = 4
+	// isNilNode marks the nil nodes of malformed models in flat trees.
+	isNilNode = 8
 )
 
+// zeroThreshold is the absolute value under which LightGBM considers a feature value to be zero.
+const zeroThreshold = 1e-35
+
 type xgbNode struct {
 	NodeID     int
 	Threshold  float64
@@ -20,30 +30,326 @@ type xgbNode struct {
 	Feature    int
 	Flags      uint8
 	LeafValues float64
+	Cover      float64
+	// Gain is the loss reduction of split nodes, 0 if the model does not contain it.
+	Gain float64
+	// Categories contains categories going to the yes child for categorical splits.
+	Categories map[int]struct{}
+}
+
+// flatTree is the struct of arrays layout of a tree used to predict. Arrays are indexed by node position so that
+// traversals read a few contiguous values per split instead of chasing a pointer to each node.
+type flatTree struct {
+	features   []int32
+	thresholds []float64
+	yes        []int32
+	no         []int32
+	missing    []int32
+	flags      []uint8
+	values     []float64
+	// thresholds32 and values32 replace thresholds and values, which are then nil, for trees storing float32
+	// values.
+	thresholds32 []float32
+	values32     []float32
+	// bins replace the thresholds of quantized trees, they index the sorted thresholds of the split feature in
+	// cuts, which is shared by the trees of the ensemble.
+	bins []uint16
+	cuts [][]float64
+	// categories contains the categories going to the yes child of categorical splits, nil if there is none.
+	categories []map[int]struct{}
+}
+
+// value returns the value of leaf idx.
+func (f *flatTree) value(idx int) float64 {
+	if f.values32 != nil {
+		return float64(f.values32[idx])
+	}
+	return f.values[idx]
 }
 
 type xgbTree struct {
+	// nodes contains the nodes as loaded, with the statistics used by explanations and exports.
 	nodes []*xgbNode
+	// flat is the layout of nodes used to predict, filled by flatten once nodes are final.
+	flat flatTree
+	// float32Comparison compares feature values and thresholds in float32 like XGBoost does.
+	float32Comparison bool
+	// float32Values stores thresholds and leaf values of the flat layout as float32, which implies float32
+	// comparisons.
+	float32Values bool
+	// nodeMeans is the cover weighted mean of leaf values below each node, only available
+	// when the model was dumped with statistics.
+	nodeMeans []float64
+}
+
+// maxCategory is the exclusive upper bound of categories, XGBoost stores feature values as float32 which represent
+// every integer exactly up to 2^24.
+const maxCategory = 1 << 24
+
+// next returns the child index of split node idx for feature value v, ok is false when the value is missing.
+// Like XGBoost the yes (left) child is taken when v < threshold, so values equal to the threshold go to the
+// no (right) child, and missing values follow the default direction given by the missing child.
+// For categorical splits the yes child is taken when the integer part of v is one of the split categories.
+func (t *xgbTree) next(idx int, v float64, ok bool) int {
+	f := &t.flat
+	if !ok {
+		// missing value will be represented as NaN value.
+		return int(f.missing[idx])
+	}
+	flags := f.flags[idx]
+	if flags&isZeroMissing > 0 && v >= -zeroThreshold && v <= zeroThreshold {
+		return int(f.missing[idx])
+	}
+	if flags&isCategorical > 0 {
+		// like XGBoost values are truncated to integer categories, negative values and values past
+		// maxCategory are not valid categories and are never part of the split set.
+		if v >= 0 && v < maxCategory {
+			if _, in := f.categories[idx][int(v)]; in {
+				return int(f.yes[idx])
+			}
+		}
+		return int(f.no[idx])
+	}
+	if f.bins != nil {
+		if t.float32Comparison || t.float32Values {
+			v = float64(float32(v))
+		}
+		if v >= f.cuts[f.features[idx]][f.bins[idx]] {
+			return int(f.no[idx])
+		}
+		return int(f.yes[idx])
+	}
+	if f.thresholds32 != nil {
+		if float32(v) >= f.thresholds32[idx] {
+			return int(f.no[idx])
+		}
+		return int(f.yes[idx])
+	}
+	if t.float32Comparison {
+		if float32(v) >= float32(f.thresholds[idx]) {
+			return int(f.no[idx])
+		}
+		return int(f.yes[idx])
+	}
+	if v >= f.thresholds[idx] {
+		return int(f.no[idx])
+	}
+	return int(f.yes[idx])
+}
+
+// featureLookup returns the value of a feature and false if it is missing.
+type featureLookup func(idx int) (float64, bool)
+
+// sparseLookup treats absent features as missing, as well as features equal to missing if it is not nil.
+func sparseLookup(features mat.SparseVector, missing *float64) featureLookup {
+	return func(idx int) (float64, bool) {
+		v, ok := features[idx]
+		if ok && missing != nil && v == *missing {
+			return 0, false
+		}
+		return v, ok
+	}
+}
+
+// zeroSparseLookup treats absent features as zeros like LightGBM does, NaN values are missing as well as features
+// equal to missing if it is not nil.
+func zeroSparseLookup(features mat.SparseVector, missing *float64) featureLookup {
+	return func(idx int) (float64, bool) {
+		v := features[idx]
+		if math.IsNaN(v) || (missing != nil && v == *missing) {
+			return 0, false
+		}
+		return v, true
+	}
+}
+
+// shiftedLookup reads feature idx at index idx+base of the row, for rows whose feature indices start from base.
+func shiftedLookup(features featureLookup, base int) featureLookup {
+	if base == 0 {
+		return features
+	}
+	return func(idx int) (float64, bool) {
+		return features(idx + base)
+	}
+}
+
+// csrLookup treats features absent from a CSR row, whose indices are sorted, like sparseLookup. Values are
+// found by binary search in the contiguous indices of the row.
+func csrLookup(indices []int, values []float64, missing *float64) featureLookup {
+	return func(idx int) (float64, bool) {
+		i := sort.SearchInts(indices, idx)
+		if i == len(indices) || indices[i] != idx || (missing != nil && values[i] == *missing) {
+			return 0, false
+		}
+		return values[i], true
+	}
+}
+
+// zeroCSRLookup treats features absent from a CSR row as zeros like zeroSparseLookup.
+func zeroCSRLookup(indices []int, values []float64, missing *float64) featureLookup {
+	return func(idx int) (float64, bool) {
+		v := 0.0
+		if i := sort.SearchInts(indices, idx); i < len(indices) && indices[i] == idx {
+			v = values[i]
+		}
+		if math.IsNaN(v) || (missing != nil && v == *missing) {
+			return 0, false
+		}
+		return v, true
+	}
 }
 
-func (t *xgbTree) predict(features mat.SparseVector) (float64, error) {
+// dense32Lookup treats float32 features like denseLookup.
+func dense32Lookup(features []float32, missing *float64) featureLookup {
+	return func(idx int) (float64, bool) {
+		if idx >= len(features) {
+			return 0, false
+		}
+		v := float64(features[idx])
+		if math.IsNaN(v) || (missing != nil && v == *missing) {
+			return 0, false
+		}
+		return v, true
+	}
+}
+
+// denseLookup treats NaN and out of range features as missing, as well as features equal to missing if it is not
+// nil. Otherwise 0 is a real value.
+func denseLookup(features mat.Vector, missing *float64) featureLookup {
+	return func(idx int) (float64, bool) {
+		if idx >= len(features) || math.IsNaN(features[idx]) {
+			return 0, false
+		}
+		if missing != nil && features[idx] == *missing {
+			return 0, false
+		}
+		return features[idx], true
+	}
+}
+
+func (t *xgbTree) predict(features featureLookup) (float64, error) {
+	idx, _, err := t.leaf(features)
+	if err != nil {
+		return 0, err
+	}
+	return t.flat.value(idx), nil
+}
+
+// leaf returns the index of the leaf reached by features and the number of visited nodes.
+func (t *xgbTree) leaf(features featureLookup) (int, int, error) {
+	f := &t.flat
 	idx := 0
-	for {
-		node := t.nodes[idx]
-		if node == nil {
-			return 0, fmt.Errorf("nil node")
+	for visited := 1; ; visited++ {
+		flags := f.flags[idx]
+		if flags&(isLeaf|isNilNode) > 0 {
+			if flags&isNilNode > 0 {
+				return 0, visited, fmt.Errorf("%w: nil node", ErrMalformedModel)
+			}
+			return idx, visited, nil
 		}
-		if node.Flags&isLeaf > 0 {
-			return node.LeafValues, nil
+		v, ok := features(int(f.features[idx]))
+		if flags == 0 && ok && f.thresholds != nil && !t.float32Comparison {
+			// plain numerical splits are inlined as they are the vast majority of nodes.
+			if v >= f.thresholds[idx] {
+				idx = int(f.no[idx])
+			} else {
+				idx = int(f.yes[idx])
+			}
+			continue
 		}
-		v, ok := features[node.Feature]
-		if !ok {
-			// missing value will be represented as NaN value.
-			idx = node.Missing
-		} else if v >= node.Threshold {
-			idx = node.No
+		idx = t.next(idx, v, ok)
+	}
+}
+
+// flatten fills the flat layout from nodes, it must be called again if nodes are modified.
+func (t *xgbTree) flatten() {
+	n := len(t.nodes)
+	f := flatTree{
+		features: make([]int32, n),
+		yes:      make([]int32, n),
+		no:       make([]int32, n),
+		missing:  make([]int32, n),
+		flags:    make([]uint8, n),
+	}
+	if t.float32Values {
+		f.thresholds32, f.values32 = make([]float32, n), make([]float32, n)
+	} else {
+		f.thresholds, f.values = make([]float64, n), make([]float64, n)
+	}
+	for i, node := range t.nodes {
+		if node == nil {
+			f.flags[i] = isNilNode
+			continue
+		}
+		f.features[i] = int32(node.Feature)
+		f.yes[i] = int32(node.Yes)
+		f.no[i] = int32(node.No)
+		f.missing[i] = int32(node.Missing)
+		f.flags[i] = node.Flags
+		if t.float32Values {
+			f.thresholds32[i], f.values32[i] = float32(node.Threshold), float32(node.LeafValues)
 		} else {
-			idx = node.Yes
+			f.thresholds[i], f.values[i] = node.Threshold, node.LeafValues
+		}
+		if node.Flags&isCategorical > 0 {
+			if f.categories == nil {
+				f.categories = make([]map[int]struct{}, n)
+			}
+			f.categories[i] = node.Categories
+		}
+	}
+	t.flat = f
+}
+
+// validate returns an error if a leaf value or split threshold of the tree is NaN or infinite.
+func (t *xgbTree) validate() error {
+	for i, n := range t.nodes {
+		if n == nil {
+			continue
+		}
+		if n.Flags&isLeaf > 0 {
+			if math.IsNaN(n.LeafValues) || math.IsInf(n.LeafValues, 0) {
+				return fmt.Errorf("%w: node %d has %v leaf value", ErrMalformedModel, i, n.LeafValues)
+			}
+		} else if n.Flags&isCategorical == 0 && (math.IsNaN(n.Threshold) || math.IsInf(n.Threshold, 0)) {
+			return fmt.Errorf("%w: node %d has %v split threshold", ErrMalformedModel, i, n.Threshold)
+		}
+	}
+	return nil
+}
+
+// addToLeaves adds v to every leaf value of the tree.
+func (t *xgbTree) addToLeaves(v float64) {
+	for _, n := range t.nodes {
+		if n != nil && n.Flags&isLeaf > 0 {
+			n.LeafValues += v
+		}
+	}
+	for i := range t.nodeMeans {
+		t.nodeMeans[i] += v
+	}
+}
+
+// computeNodeMeans fills nodeMeans if every split node has cover statistics.
+func (t *xgbTree) computeNodeMeans() {
+	for _, n := range t.nodes {
+		if n == nil || (n.Flags&isLeaf == 0 && n.Cover <= 0) {
+			return
 		}
 	}
+	t.nodeMeans = make([]float64, len(t.nodes))
+	t.fillNodeMean(0)
+}
+
+func (t *xgbTree) fillNodeMean(idx int) float64 {
+	node := t.nodes[idx]
+	var r float64
+	if node.Flags&isLeaf > 0 {
+		r = node.LeafValues
+	} else {
+		yes, no := t.nodes[node.Yes], t.nodes[node.No]
+		r = (t.fillNodeMean(node.Yes)*yes.Cover + t.fillNodeMean(node.No)*no.Cover) / node.Cover
+	}
+	t.nodeMeans[idx] = r
+	return r
 }
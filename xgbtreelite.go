@@ -0,0 +1,405 @@
+package xgboost
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/lordberre/xgboost-go/activation"
+	"github.com/lordberre/xgboost-go/inference"
+)
+
+// Values of the enums of Treelite 4 checkpoints, they are written as raw little endian C++ values like the legacy
+// XGBoost binary format.
+const (
+	treeliteVersion     = 4
+	treeliteFloat32     = 2
+	treeliteFloat64     = 3
+	treeliteLeafNode    = 0
+	treeliteNumerical   = 1
+	treeliteCategorical = 2
+	treeliteOpLT        = 2
+	treeliteOpLE        = 3
+	treeliteOpGT        = 4
+	treeliteOpGE        = 5
+)
+
+func (b *binaryReader) uint64() uint64 {
+	buf := b.read(8)
+	if buf == nil {
+		return 0
+	}
+	return binary.LittleEndian.Uint64(buf)
+}
+
+// array reads a Treelite array, i.e. its length followed by its elements, and calls f on each element.
+func (b *binaryReader) array(elemSize int, f func([]byte)) int {
+	n := b.uint64()
+	i := uint64(0)
+	for ; i < n && b.err == nil; i++ {
+		if buf := b.read(elemSize); buf != nil {
+			f(buf)
+		}
+	}
+	return int(i)
+}
+
+func (b *binaryReader) int32s() []int {
+	var r []int
+	b.array(4, func(buf []byte) { r = append(r, int(int32(binary.LittleEndian.Uint32(buf)))) })
+	return r
+}
+
+func (b *binaryReader) bools() []bool {
+	var r []bool
+	b.array(1, func(buf []byte) { r = append(r, buf[0] != 0) })
+	return r
+}
+
+func (b *binaryReader) uint64s() []uint64 {
+	var r []uint64
+	b.array(8, func(buf []byte) { r = append(r, binary.LittleEndian.Uint64(buf)) })
+	return r
+}
+
+// treeliteSize returns the size in bytes of a float type.
+func treeliteSize(typeInfo byte) int {
+	if typeInfo == treeliteFloat32 {
+		return 4
+	}
+	return 8
+}
+
+// floats reads an array of float32 or float64 values depending on typeInfo.
+func (b *binaryReader) floats(typeInfo byte) []float64 {
+	var r []float64
+	if typeInfo == treeliteFloat32 {
+		b.array(4, func(buf []byte) { r = append(r, float64(math.Float32frombits(binary.LittleEndian.Uint32(buf)))) })
+	} else {
+		b.array(8, func(buf []byte) { r = append(r, math.Float64frombits(binary.LittleEndian.Uint64(buf))) })
+	}
+	return r
+}
+
+// skipOptFields skips the optional fields of the extension slots of checkpoints, each field is its name, the
+// size of its elements and its elements.
+func (b *binaryReader) skipOptFields() {
+	n := b.int32()
+	for i := int32(0); i < n && b.err == nil; i++ {
+		b.array(1, func([]byte) {})
+		elemSize := b.uint64()
+		if elemSize > 8 {
+			b.err = fmt.Errorf("invalid optional field element size %d", elemSize)
+			return
+		}
+		b.array(int(elemSize), func([]byte) {})
+	}
+}
+
+// treeliteModel is the header of a Treelite checkpoint.
+type treeliteModel struct {
+	thresholdType, leafType byte
+	numTrees                uint64
+	numFeature              int
+	averageTreeOutput       bool
+	numTarget               int
+	numClass                []int
+	leafVectorShape         []int
+	targetID, classID       []int
+	postprocessor           string
+	sigmoidAlpha            float64
+	baseScores              []float64
+}
+
+// readTreeliteHeader reads the header of a Treelite checkpoint.
+func readTreeliteHeader(b *binaryReader) (*treeliteModel, error) {
+	major, minor, patch := b.int32(), b.int32(), b.int32()
+	if b.err != nil {
+		return nil, b.err
+	}
+	if major != treeliteVersion {
+		return nil, fmt.Errorf("%w: treelite checkpoint version %d.%d.%d is not supported, expected %d.x",
+			ErrUnsupportedFormat, major, minor, patch, treeliteVersion)
+	}
+	m := &treeliteModel{}
+	types := b.read(2)
+	if types == nil {
+		return nil, b.err
+	}
+	m.thresholdType, m.leafType = types[0], types[1]
+	for _, t := range types {
+		if t != treeliteFloat32 && t != treeliteFloat64 {
+			return nil, fmt.Errorf("%w: treelite type %d is not supported", ErrUnsupportedFormat, t)
+		}
+	}
+	m.numTrees = b.uint64()
+	m.numFeature = int(b.int32())
+	// task type is not needed, the postprocessor gives the activation.
+	b.read(1)
+	if flag := b.read(1); flag != nil {
+		m.averageTreeOutput = flag[0] != 0
+	}
+	m.numTarget = int(b.int32())
+	m.numClass = b.int32s()
+	m.leafVectorShape = b.int32s()
+	m.targetID = b.int32s()
+	m.classID = b.int32s()
+	var postprocessor []byte
+	b.array(1, func(buf []byte) { postprocessor = append(postprocessor, buf[0]) })
+	m.postprocessor = string(postprocessor)
+	m.sigmoidAlpha = b.float32()
+	// ratio_c is only used by isolation forests.
+	b.float32()
+	m.baseScores = b.floats(treeliteFloat64)
+	// attributes are a json document of frontend specific values.
+	b.array(1, func([]byte) {})
+	b.skipOptFields()
+	if b.err != nil {
+		return nil, b.err
+	}
+	return m, nil
+}
+
+// readTreeliteTree reads a tree of a Treelite checkpoint, maxFeat is the largest split feature index.
+func readTreeliteTree(b *binaryReader, m *treeliteModel) (*xgbTree, int, error) {
+	numNodes := int(b.int32())
+	// has_categorical_split is recomputed from node types.
+	b.read(1)
+	var nodeTypes, ops []byte
+	b.array(1, func(buf []byte) { nodeTypes = append(nodeTypes, buf[0]) })
+	left, right, features := b.int32s(), b.int32s(), b.int32s()
+	defaultLeft := b.bools()
+	leafValues := b.floats(m.leafType)
+	thresholds := b.floats(m.thresholdType)
+	b.array(1, func(buf []byte) { ops = append(ops, buf[0]) })
+	categoriesRight := b.bools()
+	if n := b.array(treeliteSize(m.leafType), func([]byte) {}); n > 0 {
+		return nil, 0, fmt.Errorf("%w: leaf vectors are not supported", ErrUnsupportedFormat)
+	}
+	b.uint64s()
+	b.uint64s()
+	var categories []int
+	b.array(4, func(buf []byte) { categories = append(categories, int(binary.LittleEndian.Uint32(buf))) })
+	categoriesBegin, categoriesEnd := b.uint64s(), b.uint64s()
+	// data counts, hessian sums and gains are each followed by presence flags.
+	dataCounts, dataCountPresent := b.uint64s(), b.bools()
+	hessians, hessianPresent := b.floats(treeliteFloat64), b.bools()
+	b.floats(treeliteFloat64)
+	b.bools()
+	b.skipOptFields()
+	b.skipOptFields()
+	if b.err != nil {
+		return nil, 0, b.err
+	}
+	if numNodes <= 0 {
+		return nil, 0, fmt.Errorf("%w: invalid number of nodes %d", ErrMalformedModel, numNodes)
+	}
+	for _, n := range []int{len(nodeTypes), len(left), len(right), len(features), len(defaultLeft),
+		len(leafValues), len(thresholds), len(ops), len(categoriesRight), len(categoriesBegin),
+		len(categoriesEnd)} {
+		if n != numNodes {
+			return nil, 0, fmt.Errorf("%w: node array of size %d for %d nodes", ErrMalformedModel, n, numNodes)
+		}
+	}
+
+	maxFeat := 0
+	t := &xgbTree{nodes: make([]*xgbNode, numNodes), float32Comparison: m.thresholdType == treeliteFloat32}
+	for i := range t.nodes {
+		n := &xgbNode{NodeID: i}
+		t.nodes[i] = n
+		if len(hessianPresent) == numNodes && len(hessians) == numNodes && hessianPresent[i] {
+			n.Cover = hessians[i]
+		} else if len(dataCountPresent) == numNodes && len(dataCounts) == numNodes && dataCountPresent[i] {
+			n.Cover = float64(dataCounts[i])
+		}
+		if nodeTypes[i] == treeliteLeafNode {
+			n.Flags = isLeaf
+			n.LeafValues = leafValues[i]
+			continue
+		}
+		if left[i] <= i || left[i] >= numNodes || right[i] <= i || right[i] >= numNodes {
+			return nil, 0, fmt.Errorf("%w: node %d has invalid children %d and %d", ErrMalformedModel, i, left[i],
+				right[i])
+		}
+		n.Feature = features[i]
+		if n.Feature < 0 {
+			return nil, 0, fmt.Errorf("%w: node %d has invalid feature %d", ErrMalformedModel, i, n.Feature)
+		}
+		if n.Feature > maxFeat {
+			maxFeat = n.Feature
+		}
+		n.Yes, n.No, n.Missing = left[i], right[i], right[i]
+		if defaultLeft[i] {
+			n.Missing = left[i]
+		}
+		switch nodeTypes[i] {
+		case treeliteNumerical:
+			// splits send values to the left child when the comparison is true.
+			next := math.Nextafter(thresholds[i], math.Inf(1))
+			if t.float32Comparison {
+				next = float64(math.Nextafter32(float32(thresholds[i]), float32(math.Inf(1))))
+			}
+			switch ops[i] {
+			case treeliteOpLT:
+				n.Threshold = thresholds[i]
+			case treeliteOpLE:
+				n.Threshold = next
+			case treeliteOpGE:
+				n.Threshold, n.Yes, n.No = thresholds[i], right[i], left[i]
+			case treeliteOpGT:
+				n.Threshold, n.Yes, n.No = next, right[i], left[i]
+			default:
+				return nil, 0, fmt.Errorf("%w: node %d has unsupported comparison operator %d", ErrUnsupportedFormat,
+					i, ops[i])
+			}
+		case treeliteCategorical:
+			begin, end := categoriesBegin[i], categoriesEnd[i]
+			if begin > end || end > uint64(len(categories)) {
+				return nil, 0, fmt.Errorf("%w: node %d has invalid category range [%d, %d)", ErrMalformedModel, i,
+					begin, end)
+			}
+			n.Flags = isCategorical
+			n.Categories = make(map[int]struct{}, end-begin)
+			for _, c := range categories[begin:end] {
+				n.Categories[c] = struct{}{}
+			}
+			if categoriesRight[i] {
+				n.Yes, n.No = right[i], left[i]
+			}
+		default:
+			return nil, 0, fmt.Errorf("%w: node %d has unknown type %d", ErrMalformedModel, i, nodeTypes[i])
+		}
+	}
+	t.computeNodeMeans()
+	return t, maxFeat, nil
+}
+
+// treeliteActivation returns the activation matching a Treelite postprocessor.
+func treeliteActivation(m *treeliteModel) (activation.Activation, error) {
+	switch m.postprocessor {
+	case "identity", "identity_multiclass":
+		return &activation.Raw{}, nil
+	case "sigmoid":
+		if m.sigmoidAlpha != 1 {
+			return nil, fmt.Errorf("%w: sigmoid_alpha %v is not supported", ErrUnsupportedFormat, m.sigmoidAlpha)
+		}
+		return &activation.Logistic{}, nil
+	case "softmax":
+		return &activation.Softmax{}, nil
+	case "exponential":
+		return &activation.Exp{}, nil
+	}
+	if a, err := activation.ByName(m.postprocessor); err == nil {
+		return a, nil
+	}
+	return nil, fmt.Errorf("%w postprocessor %s", activation.ErrUnsupportedObjective, m.postprocessor)
+}
+
+// loadTreelite loads a model from Treelite checkpoint content.
+func loadTreelite(r io.Reader, numClasses int, act activation.Activation, opts ...LoadOption) (*inference.Ensemble,
+	error) {
+	cfg := newLoadConfig(opts)
+	b := &binaryReader{r: bufio.NewReader(r)}
+	m, err := readTreeliteHeader(b)
+	if err != nil {
+		if errors.Is(err, ErrUnsupportedFormat) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("%w: %s", ErrMalformedModel, err)
+	}
+	if m.averageTreeOutput {
+		return nil, fmt.Errorf("%w: random forest models are not supported", ErrUnsupportedFormat)
+	}
+	if m.numTarget != 1 || len(m.numClass) != 1 || len(m.leafVectorShape) != 2 || m.leafVectorShape[0] != 1 ||
+		m.leafVectorShape[1] != 1 {
+		return nil, fmt.Errorf("%w: multi target models and leaf vectors are not supported", ErrUnsupportedFormat)
+	}
+	modelAct, err := treeliteActivation(m)
+	if err != nil && (act == nil || !errors.Is(err, activation.ErrUnsupportedObjective)) {
+		return nil, err
+	}
+	if act == nil {
+		act = modelAct
+	}
+
+	modelClasses := m.numClass[0]
+	if modelClasses < 1 {
+		return nil, fmt.Errorf("%w: invalid number of classes %d", ErrMalformedModel, modelClasses)
+	}
+	if numClasses > 0 && numClasses != modelClasses {
+		return nil, fmt.Errorf("model has %d classes, got %d", modelClasses, numClasses)
+	}
+	numClasses = modelClasses
+	if m.numTrees == 0 || m.numTrees%uint64(numClasses) != 0 {
+		return nil, fmt.Errorf("%w: wrong number of trees %d for number of class %d", ErrMalformedModel,
+			m.numTrees, numClasses)
+	}
+	numTrees := int(m.numTrees)
+	if len(m.targetID) != numTrees || len(m.classID) != numTrees || len(m.baseScores) != numClasses {
+		return nil, fmt.Errorf("%w: tree and class arrays do not match the number of trees and classes",
+			ErrMalformedModel)
+	}
+	// trees are kept in the XGBoost layout where tree i belongs to class i % numClasses.
+	for i := range m.classID {
+		if m.targetID[i] != 0 || m.classID[i] != i%numClasses {
+			return nil, fmt.Errorf("%w: tree %d of class %d is not in round robin class order", ErrUnsupportedFormat,
+				i, m.classID[i])
+		}
+	}
+	begin, end, err := cfg.iterationRange(numTrees / numClasses)
+	if err != nil {
+		return nil, err
+	}
+
+	e := &xgbEnsemble{name: "treelite", numClasses: numClasses, missing: cfg.missing, baseMargin: m.baseScores[0]}
+	e.Trees = make([]*xgbTree, 0, (end-begin)*numClasses)
+	maxFeat := m.numFeature - 1
+	for i := 0; i < end*numClasses; i++ {
+		tree, numFeat, err := readTreeliteTree(b, m)
+		if err != nil {
+			if !errors.Is(err, ErrUnsupportedFormat) && !errors.Is(err, ErrMalformedModel) {
+				err = fmt.Errorf("%w: %s", ErrMalformedModel, err)
+			}
+			return nil, fmt.Errorf("error while reading %d tree: %w", i, err)
+		}
+		if i < begin*numClasses {
+			continue
+		}
+		// the ensemble has a single base margin, the difference with the base score of other classes is added
+		// to their first tree.
+		if c := i % numClasses; i/numClasses == begin && m.baseScores[c] != e.baseMargin {
+			tree.addToLeaves(m.baseScores[c] - e.baseMargin)
+		}
+		if cfg.float32Comparison {
+			tree.float32Comparison = true
+		}
+		if cfg.validateValues {
+			if err := tree.validate(); err != nil {
+				return nil, fmt.Errorf("error while reading %d tree: %w", i, err)
+			}
+		}
+		e.Trees = append(e.Trees, tree)
+		if numFeat > maxFeat {
+			maxFeat = numFeat
+		}
+	}
+	e.numFeat = maxFeat + 1
+	return e.ensemble(&cfg, act)
+}
+
+// LoadTreelite loads a model saved as a Treelite 4 checkpoint with Model.serialize, e.g. a model converted from
+// XGBoost, LightGBM or scikit-learn by Treelite. Only gradient boosted models with one output per tree are
+// supported, trees must follow the XGBoost layout where tree i belongs to class i % number of classes. The
+// activation matching the postprocessor of the model, or registered under its name, is used if act is nil, act is
+// required for other postprocessors such as hinge. Thresholds stored in float32 are compared in float32.
+func LoadTreelite(modelPath string, act activation.Activation, opts ...LoadOption) (*inference.Ensemble, error) {
+	modelFile, _, err := openModelFile(modelPath)
+	if err != nil {
+		return nil, err
+	}
+	defer modelFile.Close()
+	return loadTreelite(modelFile, 0, act, opts...)
+}
@@ -0,0 +1,325 @@
+package xgboost
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+
+	"github.com/lordberre/xgboost-go/activation"
+	"github.com/lordberre/xgboost-go/inference"
+)
+
+// ubjMaxPrealloc bounds the capacity allocated from container counts so that corrupted counts fail on read
+// instead of allocating huge slices.
+const ubjMaxPrealloc = 1 << 16
+
+// ubjMaxNest is the maximum nesting depth of containers, XGBoost models are only nested a few levels deep.
+const ubjMaxNest = 64
+
+// ubjDecoder decodes the subset of UBJSON (https://ubjson.org) written by XGBoost. Objects are decoded to
+// map[string]interface{}, arrays to []interface{} or to []float64 when they are typed with a numeric type, and
+// scalars to int64, float64, string, bool or nil.
+type ubjDecoder struct {
+	r *bufio.Reader
+}
+
+func (d *ubjDecoder) readByte() (byte, error) {
+	b, err := d.r.ReadByte()
+	if err == io.EOF {
+		return 0, io.ErrUnexpectedEOF
+	}
+	return b, err
+}
+
+// readMarker returns the next type marker, skipping no-op markers.
+func (d *ubjDecoder) readMarker() (byte, error) {
+	for {
+		b, err := d.readByte()
+		if err != nil || b != 'N' {
+			return b, err
+		}
+	}
+}
+
+// readFull reads n bytes. Large lengths come from the input and may be corrupted, their buffer grows with the
+// bytes actually read so that a truncated input fails without allocating n bytes.
+func (d *ubjDecoder) readFull(n int) ([]byte, error) {
+	if n > ubjMaxPrealloc {
+		var buf bytes.Buffer
+		if read, err := io.CopyN(&buf, d.r, int64(n)); read < int64(n) {
+			if err == nil || err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+	buf := make([]byte, n)
+	if _, err := io.ReadFull(d.r, buf); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	return buf, nil
+}
+
+// readInt reads an integer value of the given type marker.
+func (d *ubjDecoder) readInt(marker byte) (int64, error) {
+	var size int
+	switch marker {
+	case 'i', 'U':
+		size = 1
+	case 'I':
+		size = 2
+	case 'l':
+		size = 4
+	case 'L':
+		size = 8
+	default:
+		return 0, fmt.Errorf("unexpected integer marker %q", marker)
+	}
+	b, err := d.readFull(size)
+	if err != nil {
+		return 0, err
+	}
+	switch marker {
+	case 'i':
+		return int64(int8(b[0])), nil
+	case 'U':
+		return int64(b[0]), nil
+	case 'I':
+		return int64(int16(binary.BigEndian.Uint16(b))), nil
+	case 'l':
+		return int64(int32(binary.BigEndian.Uint32(b))), nil
+	}
+	return int64(binary.BigEndian.Uint64(b)), nil
+}
+
+// readLength reads a non negative length, the length type marker is read first.
+func (d *ubjDecoder) readLength() (int, error) {
+	marker, err := d.readMarker()
+	if err != nil {
+		return 0, err
+	}
+	n, err := d.readInt(marker)
+	if err != nil {
+		return 0, err
+	}
+	if n < 0 || n > math.MaxInt32 {
+		return 0, fmt.Errorf("invalid length %d", n)
+	}
+	return int(n), nil
+}
+
+func (d *ubjDecoder) readString() (string, error) {
+	n, err := d.readLength()
+	if err != nil {
+		return "", err
+	}
+	b, err := d.readFull(n)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// readValue reads a value of the given type marker nested in depth containers.
+func (d *ubjDecoder) readValue(marker byte, depth int) (interface{}, error) {
+	switch marker {
+	case 'Z':
+		return nil, nil
+	case 'T':
+		return true, nil
+	case 'F':
+		return false, nil
+	case 'i', 'U', 'I', 'l', 'L':
+		return d.readInt(marker)
+	case 'd':
+		b, err := d.readFull(4)
+		if err != nil {
+			return nil, err
+		}
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(b))), nil
+	case 'D':
+		b, err := d.readFull(8)
+		if err != nil {
+			return nil, err
+		}
+		return math.Float64frombits(binary.BigEndian.Uint64(b)), nil
+	case 'H':
+		s, err := d.readString()
+		if err != nil {
+			return nil, err
+		}
+		return strconv.ParseFloat(s, 64)
+	case 'C':
+		b, err := d.readByte()
+		return string([]byte{b}), err
+	case 'S':
+		return d.readString()
+	case '[':
+		return d.readArray(depth + 1)
+	case '{':
+		return d.readObject(depth + 1)
+	}
+	return nil, fmt.Errorf("unexpected marker %q", marker)
+}
+
+// readContainerHeader reads the optional type and count of a container, count is -1 if the container is
+// terminated by an end marker.
+func (d *ubjDecoder) readContainerHeader() (byte, int, error) {
+	b, err := d.r.Peek(1)
+	if err != nil {
+		return 0, 0, io.ErrUnexpectedEOF
+	}
+	var elemType byte
+	if b[0] == '$' {
+		d.r.ReadByte()
+		if elemType, err = d.readByte(); err != nil {
+			return 0, 0, err
+		}
+		if b, err = d.r.Peek(1); err != nil || b[0] != '#' {
+			return 0, 0, fmt.Errorf("typed container without count")
+		}
+	}
+	if b[0] != '#' {
+		return 0, -1, nil
+	}
+	d.r.ReadByte()
+	n, err := d.readLength()
+	return elemType, n, err
+}
+
+// readArray reads an array nested in depth containers, counting itself.
+func (d *ubjDecoder) readArray(depth int) (interface{}, error) {
+	if depth > ubjMaxNest {
+		return nil, fmt.Errorf("containers are nested too deeply")
+	}
+	elemType, n, err := d.readContainerHeader()
+	if err != nil {
+		return nil, err
+	}
+	switch elemType {
+	case 'i', 'U', 'I', 'l', 'L', 'd', 'D':
+		r := make([]float64, 0, minInt(n, ubjMaxPrealloc))
+		for i := 0; i < n; i++ {
+			v, err := d.readValue(elemType, depth)
+			if err != nil {
+				return nil, err
+			}
+			if f, ok := v.(float64); ok {
+				r = append(r, f)
+			} else {
+				r = append(r, float64(v.(int64)))
+			}
+		}
+		return r, nil
+	}
+	r := make([]interface{}, 0, minInt(maxInt(n, 0), ubjMaxPrealloc))
+	for i := 0; n < 0 || i < n; i++ {
+		marker := elemType
+		if marker == 0 {
+			if marker, err = d.readMarker(); err != nil {
+				return nil, err
+			}
+			if n < 0 && marker == ']' {
+				break
+			}
+		}
+		v, err := d.readValue(marker, depth)
+		if err != nil {
+			return nil, err
+		}
+		r = append(r, v)
+	}
+	return r, nil
+}
+
+// readObject reads an object nested in depth containers, counting itself.
+func (d *ubjDecoder) readObject(depth int) (interface{}, error) {
+	if depth > ubjMaxNest {
+		return nil, fmt.Errorf("containers are nested too deeply")
+	}
+	elemType, n, err := d.readContainerHeader()
+	if err != nil {
+		return nil, err
+	}
+	r := make(map[string]interface{}, minInt(maxInt(n, 0), ubjMaxPrealloc))
+	for i := 0; n < 0 || i < n; i++ {
+		if n < 0 {
+			b, err := d.r.Peek(1)
+			if err != nil {
+				return nil, io.ErrUnexpectedEOF
+			}
+			if b[0] == '}' {
+				d.r.ReadByte()
+				break
+			}
+		}
+		key, err := d.readString()
+		if err != nil {
+			return nil, err
+		}
+		marker := elemType
+		if marker == 0 {
+			if marker, err = d.readMarker(); err != nil {
+				return nil, err
+			}
+		}
+		if r[key], err = d.readValue(marker, depth); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// loadXGBoostModelUBJ loads xgboost model from save_model UBJSON content.
+func loadXGBoostModelUBJ(
+	r io.Reader,
+	numClasses int,
+	act activation.Activation,
+	opts ...LoadOption) (*inference.Ensemble, error) {
+	br, ok := r.(*bufio.Reader)
+	if !ok {
+		br = bufio.NewReader(r)
+	}
+	d := &ubjDecoder{r: br}
+	marker, err := d.readMarker()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrMalformedModel, err)
+	}
+	if marker != '{' {
+		return nil, fmt.Errorf("%w: ubjson model must be an object", ErrMalformedModel)
+	}
+	doc, err := d.readObject(1)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrMalformedModel, err)
+	}
+	return buildModelEnsemble(doc.(map[string]interface{}), numClasses, act, opts...)
+}
+
+// LoadXGBoostFromUBJ loads xgboost model saved with Booster.save_model in UBJSON format, which is the default
+// format since XGBoost 1.6. Like LoadXGBoostFromModelJSON the number of classes, the objective and base_score
+// are read from the model and the activation matching the objective is used if act is nil.
+func LoadXGBoostFromUBJ(r io.Reader, act activation.Activation, opts ...LoadOption) (*inference.Ensemble, error) {
+	return loadXGBoostModelUBJ(r, 0, act, opts...)
+}